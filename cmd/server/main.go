@@ -17,6 +17,7 @@ import (
 
 	"github.com/joho/godotenv"
 	configaccess "github.com/router-for-me/CLIProxyAPI/v6/internal/access/config_access"
+	jwtaccess "github.com/router-for-me/CLIProxyAPI/v6/internal/access/jwt_access"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/buildinfo"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/cmd"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
@@ -67,6 +68,8 @@ func main() {
 	var vertexImport string
 	var configPath string
 	var password string
+	var validate bool
+	var encryptSecret string
 
 	// Define command-line flags for different operation modes.
 	flag.BoolVar(&login, "login", false, "Login Google Account")
@@ -82,6 +85,8 @@ func main() {
 	flag.StringVar(&configPath, "config", DefaultConfigPath, "Configure File Path")
 	flag.StringVar(&vertexImport, "vertex-import", "", "Import Vertex service account key JSON file")
 	flag.StringVar(&password, "password", "", "")
+	flag.BoolVar(&validate, "validate", false, "Validate the config file, print a report and exit (non-zero on errors)")
+	flag.StringVar(&encryptSecret, "encrypt-secret", "", "Encrypt a secret read from stdin into this file for an enc: config reference (key in CONFIG_SECRETS_KEY)")
 
 	flag.CommandLine.Usage = func() {
 		out := flag.CommandLine.Output()
@@ -113,6 +118,13 @@ func main() {
 	// Parse the command-line flags.
 	flag.Parse()
 
+	if validate {
+		os.Exit(cmd.DoValidateConfig(configPath, os.Stdout))
+	}
+	if encryptSecret != "" {
+		os.Exit(cmd.DoEncryptSecret(encryptSecret, os.Stdin, os.Stdout))
+	}
+
 	// Core application variables.
 	var err error
 	var cfg *config.Config
@@ -253,6 +265,7 @@ func main() {
 			cfg.AuthDir = pgStoreInst.AuthDir()
 			log.Infof("postgres-backed token store enabled, workspace path: %s", pgStoreInst.WorkDir())
 		}
+		usage.SetSharedPostgres(pgStoreInst.DB(), pgStoreSchema)
 	} else if useObjectStore {
 		if objectStoreLocalPath == "" {
 			if writableBase != "" {
@@ -405,6 +418,7 @@ func main() {
 		}
 	}
 	usage.SetStatisticsEnabled(cfg.UsageStatisticsEnabled)
+	usage.SetPricing(cfg.Pricing)
 	coreauth.SetQuotaCooldownDisabled(cfg.DisableCooling)
 
 	if err = logging.ConfigureLogOutput(cfg); err != nil {
@@ -444,6 +458,7 @@ func main() {
 
 	// Register built-in access providers before constructing services.
 	configaccess.Register()
+	jwtaccess.Register()
 
 	// Handle different command modes based on the provided flags.
 
@@ -24,7 +24,7 @@ type provider struct {
 	keys map[string]struct{}
 }
 
-func newProvider(cfg *sdkconfig.AccessProvider, _ *sdkconfig.SDKConfig) (sdkaccess.Provider, error) {
+func newProvider(cfg *sdkconfig.AccessProvider, root *sdkconfig.SDKConfig) (sdkaccess.Provider, error) {
 	name := cfg.Name
 	if name == "" {
 		name = sdkconfig.DefaultAccessProviderName
@@ -36,6 +36,15 @@ func newProvider(cfg *sdkconfig.AccessProvider, _ *sdkconfig.SDKConfig) (sdkacce
 		}
 		keys[key] = struct{}{}
 	}
+	// Structured client keys are accepted alongside the inline list; their policies are
+	// enforced later by the request handlers.
+	if root != nil {
+		for i := range root.ClientKeys {
+			if key := root.ClientKeys[i].Key; key != "" {
+				keys[key] = struct{}{}
+			}
+		}
+	}
 	return &provider{name: name, keys: keys}, nil
 }
 
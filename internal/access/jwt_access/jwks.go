@@ -0,0 +1,343 @@
+package jwtaccess
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// minRefreshInterval throttles JWKS refreshes, whether the cached set expired or a token
+	// named an unknown key.
+	minRefreshInterval = 30 * time.Second
+	maxJWKSBytes       = 1 << 20
+)
+
+// jsonWebKey is the subset of RFC 7517 fields needed to build verification keys.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type verificationKey struct {
+	kid string
+	alg string
+	key crypto.PublicKey
+}
+
+// parseJWKS decodes a JSON Web Key Set, skipping keys that cannot verify signatures.
+func parseJWKS(data []byte) ([]verificationKey, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("decode jwks: %w", err)
+	}
+	keys := make([]verificationKey, 0, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		pub, err := jwk.publicKey()
+		if err != nil {
+			log.Debugf("jwt access: skipping jwks key %q: %v", jwk.Kid, err)
+			continue
+		}
+		keys = append(keys, verificationKey{kid: jwk.Kid, alg: jwk.Alg, key: pub})
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("jwks contains no usable signing keys")
+	}
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("modulus: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("exponent: %w", err)
+		}
+		if !e.IsInt64() || e.Int64() < 2 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("unsupported exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("x: %w", err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("y: %w", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 public key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
+
+// keySource serves verification keys from a static JWKS file or a remote JWKS URL.
+// Remote key sets are cached for ttl, refreshed early when a token names an unknown key,
+// and optionally mirrored to cacheFile so the provider keeps working while offline.
+// Refreshes run at most once per minRefreshInterval, whatever triggers them, and never
+// under mu: stale keys keep being served while a refresh is in flight.
+type keySource struct {
+	file      string
+	url       string
+	cacheFile string
+	ttl       time.Duration
+	client    *http.Client
+
+	mu          sync.Mutex
+	keys        []verificationKey
+	loadedAt    time.Time
+	lastAttempt time.Time
+	lastErr     error
+	fileModTime time.Time
+	// refreshing is closed when the refresh in flight, if any, completes.
+	refreshing chan struct{}
+}
+
+// keysFor returns candidate keys for a token header, refreshing the set when needed. Requests
+// that find a matching key are answered from the cached set and refresh it in the background;
+// the others wait for the refresh.
+func (s *keySource) keysFor(ctx context.Context, kid, alg string) ([]verificationKey, error) {
+	s.mu.Lock()
+	now := time.Now()
+	matches := selectKeys(s.keys, kid, alg)
+	stale := s.keys == nil || now.Sub(s.loadedAt) >= s.ttl
+	throttled := !s.lastAttempt.IsZero() && now.Sub(s.lastAttempt) < minRefreshInterval
+	var done chan struct{}
+	if !throttled && (stale || (len(matches) == 0 && kid != "")) {
+		done = s.startRefreshLocked(now)
+	} else if len(matches) == 0 {
+		done = s.refreshing
+	}
+	s.mu.Unlock()
+
+	if len(matches) > 0 {
+		return matches, nil
+	}
+	if done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	s.mu.Lock()
+	matches = selectKeys(s.keys, kid, alg)
+	noKeys, lastErr := s.keys == nil, s.lastErr
+	s.mu.Unlock()
+	if len(matches) == 0 {
+		if noKeys && lastErr != nil {
+			return nil, lastErr
+		}
+		return nil, fmt.Errorf("no jwks key matches kid %q", kid)
+	}
+	return matches, nil
+}
+
+// startRefreshLocked starts a background refresh unless one is in flight and returns the
+// channel closed when it completes. Callers must hold mu.
+func (s *keySource) startRefreshLocked(now time.Time) chan struct{} {
+	if s.refreshing != nil {
+		return s.refreshing
+	}
+	s.lastAttempt = now
+	done := make(chan struct{})
+	s.refreshing = done
+	go func() {
+		// The refresh is shared by every waiting request, so it does not follow any one
+		// request's cancellation; the HTTP client timeout bounds it.
+		err := s.refresh(now)
+		s.mu.Lock()
+		s.lastErr = err
+		s.refreshing = nil
+		s.mu.Unlock()
+		close(done)
+	}()
+	return done
+}
+
+func (s *keySource) refresh(now time.Time) error {
+	if s.file != "" {
+		return s.loadFile(now)
+	}
+	data, err := s.fetch(context.Background())
+	if err == nil {
+		keys, errParse := parseJWKS(data)
+		if errParse == nil {
+			s.mu.Lock()
+			s.keys, s.loadedAt = keys, now
+			s.mu.Unlock()
+			s.writeCache(data)
+			return nil
+		}
+		err = errParse
+	}
+	log.Warnf("jwt access: failed to refresh jwks from %s: %v", s.url, err)
+	if !s.hasKeys() && s.cacheFile != "" {
+		if data, errRead := os.ReadFile(s.cacheFile); errRead == nil {
+			if keys, errParse := parseJWKS(data); errParse == nil {
+				log.Infof("jwt access: using cached jwks from %s", s.cacheFile)
+				// loadedAt stays zero, so the remote source is retried once per throttle interval.
+				s.mu.Lock()
+				if s.keys == nil {
+					s.keys = keys
+				}
+				s.mu.Unlock()
+				return nil
+			}
+		}
+	}
+	return err
+}
+
+func (s *keySource) loadFile(now time.Time) error {
+	info, err := os.Stat(s.file)
+	if err != nil {
+		return fmt.Errorf("stat jwks file: %w", err)
+	}
+	s.mu.Lock()
+	unchanged := s.keys != nil && info.ModTime().Equal(s.fileModTime)
+	if unchanged {
+		s.loadedAt = now
+	}
+	s.mu.Unlock()
+	if unchanged {
+		return nil
+	}
+	data, err := os.ReadFile(s.file)
+	if err != nil {
+		return fmt.Errorf("read jwks file: %w", err)
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return fmt.Errorf("jwks file %s: %w", s.file, err)
+	}
+	s.mu.Lock()
+	s.keys, s.loadedAt, s.fileModTime = keys, now, info.ModTime()
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *keySource) fetch(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			log.Errorf("jwt access: close jwks response body error: %v", errClose)
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxJWKSBytes))
+}
+
+func (s *keySource) writeCache(data []byte) {
+	if s.cacheFile == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(s.cacheFile), 0o700); err != nil {
+		log.Warnf("jwt access: failed to create jwks cache directory: %v", err)
+		return
+	}
+	tmp := s.cacheFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		log.Warnf("jwt access: failed to write jwks cache: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, s.cacheFile); err != nil {
+		log.Warnf("jwt access: failed to replace jwks cache: %v", err)
+	}
+}
+
+// selectKeys returns keys matching kid (all keys when kid is empty) that can verify alg.
+func selectKeys(keys []verificationKey, kid, alg string) []verificationKey {
+	var out []verificationKey
+	for _, key := range keys {
+		if kid != "" && key.kid != kid {
+			continue
+		}
+		if key.alg != "" && key.alg != alg {
+			continue
+		}
+		if !keyFitsAlgorithm(key.key, alg) {
+			continue
+		}
+		out = append(out, key)
+	}
+	return out
+}
@@ -0,0 +1,344 @@
+// Package jwtaccess provides an access provider that authenticates clients with JWT bearer
+// tokens verified against a JSON Web Key Set, mapping token claims to the request principal.
+package jwtaccess
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	defaultCacheTTL       = time.Hour
+	defaultClockSkew      = time.Minute
+	defaultPrincipalClaim = "sub"
+	jwksFetchTimeout      = 10 * time.Second
+)
+
+// defaultAlgorithms lists the asymmetric algorithms accepted when none are configured.
+// Symmetric and unsigned tokens are never accepted.
+var defaultAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+var registerOnce sync.Once
+
+// Register ensures the JWT access provider is available to the access manager.
+func Register() {
+	registerOnce.Do(func() {
+		sdkaccess.RegisterProvider(sdkconfig.AccessProviderTypeJWT, newProvider)
+	})
+}
+
+type provider struct {
+	name           string
+	source         *keySource
+	issuers        []string
+	audiences      []string
+	algorithms     map[string]struct{}
+	principalClaim string
+	metadataClaims []string
+	clockSkew      time.Duration
+	now            func() time.Time
+}
+
+// newProvider builds a JWT provider from the access provider config map. Supported options:
+// jwks-file or jwks-url (one required), jwks-cache-file, jwks-cache-ttl, issuer, audience,
+// algorithms, principal-claim, metadata-claims and clock-skew.
+func newProvider(cfg *sdkconfig.AccessProvider, root *sdkconfig.SDKConfig) (sdkaccess.Provider, error) {
+	name := strings.TrimSpace(cfg.Name)
+	if name == "" {
+		name = sdkconfig.AccessProviderTypeJWT
+	}
+	opts := cfg.Config
+
+	source := &keySource{
+		file:      stringOption(opts, "jwks-file"),
+		url:       stringOption(opts, "jwks-url"),
+		cacheFile: stringOption(opts, "jwks-cache-file"),
+	}
+	if (source.file == "") == (source.url == "") {
+		return nil, errors.New("exactly one of jwks-file or jwks-url is required")
+	}
+	var err error
+	if source.file, err = util.ResolveAuthDir(source.file); err != nil {
+		return nil, err
+	}
+	if source.cacheFile, err = util.ResolveAuthDir(source.cacheFile); err != nil {
+		return nil, err
+	}
+	if source.ttl, err = durationOption(opts, "jwks-cache-ttl", defaultCacheTTL); err != nil {
+		return nil, err
+	}
+	if source.file != "" {
+		if err = source.loadFile(time.Now()); err != nil {
+			return nil, err
+		}
+	} else {
+		source.client = &http.Client{Timeout: jwksFetchTimeout}
+		if root != nil && root.ProxyURL != "" {
+			source.client = util.SetProxy(root, source.client)
+		}
+	}
+
+	p := &provider{
+		name:           name,
+		source:         source,
+		issuers:        stringListOption(opts, "issuer"),
+		audiences:      stringListOption(opts, "audience"),
+		principalClaim: stringOption(opts, "principal-claim"),
+		metadataClaims: stringListOption(opts, "metadata-claims"),
+		now:            time.Now,
+	}
+	if p.principalClaim == "" {
+		p.principalClaim = defaultPrincipalClaim
+	}
+	if p.clockSkew, err = durationOption(opts, "clock-skew", defaultClockSkew); err != nil {
+		return nil, err
+	}
+	algorithms := stringListOption(opts, "algorithms")
+	if len(algorithms) == 0 {
+		algorithms = defaultAlgorithms
+	}
+	p.algorithms = make(map[string]struct{}, len(algorithms))
+	for _, alg := range algorithms {
+		if _, ok := algorithmHash(alg); !ok {
+			return nil, fmt.Errorf("unsupported algorithm %q", alg)
+		}
+		p.algorithms[alg] = struct{}{}
+	}
+	return p, nil
+}
+
+func (p *provider) Identifier() string {
+	if p == nil || p.name == "" {
+		return sdkconfig.AccessProviderTypeJWT
+	}
+	return p.name
+}
+
+func (p *provider) Authenticate(ctx context.Context, r *http.Request) (*sdkaccess.Result, error) {
+	if p == nil || p.source == nil {
+		return nil, sdkaccess.ErrNotHandled
+	}
+	candidates := []struct {
+		value  string
+		source string
+	}{
+		{extractBearerToken(r.Header.Get("Authorization")), "authorization"},
+		{r.Header.Get("X-Goog-Api-Key"), "x-goog-api-key"},
+		{r.Header.Get("X-Api-Key"), "x-api-key"},
+	}
+	if r.URL != nil {
+		query := r.URL.Query()
+		candidates = append(candidates,
+			struct{ value, source string }{query.Get("key"), "query-key"},
+			struct{ value, source string }{query.Get("auth_token"), "query-auth-token"},
+		)
+	}
+
+	seen := false
+	for _, candidate := range candidates {
+		if candidate.value == "" {
+			continue
+		}
+		seen = true
+		tok, isJWT, errParse := parseToken(candidate.value)
+		if !isJWT {
+			continue
+		}
+		if errParse != nil {
+			log.Debugf("jwt access: malformed token from %s: %v", candidate.source, errParse)
+			return nil, sdkaccess.ErrInvalidCredential
+		}
+		result, errVerify := p.verify(ctx, tok)
+		if errVerify != nil {
+			if errors.Is(errVerify, sdkaccess.ErrInvalidCredential) {
+				log.Debugf("jwt access: rejected token from %s: %v", candidate.source, errVerify)
+				return nil, sdkaccess.ErrInvalidCredential
+			}
+			return nil, errVerify
+		}
+		result.Metadata["source"] = candidate.source
+		return result, nil
+	}
+	if !seen {
+		return nil, sdkaccess.ErrNoCredentials
+	}
+	return nil, sdkaccess.ErrInvalidCredential
+}
+
+// verify checks the token signature and registered claims, then maps claims to a result.
+// Credential problems wrap ErrInvalidCredential; key-set failures are returned as-is.
+func (p *provider) verify(ctx context.Context, tok *parsedToken) (*sdkaccess.Result, error) {
+	alg := tok.header.Alg
+	if _, ok := p.algorithms[alg]; !ok {
+		return nil, fmt.Errorf("%w: algorithm %q not allowed", sdkaccess.ErrInvalidCredential, alg)
+	}
+	keys, err := p.source.keysFor(ctx, tok.header.Kid, alg)
+	if err != nil {
+		if p.source.hasKeys() {
+			return nil, fmt.Errorf("%w: %v", sdkaccess.ErrInvalidCredential, err)
+		}
+		return nil, fmt.Errorf("jwt access: %w", err)
+	}
+	verified := false
+	for _, key := range keys {
+		if tok.verifySignature(key.key) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("%w: signature verification failed", sdkaccess.ErrInvalidCredential)
+	}
+
+	claims := gjson.ParseBytes(tok.payload)
+	now := p.now()
+	exp := claims.Get("exp")
+	if exp.Type != gjson.Number {
+		return nil, fmt.Errorf("%w: missing exp claim", sdkaccess.ErrInvalidCredential)
+	}
+	if now.After(unixTime(exp.Float()).Add(p.clockSkew)) {
+		return nil, fmt.Errorf("%w: token expired", sdkaccess.ErrInvalidCredential)
+	}
+	if nbf := claims.Get("nbf"); nbf.Type == gjson.Number && now.Add(p.clockSkew).Before(unixTime(nbf.Float())) {
+		return nil, fmt.Errorf("%w: token not yet valid", sdkaccess.ErrInvalidCredential)
+	}
+	issuer := claims.Get("iss").String()
+	if len(p.issuers) > 0 && !containsString(p.issuers, issuer) {
+		return nil, fmt.Errorf("%w: issuer %q not accepted", sdkaccess.ErrInvalidCredential, issuer)
+	}
+	if len(p.audiences) > 0 && !audienceMatches(claims.Get("aud"), p.audiences) {
+		return nil, fmt.Errorf("%w: audience not accepted", sdkaccess.ErrInvalidCredential)
+	}
+	principal := claimString(claims.Get(p.principalClaim))
+	if principal == "" {
+		return nil, fmt.Errorf("%w: missing %s claim", sdkaccess.ErrInvalidCredential, p.principalClaim)
+	}
+
+	metadata := map[string]string{"subject": claims.Get("sub").String()}
+	if issuer != "" {
+		metadata["issuer"] = issuer
+	}
+	for _, path := range p.metadataClaims {
+		if value := claimString(claims.Get(path)); value != "" {
+			metadata[path] = value
+		}
+	}
+	return &sdkaccess.Result{Provider: p.Identifier(), Principal: principal, Metadata: metadata}, nil
+}
+
+func (s *keySource) hasKeys() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.keys) > 0
+}
+
+func unixTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+func audienceMatches(aud gjson.Result, accepted []string) bool {
+	if aud.IsArray() {
+		for _, item := range aud.Array() {
+			if containsString(accepted, item.String()) {
+				return true
+			}
+		}
+		return false
+	}
+	return containsString(accepted, aud.String())
+}
+
+// claimString renders a claim as a string; arrays are joined with commas.
+func claimString(value gjson.Result) string {
+	if !value.Exists() {
+		return ""
+	}
+	if value.IsArray() {
+		items := value.Array()
+		parts := make([]string, 0, len(items))
+		for _, item := range items {
+			parts = append(parts, item.String())
+		}
+		return strings.Join(parts, ",")
+	}
+	if value.IsObject() {
+		return value.Raw
+	}
+	return value.String()
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+func stringOption(opts map[string]any, key string) string {
+	if raw, ok := opts[key]; ok && raw != nil {
+		return strings.TrimSpace(fmt.Sprint(raw))
+	}
+	return ""
+}
+
+// stringListOption accepts a single string or a list for key.
+func stringListOption(opts map[string]any, key string) []string {
+	raw, ok := opts[key]
+	if !ok || raw == nil {
+		return nil
+	}
+	var values []string
+	switch v := raw.(type) {
+	case []any:
+		for _, item := range v {
+			values = append(values, fmt.Sprint(item))
+		}
+	case []string:
+		values = append(values, v...)
+	default:
+		values = []string{fmt.Sprint(v)}
+	}
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			out = append(out, trimmed)
+		}
+	}
+	return out
+}
+
+func durationOption(opts map[string]any, key string, fallback time.Duration) (time.Duration, error) {
+	value := stringOption(opts, key)
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid %s %q", key, value)
+	}
+	return d, nil
+}
+
+func extractBearerToken(header string) string {
+	if header == "" {
+		return ""
+	}
+	parts := strings.SplitN(header, " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") {
+		return header
+	}
+	return strings.TrimSpace(parts[1])
+}
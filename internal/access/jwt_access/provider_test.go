@@ -0,0 +1,217 @@
+package jwtaccess
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+func b64(data []byte) string { return base64.RawURLEncoding.EncodeToString(data) }
+
+func rsaJWKS(t *testing.T, kid string, key *rsa.PrivateKey) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{"keys": []map[string]string{{
+		"kty": "RSA", "kid": kid, "use": "sig", "alg": "RS256",
+		"n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func signRS256(t *testing.T, kid string, key *rsa.PrivateKey, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	input := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input + "." + b64(sig)
+}
+
+func newTestProvider(t *testing.T, opts map[string]any) *provider {
+	t.Helper()
+	p, err := newProvider(&sdkconfig.AccessProvider{Name: "corp", Type: sdkconfig.AccessProviderTypeJWT, Config: opts}, nil)
+	if err != nil {
+		t.Fatalf("newProvider: %v", err)
+	}
+	return p.(*provider)
+}
+
+func bearerRequest(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+func TestAuthenticateFromJWKSFile(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err = os.WriteFile(path, rsaJWKS(t, "k1", key), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p := newTestProvider(t, map[string]any{
+		"jwks-file":       path,
+		"issuer":          "https://idp.example.com",
+		"audience":        []any{"cliproxy"},
+		"principal-claim": "email",
+		"metadata-claims": []any{"groups"},
+	})
+
+	exp := time.Now().Add(time.Hour).Unix()
+	valid := map[string]any{
+		"iss": "https://idp.example.com", "aud": []string{"other", "cliproxy"}, "exp": exp,
+		"sub": "u-1", "email": "alice@example.com", "groups": []string{"eng", "ml"},
+	}
+	res, err := p.Authenticate(context.Background(), bearerRequest(signRS256(t, "k1", key, valid)))
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if res.Provider != "corp" || res.Principal != "alice@example.com" {
+		t.Fatalf("result = %+v", res)
+	}
+	if res.Metadata["subject"] != "u-1" || res.Metadata["groups"] != "eng,ml" || res.Metadata["source"] != "authorization" {
+		t.Fatalf("metadata = %v", res.Metadata)
+	}
+
+	rejected := []map[string]any{
+		{"iss": "https://idp.example.com", "aud": "cliproxy", "exp": time.Now().Add(-time.Hour).Unix(), "email": "a"},
+		{"iss": "https://evil.example.com", "aud": "cliproxy", "exp": exp, "email": "a"},
+		{"iss": "https://idp.example.com", "aud": "other", "exp": exp, "email": "a"},
+		{"iss": "https://idp.example.com", "aud": "cliproxy", "email": "a"},
+	}
+	for i, claims := range rejected {
+		if _, err = p.Authenticate(context.Background(), bearerRequest(signRS256(t, "k1", key, claims))); !errors.Is(err, sdkaccess.ErrInvalidCredential) {
+			t.Errorf("case %d: err = %v, want invalid credential", i, err)
+		}
+	}
+
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+	if _, err = p.Authenticate(context.Background(), bearerRequest(signRS256(t, "k1", other, valid))); !errors.Is(err, sdkaccess.ErrInvalidCredential) {
+		t.Fatalf("foreign signature: err = %v, want invalid credential", err)
+	}
+	if _, err = p.Authenticate(context.Background(), bearerRequest("plain-api-key")); !errors.Is(err, sdkaccess.ErrInvalidCredential) {
+		t.Fatalf("non-JWT credential: err = %v, want invalid credential", err)
+	}
+	if _, err = p.Authenticate(context.Background(), httptest.NewRequest(http.MethodGet, "/", nil)); !errors.Is(err, sdkaccess.ErrNoCredentials) {
+		t.Fatalf("no credential: err = %v, want no credentials", err)
+	}
+}
+
+func TestAuthenticateES256FromJWKSURLWithOfflineCache(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks, _ := json.Marshal(map[string]any{"keys": []map[string]string{{
+		"kty": "EC", "kid": "ec1", "crv": "P-256", "x": b64(key.X.FillBytes(make([]byte, 32))), "y": b64(key.Y.FillBytes(make([]byte, 32))),
+	}}})
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		_, _ = w.Write(jwks)
+	}))
+	cacheFile := filepath.Join(t.TempDir(), "jwks-cache.json")
+
+	header, _ := json.Marshal(map[string]string{"alg": "ES256", "kid": "ec1"})
+	payload, _ := json.Marshal(map[string]any{"sub": "svc-build", "exp": time.Now().Add(time.Hour).Unix()})
+	input := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(input))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := input + "." + b64(append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...))
+
+	p := newTestProvider(t, map[string]any{"jwks-url": srv.URL, "jwks-cache-file": cacheFile})
+	for i := 0; i < 2; i++ {
+		res, errAuth := p.Authenticate(context.Background(), bearerRequest(token))
+		if errAuth != nil || res.Principal != "svc-build" {
+			t.Fatalf("Authenticate = %+v, %v", res, errAuth)
+		}
+	}
+	if hits.Load() != 1 {
+		t.Fatalf("jwks fetched %d times, want cached after first fetch", hits.Load())
+	}
+	srv.Close()
+
+	offline := newTestProvider(t, map[string]any{"jwks-url": srv.URL, "jwks-cache-file": cacheFile})
+	if res, errAuth := offline.Authenticate(context.Background(), bearerRequest(token)); errAuth != nil || res.Principal != "svc-build" {
+		t.Fatalf("offline Authenticate = %+v, %v", res, errAuth)
+	}
+}
+
+func TestAuthenticateThrottlesRefreshWhileJWKSURLIsDown(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks := rsaJWKS(t, "k1", key)
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if hits.Add(1) > 1 {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(jwks)
+	}))
+	defer srv.Close()
+
+	p := newTestProvider(t, map[string]any{"jwks-url": srv.URL, "jwks-cache-ttl": "1ms"})
+	token := signRS256(t, "k1", key, map[string]any{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()})
+	if _, err = p.Authenticate(context.Background(), bearerRequest(token)); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	// Let the next expired-cache request refresh; the IdP is down from then on.
+	p.source.mu.Lock()
+	p.source.lastAttempt = p.source.lastAttempt.Add(-minRefreshInterval)
+	p.source.mu.Unlock()
+	for i := 0; i < 20; i++ {
+		if res, errAuth := p.Authenticate(context.Background(), bearerRequest(token)); errAuth != nil || res.Principal != "alice" {
+			t.Fatalf("Authenticate #%d = %+v, %v", i, res, errAuth)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	p.source.mu.Lock()
+	done := p.source.refreshing
+	p.source.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+	if got := hits.Load(); got != 2 {
+		t.Fatalf("jwks fetched %d times, want the initial fetch and one failed refresh", got)
+	}
+}
+
+func TestNewProviderRequiresSingleKeySource(t *testing.T) {
+	for _, opts := range []map[string]any{{}, {"jwks-file": "a", "jwks-url": "https://b"}} {
+		if _, err := newProvider(&sdkconfig.AccessProvider{Type: sdkconfig.AccessProviderTypeJWT, Config: opts}, nil); err == nil {
+			t.Errorf("newProvider(%v) succeeded, want error", opts)
+		}
+	}
+}
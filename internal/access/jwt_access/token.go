@@ -0,0 +1,141 @@
+package jwtaccess
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// tokenHeader is the JOSE header of a compact JWS.
+type tokenHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+// parsedToken is a structurally valid, not yet verified, compact JWT.
+type parsedToken struct {
+	header       tokenHeader
+	payload      []byte
+	signingInput string
+	signature    []byte
+}
+
+// parseToken splits and decodes a compact JWT. It returns ok=false when raw does not look
+// like a JWT at all, so other access providers can try it.
+func parseToken(raw string) (tok *parsedToken, ok bool, err error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, false, nil
+	}
+	headerJSON, errDecode := base64.RawURLEncoding.DecodeString(parts[0])
+	if errDecode != nil {
+		return nil, false, nil
+	}
+	var header tokenHeader
+	if errUnmarshal := json.Unmarshal(headerJSON, &header); errUnmarshal != nil || header.Alg == "" {
+		return nil, false, nil
+	}
+	payload, errDecode := base64.RawURLEncoding.DecodeString(parts[1])
+	if errDecode != nil {
+		return nil, true, fmt.Errorf("decode payload: %w", errDecode)
+	}
+	if !json.Valid(payload) {
+		return nil, true, errors.New("payload is not valid JSON")
+	}
+	signature, errDecode := base64.RawURLEncoding.DecodeString(parts[2])
+	if errDecode != nil {
+		return nil, true, fmt.Errorf("decode signature: %w", errDecode)
+	}
+	return &parsedToken{
+		header:       header,
+		payload:      payload,
+		signingInput: parts[0] + "." + parts[1],
+		signature:    signature,
+	}, true, nil
+}
+
+// algorithmHash maps a JWS algorithm to its digest.
+func algorithmHash(alg string) (crypto.Hash, bool) {
+	switch alg {
+	case "RS256", "PS256", "ES256":
+		return crypto.SHA256, true
+	case "RS384", "PS384", "ES384":
+		return crypto.SHA384, true
+	case "RS512", "PS512", "ES512":
+		return crypto.SHA512, true
+	case "EdDSA":
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+// keyFitsAlgorithm reports whether key can verify signatures produced with alg.
+func keyFitsAlgorithm(key crypto.PublicKey, alg string) bool {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS")
+	case *ecdsa.PublicKey:
+		switch alg {
+		case "ES256":
+			return k.Curve.Params().BitSize == 256
+		case "ES384":
+			return k.Curve.Params().BitSize == 384
+		case "ES512":
+			return k.Curve.Params().BitSize == 521
+		}
+		return false
+	case ed25519.PublicKey:
+		return alg == "EdDSA"
+	default:
+		return false
+	}
+}
+
+// verifySignature checks the token signature with key using the header algorithm.
+func (t *parsedToken) verifySignature(key crypto.PublicKey) error {
+	alg := t.header.Alg
+	hash, ok := algorithmHash(alg)
+	if !ok {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	if ed, isEd := key.(ed25519.PublicKey); isEd {
+		if !ed25519.Verify(ed, []byte(t.signingInput), t.signature) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+	h := hash.New()
+	h.Write([]byte(t.signingInput))
+	digest := h.Sum(nil)
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if strings.HasPrefix(alg, "PS") {
+			return rsa.VerifyPSS(k, hash, digest, t.signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto})
+		}
+		return rsa.VerifyPKCS1v15(k, hash, digest, t.signature)
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(t.signature) != 2*size {
+			return errors.New("invalid signature length")
+		}
+		r := new(big.Int).SetBytes(t.signature[:size])
+		s := new(big.Int).SetBytes(t.signature[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	default:
+		return errors.New("unsupported key type")
+	}
+}
@@ -0,0 +1,503 @@
+// Package policy enforces per-client API key policies: model and provider restrictions,
+// request and token rate limits, token and cost budgets, and key expiry.
+package policy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	rateWindow = time.Minute
+	// budgetSeedTimeout bounds the persistent-store query that restores budget counters.
+	budgetSeedTimeout = 10 * time.Second
+)
+
+// Violation codes reported to clients.
+const (
+	CodeKeyExpired         = "api_key_expired"
+	CodeModelNotAllowed    = "model_not_allowed"
+	CodeProviderNotAllowed = "provider_not_allowed"
+	CodeRateLimitExceeded  = "rate_limit_exceeded"
+	CodeBudgetExceeded     = "insufficient_quota"
+)
+
+// Violation describes why a client request was rejected.
+type Violation struct {
+	// Status is the HTTP status returned to the client (403 or 429).
+	Status int
+	// Code is a machine-readable reason such as CodeModelNotAllowed.
+	Code string
+	// Message is the human-readable explanation.
+	Message string
+	// RetryAfter hints when a rate-limited request may succeed.
+	RetryAfter time.Duration
+}
+
+// Error implements error.
+func (v *Violation) Error() string { return v.Message }
+
+type compiledKey struct {
+	cfg       config.ClientKey
+	label     string
+	expiry    time.Time
+	expiryErr error
+	providers map[string]struct{}
+	prefixes  map[string]struct{}
+	tags      []string
+}
+
+func (k *compiledKey) hasBudget() bool {
+	c := k.cfg
+	return c.DailyTokenBudget > 0 || c.MonthlyTokenBudget > 0 || c.DailyCostBudget > 0 || c.MonthlyCostBudget > 0
+}
+
+type tokenSample struct {
+	at     time.Time
+	tokens int64
+}
+
+type keyState struct {
+	requests []time.Time
+	tokens   []tokenSample
+
+	day         string
+	dayTokens   int64
+	dayCost     float64
+	month       string
+	monthTokens int64
+	monthCost   float64
+	seeded      bool
+	// seeding is closed once the budget counters have been loaded from the usage store.
+	seeding chan struct{}
+}
+
+func (s *keyState) prune(now time.Time) {
+	cutoff := now.Add(-rateWindow)
+	i := 0
+	for i < len(s.requests) && !s.requests[i].After(cutoff) {
+		i++
+	}
+	s.requests = s.requests[i:]
+	j := 0
+	for j < len(s.tokens) && !s.tokens[j].at.After(cutoff) {
+		j++
+	}
+	s.tokens = s.tokens[j:]
+}
+
+func (s *keyState) tokenTotal() int64 {
+	var total int64
+	for i := range s.tokens {
+		total += s.tokens[i].tokens
+	}
+	return total
+}
+
+// roll resets budget counters when now falls into a new UTC day or month.
+func (s *keyState) roll(now time.Time) {
+	now = now.UTC()
+	if day := now.Format("2006-01-02"); s.day != day {
+		s.day, s.dayTokens, s.dayCost = day, 0, 0
+	}
+	if month := now.Format("2006-01"); s.month != month {
+		s.month, s.monthTokens, s.monthCost = month, 0, 0
+	}
+}
+
+// Enforcer evaluates client key policies and tracks the usage they depend on.
+// It implements coreusage.Plugin so token and cost budgets follow real consumption.
+type Enforcer struct {
+	mu     sync.Mutex
+	keys   map[string]*compiledKey
+	states map[string]*keyState
+	now    func() time.Time
+	store  func() usage.RecordStore
+}
+
+// NewEnforcer constructs an enforcer without any policies.
+func NewEnforcer() *Enforcer {
+	return &Enforcer{
+		keys:   make(map[string]*compiledKey),
+		states: make(map[string]*keyState),
+		now:    time.Now,
+		store:  usage.PersistentStore,
+	}
+}
+
+var defaultEnforcer = NewEnforcer()
+
+func init() {
+	coreusage.RegisterPlugin(defaultEnforcer)
+}
+
+// Default returns the process-wide enforcer used by the API handlers.
+func Default() *Enforcer { return defaultEnforcer }
+
+// Configure replaces the active policies. Rate and budget counters of keys that remain
+// configured are preserved.
+func (e *Enforcer) Configure(keys []config.ClientKey) {
+	compiled := make(map[string]*compiledKey, len(keys))
+	for _, key := range keys {
+		label := strings.TrimSpace(key.Name)
+		if label == "" {
+			label = "this API key"
+		} else {
+			label = fmt.Sprintf("API key %q", label)
+		}
+		entry := &compiledKey{cfg: key, label: label}
+		if entry.expiry, entry.expiryErr = key.Expiry(); entry.expiryErr != nil {
+			// Fail closed: a key whose expiry cannot be read is treated as already expired.
+			log.Warnf("client key %s: %v, key disabled", label, entry.expiryErr)
+		}
+		entry.providers = lowerSet(key.AllowedProviders)
+		entry.prefixes = lowerSet(key.AllowedPrefixes)
+		for tag := range lowerSet(key.CredentialTags) {
+			entry.tags = append(entry.tags, tag)
+		}
+		sort.Strings(entry.tags)
+		if secret := strings.TrimSpace(key.Key); secret != "" {
+			compiled[secret] = entry
+		}
+		if subject := strings.TrimSpace(key.Subject); subject != "" {
+			compiled[subject] = entry
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.keys = compiled
+	for secret := range e.states {
+		if _, ok := compiled[secret]; !ok {
+			delete(e.states, secret)
+		}
+	}
+}
+
+func lowerSet(values []string) map[string]struct{} {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		if trimmed := strings.ToLower(strings.Trim(strings.TrimSpace(value), "/")); trimmed != "" {
+			set[trimmed] = struct{}{}
+		}
+	}
+	return set
+}
+
+// Admit checks whether apiKey may call model right now. It does not count the request
+// against the key's RPM limit; callers do that with CountRequest once every other check,
+// such as FilterProviders, has passed. An empty model skips the model rules, for callers that
+// check the models a request resolves to separately. Keys without a configured policy are always
+// admitted.
+func (e *Enforcer) Admit(apiKey, model string) *Violation {
+	if e == nil || apiKey == "" {
+		return nil
+	}
+	e.mu.Lock()
+	key := e.keys[apiKey]
+	e.mu.Unlock()
+	if key == nil {
+		return nil
+	}
+	now := e.now()
+	if key.expiryErr != nil {
+		return &Violation{Status: http.StatusForbidden, Code: CodeKeyExpired, Message: fmt.Sprintf("%s has an invalid expiry and is disabled", key.label)}
+	}
+	if !key.expiry.IsZero() && !now.Before(key.expiry) {
+		return &Violation{Status: http.StatusForbidden, Code: CodeKeyExpired, Message: fmt.Sprintf("%s expired at %s", key.label, key.expiry.UTC().Format(time.RFC3339))}
+	}
+	if model != "" {
+		if v := key.checkModel(model); v != nil {
+			return v
+		}
+	}
+	if key.hasBudget() {
+		e.seedBudget(apiKey, now)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	state := e.states[apiKey]
+	if state == nil {
+		state = &keyState{}
+		e.states[apiKey] = state
+	}
+	state.prune(now)
+	state.roll(now)
+	if v := key.checkBudget(state, now); v != nil {
+		return v
+	}
+	if key.cfg.TPM > 0 && len(state.tokens) > 0 && state.tokenTotal() >= int64(key.cfg.TPM) {
+		return &Violation{
+			Status:     http.StatusTooManyRequests,
+			Code:       CodeRateLimitExceeded,
+			Message:    fmt.Sprintf("%s exceeded its limit of %d tokens per minute", key.label, key.cfg.TPM),
+			RetryAfter: state.tokens[0].at.Add(rateWindow).Sub(now),
+		}
+	}
+	return key.checkRPM(state, now)
+}
+
+// CountRequest counts an admitted request against the RPM limit of apiKey. It re-checks
+// the limit so concurrent requests admitted together cannot exceed it.
+func (e *Enforcer) CountRequest(apiKey string) *Violation {
+	if e == nil || apiKey == "" {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	key := e.keys[apiKey]
+	if key == nil || key.cfg.RPM <= 0 {
+		return nil
+	}
+	now := e.now()
+	state := e.states[apiKey]
+	if state == nil {
+		state = &keyState{}
+		e.states[apiKey] = state
+	}
+	state.prune(now)
+	if v := key.checkRPM(state, now); v != nil {
+		return v
+	}
+	state.requests = append(state.requests, now)
+	return nil
+}
+
+// checkRPM reports a violation when the key used up its requests per minute; callers must hold e.mu.
+func (k *compiledKey) checkRPM(state *keyState, now time.Time) *Violation {
+	if k.cfg.RPM > 0 && len(state.requests) >= k.cfg.RPM {
+		return &Violation{
+			Status:     http.StatusTooManyRequests,
+			Code:       CodeRateLimitExceeded,
+			Message:    fmt.Sprintf("%s exceeded its limit of %d requests per minute", k.label, k.cfg.RPM),
+			RetryAfter: state.requests[0].Add(rateWindow).Sub(now),
+		}
+	}
+	return nil
+}
+
+func (k *compiledKey) checkModel(model string) *Violation {
+	base := strings.TrimSpace(thinking.ParseSuffix(model).ModelName)
+	prefix, bare := "", base
+	if idx := strings.Index(base, "/"); idx > 0 {
+		prefix, bare = base[:idx], base[idx+1:]
+	}
+	denied := &Violation{Status: http.StatusForbidden, Code: CodeModelNotAllowed, Message: fmt.Sprintf("%s is not allowed to use model %s", k.label, base)}
+	if len(k.prefixes) > 0 {
+		if _, ok := k.prefixes[strings.ToLower(prefix)]; !ok {
+			return denied
+		}
+	}
+	lowerBase, lowerBare := strings.ToLower(base), strings.ToLower(bare)
+	matches := func(pattern string) bool {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		return pattern != "" && (util.MatchWildcard(pattern, lowerBase) || util.MatchWildcard(pattern, lowerBare))
+	}
+	for _, pattern := range k.cfg.DeniedModels {
+		if matches(pattern) {
+			return denied
+		}
+	}
+	if len(k.cfg.AllowedModels) == 0 {
+		return nil
+	}
+	for _, pattern := range k.cfg.AllowedModels {
+		if matches(pattern) {
+			return nil
+		}
+	}
+	return denied
+}
+
+func (k *compiledKey) checkBudget(state *keyState, now time.Time) *Violation {
+	c := k.cfg
+	exceeded := func(kind string) *Violation {
+		utc := now.UTC()
+		reset := time.Date(utc.Year(), utc.Month(), utc.Day()+1, 0, 0, 0, 0, time.UTC)
+		if strings.HasPrefix(kind, "monthly") {
+			reset = time.Date(utc.Year(), utc.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		}
+		return &Violation{
+			Status:     http.StatusTooManyRequests,
+			Code:       CodeBudgetExceeded,
+			Message:    fmt.Sprintf("%s exhausted its %s budget", k.label, kind),
+			RetryAfter: reset.Sub(now),
+		}
+	}
+	switch {
+	case c.DailyTokenBudget > 0 && state.dayTokens >= c.DailyTokenBudget:
+		return exceeded("daily token")
+	case c.DailyCostBudget > 0 && state.dayCost >= c.DailyCostBudget:
+		return exceeded("daily cost")
+	case c.MonthlyTokenBudget > 0 && state.monthTokens >= c.MonthlyTokenBudget:
+		return exceeded("monthly token")
+	case c.MonthlyCostBudget > 0 && state.monthCost >= c.MonthlyCostBudget:
+		return exceeded("monthly cost")
+	}
+	return nil
+}
+
+// seedBudget initialises budget counters from the persistent usage store once per key so
+// budgets survive restarts when the store is enabled. Concurrent callers for the same key wait
+// for the first one to finish, so no request is admitted against counters not yet loaded.
+func (e *Enforcer) seedBudget(apiKey string, now time.Time) {
+	e.mu.Lock()
+	state := e.states[apiKey]
+	if state == nil {
+		state = &keyState{}
+		e.states[apiKey] = state
+	}
+	if state.seeded {
+		e.mu.Unlock()
+		return
+	}
+	if seeding := state.seeding; seeding != nil {
+		e.mu.Unlock()
+		<-seeding
+		return
+	}
+	done := make(chan struct{})
+	state.seeding = done
+	e.mu.Unlock()
+
+	days, ok := e.loadBudgetUsage(apiKey, now)
+
+	e.mu.Lock()
+	defer close(done)
+	defer e.mu.Unlock()
+	state.seeded = true
+	state.seeding = nil
+	if !ok {
+		return
+	}
+	// The store already holds the usage charged so far, so it replaces the live counters
+	// instead of adding to them.
+	state.roll(now)
+	today := now.UTC().Format("2006-01-02")
+	state.dayTokens, state.dayCost, state.monthTokens, state.monthCost = 0, 0, 0, 0
+	for _, day := range days {
+		state.monthTokens += day.Tokens.TotalTokens
+		state.monthCost += day.Cost
+		if day.Key == today {
+			state.dayTokens += day.Tokens.TotalTokens
+			state.dayCost += day.Cost
+		}
+	}
+}
+
+// loadBudgetUsage returns the daily usage of apiKey in the current UTC month. It returns false
+// when the usage store is disabled or cannot be read.
+func (e *Enforcer) loadBudgetUsage(apiKey string, now time.Time) ([]usage.Aggregate, bool) {
+	store := e.store()
+	if store == nil {
+		return nil, false
+	}
+	utc := now.UTC()
+	monthStart := time.Date(utc.Year(), utc.Month(), 1, 0, 0, 0, 0, time.UTC)
+	ctx, cancel := context.WithTimeout(context.Background(), budgetSeedTimeout)
+	defer cancel()
+	days, err := store.Summarize(ctx, usage.Query{From: monthStart, APIKey: apiKey}, usage.GroupByDay)
+	if err != nil {
+		log.Warnf("client policy: failed to load usage for budget accounting: %v", err)
+		return nil, false
+	}
+	return days, true
+}
+
+// AllowsModel reports whether the model rules of apiKey permit model. Unlike Admit it checks
+// nothing else and counts nothing, so callers can test candidate models, e.g. traffic split
+// variants, before choosing one.
+func (e *Enforcer) AllowsModel(apiKey, model string) bool {
+	if e == nil || apiKey == "" {
+		return true
+	}
+	e.mu.Lock()
+	key := e.keys[apiKey]
+	e.mu.Unlock()
+	return key == nil || key.checkModel(model) == nil
+}
+
+// FilterProviders removes providers the key may not route to. It returns a violation
+// when none of the candidate providers remain.
+func (e *Enforcer) FilterProviders(apiKey, model string, providers []string) ([]string, *Violation) {
+	if e == nil || apiKey == "" {
+		return providers, nil
+	}
+	e.mu.Lock()
+	key := e.keys[apiKey]
+	e.mu.Unlock()
+	if key == nil || len(key.providers) == 0 {
+		return providers, nil
+	}
+	filtered := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		if _, ok := key.providers[strings.ToLower(provider)]; ok {
+			filtered = append(filtered, provider)
+		}
+	}
+	if len(filtered) == 0 {
+		return nil, &Violation{Status: http.StatusForbidden, Code: CodeProviderNotAllowed, Message: fmt.Sprintf("%s is not allowed to use the providers serving model %s", key.label, model)}
+	}
+	return filtered, nil
+}
+
+// CredentialTags returns the tags a credential must carry to serve requests made with apiKey.
+func (e *Enforcer) CredentialTags(apiKey string) []string {
+	if e == nil || apiKey == "" {
+		return nil
+	}
+	e.mu.Lock()
+	key := e.keys[apiKey]
+	e.mu.Unlock()
+	if key == nil {
+		return nil
+	}
+	return key.tags
+}
+
+// HandleUsage implements coreusage.Plugin by charging consumed tokens and cost to the
+// client key that issued the request. Requests mirrored by shadow traffic policies are
+// operator traffic and are not charged.
+func (e *Enforcer) HandleUsage(_ context.Context, record coreusage.Record) {
+	if e == nil || record.APIKey == "" || record.Shadow {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.keys[record.APIKey]; !ok {
+		return
+	}
+	tokens, cost := usage.RecordTotals(record)
+	if tokens <= 0 && cost <= 0 {
+		return
+	}
+	now := e.now()
+	state := e.states[record.APIKey]
+	if state == nil {
+		state = &keyState{}
+		e.states[record.APIKey] = state
+	}
+	state.prune(now)
+	state.roll(now)
+	state.tokens = append(state.tokens, tokenSample{at: now, tokens: tokens})
+	state.dayTokens += tokens
+	state.dayCost += cost
+	state.monthTokens += tokens
+	state.monthCost += cost
+}
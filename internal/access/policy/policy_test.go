@@ -0,0 +1,205 @@
+package policy
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func newTestEnforcer(now time.Time, keys ...config.ClientKey) *Enforcer {
+	e := NewEnforcer()
+	e.now = func() time.Time { return now }
+	e.Configure(keys)
+	return e
+}
+
+func TestAdmitModelRules(t *testing.T) {
+	e := newTestEnforcer(time.Now(),
+		config.ClientKey{Key: "a", AllowedModels: []string{"gpt-5*", "claude-sonnet-*"}, DeniedModels: []string{"gpt-5-pro"}},
+		config.ClientKey{Key: "b", AllowedPrefixes: []string{"team"}},
+	)
+
+	cases := []struct {
+		key, model string
+		allowed    bool
+	}{
+		{"a", "gpt-5-codex", true},
+		{"a", "gpt-5-codex(high)", true},
+		{"a", "gpt-5-pro", false},
+		{"a", "gemini-2.5-pro", false},
+		{"a", "team/claude-sonnet-4-5", true},
+		{"b", "team/gpt-5", true},
+		{"b", "gpt-5", false},
+		{"b", "", true},
+		{"unknown", "anything", true},
+	}
+	for _, tc := range cases {
+		v := e.Admit(tc.key, tc.model)
+		if tc.allowed && v != nil {
+			t.Errorf("Admit(%s, %s) = %v, want allowed", tc.key, tc.model, v)
+		}
+		if !tc.allowed && (v == nil || v.Status != http.StatusForbidden || v.Code != CodeModelNotAllowed) {
+			t.Errorf("Admit(%s, %s) = %+v, want model_not_allowed", tc.key, tc.model, v)
+		}
+	}
+}
+
+func TestAdmitExpiredKey(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	e := newTestEnforcer(now, config.ClientKey{Key: "a", ExpiresAt: "2026-02-28T23:59:59Z"})
+	if v := e.Admit("a", "gpt-5"); v == nil || v.Code != CodeKeyExpired {
+		t.Fatalf("Admit = %+v, want api_key_expired", v)
+	}
+}
+
+func TestAdmitRequestRateLimit(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	e := newTestEnforcer(now, config.ClientKey{Key: "a", RPM: 2})
+	if v := e.Admit("a", "gpt-5"); v != nil {
+		t.Fatalf("uncounted request rejected: %v", v)
+	}
+	for i := 0; i < 2; i++ {
+		if v := e.Admit("a", "gpt-5"); v != nil {
+			t.Fatalf("request %d rejected: %v", i, v)
+		}
+		if v := e.CountRequest("a"); v != nil {
+			t.Fatalf("request %d not counted: %v", i, v)
+		}
+	}
+	if v := e.CountRequest("a"); v == nil || v.Status != http.StatusTooManyRequests {
+		t.Fatalf("CountRequest = %+v, want 429", v)
+	}
+	v := e.Admit("a", "gpt-5")
+	if v == nil || v.Status != http.StatusTooManyRequests || v.RetryAfter != time.Minute {
+		t.Fatalf("Admit = %+v, want 429 with one minute retry", v)
+	}
+	e.now = func() time.Time { return now.Add(time.Minute) }
+	if v := e.Admit("a", "gpt-5"); v != nil {
+		t.Fatalf("request after window rejected: %v", v)
+	}
+}
+
+func TestAdmitInvalidExpiryFailsClosed(t *testing.T) {
+	e := newTestEnforcer(time.Now(), config.ClientKey{Key: "a", ExpiresAt: "next tuesday"})
+	if v := e.Admit("a", "gpt-5"); v == nil || v.Code != CodeKeyExpired {
+		t.Fatalf("Admit = %+v, want api_key_expired", v)
+	}
+}
+
+func TestAdmitTokenBudget(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	e := newTestEnforcer(now, config.ClientKey{Key: "a", DailyTokenBudget: 1000})
+	if v := e.Admit("a", "gpt-5"); v != nil {
+		t.Fatalf("first request rejected: %v", v)
+	}
+	e.HandleUsage(context.Background(), coreusage.Record{APIKey: "a", Model: "gpt-5", Detail: coreusage.Detail{InputTokens: 600, OutputTokens: 400}})
+	v := e.Admit("a", "gpt-5")
+	if v == nil || v.Code != CodeBudgetExceeded || v.RetryAfter != 12*time.Hour {
+		t.Fatalf("Admit = %+v, want insufficient_quota until midnight", v)
+	}
+	e.now = func() time.Time { return now.Add(12 * time.Hour) }
+	if v := e.Admit("a", "gpt-5"); v != nil {
+		t.Fatalf("request on next day rejected: %v", v)
+	}
+}
+
+// seedTestStore answers Summarize with days once release is closed.
+type seedTestStore struct {
+	release chan struct{}
+	calls   atomic.Int32
+	days    []usage.Aggregate
+}
+
+func (s *seedTestStore) Insert(context.Context, []usage.StoredRecord) error { return nil }
+
+func (s *seedTestStore) Query(context.Context, usage.Query) ([]usage.StoredRecord, error) {
+	return nil, nil
+}
+
+func (s *seedTestStore) Summarize(context.Context, usage.Query, string) ([]usage.Aggregate, error) {
+	s.calls.Add(1)
+	<-s.release
+	return s.days, nil
+}
+
+func (s *seedTestStore) Prune(context.Context, time.Time) (int64, error) { return 0, nil }
+
+func (s *seedTestStore) Close() error { return nil }
+
+func TestAdmitWaitsForBudgetSeeding(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	e := newTestEnforcer(now, config.ClientKey{Key: "a", DailyTokenBudget: 1000})
+	store := &seedTestStore{release: make(chan struct{}), days: []usage.Aggregate{{Key: "2026-03-01", Tokens: usage.TokenStats{TotalTokens: 1000}}}}
+	e.store = func() usage.RecordStore { return store }
+
+	results := make(chan *Violation, 2)
+	for i := 0; i < 2; i++ {
+		go func() { results <- e.Admit("a", "gpt-5") }()
+	}
+	select {
+	case v := <-results:
+		t.Fatalf("request decided before the budget was loaded: %v", v)
+	case <-time.After(50 * time.Millisecond):
+	}
+	// Usage charged while seeding is already part of the stored summary.
+	e.HandleUsage(context.Background(), coreusage.Record{APIKey: "a", Model: "gpt-5", Detail: coreusage.Detail{TotalTokens: 400}})
+	close(store.release)
+
+	for i := 0; i < 2; i++ {
+		if v := <-results; v == nil || v.Code != CodeBudgetExceeded {
+			t.Fatalf("Admit = %+v, want insufficient_quota from the stored usage", v)
+		}
+	}
+	if calls := store.calls.Load(); calls != 1 {
+		t.Fatalf("usage store queried %d times, want 1", calls)
+	}
+	if tokens := e.states["a"].dayTokens; tokens != 1000 {
+		t.Fatalf("day tokens = %d, want the stored 1000 without double counting", tokens)
+	}
+}
+
+func TestShadowUsageIsNotCharged(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	e := newTestEnforcer(now, config.ClientKey{Key: "a", DailyTokenBudget: 1000})
+	e.HandleUsage(context.Background(), coreusage.Record{APIKey: "a", Model: "gpt-5", Shadow: true, Detail: coreusage.Detail{InputTokens: 600, OutputTokens: 400}})
+	if v := e.Admit("a", "gpt-5"); v != nil {
+		t.Fatalf("shadow usage was charged to the client key: %v", v)
+	}
+}
+
+func TestAllowsModelDoesNotCount(t *testing.T) {
+	e := newTestEnforcer(time.Now(), config.ClientKey{Key: "a", RPM: 1, AllowedModels: []string{"gpt-5"}})
+	if !e.AllowsModel("a", "gpt-5") || e.AllowsModel("a", "gpt-5-canary") || !e.AllowsModel("other", "gpt-5-canary") {
+		t.Fatalf("AllowsModel does not follow the model rules")
+	}
+	if v := e.Admit("a", "gpt-5"); v != nil {
+		t.Fatalf("AllowsModel used up the RPM limit: %v", v)
+	}
+}
+
+func TestFilterProviders(t *testing.T) {
+	e := newTestEnforcer(time.Now(), config.ClientKey{Key: "a", AllowedProviders: []string{"Codex"}})
+	got, v := e.FilterProviders("a", "gpt-5", []string{"openai-compat", "codex"})
+	if v != nil || len(got) != 1 || got[0] != "codex" {
+		t.Fatalf("FilterProviders = %v, %v; want [codex]", got, v)
+	}
+	if _, v = e.FilterProviders("a", "claude-sonnet-4-5", []string{"claude"}); v == nil || v.Code != CodeProviderNotAllowed {
+		t.Fatalf("FilterProviders violation = %+v, want provider_not_allowed", v)
+	}
+}
+
+func TestCredentialTags(t *testing.T) {
+	e := newTestEnforcer(time.Now(), config.ClientKey{Key: "a", CredentialTags: []string{" EU ", "team-a", "eu"}})
+	if got := e.CredentialTags("a"); len(got) != 2 || got[0] != "eu" || got[1] != "team-a" {
+		t.Fatalf("CredentialTags = %v, want [eu team-a]", got)
+	}
+	if got := e.CredentialTags("unknown"); got != nil {
+		t.Fatalf("CredentialTags for an unconfigured key = %v, want none", got)
+	}
+}
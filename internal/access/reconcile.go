@@ -80,8 +80,9 @@ func ReconcileProviders(oldCfg, newCfg *config.Config, existing []sdkaccess.Prov
 		finalIDs[key] = struct{}{}
 	}
 
-	if len(result) == 0 {
-		if inline := sdkConfig.MakeInlineAPIKeyProvider(newCfg.APIKeys); inline != nil {
+	// Inline API keys stay accepted next to token-based providers unless configured explicitly.
+	if _, ok := finalIDs[sdkConfig.DefaultAccessProviderName]; !ok {
+		if inline := sdkConfig.MakeInlineAPIKeyProvider(newCfg.InlineAPIKeys()); inline != nil {
 			key := providerIdentifier(inline)
 			if key != "" {
 				if oldCfgProvider, ok := oldCfgMap[key]; ok {
@@ -176,8 +177,8 @@ func accessProviderMap(cfg *config.Config) map[string]*sdkConfig.AccessProvider
 		}
 		result[key] = providerCfg
 	}
-	if len(result) == 0 && len(cfg.APIKeys) > 0 {
-		if provider := sdkConfig.MakeInlineAPIKeyProvider(cfg.APIKeys); provider != nil {
+	if _, ok := result[sdkConfig.DefaultAccessProviderName]; !ok {
+		if provider := sdkConfig.MakeInlineAPIKeyProvider(cfg.InlineAPIKeys()); provider != nil {
 			if key := providerIdentifier(provider); key != "" {
 				result[key] = provider
 			}
@@ -187,7 +188,8 @@ func accessProviderMap(cfg *config.Config) map[string]*sdkConfig.AccessProvider
 }
 
 func collectProviderEntries(cfg *config.Config) []*sdkConfig.AccessProvider {
-	entries := make([]*sdkConfig.AccessProvider, 0, len(cfg.Access.Providers))
+	entries := make([]*sdkConfig.AccessProvider, 0, len(cfg.Access.Providers)+1)
+	hasInline := false
 	for i := range cfg.Access.Providers {
 		providerCfg := &cfg.Access.Providers[i]
 		if providerCfg.Type == "" {
@@ -195,10 +197,13 @@ func collectProviderEntries(cfg *config.Config) []*sdkConfig.AccessProvider {
 		}
 		if key := providerIdentifier(providerCfg); key != "" {
 			entries = append(entries, providerCfg)
+			if key == sdkConfig.DefaultAccessProviderName {
+				hasInline = true
+			}
 		}
 	}
-	if len(entries) == 0 && len(cfg.APIKeys) > 0 {
-		if inline := sdkConfig.MakeInlineAPIKeyProvider(cfg.APIKeys); inline != nil {
+	if !hasInline {
+		if inline := sdkConfig.MakeInlineAPIKeyProvider(cfg.InlineAPIKeys()); inline != nil {
 			entries = append(entries, inline)
 		}
 	}
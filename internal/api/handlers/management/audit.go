@@ -0,0 +1,259 @@
+package management
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/watcher/diff"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultAuditLogFile   = "management-audit.jsonl"
+	defaultAuditQuerySize = 100
+	maxAuditQuerySize     = 1000
+)
+
+// managementActorContextKey stores who authenticated a management request.
+const managementActorContextKey = "managementActor"
+
+// Actors recorded for requests not authenticated with a scoped token, which are recorded as
+// "token:<name>".
+const (
+	actorSecretKey     = "secret-key"
+	actorEnvPassword   = "management-password"
+	actorLocalPassword = "local-password"
+)
+
+// auditEntry is one line of the management audit log.
+type auditEntry struct {
+	Time     time.Time `json:"time"`
+	ClientIP string    `json:"client_ip"`
+	Actor    string    `json:"actor"`
+	Method   string    `json:"method"`
+	Route    string    `json:"route"`
+	Status   int       `json:"status"`
+	// Changes is the redacted diff of the config and of the auth files made by the request.
+	Changes []string `json:"changes,omitempty"`
+}
+
+// auditLogMu serializes appends so concurrent requests never interleave lines.
+var auditLogMu sync.Mutex
+
+// auditLogPath returns the audit log file, or an empty string when there is no log directory
+// to place the default file in.
+func (h *Handler) auditLogPath() string {
+	var path string
+	if h.cfg != nil {
+		path = strings.TrimSpace(h.cfg.RemoteManagement.AuditLog)
+	}
+	if path == "" {
+		path = defaultAuditLogFile
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	if h.logDir == "" {
+		return ""
+	}
+	return filepath.Join(h.logDir, path)
+}
+
+func appendAuditEntry(path string, entry auditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	auditLogMu.Lock()
+	defer auditLogMu.Unlock()
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(append(line, '\n')); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// AuditMiddleware records every management request that may change state (any method but GET
+// and HEAD) in the audit log, with a redacted diff of the config and auth files it changed.
+func (h *Handler) AuditMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		path := h.auditLogPath()
+		if path == "" {
+			c.Next()
+			return
+		}
+		beforeCfg := snapshotConfig(h.cfg)
+		beforeAuths := h.snapshotAuths()
+
+		c.Next()
+
+		actor, _ := c.Get(managementActorContextKey)
+		actorName, _ := actor.(string)
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		entry := auditEntry{
+			Time:     time.Now().UTC(),
+			ClientIP: c.ClientIP(),
+			Actor:    actorName,
+			Method:   c.Request.Method,
+			Route:    route,
+			Status:   c.Writer.Status(),
+		}
+		if beforeCfg != nil {
+			if afterCfg := snapshotConfig(h.cfg); afterCfg != nil {
+				entry.Changes = diff.BuildConfigChangeDetails(beforeCfg, afterCfg)
+			}
+		}
+		entry.Changes = append(entry.Changes, authChanges(beforeAuths, h.snapshotAuths())...)
+		if err := appendAuditEntry(path, entry); err != nil {
+			log.WithError(err).Warn("failed to write management audit log")
+		}
+	}
+}
+
+// snapshotConfig deep-copies cfg through YAML, the same way the config watcher keeps the
+// previous config for diffing, so later in-place edits do not affect the copy.
+func snapshotConfig(cfg *config.Config) *config.Config {
+	if cfg == nil {
+		return nil
+	}
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil
+	}
+	var out config.Config
+	if err = yaml.Unmarshal(data, &out); err != nil {
+		return nil
+	}
+	return &out
+}
+
+func (h *Handler) snapshotAuths() map[string]*coreauth.Auth {
+	if h.authManager == nil {
+		return nil
+	}
+	auths := h.authManager.List()
+	out := make(map[string]*coreauth.Auth, len(auths))
+	for _, auth := range auths {
+		out[auth.ID] = auth
+	}
+	return out
+}
+
+// authChanges describes auths added, removed or changed between two snapshots.
+func authChanges(before, after map[string]*coreauth.Auth) []string {
+	var changes []string
+	for id, next := range after {
+		prev, existed := before[id]
+		if !existed {
+			changes = append(changes, fmt.Sprintf("auth %s: added", authLabel(next)))
+			continue
+		}
+		for _, detail := range diff.BuildAuthChangeDetails(prev, next) {
+			changes = append(changes, fmt.Sprintf("auth %s: %s", authLabel(next), detail))
+		}
+	}
+	for id, prev := range before {
+		if _, exists := after[id]; !exists {
+			changes = append(changes, fmt.Sprintf("auth %s: removed", authLabel(prev)))
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+func authLabel(auth *coreauth.Auth) string {
+	if name := strings.TrimSpace(auth.FileName); name != "" {
+		return name
+	}
+	return auth.ID
+}
+
+// GetAuditLog returns management audit entries, oldest first. Query parameters: since
+// (RFC3339), actor, route (substring match) and limit (default 100, newest entries kept).
+func (h *Handler) GetAuditLog(c *gin.Context) {
+	var since time.Time
+	if raw := strings.TrimSpace(c.Query("since")); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid since, expected RFC3339"})
+			return
+		}
+		since = parsed
+	}
+	limit := defaultAuditQuerySize
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		limit = min(parsed, maxAuditQuerySize)
+	}
+	actor := strings.TrimSpace(c.Query("actor"))
+	route := strings.TrimSpace(c.Query("route"))
+
+	entries := []auditEntry{}
+	path := h.auditLogPath()
+	if path == "" {
+		c.JSON(http.StatusOK, gin.H{"entries": entries})
+		return
+	}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		c.JSON(http.StatusOK, gin.H{"entries": entries})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to open audit log: %v", err)})
+		return
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var entry auditEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+			continue
+		}
+		if (!since.IsZero() && entry.Time.Before(since)) || (actor != "" && entry.Actor != actor) || (route != "" && !strings.Contains(entry.Route, route)) {
+			continue
+		}
+		entries = append(entries, entry)
+		if len(entries) > limit {
+			entries = entries[1:]
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to read audit log: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
@@ -424,6 +424,54 @@ func (h *Handler) buildAuthFileEntry(auth *coreauth.Auth) gin.H {
 	if claims := extractCodexIDTokenClaims(auth); claims != nil {
 		entry["id_token"] = claims
 	}
+	if tags := auth.Tags(); len(tags) > 0 {
+		entry["tags"] = tags
+	}
+	if availability, ok := auth.AvailabilityStatus(time.Now()); ok {
+		entry["availability"] = availabilityEntry(availability)
+	}
+	if h.authManager != nil {
+		if circuit, ok := h.authManager.CircuitBreakerStatus(auth.ID); ok {
+			entry["circuit_breaker"] = circuitBreakerEntry(circuit)
+		}
+	}
+	return entry
+}
+
+func availabilityEntry(status coreauth.AvailabilityStatus) gin.H {
+	entry := gin.H{"windows": status.Windows, "available": status.Available}
+	if status.Timezone != "" {
+		entry["timezone"] = status.Timezone
+	}
+	if !status.NextAvailableAt.IsZero() {
+		entry["next_available_at"] = status.NextAvailableAt
+	}
+	if status.Error != "" {
+		entry["error"] = status.Error
+	}
+	return entry
+}
+
+func circuitBreakerEntry(circuit coreauth.AuthCircuit) gin.H {
+	entry := circuitStatusEntry(circuit.CircuitStatus)
+	if len(circuit.Models) > 0 {
+		models := make(gin.H, len(circuit.Models))
+		for model, status := range circuit.Models {
+			models[model] = circuitStatusEntry(status)
+		}
+		entry["models"] = models
+	}
+	return entry
+}
+
+func circuitStatusEntry(status coreauth.CircuitStatus) gin.H {
+	entry := gin.H{"state": status.State, "failures": status.Failures}
+	if !status.OpenedAt.IsZero() {
+		entry["opened_at"] = status.OpenedAt
+	}
+	if !status.ProbeAt.IsZero() {
+		entry["probe_at"] = status.ProbeAt
+	}
 	return entry
 }
 
@@ -769,20 +817,7 @@ func (h *Handler) PatchAuthFileStatus(c *gin.Context) {
 
 	ctx := c.Request.Context()
 
-	// Find auth by name or ID
-	var targetAuth *coreauth.Auth
-	if auth, ok := h.authManager.GetByID(name); ok {
-		targetAuth = auth
-	} else {
-		auths := h.authManager.List()
-		for _, auth := range auths {
-			if auth.FileName == name {
-				targetAuth = auth
-				break
-			}
-		}
-	}
-
+	targetAuth := h.findAuthByName(name)
 	if targetAuth == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "auth file not found"})
 		return
@@ -807,6 +842,77 @@ func (h *Handler) PatchAuthFileStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok", "disabled": *req.Disabled})
 }
 
+// PatchAuthFileTags replaces the routing tags of an auth file. An empty list removes them.
+func (h *Handler) PatchAuthFileTags(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+
+	var req struct {
+		Name string    `json:"name"`
+		Tags *[]string `json:"tags"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+	if req.Tags == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tags is required"})
+		return
+	}
+
+	targetAuth := h.findAuthByName(name)
+	if targetAuth == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "auth file not found"})
+		return
+	}
+	if strings.HasPrefix(authAttribute(targetAuth, "source"), "config:") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tags of config credentials are edited through their config entry"})
+		return
+	}
+
+	tags := coreauth.NormalizeTags(*req.Tags)
+	if targetAuth.Metadata == nil {
+		targetAuth.Metadata = make(map[string]any)
+	}
+	if len(tags) == 0 {
+		delete(targetAuth.Metadata, "tags")
+	} else {
+		targetAuth.Metadata["tags"] = tags
+	}
+	targetAuth.UpdatedAt = time.Now()
+
+	if _, err := h.authManager.Update(c.Request.Context(), targetAuth); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to update auth: %v", err)})
+		return
+	}
+
+	if tags == nil {
+		tags = []string{}
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "tags": tags})
+}
+
+// findAuthByName returns the auth with the given ID or file name.
+func (h *Handler) findAuthByName(name string) *coreauth.Auth {
+	if auth, ok := h.authManager.GetByID(name); ok {
+		return auth
+	}
+	for _, auth := range h.authManager.List() {
+		if auth.FileName == name {
+			return auth
+		}
+	}
+	return nil
+}
+
 func (h *Handler) disableAuth(ctx context.Context, id string) {
 	if h == nil || h.authManager == nil {
 		return
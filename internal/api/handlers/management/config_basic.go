@@ -13,6 +13,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/watcher/diff"
 	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
@@ -28,8 +29,7 @@ func (h *Handler) GetConfig(c *gin.Context) {
 		c.JSON(200, gin.H{})
 		return
 	}
-	cfgCopy := *h.cfg
-	c.JSON(200, &cfgCopy)
+	c.JSON(200, h.cfg.WithSecretReferences())
 }
 
 type releaseInfo struct {
@@ -115,40 +115,42 @@ func (h *Handler) PutConfigYAML(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_yaml", "message": "cannot read request body"})
 		return
 	}
+	h.applyConfigYAML(c, body)
+}
+
+// applyConfigYAML validates body as a complete config, writes it to the config file and
+// reloads it. The previous and the new file are recorded in the config history; the previous
+// file is restored when the written config fails to reload.
+func (h *Handler) applyConfigYAML(c *gin.Context, body []byte) {
 	var cfg config.Config
-	if err = yaml.Unmarshal(body, &cfg); err != nil {
+	if err := yaml.Unmarshal(body, &cfg); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_yaml", "message": err.Error()})
 		return
 	}
-	// Validate config using LoadConfigOptional with optional=false to enforce parsing
-	tmpDir := filepath.Dir(h.configFilePath)
-	tmpFile, err := os.CreateTemp(tmpDir, "config-validate-*.yaml")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "write_failed", "message": err.Error()})
+	if !hasAdminAccess(c) && h.cfg != nil && managementAccessChanged(h.cfg.RemoteManagement, cfg.RemoteManagement) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden", "message": "changing remote-management requires the admin scope"})
 		return
 	}
-	tempFile := tmpFile.Name()
-	if _, errWrite := tmpFile.Write(body); errWrite != nil {
-		_ = tmpFile.Close()
-		_ = os.Remove(tempFile)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "write_failed", "message": errWrite.Error()})
-		return
-	}
-	if errClose := tmpFile.Close(); errClose != nil {
-		_ = os.Remove(tempFile)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "write_failed", "message": errClose.Error()})
-		return
-	}
-	defer func() {
-		_ = os.Remove(tempFile)
-	}()
-	_, err = config.LoadConfigOptional(tempFile, false)
+	validated, status, err := h.loadConfigData(body)
 	if err != nil {
-		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid_config", "message": err.Error()})
+		code := "invalid_config"
+		if status == http.StatusInternalServerError {
+			code = "write_failed"
+		}
+		c.JSON(status, gin.H{"error": code, "message": err.Error()})
 		return
 	}
+	changes := []string{}
+	if h.cfg != nil {
+		changes = append(changes, diff.BuildConfigChangeDetails(h.cfg, validated)...)
+	}
+
 	h.mu.Lock()
 	defer h.mu.Unlock()
+	previous, errPrevious := os.ReadFile(h.configFilePath)
+	if errPrevious == nil {
+		h.recordConfigVersion(previous)
+	}
 	if WriteConfig(h.configFilePath, body) != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "write_failed", "message": "failed to write config"})
 		return
@@ -156,11 +158,43 @@ func (h *Handler) PutConfigYAML(c *gin.Context) {
 	// Reload into handler to keep memory in sync
 	newCfg, err := config.LoadConfig(h.configFilePath)
 	if err != nil {
+		if errPrevious == nil {
+			if errRestore := WriteConfig(h.configFilePath, previous); errRestore != nil {
+				log.WithError(errRestore).Error("failed to restore the previous config after a failed reload")
+			}
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "reload_failed", "message": err.Error()})
 		return
 	}
 	h.cfg = newCfg
-	c.JSON(http.StatusOK, gin.H{"ok": true, "changed": []string{"config"}})
+	h.recordConfigFile()
+	c.JSON(http.StatusOK, gin.H{"ok": true, "changed": []string{"config"}, "changes": changes})
+}
+
+// loadConfigData validates data with LoadConfigOptional (optional=false to enforce parsing)
+// and returns the config it loads, with defaults and sanitization applied. On failure it also
+// returns the HTTP status to report.
+func (h *Handler) loadConfigData(data []byte) (*config.Config, int, error) {
+	tmpFile, err := os.CreateTemp(filepath.Dir(h.configFilePath), "config-validate-*.yaml")
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	tempFile := tmpFile.Name()
+	defer func() {
+		_ = os.Remove(tempFile)
+	}()
+	if _, errWrite := tmpFile.Write(data); errWrite != nil {
+		_ = tmpFile.Close()
+		return nil, http.StatusInternalServerError, errWrite
+	}
+	if errClose := tmpFile.Close(); errClose != nil {
+		return nil, http.StatusInternalServerError, errClose
+	}
+	cfg, err := config.LoadConfigOptional(tempFile, false)
+	if err != nil {
+		return nil, http.StatusUnprocessableEntity, err
+	}
+	return cfg, http.StatusOK, nil
 }
 
 // GetConfigYAML returns the raw config.yaml file bytes without re-encoding.
@@ -287,6 +321,8 @@ func normalizeRoutingStrategy(strategy string) (string, bool) {
 		return "round-robin", true
 	case "fill-first", "fillfirst", "ff":
 		return "fill-first", true
+	case "latency", "least-latency", "ll":
+		return "latency", true
 	default:
 		return "", false
 	}
@@ -0,0 +1,127 @@
+package management
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/watcher/diff"
+	log "github.com/sirupsen/logrus"
+)
+
+// configHistory returns the version history of the config file, or nil when it is disabled.
+func (h *Handler) configHistory() *config.ConfigHistory {
+	maxVersions := 0
+	if h.cfg != nil {
+		maxVersions = h.cfg.RemoteManagement.ConfigHistory
+	}
+	return config.NewConfigHistory(h.configFilePath, maxVersions)
+}
+
+// recordConfigVersion stores data in the config history. Failures are logged and never block
+// the config write that triggered them.
+func (h *Handler) recordConfigVersion(data []byte) {
+	if _, _, err := h.configHistory().Record(data); err != nil {
+		log.WithError(err).Warn("failed to record config version")
+	}
+}
+
+// recordConfigFile stores the current config file in the config history. Writers call it
+// before and after changing the file, so edits made outside the management API are kept too.
+func (h *Handler) recordConfigFile() {
+	data, err := os.ReadFile(h.configFilePath)
+	if err != nil {
+		return
+	}
+	h.recordConfigVersion(data)
+}
+
+// GetConfigHistory lists the stored config versions, newest first.
+func (h *Handler) GetConfigHistory(c *gin.Context) {
+	versions, err := h.configHistory().List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if versions == nil {
+		versions = []config.ConfigVersion{}
+	}
+	c.JSON(http.StatusOK, gin.H{"versions": versions})
+}
+
+// GetConfigVersion returns the raw YAML of a stored config version.
+func (h *Handler) GetConfigVersion(c *gin.Context) {
+	data, ok := h.readConfigVersion(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	c.Header("Content-Type", "application/yaml; charset=utf-8")
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Content-Type-Options", "nosniff")
+	_, _ = c.Writer.Write(data)
+}
+
+// GetConfigVersionDiff describes the changes from the config given by the against query
+// parameter (another version ID, or "current" by default) to a stored version, i.e. what a
+// rollback to that version would change.
+func (h *Handler) GetConfigVersionDiff(c *gin.Context) {
+	data, ok := h.readConfigVersion(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	target, status, err := h.loadConfigData(data)
+	if err != nil {
+		c.JSON(status, gin.H{"error": "invalid_config", "message": err.Error()})
+		return
+	}
+	against := strings.TrimSpace(c.Query("against"))
+	var base *config.Config
+	if against == "" || against == "current" {
+		base = snapshotConfig(h.cfg)
+	} else {
+		baseData, found := h.readConfigVersion(c, against)
+		if !found {
+			return
+		}
+		if base, status, err = h.loadConfigData(baseData); err != nil {
+			c.JSON(status, gin.H{"error": "invalid_config", "message": err.Error()})
+			return
+		}
+	}
+	changes := []string{}
+	if base != nil {
+		changes = append(changes, diff.BuildConfigChangeDetails(base, target)...)
+	}
+	c.JSON(http.StatusOK, gin.H{"id": c.Param("id"), "against": against, "changes": changes})
+}
+
+// PostConfigRollback restores a stored config version. The version goes through the same
+// validation as PUT /config.yaml, and the config it replaces is kept in the history.
+func (h *Handler) PostConfigRollback(c *gin.Context) {
+	data, ok := h.readConfigVersion(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	h.applyConfigYAML(c, data)
+}
+
+func (h *Handler) readConfigVersion(c *gin.Context, id string) ([]byte, bool) {
+	history := h.configHistory()
+	if history == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "config history is disabled"})
+		return nil, false
+	}
+	data, err := history.Read(strings.TrimSpace(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("config version %q not found", id)})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return data, true
+}
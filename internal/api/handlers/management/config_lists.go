@@ -7,6 +7,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 )
 
 // Generic helpers for list[string]
@@ -109,19 +110,24 @@ func (h *Handler) GetAPIKeys(c *gin.Context) { c.JSON(200, gin.H{"api-keys": h.c
 func (h *Handler) PutAPIKeys(c *gin.Context) {
 	h.putStringList(c, func(v []string) {
 		h.cfg.APIKeys = append([]string(nil), v...)
-		h.cfg.Access.Providers = nil
 	}, nil)
 }
 func (h *Handler) PatchAPIKeys(c *gin.Context) {
-	h.patchStringList(c, &h.cfg.APIKeys, func() { h.cfg.Access.Providers = nil })
+	h.patchStringList(c, &h.cfg.APIKeys, nil)
 }
 func (h *Handler) DeleteAPIKeys(c *gin.Context) {
-	h.deleteFromStringList(c, &h.cfg.APIKeys, func() { h.cfg.Access.Providers = nil })
+	h.deleteFromStringList(c, &h.cfg.APIKeys, nil)
+}
+
+// apiKeyMatches reports whether value identifies key, either as the key itself or as the
+// secret reference it was resolved from.
+func (h *Handler) apiKeyMatches(key, value string) bool {
+	return key == value || h.cfg.SecretReference(key) == value
 }
 
 // gemini-api-key: []GeminiKey
 func (h *Handler) GetGeminiKeys(c *gin.Context) {
-	c.JSON(200, gin.H{"gemini-api-key": h.cfg.GeminiKey})
+	c.JSON(200, gin.H{"gemini-api-key": h.cfg.WithSecretReferences().GeminiKey})
 }
 func (h *Handler) PutGeminiKeys(c *gin.Context) {
 	data, err := c.GetRawData()
@@ -152,6 +158,7 @@ func (h *Handler) PatchGeminiKey(c *gin.Context) {
 		ProxyURL       *string            `json:"proxy-url"`
 		Headers        *map[string]string `json:"headers"`
 		ExcludedModels *[]string          `json:"excluded-models"`
+		Tags           *[]string          `json:"tags"`
 	}
 	var body struct {
 		Index *int            `json:"index"`
@@ -170,7 +177,7 @@ func (h *Handler) PatchGeminiKey(c *gin.Context) {
 		match := strings.TrimSpace(*body.Match)
 		if match != "" {
 			for i := range h.cfg.GeminiKey {
-				if h.cfg.GeminiKey[i].APIKey == match {
+				if h.apiKeyMatches(h.cfg.GeminiKey[i].APIKey, match) {
 					targetIndex = i
 					break
 				}
@@ -208,6 +215,9 @@ func (h *Handler) PatchGeminiKey(c *gin.Context) {
 	if body.Value.ExcludedModels != nil {
 		entry.ExcludedModels = config.NormalizeExcludedModels(*body.Value.ExcludedModels)
 	}
+	if body.Value.Tags != nil {
+		entry.Tags = coreauth.NormalizeTags(*body.Value.Tags)
+	}
 	h.cfg.GeminiKey[targetIndex] = entry
 	h.cfg.SanitizeGeminiKeys()
 	h.persist(c)
@@ -217,7 +227,7 @@ func (h *Handler) DeleteGeminiKey(c *gin.Context) {
 	if val := strings.TrimSpace(c.Query("api-key")); val != "" {
 		out := make([]config.GeminiKey, 0, len(h.cfg.GeminiKey))
 		for _, v := range h.cfg.GeminiKey {
-			if v.APIKey != val {
+			if !h.apiKeyMatches(v.APIKey, val) {
 				out = append(out, v)
 			}
 		}
@@ -244,7 +254,7 @@ func (h *Handler) DeleteGeminiKey(c *gin.Context) {
 
 // claude-api-key: []ClaudeKey
 func (h *Handler) GetClaudeKeys(c *gin.Context) {
-	c.JSON(200, gin.H{"claude-api-key": h.cfg.ClaudeKey})
+	c.JSON(200, gin.H{"claude-api-key": h.cfg.WithSecretReferences().ClaudeKey})
 }
 func (h *Handler) PutClaudeKeys(c *gin.Context) {
 	data, err := c.GetRawData()
@@ -279,6 +289,7 @@ func (h *Handler) PatchClaudeKey(c *gin.Context) {
 		Models         *[]config.ClaudeModel `json:"models"`
 		Headers        *map[string]string    `json:"headers"`
 		ExcludedModels *[]string             `json:"excluded-models"`
+		Tags           *[]string             `json:"tags"`
 	}
 	var body struct {
 		Index *int            `json:"index"`
@@ -296,7 +307,7 @@ func (h *Handler) PatchClaudeKey(c *gin.Context) {
 	if targetIndex == -1 && body.Match != nil {
 		match := strings.TrimSpace(*body.Match)
 		for i := range h.cfg.ClaudeKey {
-			if h.cfg.ClaudeKey[i].APIKey == match {
+			if h.apiKeyMatches(h.cfg.ClaudeKey[i].APIKey, match) {
 				targetIndex = i
 				break
 			}
@@ -330,6 +341,9 @@ func (h *Handler) PatchClaudeKey(c *gin.Context) {
 		entry.ExcludedModels = config.NormalizeExcludedModels(*body.Value.ExcludedModels)
 	}
 	normalizeClaudeKey(&entry)
+	if body.Value.Tags != nil {
+		entry.Tags = coreauth.NormalizeTags(*body.Value.Tags)
+	}
 	h.cfg.ClaudeKey[targetIndex] = entry
 	h.cfg.SanitizeClaudeKeys()
 	h.persist(c)
@@ -339,7 +353,7 @@ func (h *Handler) DeleteClaudeKey(c *gin.Context) {
 	if val := c.Query("api-key"); val != "" {
 		out := make([]config.ClaudeKey, 0, len(h.cfg.ClaudeKey))
 		for _, v := range h.cfg.ClaudeKey {
-			if v.APIKey != val {
+			if !h.apiKeyMatches(v.APIKey, val) {
 				out = append(out, v)
 			}
 		}
@@ -363,7 +377,7 @@ func (h *Handler) DeleteClaudeKey(c *gin.Context) {
 
 // openai-compatibility: []OpenAICompatibility
 func (h *Handler) GetOpenAICompat(c *gin.Context) {
-	c.JSON(200, gin.H{"openai-compatibility": normalizedOpenAICompatibilityEntries(h.cfg.OpenAICompatibility)})
+	c.JSON(200, gin.H{"openai-compatibility": normalizedOpenAICompatibilityEntries(h.cfg.WithSecretReferences().OpenAICompatibility)})
 }
 func (h *Handler) PutOpenAICompat(c *gin.Context) {
 	data, err := c.GetRawData()
@@ -489,7 +503,7 @@ func (h *Handler) DeleteOpenAICompat(c *gin.Context) {
 
 // vertex-api-key: []VertexCompatKey
 func (h *Handler) GetVertexCompatKeys(c *gin.Context) {
-	c.JSON(200, gin.H{"vertex-api-key": h.cfg.VertexCompatAPIKey})
+	c.JSON(200, gin.H{"vertex-api-key": h.cfg.WithSecretReferences().VertexCompatAPIKey})
 }
 func (h *Handler) PutVertexCompatKeys(c *gin.Context) {
 	data, err := c.GetRawData()
@@ -523,6 +537,7 @@ func (h *Handler) PatchVertexCompatKey(c *gin.Context) {
 		ProxyURL *string                     `json:"proxy-url"`
 		Headers  *map[string]string          `json:"headers"`
 		Models   *[]config.VertexCompatModel `json:"models"`
+		Tags     *[]string                   `json:"tags"`
 	}
 	var body struct {
 		Index *int               `json:"index"`
@@ -541,7 +556,7 @@ func (h *Handler) PatchVertexCompatKey(c *gin.Context) {
 		match := strings.TrimSpace(*body.Match)
 		if match != "" {
 			for i := range h.cfg.VertexCompatAPIKey {
-				if h.cfg.VertexCompatAPIKey[i].APIKey == match {
+				if h.apiKeyMatches(h.cfg.VertexCompatAPIKey[i].APIKey, match) {
 					targetIndex = i
 					break
 				}
@@ -587,6 +602,9 @@ func (h *Handler) PatchVertexCompatKey(c *gin.Context) {
 		entry.Models = append([]config.VertexCompatModel(nil), (*body.Value.Models)...)
 	}
 	normalizeVertexCompatKey(&entry)
+	if body.Value.Tags != nil {
+		entry.Tags = coreauth.NormalizeTags(*body.Value.Tags)
+	}
 	h.cfg.VertexCompatAPIKey[targetIndex] = entry
 	h.cfg.SanitizeVertexCompatKeys()
 	h.persist(c)
@@ -596,7 +614,7 @@ func (h *Handler) DeleteVertexCompatKey(c *gin.Context) {
 	if val := strings.TrimSpace(c.Query("api-key")); val != "" {
 		out := make([]config.VertexCompatKey, 0, len(h.cfg.VertexCompatAPIKey))
 		for _, v := range h.cfg.VertexCompatAPIKey {
-			if v.APIKey != val {
+			if !h.apiKeyMatches(v.APIKey, val) {
 				out = append(out, v)
 			}
 		}
@@ -802,7 +820,7 @@ func (h *Handler) DeleteOAuthModelAlias(c *gin.Context) {
 
 // codex-api-key: []CodexKey
 func (h *Handler) GetCodexKeys(c *gin.Context) {
-	c.JSON(200, gin.H{"codex-api-key": h.cfg.CodexKey})
+	c.JSON(200, gin.H{"codex-api-key": h.cfg.WithSecretReferences().CodexKey})
 }
 func (h *Handler) PutCodexKeys(c *gin.Context) {
 	data, err := c.GetRawData()
@@ -844,6 +862,7 @@ func (h *Handler) PatchCodexKey(c *gin.Context) {
 		Models         *[]config.CodexModel `json:"models"`
 		Headers        *map[string]string   `json:"headers"`
 		ExcludedModels *[]string            `json:"excluded-models"`
+		Tags           *[]string            `json:"tags"`
 	}
 	var body struct {
 		Index *int           `json:"index"`
@@ -861,7 +880,7 @@ func (h *Handler) PatchCodexKey(c *gin.Context) {
 	if targetIndex == -1 && body.Match != nil {
 		match := strings.TrimSpace(*body.Match)
 		for i := range h.cfg.CodexKey {
-			if h.cfg.CodexKey[i].APIKey == match {
+			if h.apiKeyMatches(h.cfg.CodexKey[i].APIKey, match) {
 				targetIndex = i
 				break
 			}
@@ -902,6 +921,9 @@ func (h *Handler) PatchCodexKey(c *gin.Context) {
 		entry.ExcludedModels = config.NormalizeExcludedModels(*body.Value.ExcludedModels)
 	}
 	normalizeCodexKey(&entry)
+	if body.Value.Tags != nil {
+		entry.Tags = coreauth.NormalizeTags(*body.Value.Tags)
+	}
 	h.cfg.CodexKey[targetIndex] = entry
 	h.cfg.SanitizeCodexKeys()
 	h.persist(c)
@@ -911,7 +933,7 @@ func (h *Handler) DeleteCodexKey(c *gin.Context) {
 	if val := c.Query("api-key"); val != "" {
 		out := make([]config.CodexKey, 0, len(h.cfg.CodexKey))
 		for _, v := range h.cfg.CodexKey {
-			if v.APIKey != val {
+			if !h.apiKeyMatches(v.APIKey, val) {
 				out = append(out, v)
 			}
 		}
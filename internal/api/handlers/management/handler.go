@@ -129,7 +129,8 @@ func (h *Handler) SetLogDirectory(dir string) {
 }
 
 // Middleware enforces access control for management endpoints.
-// All requests (local and remote) require a valid management key.
+// All requests (local and remote) require a valid management key: the secret key, which
+// grants full access, or a scoped management token.
 // Additionally, remote access requires allow-remote-management=true.
 func (h *Handler) Middleware() gin.HandlerFunc {
 	const maxFailures = 5
@@ -146,10 +147,12 @@ func (h *Handler) Middleware() gin.HandlerFunc {
 		var (
 			allowRemote bool
 			secretHash  string
+			tokens      []config.ManagementToken
 		)
 		if cfg != nil {
 			allowRemote = cfg.RemoteManagement.AllowRemote
 			secretHash = cfg.RemoteManagement.SecretKey
+			tokens = cfg.RemoteManagement.Tokens
 		}
 		if h.allowRemoteOverride {
 			allowRemote = true
@@ -196,7 +199,7 @@ func (h *Handler) Middleware() gin.HandlerFunc {
 				h.attemptsMu.Unlock()
 			}
 		}
-		if secretHash == "" && envSecret == "" {
+		if secretHash == "" && envSecret == "" && len(tokens) == 0 {
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "remote management key not set"})
 			return
 		}
@@ -226,6 +229,7 @@ func (h *Handler) Middleware() gin.HandlerFunc {
 		if localClient {
 			if lp := h.localPassword; lp != "" {
 				if subtle.ConstantTimeCompare([]byte(provided), []byte(lp)) == 1 {
+					c.Set(managementActorContextKey, actorLocalPassword)
 					c.Next()
 					return
 				}
@@ -241,16 +245,25 @@ func (h *Handler) Middleware() gin.HandlerFunc {
 				}
 				h.attemptsMu.Unlock()
 			}
+			c.Set(managementActorContextKey, actorEnvPassword)
 			c.Next()
 			return
 		}
 
 		if secretHash == "" || bcrypt.CompareHashAndPassword([]byte(secretHash), []byte(provided)) != nil {
-			if !localClient {
-				fail()
+			token, ok := matchManagementToken(tokens, provided)
+			if !ok {
+				if !localClient {
+					fail()
+				}
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid management key"})
+				return
 			}
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid management key"})
-			return
+			// Scoped tokens are checked per route by RequireScope.
+			c.Set(managementTokenContextKey, token)
+			c.Set(managementActorContextKey, "token:"+token.Name)
+		} else {
+			c.Set(managementActorContextKey, actorSecretKey)
 		}
 
 		if !localClient {
@@ -268,10 +281,7 @@ func (h *Handler) Middleware() gin.HandlerFunc {
 
 // persist saves the current in-memory config to disk.
 func (h *Handler) persist(c *gin.Context) bool {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	// Preserve comments when writing
-	if err := config.SaveConfigPreserveComments(h.configFilePath, h.cfg); err != nil {
+	if err := h.saveConfig(); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to save config: %v", err)})
 		return false
 	}
@@ -279,6 +289,23 @@ func (h *Handler) persist(c *gin.Context) bool {
 	return true
 }
 
+// saveConfig writes the current in-memory config to disk, preserving comments.
+func (h *Handler) saveConfig() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// Entries added through the API may carry secret references; resolve them now so the
+	// running config uses the secrets while the file keeps the references.
+	if err := h.cfg.ResolveSecrets(); err != nil {
+		return err
+	}
+	h.recordConfigFile()
+	if err := config.SaveConfigPreserveComments(h.configFilePath, h.cfg); err != nil {
+		return err
+	}
+	h.recordConfigFile()
+	return nil
+}
+
 // Helper methods for simple types
 func (h *Handler) updateBoolField(c *gin.Context, set func(bool)) {
 	var body struct {
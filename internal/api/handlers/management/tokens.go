@@ -0,0 +1,235 @@
+package management
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// managementTokenContextKey stores the scoped token that authenticated a request. Requests
+// authenticated with the secret key carry no token and may use every route.
+const managementTokenContextKey = "managementToken"
+
+// matchManagementToken returns the configured token whose key equals provided.
+func matchManagementToken(tokens []config.ManagementToken, provided string) (config.ManagementToken, bool) {
+	for _, token := range tokens {
+		if strings.HasPrefix(token.Token, "$2") {
+			if bcrypt.CompareHashAndPassword([]byte(token.Token), []byte(provided)) == nil {
+				return token, true
+			}
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(token.Token), []byte(provided)) == 1 {
+			return token, true
+		}
+	}
+	return config.ManagementToken{}, false
+}
+
+// ManagementTokenName returns the name of the scoped token that authenticated the request,
+// or an empty string when the secret key was used.
+func ManagementTokenName(c *gin.Context) string {
+	if token, ok := managementTokenFromContext(c); ok {
+		return token.Name
+	}
+	return ""
+}
+
+func managementTokenFromContext(c *gin.Context) (config.ManagementToken, bool) {
+	if c == nil {
+		return config.ManagementToken{}, false
+	}
+	v, exists := c.Get(managementTokenContextKey)
+	if !exists {
+		return config.ManagementToken{}, false
+	}
+	token, ok := v.(config.ManagementToken)
+	return token, ok
+}
+
+// RequireScope returns a middleware admitting requests authenticated with the secret key or
+// with a token holding scope or admin. Tokens holding read-only may also use GET requests.
+func (h *Handler) RequireScope(scope string) gin.HandlerFunc {
+	return requireScope(scope, true)
+}
+
+// RequireWriteScope is like RequireScope but never admits read-only tokens. It guards GET
+// routes with side effects, such as those starting an OAuth login.
+func (h *Handler) RequireWriteScope(scope string) gin.HandlerFunc {
+	return requireScope(scope, false)
+}
+
+func requireScope(scope string, allowReadOnly bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := managementTokenFromContext(c)
+		if !ok {
+			c.Next()
+			return
+		}
+		readOnly := allowReadOnly && (c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead)
+		for _, granted := range token.Scopes {
+			if granted == scope || granted == config.ManagementScopeAdmin || (readOnly && granted == config.ManagementScopeReadOnly) {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("management token %q lacks the %s scope", token.Name, scope)})
+	}
+}
+
+// hasAdminAccess reports whether the request was authenticated with the secret key or with a
+// token holding the admin scope.
+func hasAdminAccess(c *gin.Context) bool {
+	token, ok := managementTokenFromContext(c)
+	if !ok {
+		return true
+	}
+	for _, scope := range token.Scopes {
+		if scope == config.ManagementScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// managementAccessChanged reports whether next changes who may access the management API.
+// Scoped tokens without admin must not be able to grant themselves more access that way.
+func managementAccessChanged(current, next config.RemoteManagement) bool {
+	normalized := config.Config{RemoteManagement: next}
+	normalized.SanitizeManagementTokens()
+	next = normalized.RemoteManagement
+	return current.AllowRemote != next.AllowRemote ||
+		strings.TrimSpace(current.SecretKey) != strings.TrimSpace(next.SecretKey) ||
+		(len(current.Tokens) > 0 || len(next.Tokens) > 0) && !reflect.DeepEqual(current.Tokens, next.Tokens)
+}
+
+// GetManagementTokens lists the scoped management tokens without their keys.
+func (h *Handler) GetManagementTokens(c *gin.Context) {
+	tokens := h.cfg.RemoteManagement.Tokens
+	if tokens == nil {
+		tokens = []config.ManagementToken{}
+	}
+	c.JSON(http.StatusOK, gin.H{"management-tokens": tokens})
+}
+
+// PostManagementToken creates a scoped management token. The key is generated unless the body
+// provides one and is returned only in this response; the config stores its bcrypt hash.
+func (h *Handler) PostManagementToken(c *gin.Context) {
+	var body struct {
+		Name   string   `json:"name"`
+		Token  string   `json:"token"`
+		Scopes []string `json:"scopes"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	name := strings.TrimSpace(body.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+	scopes, errScopes := parseManagementScopes(body.Scopes)
+	if errScopes != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errScopes.Error()})
+		return
+	}
+	for _, existing := range h.cfg.RemoteManagement.Tokens {
+		if existing.Name == name {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("management token %q already exists", name)})
+			return
+		}
+	}
+	key := strings.TrimSpace(body.Token)
+	if key == "" {
+		raw := make([]byte, 24)
+		if _, err := rand.Read(raw); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to generate token: %v", err)})
+			return
+		}
+		key = "mgmt-" + hex.EncodeToString(raw)
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(key), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to hash token: %v", err)})
+		return
+	}
+
+	tokens := append([]config.ManagementToken(nil), h.cfg.RemoteManagement.Tokens...)
+	h.cfg.RemoteManagement.Tokens = append(tokens, config.ManagementToken{Name: name, Token: string(hashed), Scopes: scopes})
+	if err = h.saveConfig(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to save config: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "name": name, "token": key, "scopes": scopes})
+}
+
+// PatchManagementToken replaces the scopes of a management token.
+func (h *Handler) PatchManagementToken(c *gin.Context) {
+	var body struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	scopes, errScopes := parseManagementScopes(body.Scopes)
+	if errScopes != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errScopes.Error()})
+		return
+	}
+	name := strings.TrimSpace(body.Name)
+	tokens := append([]config.ManagementToken(nil), h.cfg.RemoteManagement.Tokens...)
+	for i := range tokens {
+		if tokens[i].Name == name {
+			tokens[i].Scopes = scopes
+			h.cfg.RemoteManagement.Tokens = tokens
+			h.persist(c)
+			return
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "item not found"})
+}
+
+// DeleteManagementToken revokes the management token named by the name query parameter.
+func (h *Handler) DeleteManagementToken(c *gin.Context) {
+	name := strings.TrimSpace(c.Query("name"))
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing name"})
+		return
+	}
+	tokens := make([]config.ManagementToken, 0, len(h.cfg.RemoteManagement.Tokens))
+	for _, token := range h.cfg.RemoteManagement.Tokens {
+		if token.Name != name {
+			tokens = append(tokens, token)
+		}
+	}
+	if len(tokens) == len(h.cfg.RemoteManagement.Tokens) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "item not found"})
+		return
+	}
+	h.cfg.RemoteManagement.Tokens = tokens
+	h.persist(c)
+}
+
+func parseManagementScopes(raw []string) ([]string, error) {
+	for _, scope := range raw {
+		if scope = strings.ToLower(strings.TrimSpace(scope)); !config.IsManagementScope(scope) {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	scopes := config.NormalizeManagementScopes(raw)
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	return scopes, nil
+}
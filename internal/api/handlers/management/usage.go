@@ -2,7 +2,10 @@ package management
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -26,10 +29,14 @@ func (h *Handler) GetUsageStatistics(c *gin.Context) {
 	if h != nil && h.usageStats != nil {
 		snapshot = h.usageStats.Snapshot()
 	}
-	c.JSON(http.StatusOK, gin.H{
+	body := gin.H{
 		"usage":           snapshot,
 		"failed_requests": snapshot.FailureCount,
-	})
+	}
+	if h != nil && h.authManager != nil {
+		body["cooldown_queue"] = h.authManager.QueueStats()
+	}
+	c.JSON(http.StatusOK, body)
 }
 
 // ExportUsageStatistics returns a complete usage snapshot for backup/migration.
@@ -77,3 +84,112 @@ func (h *Handler) ImportUsageStatistics(c *gin.Context) {
 		"failed_requests": snapshot.FailureCount,
 	})
 }
+
+const (
+	defaultUsageRecordLimit = 100
+	maxUsageRecordLimit     = 10000
+)
+
+// ListUsageRecords returns persisted usage records filtered by time range, client API key,
+// auth index, provider and model.
+func (h *Handler) ListUsageRecords(c *gin.Context) {
+	store := usage.PersistentStore()
+	if store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "usage store disabled"})
+		return
+	}
+	query, err := parseUsageQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query.Limit = defaultUsageRecordLimit
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		limit, errParse := strconv.Atoi(raw)
+		if errParse != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		query.Limit = min(limit, maxUsageRecordLimit)
+	}
+	records, err := store.Query(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if records == nil {
+		records = []usage.StoredRecord{}
+	}
+	c.JSON(http.StatusOK, gin.H{"records": records, "count": len(records)})
+}
+
+// GetUsageSummary aggregates persisted usage records by the dimension given in group-by.
+func (h *Handler) GetUsageSummary(c *gin.Context) {
+	store := usage.PersistentStore()
+	if store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "usage store disabled"})
+		return
+	}
+	query, err := parseUsageQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	groupBy := strings.ToLower(strings.TrimSpace(c.DefaultQuery("group-by", usage.GroupByModel)))
+	if !usage.ValidGroupBy(groupBy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group-by"})
+		return
+	}
+	groups, err := store.Summarize(c.Request.Context(), query, groupBy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	total := usage.Aggregate{Key: "total"}
+	for _, group := range groups {
+		total.Requests += group.Requests
+		total.Failures += group.Failures
+		total.Tokens.InputTokens += group.Tokens.InputTokens
+		total.Tokens.OutputTokens += group.Tokens.OutputTokens
+		total.Tokens.ReasoningTokens += group.Tokens.ReasoningTokens
+		total.Tokens.CachedTokens += group.Tokens.CachedTokens
+		total.Tokens.CacheWriteTokens += group.Tokens.CacheWriteTokens
+		total.Tokens.TotalTokens += group.Tokens.TotalTokens
+		total.Cost += group.Cost
+	}
+	if groups == nil {
+		groups = []usage.Aggregate{}
+	}
+	c.JSON(http.StatusOK, gin.H{"group_by": groupBy, "groups": groups, "total": total})
+}
+
+func parseUsageQuery(c *gin.Context) (usage.Query, error) {
+	var query usage.Query
+	var err error
+	if query.From, err = parseUsageTime(c.Query("from")); err != nil {
+		return query, fmt.Errorf("invalid from: %w", err)
+	}
+	if query.To, err = parseUsageTime(c.Query("to")); err != nil {
+		return query, fmt.Errorf("invalid to: %w", err)
+	}
+	query.APIKey = strings.TrimSpace(c.Query("api-key"))
+	query.AuthIndex = strings.TrimSpace(c.Query("auth-index"))
+	query.Provider = strings.TrimSpace(c.Query("provider"))
+	query.Model = strings.TrimSpace(c.Query("model"))
+	return query, nil
+}
+
+// parseUsageTime accepts RFC3339 timestamps, YYYY-MM-DD dates (UTC) or Unix seconds.
+func parseUsageTime(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if seconds, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", raw)
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/access"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/access/policy"
 	managementHandlers "github.com/router-for-me/CLIProxyAPI/v6/internal/api/handlers/management"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/api/middleware"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/api/modules"
@@ -27,6 +28,8 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/managementasset"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/metrics"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tracing"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
@@ -36,6 +39,7 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers/openai"
 	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/responsestore"
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
 )
@@ -64,6 +68,50 @@ func defaultRequestLoggerFactory(cfg *config.Config, configPath string) logging.
 	return logging.NewFileRequestLogger(cfg.RequestLog, "logs", configDir, cfg.ErrorLogsMaxFiles)
 }
 
+// configureResponsesStore applies the responses-store settings to the built-in
+// Responses API store used to expand previous_response_id.
+func configureResponsesStore(cfg *config.Config) {
+	storeCfg := cfg.ResponsesStore
+	dir := strings.TrimSpace(storeCfg.Dir)
+	if dir == "" {
+		if base := util.WritablePath(); base != "" {
+			dir = filepath.Join(base, "responses")
+		} else {
+			dir = "responses"
+		}
+	}
+	ttlHours := storeCfg.TTLHours
+	if ttlHours <= 0 {
+		ttlHours = 720
+	}
+	if err := responsestore.Configure(!storeCfg.Disable, storeCfg.Driver, dir, time.Duration(ttlHours)*time.Hour); err != nil {
+		log.Errorf("failed to configure responses store: %v", err)
+	}
+}
+
+// configureTracing applies the tracing settings, defaulting the file exporter
+// to traces.jsonl inside the resolved log directory.
+func configureTracing(cfg *config.Config) {
+	defaultFile := filepath.Join(logging.ResolveLogDirectory(cfg), "traces.jsonl")
+	if err := tracing.Configure(cfg.Tracing, defaultFile); err != nil {
+		log.Errorf("failed to configure tracing: %v", err)
+	}
+}
+
+// configureUsageStore applies the persistent usage settings, keeping local backends
+// under the writable path unless an explicit path is configured.
+func configureUsageStore(cfg *config.Config) {
+	dir := util.WritablePath()
+	if dir == "" {
+		dir = "."
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := usage.ConfigurePersistence(ctx, cfg.UsageStore, dir); err != nil {
+		log.Errorf("failed to configure usage store: %v", err)
+	}
+}
+
 // WithMiddleware appends additional Gin middleware during server construction.
 func WithMiddleware(mw ...gin.HandlerFunc) ServerOption {
 	return func(cfg *serverOptionConfig) {
@@ -203,6 +251,7 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 	// Add middleware
 	engine.Use(logging.GinLogrusLogger())
 	engine.Use(logging.GinLogrusRecovery())
+	engine.Use(tracing.Middleware())
 	for _, mw := range optionState.extraMiddleware {
 		engine.Use(mw)
 	}
@@ -252,9 +301,18 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 	s.applyAccessConfig(nil, cfg)
 	if authManager != nil {
 		authManager.SetRetryConfig(cfg.RequestRetry, time.Duration(cfg.MaxRetryInterval)*time.Second)
+		if requestLogger != nil {
+			authManager.SetShadowRecorder(shadowRequestLogger{logger: requestLogger})
+		}
 	}
 	managementasset.SetCurrentConfig(cfg)
 	auth.SetQuotaCooldownDisabled(cfg.DisableCooling)
+	configureResponsesStore(cfg)
+	metrics.SetEnabled(cfg.Metrics.Enable)
+	configureTracing(cfg)
+	configureUsageStore(cfg)
+	usage.SetPricing(cfg.Pricing)
+	policy.Default().Configure(cfg.ClientKeys)
 	// Initialize management handler
 	s.mgmt = managementHandlers.NewHandler(cfg, configFilePath, authManager)
 	if optionState.localPassword != "" {
@@ -285,7 +343,7 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 	}
 
 	// Register management routes when configuration or environment secrets are available.
-	hasManagementSecret := cfg.RemoteManagement.SecretKey != "" || envManagementSecret
+	hasManagementSecret := cfg.RemoteManagement.HasKeys() || envManagementSecret
 	s.managementRoutesEnabled.Store(hasManagementSecret)
 	if hasManagementSecret {
 		s.registerManagementRoutes()
@@ -321,10 +379,14 @@ func (s *Server) setupRoutes() {
 		v1.GET("/models", s.unifiedModelsHandler(openaiHandlers, claudeCodeHandlers))
 		v1.POST("/chat/completions", openaiHandlers.ChatCompletions)
 		v1.POST("/completions", openaiHandlers.Completions)
+		v1.POST("/embeddings", openaiHandlers.Embeddings)
 		v1.POST("/messages", claudeCodeHandlers.ClaudeMessages)
 		v1.POST("/messages/count_tokens", claudeCodeHandlers.ClaudeCountTokens)
 		v1.POST("/responses", openaiResponsesHandlers.Responses)
 		v1.POST("/responses/compact", openaiResponsesHandlers.Compact)
+		v1.GET("/responses/:id", openaiResponsesHandlers.GetResponse)
+		v1.DELETE("/responses/:id", openaiResponsesHandlers.DeleteResponse)
+		v1.GET("/responses/:id/input_items", openaiResponsesHandlers.ListInputItems)
 	}
 
 	// Gemini compatible API routes
@@ -343,11 +405,14 @@ func (s *Server) setupRoutes() {
 			"endpoints": []string{
 				"POST /v1/chat/completions",
 				"POST /v1/completions",
+				"POST /v1/embeddings",
 				"GET /v1/models",
 			},
 		})
 	})
 	s.engine.POST("/v1internal:method", geminiCLIHandlers.CLIHandler)
+	// Credential labels identify accounts, so scrapers authenticate like API clients.
+	s.engine.GET("/metrics", AuthMiddleware(s.accessManager), s.handleMetrics)
 
 	// OAuth callback endpoints (reuse main server port)
 	// These endpoints receive provider redirects and persist
@@ -473,160 +538,190 @@ func (s *Server) registerManagementRoutes() {
 	log.Info("management routes registered after secret key configuration")
 
 	mgmt := s.engine.Group("/v0/management")
-	mgmt.Use(s.managementAvailabilityMiddleware(), s.mgmt.Middleware())
+	mgmt.Use(s.managementAvailabilityMiddleware(), s.mgmt.Middleware(), s.mgmt.AuditMiddleware())
+
+	// Each route names the scope a management token needs; the secret key may use every route.
+	usageScope := s.mgmt.RequireScope(config.ManagementScopeUsage)
+	configScope := s.mgmt.RequireScope(config.ManagementScopeConfig)
+	credentialsScope := s.mgmt.RequireScope(config.ManagementScopeCredentials)
+	logsScope := s.mgmt.RequireScope(config.ManagementScopeLogs)
+	adminScope := s.mgmt.RequireScope(config.ManagementScopeAdmin)
+	// OAuth login URLs are fetched with GET but start a login, so read-only tokens may not use them.
+	loginScope := s.mgmt.RequireWriteScope(config.ManagementScopeCredentials)
+	// Downloading an auth file reveals its tokens, which read-only access does not cover.
+	downloadScope := s.mgmt.RequireWriteScope(config.ManagementScopeCredentials)
+	// The raw config file and its stored versions hold API keys and management token hashes.
+	configFileScope := s.mgmt.RequireWriteScope(config.ManagementScopeConfig)
+	// Routes returning client or upstream API keys reveal them in plaintext unless they came from
+	// secret references, so read-only tokens may not use them either.
+	configKeysScope := s.mgmt.RequireWriteScope(config.ManagementScopeConfig)
+	credentialKeysScope := s.mgmt.RequireWriteScope(config.ManagementScopeCredentials)
 	{
-		mgmt.GET("/usage", s.mgmt.GetUsageStatistics)
-		mgmt.GET("/usage/export", s.mgmt.ExportUsageStatistics)
-		mgmt.POST("/usage/import", s.mgmt.ImportUsageStatistics)
-		mgmt.GET("/config", s.mgmt.GetConfig)
-		mgmt.GET("/config.yaml", s.mgmt.GetConfigYAML)
-		mgmt.PUT("/config.yaml", s.mgmt.PutConfigYAML)
-		mgmt.GET("/latest-version", s.mgmt.GetLatestVersion)
-
-		mgmt.GET("/debug", s.mgmt.GetDebug)
-		mgmt.PUT("/debug", s.mgmt.PutDebug)
-		mgmt.PATCH("/debug", s.mgmt.PutDebug)
-
-		mgmt.GET("/logging-to-file", s.mgmt.GetLoggingToFile)
-		mgmt.PUT("/logging-to-file", s.mgmt.PutLoggingToFile)
-		mgmt.PATCH("/logging-to-file", s.mgmt.PutLoggingToFile)
-
-		mgmt.GET("/logs-max-total-size-mb", s.mgmt.GetLogsMaxTotalSizeMB)
-		mgmt.PUT("/logs-max-total-size-mb", s.mgmt.PutLogsMaxTotalSizeMB)
-		mgmt.PATCH("/logs-max-total-size-mb", s.mgmt.PutLogsMaxTotalSizeMB)
-
-		mgmt.GET("/error-logs-max-files", s.mgmt.GetErrorLogsMaxFiles)
-		mgmt.PUT("/error-logs-max-files", s.mgmt.PutErrorLogsMaxFiles)
-		mgmt.PATCH("/error-logs-max-files", s.mgmt.PutErrorLogsMaxFiles)
-
-		mgmt.GET("/usage-statistics-enabled", s.mgmt.GetUsageStatisticsEnabled)
-		mgmt.PUT("/usage-statistics-enabled", s.mgmt.PutUsageStatisticsEnabled)
-		mgmt.PATCH("/usage-statistics-enabled", s.mgmt.PutUsageStatisticsEnabled)
-
-		mgmt.GET("/proxy-url", s.mgmt.GetProxyURL)
-		mgmt.PUT("/proxy-url", s.mgmt.PutProxyURL)
-		mgmt.PATCH("/proxy-url", s.mgmt.PutProxyURL)
-		mgmt.DELETE("/proxy-url", s.mgmt.DeleteProxyURL)
-
-		mgmt.POST("/api-call", s.mgmt.APICall)
-
-		mgmt.GET("/quota-exceeded/switch-project", s.mgmt.GetSwitchProject)
-		mgmt.PUT("/quota-exceeded/switch-project", s.mgmt.PutSwitchProject)
-		mgmt.PATCH("/quota-exceeded/switch-project", s.mgmt.PutSwitchProject)
-
-		mgmt.GET("/quota-exceeded/switch-preview-model", s.mgmt.GetSwitchPreviewModel)
-		mgmt.PUT("/quota-exceeded/switch-preview-model", s.mgmt.PutSwitchPreviewModel)
-		mgmt.PATCH("/quota-exceeded/switch-preview-model", s.mgmt.PutSwitchPreviewModel)
-
-		mgmt.GET("/api-keys", s.mgmt.GetAPIKeys)
-		mgmt.PUT("/api-keys", s.mgmt.PutAPIKeys)
-		mgmt.PATCH("/api-keys", s.mgmt.PatchAPIKeys)
-		mgmt.DELETE("/api-keys", s.mgmt.DeleteAPIKeys)
-
-		mgmt.GET("/gemini-api-key", s.mgmt.GetGeminiKeys)
-		mgmt.PUT("/gemini-api-key", s.mgmt.PutGeminiKeys)
-		mgmt.PATCH("/gemini-api-key", s.mgmt.PatchGeminiKey)
-		mgmt.DELETE("/gemini-api-key", s.mgmt.DeleteGeminiKey)
-
-		mgmt.GET("/logs", s.mgmt.GetLogs)
-		mgmt.DELETE("/logs", s.mgmt.DeleteLogs)
-		mgmt.GET("/request-error-logs", s.mgmt.GetRequestErrorLogs)
-		mgmt.GET("/request-error-logs/:name", s.mgmt.DownloadRequestErrorLog)
-		mgmt.GET("/request-log-by-id/:id", s.mgmt.GetRequestLogByID)
-		mgmt.GET("/request-log", s.mgmt.GetRequestLog)
-		mgmt.PUT("/request-log", s.mgmt.PutRequestLog)
-		mgmt.PATCH("/request-log", s.mgmt.PutRequestLog)
-		mgmt.GET("/ws-auth", s.mgmt.GetWebsocketAuth)
-		mgmt.PUT("/ws-auth", s.mgmt.PutWebsocketAuth)
-		mgmt.PATCH("/ws-auth", s.mgmt.PutWebsocketAuth)
-
-		mgmt.GET("/ampcode", s.mgmt.GetAmpCode)
-		mgmt.GET("/ampcode/upstream-url", s.mgmt.GetAmpUpstreamURL)
-		mgmt.PUT("/ampcode/upstream-url", s.mgmt.PutAmpUpstreamURL)
-		mgmt.PATCH("/ampcode/upstream-url", s.mgmt.PutAmpUpstreamURL)
-		mgmt.DELETE("/ampcode/upstream-url", s.mgmt.DeleteAmpUpstreamURL)
-		mgmt.GET("/ampcode/upstream-api-key", s.mgmt.GetAmpUpstreamAPIKey)
-		mgmt.PUT("/ampcode/upstream-api-key", s.mgmt.PutAmpUpstreamAPIKey)
-		mgmt.PATCH("/ampcode/upstream-api-key", s.mgmt.PutAmpUpstreamAPIKey)
-		mgmt.DELETE("/ampcode/upstream-api-key", s.mgmt.DeleteAmpUpstreamAPIKey)
-		mgmt.GET("/ampcode/restrict-management-to-localhost", s.mgmt.GetAmpRestrictManagementToLocalhost)
-		mgmt.PUT("/ampcode/restrict-management-to-localhost", s.mgmt.PutAmpRestrictManagementToLocalhost)
-		mgmt.PATCH("/ampcode/restrict-management-to-localhost", s.mgmt.PutAmpRestrictManagementToLocalhost)
-		mgmt.GET("/ampcode/model-mappings", s.mgmt.GetAmpModelMappings)
-		mgmt.PUT("/ampcode/model-mappings", s.mgmt.PutAmpModelMappings)
-		mgmt.PATCH("/ampcode/model-mappings", s.mgmt.PatchAmpModelMappings)
-		mgmt.DELETE("/ampcode/model-mappings", s.mgmt.DeleteAmpModelMappings)
-		mgmt.GET("/ampcode/force-model-mappings", s.mgmt.GetAmpForceModelMappings)
-		mgmt.PUT("/ampcode/force-model-mappings", s.mgmt.PutAmpForceModelMappings)
-		mgmt.PATCH("/ampcode/force-model-mappings", s.mgmt.PutAmpForceModelMappings)
-		mgmt.GET("/ampcode/upstream-api-keys", s.mgmt.GetAmpUpstreamAPIKeys)
-		mgmt.PUT("/ampcode/upstream-api-keys", s.mgmt.PutAmpUpstreamAPIKeys)
-		mgmt.PATCH("/ampcode/upstream-api-keys", s.mgmt.PatchAmpUpstreamAPIKeys)
-		mgmt.DELETE("/ampcode/upstream-api-keys", s.mgmt.DeleteAmpUpstreamAPIKeys)
-
-		mgmt.GET("/request-retry", s.mgmt.GetRequestRetry)
-		mgmt.PUT("/request-retry", s.mgmt.PutRequestRetry)
-		mgmt.PATCH("/request-retry", s.mgmt.PutRequestRetry)
-		mgmt.GET("/max-retry-interval", s.mgmt.GetMaxRetryInterval)
-		mgmt.PUT("/max-retry-interval", s.mgmt.PutMaxRetryInterval)
-		mgmt.PATCH("/max-retry-interval", s.mgmt.PutMaxRetryInterval)
-
-		mgmt.GET("/force-model-prefix", s.mgmt.GetForceModelPrefix)
-		mgmt.PUT("/force-model-prefix", s.mgmt.PutForceModelPrefix)
-		mgmt.PATCH("/force-model-prefix", s.mgmt.PutForceModelPrefix)
-
-		mgmt.GET("/routing/strategy", s.mgmt.GetRoutingStrategy)
-		mgmt.PUT("/routing/strategy", s.mgmt.PutRoutingStrategy)
-		mgmt.PATCH("/routing/strategy", s.mgmt.PutRoutingStrategy)
-
-		mgmt.GET("/claude-api-key", s.mgmt.GetClaudeKeys)
-		mgmt.PUT("/claude-api-key", s.mgmt.PutClaudeKeys)
-		mgmt.PATCH("/claude-api-key", s.mgmt.PatchClaudeKey)
-		mgmt.DELETE("/claude-api-key", s.mgmt.DeleteClaudeKey)
-
-		mgmt.GET("/codex-api-key", s.mgmt.GetCodexKeys)
-		mgmt.PUT("/codex-api-key", s.mgmt.PutCodexKeys)
-		mgmt.PATCH("/codex-api-key", s.mgmt.PatchCodexKey)
-		mgmt.DELETE("/codex-api-key", s.mgmt.DeleteCodexKey)
-
-		mgmt.GET("/openai-compatibility", s.mgmt.GetOpenAICompat)
-		mgmt.PUT("/openai-compatibility", s.mgmt.PutOpenAICompat)
-		mgmt.PATCH("/openai-compatibility", s.mgmt.PatchOpenAICompat)
-		mgmt.DELETE("/openai-compatibility", s.mgmt.DeleteOpenAICompat)
-
-		mgmt.GET("/vertex-api-key", s.mgmt.GetVertexCompatKeys)
-		mgmt.PUT("/vertex-api-key", s.mgmt.PutVertexCompatKeys)
-		mgmt.PATCH("/vertex-api-key", s.mgmt.PatchVertexCompatKey)
-		mgmt.DELETE("/vertex-api-key", s.mgmt.DeleteVertexCompatKey)
-
-		mgmt.GET("/oauth-excluded-models", s.mgmt.GetOAuthExcludedModels)
-		mgmt.PUT("/oauth-excluded-models", s.mgmt.PutOAuthExcludedModels)
-		mgmt.PATCH("/oauth-excluded-models", s.mgmt.PatchOAuthExcludedModels)
-		mgmt.DELETE("/oauth-excluded-models", s.mgmt.DeleteOAuthExcludedModels)
-
-		mgmt.GET("/oauth-model-alias", s.mgmt.GetOAuthModelAlias)
-		mgmt.PUT("/oauth-model-alias", s.mgmt.PutOAuthModelAlias)
-		mgmt.PATCH("/oauth-model-alias", s.mgmt.PatchOAuthModelAlias)
-		mgmt.DELETE("/oauth-model-alias", s.mgmt.DeleteOAuthModelAlias)
-
-		mgmt.GET("/auth-files", s.mgmt.ListAuthFiles)
-		mgmt.GET("/auth-files/models", s.mgmt.GetAuthFileModels)
-		mgmt.GET("/model-definitions/:channel", s.mgmt.GetStaticModelDefinitions)
-		mgmt.GET("/auth-files/download", s.mgmt.DownloadAuthFile)
-		mgmt.POST("/auth-files", s.mgmt.UploadAuthFile)
-		mgmt.DELETE("/auth-files", s.mgmt.DeleteAuthFile)
-		mgmt.PATCH("/auth-files/status", s.mgmt.PatchAuthFileStatus)
-		mgmt.POST("/vertex/import", s.mgmt.ImportVertexCredential)
-
-		mgmt.GET("/anthropic-auth-url", s.mgmt.RequestAnthropicToken)
-		mgmt.GET("/codex-auth-url", s.mgmt.RequestCodexToken)
-		mgmt.GET("/gemini-cli-auth-url", s.mgmt.RequestGeminiCLIToken)
-		mgmt.GET("/antigravity-auth-url", s.mgmt.RequestAntigravityToken)
-		mgmt.GET("/qwen-auth-url", s.mgmt.RequestQwenToken)
-		mgmt.GET("/iflow-auth-url", s.mgmt.RequestIFlowToken)
-		mgmt.POST("/iflow-auth-url", s.mgmt.RequestIFlowCookieToken)
-		mgmt.POST("/oauth-callback", s.mgmt.PostOAuthCallback)
-		mgmt.GET("/get-auth-status", s.mgmt.GetAuthStatus)
+		mgmt.GET("/usage", usageScope, s.mgmt.GetUsageStatistics)
+		mgmt.GET("/usage/export", usageScope, s.mgmt.ExportUsageStatistics)
+		mgmt.POST("/usage/import", usageScope, s.mgmt.ImportUsageStatistics)
+		mgmt.GET("/usage/records", usageScope, s.mgmt.ListUsageRecords)
+		mgmt.GET("/usage/summary", usageScope, s.mgmt.GetUsageSummary)
+		mgmt.GET("/config", configKeysScope, s.mgmt.GetConfig)
+		mgmt.GET("/config.yaml", configFileScope, s.mgmt.GetConfigYAML)
+		mgmt.PUT("/config.yaml", configScope, s.mgmt.PutConfigYAML)
+		mgmt.GET("/config/history", configScope, s.mgmt.GetConfigHistory)
+		mgmt.GET("/config/history/:id", configFileScope, s.mgmt.GetConfigVersion)
+		mgmt.GET("/config/history/:id/diff", configScope, s.mgmt.GetConfigVersionDiff)
+		mgmt.POST("/config/history/:id/rollback", configScope, s.mgmt.PostConfigRollback)
+		mgmt.GET("/latest-version", configScope, s.mgmt.GetLatestVersion)
+
+		mgmt.GET("/management-tokens", adminScope, s.mgmt.GetManagementTokens)
+		mgmt.POST("/management-tokens", adminScope, s.mgmt.PostManagementToken)
+		mgmt.PATCH("/management-tokens", adminScope, s.mgmt.PatchManagementToken)
+		mgmt.DELETE("/management-tokens", adminScope, s.mgmt.DeleteManagementToken)
+
+		mgmt.GET("/debug", configScope, s.mgmt.GetDebug)
+		mgmt.PUT("/debug", configScope, s.mgmt.PutDebug)
+		mgmt.PATCH("/debug", configScope, s.mgmt.PutDebug)
+
+		mgmt.GET("/logging-to-file", configScope, s.mgmt.GetLoggingToFile)
+		mgmt.PUT("/logging-to-file", configScope, s.mgmt.PutLoggingToFile)
+		mgmt.PATCH("/logging-to-file", configScope, s.mgmt.PutLoggingToFile)
+
+		mgmt.GET("/logs-max-total-size-mb", configScope, s.mgmt.GetLogsMaxTotalSizeMB)
+		mgmt.PUT("/logs-max-total-size-mb", configScope, s.mgmt.PutLogsMaxTotalSizeMB)
+		mgmt.PATCH("/logs-max-total-size-mb", configScope, s.mgmt.PutLogsMaxTotalSizeMB)
+
+		mgmt.GET("/error-logs-max-files", configScope, s.mgmt.GetErrorLogsMaxFiles)
+		mgmt.PUT("/error-logs-max-files", configScope, s.mgmt.PutErrorLogsMaxFiles)
+		mgmt.PATCH("/error-logs-max-files", configScope, s.mgmt.PutErrorLogsMaxFiles)
+
+		mgmt.GET("/usage-statistics-enabled", usageScope, s.mgmt.GetUsageStatisticsEnabled)
+		mgmt.PUT("/usage-statistics-enabled", usageScope, s.mgmt.PutUsageStatisticsEnabled)
+		mgmt.PATCH("/usage-statistics-enabled", usageScope, s.mgmt.PutUsageStatisticsEnabled)
+
+		mgmt.GET("/proxy-url", configScope, s.mgmt.GetProxyURL)
+		mgmt.PUT("/proxy-url", configScope, s.mgmt.PutProxyURL)
+		mgmt.PATCH("/proxy-url", configScope, s.mgmt.PutProxyURL)
+		mgmt.DELETE("/proxy-url", configScope, s.mgmt.DeleteProxyURL)
+
+		mgmt.POST("/api-call", credentialsScope, s.mgmt.APICall)
+
+		mgmt.GET("/quota-exceeded/switch-project", configScope, s.mgmt.GetSwitchProject)
+		mgmt.PUT("/quota-exceeded/switch-project", configScope, s.mgmt.PutSwitchProject)
+		mgmt.PATCH("/quota-exceeded/switch-project", configScope, s.mgmt.PutSwitchProject)
+
+		mgmt.GET("/quota-exceeded/switch-preview-model", configScope, s.mgmt.GetSwitchPreviewModel)
+		mgmt.PUT("/quota-exceeded/switch-preview-model", configScope, s.mgmt.PutSwitchPreviewModel)
+		mgmt.PATCH("/quota-exceeded/switch-preview-model", configScope, s.mgmt.PutSwitchPreviewModel)
+
+		mgmt.GET("/api-keys", configKeysScope, s.mgmt.GetAPIKeys)
+		mgmt.PUT("/api-keys", configScope, s.mgmt.PutAPIKeys)
+		mgmt.PATCH("/api-keys", configScope, s.mgmt.PatchAPIKeys)
+		mgmt.DELETE("/api-keys", configScope, s.mgmt.DeleteAPIKeys)
+
+		mgmt.GET("/gemini-api-key", credentialKeysScope, s.mgmt.GetGeminiKeys)
+		mgmt.PUT("/gemini-api-key", credentialsScope, s.mgmt.PutGeminiKeys)
+		mgmt.PATCH("/gemini-api-key", credentialsScope, s.mgmt.PatchGeminiKey)
+		mgmt.DELETE("/gemini-api-key", credentialsScope, s.mgmt.DeleteGeminiKey)
+
+		mgmt.GET("/logs", logsScope, s.mgmt.GetLogs)
+		mgmt.DELETE("/logs", logsScope, s.mgmt.DeleteLogs)
+		mgmt.GET("/request-error-logs", logsScope, s.mgmt.GetRequestErrorLogs)
+		mgmt.GET("/request-error-logs/:name", logsScope, s.mgmt.DownloadRequestErrorLog)
+		mgmt.GET("/request-log-by-id/:id", logsScope, s.mgmt.GetRequestLogByID)
+		mgmt.GET("/audit-log", logsScope, s.mgmt.GetAuditLog)
+		mgmt.GET("/request-log", configScope, s.mgmt.GetRequestLog)
+		mgmt.PUT("/request-log", configScope, s.mgmt.PutRequestLog)
+		mgmt.PATCH("/request-log", configScope, s.mgmt.PutRequestLog)
+		mgmt.GET("/ws-auth", configScope, s.mgmt.GetWebsocketAuth)
+		mgmt.PUT("/ws-auth", configScope, s.mgmt.PutWebsocketAuth)
+		mgmt.PATCH("/ws-auth", configScope, s.mgmt.PutWebsocketAuth)
+
+		mgmt.GET("/ampcode", configKeysScope, s.mgmt.GetAmpCode)
+		mgmt.GET("/ampcode/upstream-url", configScope, s.mgmt.GetAmpUpstreamURL)
+		mgmt.PUT("/ampcode/upstream-url", configScope, s.mgmt.PutAmpUpstreamURL)
+		mgmt.PATCH("/ampcode/upstream-url", configScope, s.mgmt.PutAmpUpstreamURL)
+		mgmt.DELETE("/ampcode/upstream-url", configScope, s.mgmt.DeleteAmpUpstreamURL)
+		mgmt.GET("/ampcode/upstream-api-key", configKeysScope, s.mgmt.GetAmpUpstreamAPIKey)
+		mgmt.PUT("/ampcode/upstream-api-key", configScope, s.mgmt.PutAmpUpstreamAPIKey)
+		mgmt.PATCH("/ampcode/upstream-api-key", configScope, s.mgmt.PutAmpUpstreamAPIKey)
+		mgmt.DELETE("/ampcode/upstream-api-key", configScope, s.mgmt.DeleteAmpUpstreamAPIKey)
+		mgmt.GET("/ampcode/restrict-management-to-localhost", configScope, s.mgmt.GetAmpRestrictManagementToLocalhost)
+		mgmt.PUT("/ampcode/restrict-management-to-localhost", configScope, s.mgmt.PutAmpRestrictManagementToLocalhost)
+		mgmt.PATCH("/ampcode/restrict-management-to-localhost", configScope, s.mgmt.PutAmpRestrictManagementToLocalhost)
+		mgmt.GET("/ampcode/model-mappings", configScope, s.mgmt.GetAmpModelMappings)
+		mgmt.PUT("/ampcode/model-mappings", configScope, s.mgmt.PutAmpModelMappings)
+		mgmt.PATCH("/ampcode/model-mappings", configScope, s.mgmt.PatchAmpModelMappings)
+		mgmt.DELETE("/ampcode/model-mappings", configScope, s.mgmt.DeleteAmpModelMappings)
+		mgmt.GET("/ampcode/force-model-mappings", configScope, s.mgmt.GetAmpForceModelMappings)
+		mgmt.PUT("/ampcode/force-model-mappings", configScope, s.mgmt.PutAmpForceModelMappings)
+		mgmt.PATCH("/ampcode/force-model-mappings", configScope, s.mgmt.PutAmpForceModelMappings)
+		mgmt.GET("/ampcode/upstream-api-keys", configKeysScope, s.mgmt.GetAmpUpstreamAPIKeys)
+		mgmt.PUT("/ampcode/upstream-api-keys", configScope, s.mgmt.PutAmpUpstreamAPIKeys)
+		mgmt.PATCH("/ampcode/upstream-api-keys", configScope, s.mgmt.PatchAmpUpstreamAPIKeys)
+		mgmt.DELETE("/ampcode/upstream-api-keys", configScope, s.mgmt.DeleteAmpUpstreamAPIKeys)
+
+		mgmt.GET("/request-retry", configScope, s.mgmt.GetRequestRetry)
+		mgmt.PUT("/request-retry", configScope, s.mgmt.PutRequestRetry)
+		mgmt.PATCH("/request-retry", configScope, s.mgmt.PutRequestRetry)
+		mgmt.GET("/max-retry-interval", configScope, s.mgmt.GetMaxRetryInterval)
+		mgmt.PUT("/max-retry-interval", configScope, s.mgmt.PutMaxRetryInterval)
+		mgmt.PATCH("/max-retry-interval", configScope, s.mgmt.PutMaxRetryInterval)
+
+		mgmt.GET("/force-model-prefix", configScope, s.mgmt.GetForceModelPrefix)
+		mgmt.PUT("/force-model-prefix", configScope, s.mgmt.PutForceModelPrefix)
+		mgmt.PATCH("/force-model-prefix", configScope, s.mgmt.PutForceModelPrefix)
+
+		mgmt.GET("/routing/strategy", configScope, s.mgmt.GetRoutingStrategy)
+		mgmt.PUT("/routing/strategy", configScope, s.mgmt.PutRoutingStrategy)
+		mgmt.PATCH("/routing/strategy", configScope, s.mgmt.PutRoutingStrategy)
+
+		mgmt.GET("/claude-api-key", credentialKeysScope, s.mgmt.GetClaudeKeys)
+		mgmt.PUT("/claude-api-key", credentialsScope, s.mgmt.PutClaudeKeys)
+		mgmt.PATCH("/claude-api-key", credentialsScope, s.mgmt.PatchClaudeKey)
+		mgmt.DELETE("/claude-api-key", credentialsScope, s.mgmt.DeleteClaudeKey)
+
+		mgmt.GET("/codex-api-key", credentialKeysScope, s.mgmt.GetCodexKeys)
+		mgmt.PUT("/codex-api-key", credentialsScope, s.mgmt.PutCodexKeys)
+		mgmt.PATCH("/codex-api-key", credentialsScope, s.mgmt.PatchCodexKey)
+		mgmt.DELETE("/codex-api-key", credentialsScope, s.mgmt.DeleteCodexKey)
+
+		mgmt.GET("/openai-compatibility", credentialKeysScope, s.mgmt.GetOpenAICompat)
+		mgmt.PUT("/openai-compatibility", credentialsScope, s.mgmt.PutOpenAICompat)
+		mgmt.PATCH("/openai-compatibility", credentialsScope, s.mgmt.PatchOpenAICompat)
+		mgmt.DELETE("/openai-compatibility", credentialsScope, s.mgmt.DeleteOpenAICompat)
+
+		mgmt.GET("/vertex-api-key", credentialKeysScope, s.mgmt.GetVertexCompatKeys)
+		mgmt.PUT("/vertex-api-key", credentialsScope, s.mgmt.PutVertexCompatKeys)
+		mgmt.PATCH("/vertex-api-key", credentialsScope, s.mgmt.PatchVertexCompatKey)
+		mgmt.DELETE("/vertex-api-key", credentialsScope, s.mgmt.DeleteVertexCompatKey)
+
+		mgmt.GET("/oauth-excluded-models", configScope, s.mgmt.GetOAuthExcludedModels)
+		mgmt.PUT("/oauth-excluded-models", configScope, s.mgmt.PutOAuthExcludedModels)
+		mgmt.PATCH("/oauth-excluded-models", configScope, s.mgmt.PatchOAuthExcludedModels)
+		mgmt.DELETE("/oauth-excluded-models", configScope, s.mgmt.DeleteOAuthExcludedModels)
+
+		mgmt.GET("/oauth-model-alias", configScope, s.mgmt.GetOAuthModelAlias)
+		mgmt.PUT("/oauth-model-alias", configScope, s.mgmt.PutOAuthModelAlias)
+		mgmt.PATCH("/oauth-model-alias", configScope, s.mgmt.PatchOAuthModelAlias)
+		mgmt.DELETE("/oauth-model-alias", configScope, s.mgmt.DeleteOAuthModelAlias)
+
+		mgmt.GET("/auth-files", credentialsScope, s.mgmt.ListAuthFiles)
+		mgmt.GET("/auth-files/models", credentialsScope, s.mgmt.GetAuthFileModels)
+		mgmt.GET("/model-definitions/:channel", credentialsScope, s.mgmt.GetStaticModelDefinitions)
+		mgmt.GET("/auth-files/download", downloadScope, s.mgmt.DownloadAuthFile)
+		mgmt.POST("/auth-files", credentialsScope, s.mgmt.UploadAuthFile)
+		mgmt.DELETE("/auth-files", credentialsScope, s.mgmt.DeleteAuthFile)
+		mgmt.PATCH("/auth-files/status", credentialsScope, s.mgmt.PatchAuthFileStatus)
+		mgmt.PATCH("/auth-files/tags", credentialsScope, s.mgmt.PatchAuthFileTags)
+		mgmt.POST("/vertex/import", credentialsScope, s.mgmt.ImportVertexCredential)
+
+		mgmt.GET("/anthropic-auth-url", loginScope, s.mgmt.RequestAnthropicToken)
+		mgmt.GET("/codex-auth-url", loginScope, s.mgmt.RequestCodexToken)
+		mgmt.GET("/gemini-cli-auth-url", loginScope, s.mgmt.RequestGeminiCLIToken)
+		mgmt.GET("/antigravity-auth-url", loginScope, s.mgmt.RequestAntigravityToken)
+		mgmt.GET("/qwen-auth-url", loginScope, s.mgmt.RequestQwenToken)
+		mgmt.GET("/iflow-auth-url", loginScope, s.mgmt.RequestIFlowToken)
+		mgmt.POST("/iflow-auth-url", loginScope, s.mgmt.RequestIFlowCookieToken)
+		mgmt.POST("/oauth-callback", credentialsScope, s.mgmt.PostOAuthCallback)
+		mgmt.GET("/get-auth-status", credentialsScope, s.mgmt.GetAuthStatus)
 	}
 }
 
@@ -667,6 +762,23 @@ func (s *Server) serveManagementControlPanel(c *gin.Context) {
 	c.File(filePath)
 }
 
+// handleMetrics serves Prometheus metrics when enabled via the metrics config block.
+func (s *Server) handleMetrics(c *gin.Context) {
+	if !metrics.Enabled() {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	var auths []*auth.Auth
+	if s.handlers != nil && s.handlers.AuthManager != nil {
+		auths = s.handlers.AuthManager.List()
+	}
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := metrics.Default().WritePrometheus(c.Writer, auths, time.Now()); err != nil {
+		log.Debugf("failed to write metrics: %v", err)
+	}
+}
+
 func (s *Server) enableKeepAlive(timeout time.Duration, onTimeout func()) {
 	if timeout <= 0 || onTimeout == nil {
 		return
@@ -818,6 +930,8 @@ func (s *Server) Stop(ctx context.Context) error {
 	if err := s.server.Shutdown(ctx); err != nil {
 		return fmt.Errorf("failed to shutdown HTTP server: %v", err)
 	}
+	tracing.Shutdown()
+	usage.ClosePersistence()
 
 	log.Debug("API server stopped")
 	return nil
@@ -888,6 +1002,14 @@ func (s *Server) UpdateClients(cfg *config.Config) {
 		usage.SetStatisticsEnabled(cfg.UsageStatisticsEnabled)
 	}
 
+	if oldCfg == nil || !reflect.DeepEqual(oldCfg.Pricing, cfg.Pricing) {
+		usage.SetPricing(cfg.Pricing)
+	}
+
+	if oldCfg == nil || !reflect.DeepEqual(oldCfg.ClientKeys, cfg.ClientKeys) {
+		policy.Default().Configure(cfg.ClientKeys)
+	}
+
 	if s.requestLogger != nil && (oldCfg == nil || oldCfg.ErrorLogsMaxFiles != cfg.ErrorLogsMaxFiles) {
 		if setter, ok := s.requestLogger.(interface{ SetErrorLogsMaxFiles(int) }); ok {
 			setter.SetErrorLogsMaxFiles(cfg.ErrorLogsMaxFiles)
@@ -902,6 +1024,22 @@ func (s *Server) UpdateClients(cfg *config.Config) {
 		s.handlers.AuthManager.SetRetryConfig(cfg.RequestRetry, time.Duration(cfg.MaxRetryInterval)*time.Second)
 	}
 
+	if oldCfg == nil || oldCfg.ResponsesStore != cfg.ResponsesStore {
+		configureResponsesStore(cfg)
+	}
+
+	if oldCfg == nil || oldCfg.Metrics.Enable != cfg.Metrics.Enable {
+		metrics.SetEnabled(cfg.Metrics.Enable)
+	}
+
+	if oldCfg == nil || !reflect.DeepEqual(oldCfg.Tracing, cfg.Tracing) {
+		configureTracing(cfg)
+	}
+
+	if oldCfg == nil || !reflect.DeepEqual(oldCfg.UsageStore, cfg.UsageStore) {
+		configureUsageStore(cfg)
+	}
+
 	// Update log level dynamically when debug flag changes
 	if oldCfg == nil || oldCfg.Debug != cfg.Debug {
 		util.SetLogLevel(cfg)
@@ -909,9 +1047,9 @@ func (s *Server) UpdateClients(cfg *config.Config) {
 
 	prevSecretEmpty := true
 	if oldCfg != nil {
-		prevSecretEmpty = oldCfg.RemoteManagement.SecretKey == ""
+		prevSecretEmpty = !oldCfg.RemoteManagement.HasKeys()
 	}
-	newSecretEmpty := cfg.RemoteManagement.SecretKey == ""
+	newSecretEmpty := !cfg.RemoteManagement.HasKeys()
 	if s.envManagementSecret {
 		s.registerManagementRoutes()
 		if s.managementRoutesEnabled.CompareAndSwap(false, true) {
@@ -1,15 +1,20 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	gin "github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/access"
+	configaccess "github.com/router-for-me/CLIProxyAPI/v6/internal/access/config_access"
 	proxyconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/metrics"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
@@ -109,3 +114,310 @@ func TestAmpProviderModelRoutes(t *testing.T) {
 		})
 	}
 }
+
+func TestMetricsRequiresAPIKey(t *testing.T) {
+	server := newTestServer(t)
+	configaccess.Register()
+	if _, err := access.ApplyAccessProviders(server.accessManager, nil, server.cfg); err != nil {
+		t.Fatalf("apply access providers: %v", err)
+	}
+	metrics.SetEnabled(true)
+	t.Cleanup(func() { metrics.SetEnabled(false) })
+
+	for _, tc := range []struct {
+		auth string
+		want int
+	}{{"", http.StatusUnauthorized}, {"Bearer wrong-key", http.StatusUnauthorized}, {"Bearer test-key", http.StatusOK}} {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if tc.auth != "" {
+			req.Header.Set("Authorization", tc.auth)
+		}
+		rr := httptest.NewRecorder()
+		server.engine.ServeHTTP(rr, req)
+		if rr.Code != tc.want {
+			t.Fatalf("GET /metrics with %q: got %d want %d; body=%s", tc.auth, rr.Code, tc.want, rr.Body.String())
+		}
+	}
+}
+
+func TestManagementTokenScopes(t *testing.T) {
+	server := newTestServer(t)
+	server.cfg.RemoteManagement.AllowRemote = true
+	server.cfg.RemoteManagement.Tokens = []proxyconfig.ManagementToken{
+		{Name: "dashboard", Token: "dashboard-token", Scopes: []string{proxyconfig.ManagementScopeReadOnly}},
+		{Name: "billing", Token: "billing-token", Scopes: []string{proxyconfig.ManagementScopeUsage}},
+	}
+	server.managementRoutesEnabled.Store(true)
+	server.registerManagementRoutes()
+
+	testCases := []struct {
+		name       string
+		token      string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{"read-only reads usage", "dashboard-token", http.MethodGet, "/v0/management/usage", http.StatusOK},
+		{"read-only reads auth files", "dashboard-token", http.MethodGet, "/v0/management/auth-files", http.StatusOK},
+		{"read-only lists tokens", "dashboard-token", http.MethodGet, "/v0/management/management-tokens", http.StatusOK},
+		{"read-only cannot write config", "dashboard-token", http.MethodPut, "/v0/management/config.yaml", http.StatusForbidden},
+		{"read-only cannot delete auth files", "dashboard-token", http.MethodDelete, "/v0/management/auth-files?name=a.json", http.StatusForbidden},
+		{"read-only cannot start logins", "dashboard-token", http.MethodGet, "/v0/management/anthropic-auth-url", http.StatusForbidden},
+		{"usage scope reads usage", "billing-token", http.MethodGet, "/v0/management/usage", http.StatusOK},
+		{"usage scope cannot read auth files", "billing-token", http.MethodGet, "/v0/management/auth-files", http.StatusForbidden},
+		{"unknown token", "other-token", http.MethodGet, "/v0/management/usage", http.StatusUnauthorized},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			rr := httptest.NewRecorder()
+			server.engine.ServeHTTP(rr, req)
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("%s %s: got status %d want %d; body=%s", tc.method, tc.path, rr.Code, tc.wantStatus, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestManagementReadOnlyCannotReadConfigFile(t *testing.T) {
+	server := newTestServer(t)
+	content := "remote-management:\n  allow-remote: true\n  tokens:\n    - name: dashboard\n      token: dashboard-token\n      scopes: [read-only]\n    - name: root\n      token: admin-token\n      scopes: [admin]\n"
+	if err := os.WriteFile(server.configFilePath, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	loaded, err := proxyconfig.LoadConfig(server.configFilePath)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	*server.cfg = *loaded
+	server.managementRoutesEnabled.Store(true)
+	server.registerManagementRoutes()
+
+	serve := func(token, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		server.engine.ServeHTTP(rr, req)
+		return rr
+	}
+	rr := serve("dashboard-token", "/v0/management/config.yaml")
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("read-only GET config.yaml: status %d body=%s", rr.Code, rr.Body.String())
+	}
+	if strings.Contains(rr.Body.String(), "admin-token") || strings.Contains(rr.Body.String(), "$2") {
+		t.Fatalf("read-only response leaked token values: %s", rr.Body.String())
+	}
+	if rr = serve("dashboard-token", "/v0/management/config/history/any"); rr.Code != http.StatusForbidden {
+		t.Fatalf("read-only GET config version: status %d", rr.Code)
+	}
+
+	rr = serve("admin-token", "/v0/management/config.yaml")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("admin GET config.yaml: status %d body=%s", rr.Code, rr.Body.String())
+	}
+	if strings.Contains(rr.Body.String(), "admin-token") || strings.Contains(rr.Body.String(), "dashboard-token") {
+		t.Fatalf("expected plaintext tokens hashed in the config file, got %s", rr.Body.String())
+	}
+}
+
+func TestManagementReadOnlyCannotReadAPIKeys(t *testing.T) {
+	server := newTestServer(t)
+	content := "api-keys: [client-secret]\nclaude-api-key:\n  - api-key: sk-ant-inline\nremote-management:\n  allow-remote: true\n  tokens:\n    - name: dashboard\n      token: dashboard-token\n      scopes: [read-only]\n    - name: root\n      token: admin-token\n      scopes: [admin]\n"
+	if err := os.WriteFile(server.configFilePath, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	loaded, err := proxyconfig.LoadConfig(server.configFilePath)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	*server.cfg = *loaded
+	server.managementRoutesEnabled.Store(true)
+	server.registerManagementRoutes()
+
+	serve := func(token, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		server.engine.ServeHTTP(rr, req)
+		return rr
+	}
+	for _, path := range []string{"/v0/management/claude-api-key", "/v0/management/config"} {
+		rr := serve("dashboard-token", path)
+		if rr.Code != http.StatusForbidden {
+			t.Fatalf("read-only GET %s: status %d body=%s", path, rr.Code, rr.Body.String())
+		}
+		if strings.Contains(rr.Body.String(), "sk-ant-inline") || strings.Contains(rr.Body.String(), "client-secret") {
+			t.Fatalf("read-only GET %s leaked an API key: %s", path, rr.Body.String())
+		}
+
+		rr = serve("admin-token", path)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("admin GET %s: status %d body=%s", path, rr.Code, rr.Body.String())
+		}
+		if !strings.Contains(rr.Body.String(), "sk-ant-inline") {
+			t.Fatalf("admin GET %s: expected the claude key, got %s", path, rr.Body.String())
+		}
+	}
+}
+
+func TestManagementAuditLog(t *testing.T) {
+	server := newTestServer(t)
+	if err := os.WriteFile(server.configFilePath, []byte("debug: true\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	server.cfg.RemoteManagement.AllowRemote = true
+	server.cfg.RemoteManagement.AuditLog = filepath.Join(t.TempDir(), "audit.jsonl")
+	server.cfg.RemoteManagement.Tokens = []proxyconfig.ManagementToken{
+		{Name: "ops", Token: "ops-token", Scopes: []string{proxyconfig.ManagementScopeConfig, proxyconfig.ManagementScopeLogs}},
+	}
+	server.managementRoutesEnabled.Store(true)
+	server.registerManagementRoutes()
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer ops-token")
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		server.engine.ServeHTTP(rr, req)
+		return rr
+	}
+	if rr := serve(http.MethodPut, "/v0/management/debug", `{"value":false}`); rr.Code != http.StatusOK {
+		t.Fatalf("PUT debug: status %d body=%s", rr.Code, rr.Body.String())
+	}
+	if rr := serve(http.MethodDelete, "/v0/management/auth-files?name=a.json", ""); rr.Code != http.StatusForbidden {
+		t.Fatalf("DELETE auth-files without the credentials scope: status %d", rr.Code)
+	}
+
+	rr := serve(http.MethodGet, "/v0/management/audit-log?actor=token:ops", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("GET audit-log: status %d body=%s", rr.Code, rr.Body.String())
+	}
+	var got struct {
+		Entries []struct {
+			Actor   string   `json:"actor"`
+			Method  string   `json:"method"`
+			Route   string   `json:"route"`
+			Status  int      `json:"status"`
+			Changes []string `json:"changes"`
+		} `json:"entries"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode audit log: %v", err)
+	}
+	if len(got.Entries) != 2 {
+		t.Fatalf("expected 2 audit entries, got %+v", got.Entries)
+	}
+	first, second := got.Entries[0], got.Entries[1]
+	if first.Route != "/v0/management/debug" || first.Status != http.StatusOK || len(first.Changes) != 1 || first.Changes[0] != "debug: true -> false" {
+		t.Fatalf("unexpected entry for the config change: %+v", first)
+	}
+	if second.Method != http.MethodDelete || second.Status != http.StatusForbidden || len(second.Changes) != 0 {
+		t.Fatalf("unexpected entry for the rejected request: %+v", second)
+	}
+}
+
+func TestManagementConfigHistoryRollback(t *testing.T) {
+	server := newTestServer(t)
+	content := "debug: true\nremote-management:\n  allow-remote: true\n  tokens:\n    - name: ops\n      token: ops-token\n      scopes: [config]\n"
+	if err := os.WriteFile(server.configFilePath, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	loaded, err := proxyconfig.LoadConfig(server.configFilePath)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	*server.cfg = *loaded
+	server.managementRoutesEnabled.Store(true)
+	server.registerManagementRoutes()
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer ops-token")
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		server.engine.ServeHTTP(rr, req)
+		return rr
+	}
+	if rr := serve(http.MethodPut, "/v0/management/debug", `{"value":false}`); rr.Code != http.StatusOK {
+		t.Fatalf("PUT debug: status %d body=%s", rr.Code, rr.Body.String())
+	}
+
+	rr := serve(http.MethodGet, "/v0/management/config/history", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("GET config/history: status %d body=%s", rr.Code, rr.Body.String())
+	}
+	var history struct {
+		Versions []struct {
+			ID string `json:"id"`
+		} `json:"versions"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &history); err != nil {
+		t.Fatalf("decode history: %v", err)
+	}
+	if len(history.Versions) != 2 {
+		t.Fatalf("expected the config before and after the change, got %+v", history.Versions)
+	}
+	previous := history.Versions[1].ID
+
+	rr = serve(http.MethodGet, "/v0/management/config/history/"+previous+"/diff", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("GET diff: status %d body=%s", rr.Code, rr.Body.String())
+	}
+	var diffBody struct {
+		Changes []string `json:"changes"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &diffBody); err != nil {
+		t.Fatalf("decode diff: %v", err)
+	}
+	if len(diffBody.Changes) != 1 || diffBody.Changes[0] != "debug: false -> true" {
+		t.Fatalf("unexpected diff: %+v", diffBody.Changes)
+	}
+
+	if rr = serve(http.MethodPost, "/v0/management/config/history/"+previous+"/rollback", ""); rr.Code != http.StatusOK {
+		t.Fatalf("POST rollback: status %d body=%s", rr.Code, rr.Body.String())
+	}
+	if rr = serve(http.MethodGet, "/v0/management/debug", ""); !strings.Contains(rr.Body.String(), "true") {
+		t.Fatalf("expected debug restored to true, got %s", rr.Body.String())
+	}
+	if rr = serve(http.MethodPost, "/v0/management/config/history/not-a-version/rollback", ""); rr.Code != http.StatusNotFound {
+		t.Fatalf("rollback of an unknown version: status %d", rr.Code)
+	}
+}
+
+func TestManagementShowsSecretReferences(t *testing.T) {
+	t.Setenv("TEST_MGMT_CLAUDE_KEY", "sk-resolved")
+	server := newTestServer(t)
+	content := "remote-management:\n  allow-remote: true\n  tokens:\n    - name: ops\n      token: ops-token\n      scopes: [config, credentials]\nclaude-api-key:\n  - api-key: \"${env:TEST_MGMT_CLAUDE_KEY}\"\n"
+	if err := os.WriteFile(server.configFilePath, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	loaded, err := proxyconfig.LoadConfig(server.configFilePath)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	*server.cfg = *loaded
+	server.managementRoutesEnabled.Store(true)
+	server.registerManagementRoutes()
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer ops-token")
+		rr := httptest.NewRecorder()
+		server.engine.ServeHTTP(rr, req)
+		return rr
+	}
+	for _, path := range []string{"/v0/management/claude-api-key", "/v0/management/config"} {
+		rr := serve(http.MethodGet, path)
+		if rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), "sk-resolved") || !strings.Contains(rr.Body.String(), "${env:TEST_MGMT_CLAUDE_KEY}") {
+			t.Fatalf("GET %s: status %d body=%s", path, rr.Code, rr.Body.String())
+		}
+	}
+
+	if rr := serve(http.MethodDelete, "/v0/management/claude-api-key?api-key="+url.QueryEscape("${env:TEST_MGMT_CLAUDE_KEY}")); rr.Code != http.StatusOK {
+		t.Fatalf("DELETE by reference: status %d body=%s", rr.Code, rr.Body.String())
+	}
+	if len(server.cfg.ClaudeKey) != 0 {
+		t.Fatalf("expected the key to be deleted, got %+v", server.cfg.ClaudeKey)
+	}
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+// shadowRequestLogger writes every mirrored request to the request log, next to the log of the
+// client request it was mirrored from. Latency, status and token usage are always logged; the
+// shadow response only when the policy sets log-response.
+type shadowRequestLogger struct {
+	logger logging.RequestLogger
+}
+
+// RecordShadow implements auth.ShadowRecorder.
+func (l shadowRequestLogger) RecordShadow(result auth.ShadowResult) {
+	if l.logger == nil || !l.logger.IsEnabled() {
+		return
+	}
+	headers := http.Header{}
+	headers.Set("X-Shadow-Of", result.Model)
+	headers.Set("X-Shadow-Auth", result.AuthID)
+	headers.Set("X-Shadow-Latency-Ms", strconv.FormatInt(result.Latency.Milliseconds(), 10))
+	if result.Stream {
+		headers.Set("X-Shadow-First-Byte-Ms", strconv.FormatInt(result.FirstByte.Milliseconds(), 10))
+	}
+	headers.Set("X-Shadow-Input-Tokens", strconv.FormatInt(result.Usage.InputTokens, 10))
+	headers.Set("X-Shadow-Output-Tokens", strconv.FormatInt(result.Usage.OutputTokens, 10))
+	headers.Set("X-Shadow-Reasoning-Tokens", strconv.FormatInt(result.Usage.ReasoningTokens, 10))
+	headers.Set("X-Shadow-Cached-Tokens", strconv.FormatInt(result.Usage.CachedTokens, 10))
+	headers.Set("X-Shadow-Total-Tokens", strconv.FormatInt(result.Usage.TotalTokens, 10))
+	var apiErrors []*interfaces.ErrorMessage
+	if result.Error != "" {
+		apiErrors = append(apiErrors, &interfaces.ErrorMessage{StatusCode: result.StatusCode, Error: errors.New(result.Error)})
+	}
+	if err := l.logger.LogRequest("/shadow/"+result.TargetModel, http.MethodPost, nil, result.Request, result.StatusCode, headers, result.Response, nil, nil, apiErrors, result.RequestID, result.StartedAt, result.StartedAt.Add(result.Latency)); err != nil {
+		log.WithError(err).Warn("failed to write shadow request log")
+	}
+}
@@ -0,0 +1,48 @@
+// Package cmd contains CLI helpers. This file implements the -encrypt-secret mode, which
+// writes a secret file for "enc:" references in the config.
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+// DoEncryptSecret reads a secret from in, encrypts it with the key in CONFIG_SECRETS_KEY and
+// writes it to outPath. It prints the reference to use in the config to out and returns the
+// process exit code.
+func DoEncryptSecret(outPath string, in io.Reader, out io.Writer) int {
+	key, err := config.SecretsKeyFromEnv()
+	if err != nil {
+		_, _ = fmt.Fprintf(out, "encrypt-secret: %v\n", err)
+		return 1
+	}
+	raw, err := io.ReadAll(in)
+	if err != nil {
+		_, _ = fmt.Fprintf(out, "encrypt-secret: read secret failed: %v\n", err)
+		return 1
+	}
+	secret := strings.TrimSpace(string(raw))
+	if secret == "" {
+		_, _ = fmt.Fprintln(out, "encrypt-secret: no secret on standard input")
+		return 1
+	}
+	encrypted, err := config.EncryptSecret(key, []byte(secret))
+	if err != nil {
+		_, _ = fmt.Fprintf(out, "encrypt-secret: %v\n", err)
+		return 1
+	}
+	if err = os.WriteFile(outPath, []byte(encrypted+"\n"), 0o600); err != nil {
+		_, _ = fmt.Fprintf(out, "encrypt-secret: write failed: %v\n", err)
+		return 1
+	}
+	if absPath, errAbs := filepath.Abs(outPath); errAbs == nil {
+		outPath = absPath
+	}
+	_, _ = fmt.Fprintf(out, "Encrypted secret written; reference it in the config as: enc:%s\n", outPath)
+	return 0
+}
@@ -0,0 +1,92 @@
+// Package cmd contains CLI helpers. This file implements the -validate mode, which checks a
+// config file without starting the service.
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+)
+
+// DoValidateConfig validates the config file at configPath (config.yaml in the working
+// directory when empty), prints a report to out and returns the process exit code: 0 when the
+// config has no errors, 1 otherwise. Warnings do not affect the exit code.
+func DoValidateConfig(configPath string, out io.Writer) int {
+	if strings.TrimSpace(configPath) == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			_, _ = fmt.Fprintf(out, "failed to get working directory: %v\n", err)
+			return 1
+		}
+		configPath = filepath.Join(wd, "config.yaml")
+	}
+	cfg, report := config.ValidateConfigFile(configPath)
+	if cfg != nil {
+		checkAuthDir(report, cfg.AuthDir)
+	}
+	printValidationReport(out, report)
+	if report.HasErrors() {
+		return 1
+	}
+	return 0
+}
+
+// checkAuthDir reports an auth directory the service could not read or write. A missing
+// directory is only a warning because the service creates it on start-up.
+func checkAuthDir(report *config.ValidationReport, authDir string) {
+	resolved, err := util.ResolveAuthDir(authDir)
+	if err != nil {
+		report.Errorf("auth-dir", "%v", err)
+		return
+	}
+	if resolved == "" {
+		report.Errorf("auth-dir", "auth-dir is not set")
+		return
+	}
+	info, err := os.Stat(resolved)
+	if os.IsNotExist(err) {
+		report.Warnf("auth-dir", "%s does not exist and will be created on start-up", resolved)
+		return
+	}
+	if err != nil {
+		report.Errorf("auth-dir", "cannot access %s: %v", resolved, err)
+		return
+	}
+	if !info.IsDir() {
+		report.Errorf("auth-dir", "%s is not a directory", resolved)
+		return
+	}
+	if _, err = os.ReadDir(resolved); err != nil {
+		report.Errorf("auth-dir", "cannot read %s: %v", resolved, err)
+		return
+	}
+	probe, err := os.CreateTemp(resolved, ".validate-*")
+	if err != nil {
+		report.Errorf("auth-dir", "cannot write to %s: %v", resolved, err)
+		return
+	}
+	_ = probe.Close()
+	_ = os.Remove(probe.Name())
+}
+
+func printValidationReport(out io.Writer, report *config.ValidationReport) {
+	_, _ = fmt.Fprintf(out, "Validating %s\n", report.File)
+	for _, issue := range report.Issues {
+		path := issue.Path
+		if path == "" {
+			path = "-"
+		}
+		_, _ = fmt.Fprintf(out, "  %-7s  %s: %s\n", issue.Severity, path, issue.Message)
+	}
+	errorsFound, warnings := report.Count(config.ValidationError), report.Count(config.ValidationWarning)
+	if errorsFound == 0 {
+		_, _ = fmt.Fprintf(out, "Config is valid (%d warning(s))\n", warnings)
+		return
+	}
+	_, _ = fmt.Fprintf(out, "Config is invalid: %d error(s), %d warning(s)\n", errorsFound, warnings)
+}
@@ -47,6 +47,12 @@ type Config struct {
 	// Pprof config controls the optional pprof HTTP debug server.
 	Pprof PprofConfig `yaml:"pprof" json:"pprof"`
 
+	// Metrics config controls the optional Prometheus /metrics endpoint.
+	Metrics MetricsConfig `yaml:"metrics" json:"metrics"`
+
+	// Tracing config controls optional OTLP trace export.
+	Tracing TracingConfig `yaml:"tracing" json:"tracing"`
+
 	// CommercialMode disables high-overhead HTTP middleware features to minimize per-request memory usage.
 	CommercialMode bool `yaml:"commercial-mode" json:"commercial-mode"`
 
@@ -64,6 +70,12 @@ type Config struct {
 	// UsageStatisticsEnabled toggles in-memory usage aggregation; when false, usage data is discarded.
 	UsageStatisticsEnabled bool `yaml:"usage-statistics-enabled" json:"usage-statistics-enabled"`
 
+	// UsageStore configures the optional persistent usage sink.
+	UsageStore UsageStoreConfig `yaml:"usage-store" json:"usage-store"`
+
+	// Pricing lists per-model list prices used to compute the cost of each request.
+	Pricing []ModelPrice `yaml:"pricing,omitempty" json:"pricing,omitempty"`
+
 	// DisableCooling disables quota cooldown scheduling when true.
 	DisableCooling bool `yaml:"disable-cooling" json:"disable-cooling"`
 
@@ -75,6 +87,12 @@ type Config struct {
 	// QuotaExceeded defines the behavior when a quota is exceeded.
 	QuotaExceeded QuotaExceeded `yaml:"quota-exceeded" json:"quota-exceeded"`
 
+	// CircuitBreaker takes credentials that keep failing with server errors or timeouts out of rotation.
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuit-breaker,omitempty" json:"circuit-breaker,omitempty"`
+
+	// CooldownQueue holds requests while every credential for their model is cooling down.
+	CooldownQueue CooldownQueueConfig `yaml:"cooldown-queue,omitempty" json:"cooldown-queue,omitempty"`
+
 	// Routing controls credential selection behavior.
 	Routing RoutingConfig `yaml:"routing" json:"routing"`
 
@@ -111,10 +129,23 @@ type Config struct {
 	// gemini-api-key, codex-api-key, claude-api-key, openai-compatibility, vertex-api-key, and ampcode.
 	OAuthModelAlias map[string][]OAuthModelAlias `yaml:"oauth-model-alias,omitempty" json:"oauth-model-alias,omitempty"`
 
+	// VirtualModels defines client-visible model names backed by an ordered chain of target models,
+	// possibly served by different providers. The conductor falls through the chain on failure.
+	VirtualModels []VirtualModel `yaml:"virtual-models,omitempty" json:"virtual-models,omitempty"`
+
+	// Hedging defines per-model policies that race a second attempt against a slow first attempt.
+	Hedging []HedgePolicy `yaml:"hedging,omitempty" json:"hedging,omitempty"`
+
+	// ShadowTraffic defines policies that mirror a sample of requests to a second target for
+	// offline comparison. Shadow responses are never returned to clients.
+	ShadowTraffic []ShadowPolicy `yaml:"shadow-traffic,omitempty" json:"shadow-traffic,omitempty"`
+
 	// Payload defines default and override rules for provider payload parameters.
 	Payload PayloadConfig `yaml:"payload" json:"payload"`
 
 	legacyMigrationPending bool `yaml:"-" json:"-"`
+	// secretRefs maps resolved secrets to the references they were loaded from.
+	secretRefs map[string]string `yaml:"-" json:"-"`
 }
 
 // TLSConfig holds HTTPS server settings.
@@ -135,6 +166,64 @@ type PprofConfig struct {
 	Addr string `yaml:"addr" json:"addr"`
 }
 
+// MetricsConfig holds Prometheus exposition settings.
+type MetricsConfig struct {
+	// Enable toggles metric collection and the /metrics endpoint, which requires a client API key.
+	Enable bool `yaml:"enable" json:"enable"`
+}
+
+// TracingConfig holds distributed tracing settings.
+type TracingConfig struct {
+	// Enable toggles span collection and export.
+	Enable bool `yaml:"enable" json:"enable"`
+	// Exporter selects the destination: "file" (default), "stdout" or "otlp-http".
+	Exporter string `yaml:"exporter,omitempty" json:"exporter,omitempty"`
+	// File is the OTLP/JSON lines file used by the file exporter; defaults to traces.jsonl in the log directory.
+	File string `yaml:"file,omitempty" json:"file,omitempty"`
+	// Endpoint is the OTLP/HTTP traces URL; defaults to http://localhost:4318/v1/traces.
+	Endpoint string `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
+	// Headers are added to every OTLP/HTTP export request.
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	// SampleRatio is the fraction of new traces recorded (0 < ratio <= 1); defaults to 1.
+	SampleRatio float64 `yaml:"sample-ratio,omitempty" json:"sample-ratio,omitempty"`
+	// ServiceName overrides the service.name resource attribute.
+	ServiceName string `yaml:"service-name,omitempty" json:"service-name,omitempty"`
+}
+
+// UsageStoreConfig holds persistent usage storage settings.
+type UsageStoreConfig struct {
+	// Enable toggles writing every usage record to the persistent store.
+	Enable bool `yaml:"enable" json:"enable"`
+	// Driver selects the backend: "sqlite" (default), "postgres" or "file".
+	Driver string `yaml:"driver,omitempty" json:"driver,omitempty"`
+	// Path is the database (or JSON lines) file for the local backends; defaults to
+	// usage.db (or usage.jsonl) under the writable path.
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+	// DSN is the Postgres connection string. When empty the connection of the
+	// Postgres-backed config store (PGSTORE_DSN) is reused.
+	DSN string `yaml:"dsn,omitempty" json:"dsn,omitempty"`
+	// Table overrides the usage table name; defaults to usage_records.
+	Table string `yaml:"table,omitempty" json:"table,omitempty"`
+	// RetentionDays removes records older than the given number of days. <= 0 keeps records forever.
+	RetentionDays int `yaml:"retention-days,omitempty" json:"retention-days,omitempty"`
+}
+
+// ModelPrice defines list prices in USD per million tokens for models matching Model.
+type ModelPrice struct {
+	// Model is the model name or alias; '*' wildcards are supported (e.g., "gpt-5*", "*-sonnet-*").
+	Model string `yaml:"model" json:"model"`
+	// Input is the price of uncached input tokens.
+	Input float64 `yaml:"input" json:"input"`
+	// Output is the price of output tokens.
+	Output float64 `yaml:"output" json:"output"`
+	// CachedRead is the price of input tokens served from the prompt cache; defaults to Input.
+	CachedRead float64 `yaml:"cached-read,omitempty" json:"cached-read,omitempty"`
+	// CacheWrite is the price of input tokens written to the prompt cache; defaults to Input.
+	CacheWrite float64 `yaml:"cache-write,omitempty" json:"cache-write,omitempty"`
+	// Reasoning is the price of reasoning tokens; defaults to Output.
+	Reasoning float64 `yaml:"reasoning,omitempty" json:"reasoning,omitempty"`
+}
+
 // RemoteManagement holds management API configuration under 'remote-management'.
 type RemoteManagement struct {
 	// AllowRemote toggles remote (non-localhost) access to management API.
@@ -146,6 +235,106 @@ type RemoteManagement struct {
 	// PanelGitHubRepository overrides the GitHub repository used to fetch the management panel asset.
 	// Accepts either a repository URL (https://github.com/org/repo) or an API releases endpoint.
 	PanelGitHubRepository string `yaml:"panel-github-repository"`
+	// Tokens are additional named management keys limited to a set of scopes.
+	Tokens []ManagementToken `yaml:"tokens,omitempty"`
+	// AuditLog is the JSONL file recording changes made through the management API. Relative
+	// paths are resolved against the log directory; empty uses management-audit.jsonl there.
+	AuditLog string `yaml:"audit-log,omitempty"`
+	// ConfigHistory is the number of config versions kept when the management API writes the
+	// config file. 0 keeps DefaultConfigHistoryVersions; a negative value disables the history.
+	ConfigHistory int `yaml:"config-history,omitempty"`
+}
+
+// Management token scopes. A token may use the routes of each scope it holds; read-only
+// grants read access to every route that does not return secrets and admin grants full access,
+// including token management.
+const (
+	ManagementScopeReadOnly    = "read-only"
+	ManagementScopeUsage       = "usage"
+	ManagementScopeCredentials = "credentials"
+	ManagementScopeConfig      = "config"
+	ManagementScopeLogs        = "logs"
+	ManagementScopeAdmin       = "admin"
+)
+
+// ManagementToken is a named management API key limited to a set of scopes.
+type ManagementToken struct {
+	// Name identifies the token in logs and in the token management endpoints.
+	Name string `yaml:"name" json:"name"`
+	// Token is the key presented by the client, plaintext or bcrypt hashed. Tokens created
+	// through the management API are stored hashed.
+	Token string `yaml:"token" json:"-"`
+	// Scopes lists the management scopes granted to the token.
+	Scopes []string `yaml:"scopes" json:"scopes"`
+}
+
+// HasKeys reports whether any management key is configured: the secret key or a token.
+func (r RemoteManagement) HasKeys() bool {
+	return r.SecretKey != "" || len(r.Tokens) > 0
+}
+
+// IsManagementScope reports whether scope is a known management token scope.
+func IsManagementScope(scope string) bool {
+	switch scope {
+	case ManagementScopeReadOnly, ManagementScopeUsage, ManagementScopeCredentials,
+		ManagementScopeConfig, ManagementScopeLogs, ManagementScopeAdmin:
+		return true
+	}
+	return false
+}
+
+// SanitizeManagementTokens normalizes scopes, drops unknown scopes and removes tokens without
+// a name, a key or any scope. Later tokens with a duplicate name are dropped.
+func (cfg *Config) SanitizeManagementTokens() {
+	if cfg == nil || len(cfg.RemoteManagement.Tokens) == 0 {
+		return
+	}
+	out := make([]ManagementToken, 0, len(cfg.RemoteManagement.Tokens))
+	seen := make(map[string]struct{}, len(cfg.RemoteManagement.Tokens))
+	for _, token := range cfg.RemoteManagement.Tokens {
+		token.Name = strings.TrimSpace(token.Name)
+		token.Token = strings.TrimSpace(token.Token)
+		if token.Name == "" || token.Token == "" {
+			continue
+		}
+		if _, dup := seen[token.Name]; dup {
+			log.Warnf("management token %q: duplicate name ignored", token.Name)
+			continue
+		}
+		token.Scopes = NormalizeManagementScopes(token.Scopes)
+		if len(token.Scopes) == 0 {
+			log.Warnf("management token %q: no valid scopes, token ignored", token.Name)
+			continue
+		}
+		seen[token.Name] = struct{}{}
+		out = append(out, token)
+	}
+	cfg.RemoteManagement.Tokens = out
+}
+
+// NormalizeManagementScopes lowercases scopes and drops unknown and duplicate entries.
+func NormalizeManagementScopes(scopes []string) []string {
+	out := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !IsManagementScope(scope) {
+			if scope != "" {
+				log.Warnf("unknown management scope %q ignored", scope)
+			}
+			continue
+		}
+		duplicate := false
+		for _, existing := range out {
+			if existing == scope {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			out = append(out, scope)
+		}
+	}
+	return out
 }
 
 // QuotaExceeded defines the behavior when API quota limits are exceeded.
@@ -158,11 +347,53 @@ type QuotaExceeded struct {
 	SwitchPreviewModel bool `yaml:"switch-preview-model" json:"switch-preview-model"`
 }
 
+// CircuitBreakerConfig configures the circuit breakers kept per credential and per credential
+// and model. A circuit opens after too many server errors or timeouts within the window, rejects
+// requests for the open period, and then lets a single probe request decide whether it closes.
+type CircuitBreakerConfig struct {
+	// Enable turns the circuit breakers on.
+	Enable bool `yaml:"enable" json:"enable"`
+
+	// FailureThreshold is the number of failures of one model within the window that opens the
+	// circuit of that credential and model. <= 0 uses the default of 5.
+	FailureThreshold int `yaml:"failure-threshold,omitempty" json:"failure-threshold,omitempty"`
+
+	// AuthFailureThreshold is the number of failures across all models within the window that
+	// opens the circuit of the whole credential. <= 0 uses twice the failure threshold.
+	AuthFailureThreshold int `yaml:"auth-failure-threshold,omitempty" json:"auth-failure-threshold,omitempty"`
+
+	// WindowSeconds is the sliding window in which failures are counted. <= 0 uses 60 seconds.
+	WindowSeconds int `yaml:"window-seconds,omitempty" json:"window-seconds,omitempty"`
+
+	// OpenSeconds is how long an open circuit rejects requests before admitting a probe.
+	// <= 0 uses 30 seconds.
+	OpenSeconds int `yaml:"open-seconds,omitempty" json:"open-seconds,omitempty"`
+}
+
+// CooldownQueueConfig configures the per-model wait queue used when every credential for a
+// model is cooling down for longer than max-retry-interval. Queued requests are dispatched by
+// client key priority (see client-keys queue-priority), then fairly across client keys, as
+// soon as a credential recovers.
+type CooldownQueueConfig struct {
+	// Enable turns the queue on; when off such requests fail immediately with 429.
+	Enable bool `yaml:"enable" json:"enable"`
+
+	// MaxWaitSeconds bounds how long a request may stay queued. <= 0 uses 120 seconds.
+	MaxWaitSeconds int `yaml:"max-wait-seconds,omitempty" json:"max-wait-seconds,omitempty"`
+
+	// MaxDepth caps the number of queued requests per model. <= 0 uses 100.
+	MaxDepth int `yaml:"max-depth,omitempty" json:"max-depth,omitempty"`
+}
+
 // RoutingConfig configures how credentials are selected for requests.
 type RoutingConfig struct {
 	// Strategy selects the credential selection strategy.
-	// Supported values: "round-robin" (default), "fill-first".
+	// Supported values: "round-robin" (default), "fill-first", "latency".
 	Strategy string `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+
+	// LimitWaitSeconds bounds how long a request waits for capacity when every candidate
+	// credential is at its max-concurrency, rpm or tpm limit. <= 0 uses 5 seconds.
+	LimitWaitSeconds int `yaml:"limit-wait-seconds,omitempty" json:"limit-wait-seconds,omitempty"`
 }
 
 // OAuthModelAlias defines a model ID alias for a specific channel.
@@ -175,6 +406,68 @@ type OAuthModelAlias struct {
 	Fork  bool   `yaml:"fork,omitempty" json:"fork,omitempty"`
 }
 
+// Virtual model fallback conditions accepted in VirtualModel.FallbackOn.
+const (
+	// VirtualModelFallbackCooldown falls through when no credential can serve the target
+	// (all cooling down, rate limited or missing).
+	VirtualModelFallbackCooldown = "cooldown"
+	// VirtualModelFallbackServerError falls through when the target fails with a 5xx status.
+	VirtualModelFallbackServerError = "5xx"
+	// VirtualModelFallbackContextLength falls through when the target rejects the prompt as too long.
+	VirtualModelFallbackContextLength = "context-length"
+)
+
+// VirtualModel defines a model name resolved by the conductor to the first target that succeeds.
+// Targets are regular model names (or aliases) and may belong to different providers; responses
+// are always translated back to the client's format and report Name as the model.
+type VirtualModel struct {
+	// Name is the model name clients request.
+	Name string `yaml:"name" json:"name"`
+	// Targets lists the models to try, in order.
+	Targets []string `yaml:"targets" json:"targets"`
+	// FallbackOn lists the failure conditions that move on to the next target:
+	// "cooldown", "5xx" and "context-length". Defaults to all of them.
+	FallbackOn []string `yaml:"fallback-on,omitempty" json:"fallback-on,omitempty"`
+}
+
+// HedgePolicy configures hedged requests for a set of models. When the first attempt has not
+// produced its first byte after Delay, a second attempt is launched on a different credential
+// (or against FallbackModel) and whichever responds first is returned; the other is cancelled.
+type HedgePolicy struct {
+	// Models lists the model names the policy applies to ('*' wildcards allowed).
+	Models []string `yaml:"models" json:"models"`
+	// DelayMS is how long to wait for the first byte before launching the hedge attempt.
+	DelayMS int `yaml:"delay-ms" json:"delay-ms"`
+	// FallbackModel optionally runs the hedge attempt against another model instead of
+	// another credential for the same model.
+	FallbackModel string `yaml:"fallback-model,omitempty" json:"fallback-model,omitempty"`
+}
+
+// AvailabilitySchedule restricts a credential to weekly time windows, e.g. for accounts shared
+// with people during working hours. Each window is "<days> <HH:MM-HH:MM>" where either part may
+// be omitted: days are names or ranges such as "mon-fri" or "sat,sun", and a time range ending
+// at or before its start runs past midnight into the next day.
+type AvailabilitySchedule struct {
+	// Timezone is the IANA time zone the windows are evaluated in; defaults to the local zone.
+	Timezone string `yaml:"timezone,omitempty" json:"timezone,omitempty"`
+	// Windows lists when the credential is available, e.g. "mon-fri 19:00-08:00" or "sat,sun".
+	Windows []string `yaml:"windows" json:"windows"`
+}
+
+// ShadowPolicy mirrors a sample of the requests for a set of models to TargetModel. The shadow
+// request runs asynchronously after the primary one is dispatched and its response is discarded.
+type ShadowPolicy struct {
+	// Models lists the model names the policy applies to ('*' wildcards allowed).
+	Models []string `yaml:"models" json:"models"`
+	// TargetModel is the model the mirrored request is sent to. A credential prefix
+	// (e.g. "eval/claude-sonnet-4-5") selects the credentials under evaluation.
+	TargetModel string `yaml:"target-model" json:"target-model"`
+	// SampleRate is the fraction of matching requests to mirror, in (0, 1].
+	SampleRate float64 `yaml:"sample-rate" json:"sample-rate"`
+	// LogResponse writes the full shadow response to the request log when request-log is enabled.
+	LogResponse bool `yaml:"log-response,omitempty" json:"log-response,omitempty"`
+}
+
 // AmpModelMapping defines a model name mapping for Amp CLI requests.
 // When Amp requests a model that isn't available locally, this mapping
 // allows routing to an alternative model that IS available.
@@ -300,6 +593,22 @@ type ClaudeKey struct {
 	// Higher values are preferred; defaults to 0.
 	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
 
+	// MaxConcurrency caps in-flight requests sent with this credential; 0 means unlimited.
+	MaxConcurrency int `yaml:"max-concurrency,omitempty" json:"max-concurrency,omitempty"`
+
+	// RPM caps requests per minute sent with this credential; 0 means unlimited.
+	RPM int `yaml:"rpm,omitempty" json:"rpm,omitempty"`
+
+	// TPM caps tokens per minute consumed by this credential; 0 means unlimited.
+	TPM int `yaml:"tpm,omitempty" json:"tpm,omitempty"`
+
+	// Availability optionally restricts the times this credential serves proxy traffic.
+	Availability *AvailabilitySchedule `yaml:"availability,omitempty" json:"availability,omitempty"`
+
+	// Tags label this credential for tag-based routing (see client-keys credential-tags and the
+	// X-CLIProxy-Tags header). Unlike Prefix they do not change model names.
+	Tags []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+
 	// Prefix optionally namespaces models for this credential (e.g., "teamA/claude-sonnet-4").
 	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
 
@@ -348,6 +657,22 @@ type CodexKey struct {
 	// Higher values are preferred; defaults to 0.
 	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
 
+	// MaxConcurrency caps in-flight requests sent with this credential; 0 means unlimited.
+	MaxConcurrency int `yaml:"max-concurrency,omitempty" json:"max-concurrency,omitempty"`
+
+	// RPM caps requests per minute sent with this credential; 0 means unlimited.
+	RPM int `yaml:"rpm,omitempty" json:"rpm,omitempty"`
+
+	// TPM caps tokens per minute consumed by this credential; 0 means unlimited.
+	TPM int `yaml:"tpm,omitempty" json:"tpm,omitempty"`
+
+	// Availability optionally restricts the times this credential serves proxy traffic.
+	Availability *AvailabilitySchedule `yaml:"availability,omitempty" json:"availability,omitempty"`
+
+	// Tags label this credential for tag-based routing (see client-keys credential-tags and the
+	// X-CLIProxy-Tags header). Unlike Prefix they do not change model names.
+	Tags []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+
 	// Prefix optionally namespaces models for this credential (e.g., "teamA/gpt-5-codex").
 	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
 
@@ -393,6 +718,22 @@ type GeminiKey struct {
 	// Higher values are preferred; defaults to 0.
 	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
 
+	// MaxConcurrency caps in-flight requests sent with this credential; 0 means unlimited.
+	MaxConcurrency int `yaml:"max-concurrency,omitempty" json:"max-concurrency,omitempty"`
+
+	// RPM caps requests per minute sent with this credential; 0 means unlimited.
+	RPM int `yaml:"rpm,omitempty" json:"rpm,omitempty"`
+
+	// TPM caps tokens per minute consumed by this credential; 0 means unlimited.
+	TPM int `yaml:"tpm,omitempty" json:"tpm,omitempty"`
+
+	// Availability optionally restricts the times this credential serves proxy traffic.
+	Availability *AvailabilitySchedule `yaml:"availability,omitempty" json:"availability,omitempty"`
+
+	// Tags label this credential for tag-based routing (see client-keys credential-tags and the
+	// X-CLIProxy-Tags header). Unlike Prefix they do not change model names.
+	Tags []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+
 	// Prefix optionally namespaces models for this credential (e.g., "teamA/gemini-3-pro-preview").
 	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
 
@@ -460,6 +801,21 @@ type OpenAICompatibilityAPIKey struct {
 
 	// ProxyURL overrides the global proxy setting for this API key if provided.
 	ProxyURL string `yaml:"proxy-url,omitempty" json:"proxy-url,omitempty"`
+	// MaxConcurrency caps in-flight requests sent with this credential; 0 means unlimited.
+	MaxConcurrency int `yaml:"max-concurrency,omitempty" json:"max-concurrency,omitempty"`
+
+	// RPM caps requests per minute sent with this credential; 0 means unlimited.
+	RPM int `yaml:"rpm,omitempty" json:"rpm,omitempty"`
+
+	// TPM caps tokens per minute consumed by this credential; 0 means unlimited.
+	TPM int `yaml:"tpm,omitempty" json:"tpm,omitempty"`
+
+	// Availability optionally restricts the times this credential serves proxy traffic.
+	Availability *AvailabilitySchedule `yaml:"availability,omitempty" json:"availability,omitempty"`
+
+	// Tags label this credential for tag-based routing (see client-keys credential-tags and the
+	// X-CLIProxy-Tags header). Unlike Prefix they do not change model names.
+	Tags []string `yaml:"tags,omitempty" json:"tags,omitempty"`
 }
 
 // OpenAICompatibilityModel represents a model configuration for OpenAI compatibility,
@@ -553,6 +909,18 @@ func LoadConfigOptional(configFile string, optional bool) (*Config, error) {
 		}
 	}
 
+	// Resolve secret references before sanitizing, which deduplicates entries by API key.
+	if err = cfg.ResolveSecrets(); err != nil {
+		return nil, fmt.Errorf("failed to resolve secret references: %w", err)
+	}
+
+	// An unparsable expiry must not leave a client key valid forever.
+	for i := range cfg.ClientKeys {
+		if _, errExpiry := cfg.ClientKeys[i].Expiry(); errExpiry != nil {
+			return nil, fmt.Errorf("client-keys[%d]: %w", i, errExpiry)
+		}
+	}
+
 	// Hash remote management key if plaintext is detected (nested)
 	// We consider a value to be already hashed if it looks like a bcrypt hash ($2a$, $2b$, or $2y$ prefix).
 	if cfg.RemoteManagement.SecretKey != "" && !looksLikeBcrypt(cfg.RemoteManagement.SecretKey) {
@@ -567,6 +935,27 @@ func LoadConfigOptional(configFile string, optional bool) (*Config, error) {
 		_ = SaveConfigPreserveCommentsUpdateNestedScalar(configFile, []string{"remote-management", "secret-key"}, hashed)
 	}
 
+	cfg.SanitizeManagementTokens()
+
+	// Hash plaintext management tokens like the secret key: read-only tokens may list the
+	// config, so a plaintext admin token must not stay readable there.
+	hashedTokens := make(map[string]string)
+	for i := range cfg.RemoteManagement.Tokens {
+		token := &cfg.RemoteManagement.Tokens[i]
+		if looksLikeBcrypt(token.Token) {
+			continue
+		}
+		hashed, errHash := hashSecret(token.Token)
+		if errHash != nil {
+			return nil, fmt.Errorf("failed to hash management token %q: %w", token.Name, errHash)
+		}
+		token.Token = hashed
+		hashedTokens[token.Name] = hashed
+	}
+	if len(hashedTokens) > 0 {
+		_ = SaveConfigPreserveCommentsUpdateManagementTokens(configFile, hashedTokens)
+	}
+
 	cfg.RemoteManagement.PanelGitHubRepository = strings.TrimSpace(cfg.RemoteManagement.PanelGitHubRepository)
 	if cfg.RemoteManagement.PanelGitHubRepository == "" {
 		cfg.RemoteManagement.PanelGitHubRepository = DefaultPanelGitHubRepository
@@ -612,6 +1001,14 @@ func LoadConfigOptional(configFile string, optional bool) (*Config, error) {
 	// Validate raw payload rules and drop invalid entries.
 	cfg.SanitizePayloadRules()
 
+	// Drop virtual models without a name or targets.
+	cfg.SanitizeVirtualModels()
+
+	// Drop hedging policies without models or a positive delay.
+	cfg.SanitizeHedging()
+	cfg.SanitizeShadowTraffic()
+	cfg.SanitizeTrafficSplits()
+
 	if cfg.legacyMigrationPending {
 		fmt.Println("Detected legacy configuration keys, attempting to persist the normalized config...")
 		if !optional && configFile != "" {
@@ -721,6 +1118,150 @@ func (cfg *Config) SanitizeOAuthModelAlias() {
 	cfg.OAuthModelAlias = out
 }
 
+// SanitizeVirtualModels trims virtual model definitions, drops entries without a name or
+// targets, removes duplicate names (first wins), self-references and unknown fallback conditions.
+func (cfg *Config) SanitizeVirtualModels() {
+	if cfg == nil || len(cfg.VirtualModels) == 0 {
+		return
+	}
+	seen := make(map[string]struct{}, len(cfg.VirtualModels))
+	out := make([]VirtualModel, 0, len(cfg.VirtualModels))
+	for _, entry := range cfg.VirtualModels {
+		name := strings.TrimSpace(entry.Name)
+		key := strings.ToLower(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		targets := make([]string, 0, len(entry.Targets))
+		for _, target := range entry.Targets {
+			target = strings.TrimSpace(target)
+			if target == "" || strings.EqualFold(target, name) {
+				continue
+			}
+			targets = append(targets, target)
+		}
+		if len(targets) == 0 {
+			log.Warnf("virtual model %q dropped: no targets", name)
+			continue
+		}
+		var conditions []string
+		for _, condition := range entry.FallbackOn {
+			condition = strings.ToLower(strings.TrimSpace(condition))
+			switch condition {
+			case VirtualModelFallbackCooldown, VirtualModelFallbackServerError, VirtualModelFallbackContextLength:
+				conditions = append(conditions, condition)
+			case "":
+			default:
+				log.Warnf("virtual model %q: unknown fallback condition %q ignored", name, condition)
+			}
+		}
+		seen[key] = struct{}{}
+		out = append(out, VirtualModel{Name: name, Targets: targets, FallbackOn: conditions})
+	}
+	cfg.VirtualModels = out
+}
+
+// SanitizeHedging trims hedging policies and drops entries without model patterns or a positive delay.
+func (cfg *Config) SanitizeHedging() {
+	if cfg == nil || len(cfg.Hedging) == 0 {
+		return
+	}
+	out := make([]HedgePolicy, 0, len(cfg.Hedging))
+	for _, entry := range cfg.Hedging {
+		models := make([]string, 0, len(entry.Models))
+		for _, model := range entry.Models {
+			if model = strings.TrimSpace(model); model != "" {
+				models = append(models, model)
+			}
+		}
+		if len(models) == 0 {
+			continue
+		}
+		if entry.DelayMS <= 0 {
+			log.Warnf("hedging policy for %v dropped: delay-ms must be positive", models)
+			continue
+		}
+		out = append(out, HedgePolicy{Models: models, DelayMS: entry.DelayMS, FallbackModel: strings.TrimSpace(entry.FallbackModel)})
+	}
+	cfg.Hedging = out
+}
+
+// SanitizeShadowTraffic trims shadow traffic policies, caps sample rates at 1 and drops entries
+// without model patterns, a target model or a positive sample rate.
+func (cfg *Config) SanitizeShadowTraffic() {
+	if cfg == nil || len(cfg.ShadowTraffic) == 0 {
+		return
+	}
+	out := make([]ShadowPolicy, 0, len(cfg.ShadowTraffic))
+	for _, entry := range cfg.ShadowTraffic {
+		models := make([]string, 0, len(entry.Models))
+		for _, model := range entry.Models {
+			if model = strings.TrimSpace(model); model != "" {
+				models = append(models, model)
+			}
+		}
+		if len(models) == 0 {
+			continue
+		}
+		entry.Models = models
+		entry.TargetModel = strings.TrimSpace(entry.TargetModel)
+		if entry.TargetModel == "" {
+			log.Warnf("shadow traffic policy for %v dropped: target-model is required", models)
+			continue
+		}
+		if entry.SampleRate <= 0 {
+			log.Warnf("shadow traffic policy for %v dropped: sample-rate must be positive", models)
+			continue
+		}
+		if entry.SampleRate > 1 {
+			entry.SampleRate = 1
+		}
+		out = append(out, entry)
+	}
+	cfg.ShadowTraffic = out
+}
+
+// SanitizeTrafficSplits trims traffic split rules, drops variants without a model or a positive
+// weight, and drops rules left without variants.
+func (cfg *Config) SanitizeTrafficSplits() {
+	if cfg == nil || len(cfg.TrafficSplits) == 0 {
+		return
+	}
+	out := make([]TrafficSplit, 0, len(cfg.TrafficSplits))
+	for _, rule := range cfg.TrafficSplits {
+		rule.Model = strings.TrimSpace(rule.Model)
+		if rule.Model == "" {
+			continue
+		}
+		rule.BucketBy = strings.ToLower(strings.TrimSpace(rule.BucketBy))
+		if rule.BucketBy != TrafficSplitBySession {
+			rule.BucketBy = TrafficSplitByClientKey
+		}
+		variants := make([]TrafficSplitVariant, 0, len(rule.Variants))
+		for _, variant := range rule.Variants {
+			variant.Model = strings.TrimSpace(variant.Model)
+			variant.Name = strings.TrimSpace(variant.Name)
+			if variant.Model == "" || variant.Weight <= 0 {
+				continue
+			}
+			if variant.Name == "" {
+				variant.Name = variant.Model
+			}
+			variants = append(variants, variant)
+		}
+		if len(variants) == 0 {
+			log.Warnf("traffic split for %s dropped: no variant with a model and positive weight", rule.Model)
+			continue
+		}
+		rule.Variants = variants
+		out = append(out, rule)
+	}
+	cfg.TrafficSplits = out
+}
+
 // SanitizeOpenAICompatibility removes OpenAI-compatibility provider entries that are
 // not actionable, specifically those missing a BaseURL. It trims whitespace before
 // evaluation and preserves the relative order of remaining entries.
@@ -827,7 +1368,21 @@ func syncInlineAccessProvider(cfg *Config) {
 			cfg.APIKeys = append([]string(nil), provider.APIKeys...)
 		}
 	}
-	cfg.Access.Providers = nil
+	cfg.Access.Providers = externalAccessProviders(cfg.Access.Providers)
+}
+
+// externalAccessProviders drops legacy inline API key entries, which are superseded by
+// the top-level api-keys list, and keeps providers of every other type.
+func externalAccessProviders(providers []AccessProvider) []AccessProvider {
+	var kept []AccessProvider
+	for _, provider := range providers {
+		typ := strings.TrimSpace(provider.Type)
+		if typ == "" || strings.EqualFold(typ, AccessProviderTypeConfigAPIKey) {
+			continue
+		}
+		kept = append(kept, provider)
+	}
+	return kept
 }
 
 // looksLikeBcrypt returns true if the provided string appears to be a bcrypt hash.
@@ -952,7 +1507,9 @@ func SaveConfigPreserveComments(configFile string, cfg *Config) error {
 	}
 
 	// Remove deprecated sections before merging back the sanitized config.
-	removeLegacyAuthBlock(original.Content[0])
+	if len(persistCfg.Access.Providers) == 0 {
+		removeLegacyAuthBlock(original.Content[0])
+	}
 	removeLegacyOpenAICompatAPIKeys(original.Content[0])
 	removeLegacyAmpKeys(original.Content[0])
 	removeLegacyGenerativeLanguageKeys(original.Content[0])
@@ -989,10 +1546,9 @@ func sanitizeConfigForPersist(cfg *Config) *Config {
 	if cfg == nil {
 		return nil
 	}
-	clone := *cfg
-	clone.SDKConfig = cfg.SDKConfig
-	clone.SDKConfig.Access = AccessConfig{}
-	return &clone
+	clone := cfg.WithSecretReferences()
+	clone.SDKConfig.Access = AccessConfig{Providers: externalAccessProviders(cfg.Access.Providers)}
+	return clone
 }
 
 // SaveConfigPreserveCommentsUpdateNestedScalar updates a nested scalar key path like ["a","b"]
@@ -1047,6 +1603,74 @@ func SaveConfigPreserveCommentsUpdateNestedScalar(configFile string, path []stri
 	return err
 }
 
+// SaveConfigPreserveCommentsUpdateManagementTokens replaces the key of each entry under
+// remote-management.tokens whose name is in tokens, preserving comments and positions.
+func SaveConfigPreserveCommentsUpdateManagementTokens(configFile string, tokens map[string]string) error {
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+	var root yaml.Node
+	if err = yaml.Unmarshal(data, &root); err != nil {
+		return err
+	}
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return fmt.Errorf("invalid yaml document structure")
+	}
+	remote := findMapValue(root.Content[0], "remote-management")
+	if remote == nil {
+		return nil
+	}
+	list := findMapValue(remote, "tokens")
+	if list == nil || list.Kind != yaml.SequenceNode {
+		return nil
+	}
+	updated := false
+	for _, item := range list.Content {
+		name := findMapValue(item, "name")
+		if name == nil {
+			continue
+		}
+		hashed, ok := tokens[strings.TrimSpace(name.Value)]
+		if !ok {
+			continue
+		}
+		v := getOrCreateMapValue(item, "token")
+		v.Kind = yaml.ScalarNode
+		v.Tag = "!!str"
+		v.Style = 0
+		v.Value = hashed
+		updated = true
+	}
+	if !updated {
+		return nil
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err = enc.Encode(&root); err != nil {
+		_ = enc.Close()
+		return err
+	}
+	if err = enc.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(configFile, NormalizeCommentIndentation(buf.Bytes()), 0o600)
+}
+
+// findMapValue returns the value node for key in a mapping node, or nil when it is absent.
+func findMapValue(mapNode *yaml.Node, key string) *yaml.Node {
+	if mapNode == nil || mapNode.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapNode.Content); i += 2 {
+		if mapNode.Content[i].Value == key {
+			return mapNode.Content[i+1]
+		}
+	}
+	return nil
+}
+
 // NormalizeCommentIndentation removes indentation from standalone YAML comment lines to keep them left aligned.
 func NormalizeCommentIndentation(data []byte) []byte {
 	lines := bytes.Split(data, []byte("\n"))
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultConfigHistoryVersions is the number of config versions kept when
+// remote-management.config-history is not set.
+const DefaultConfigHistoryVersions = 20
+
+const (
+	configHistoryDirName = "config-history"
+	// configVersionLayout names version files; it sorts chronologically as a string.
+	configVersionLayout = "20060102T150405.000000000Z"
+)
+
+// configHistoryMu serializes writes to every config history directory.
+var configHistoryMu sync.Mutex
+
+// ConfigVersion describes a stored config version.
+type ConfigVersion struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+}
+
+// ConfigHistory keeps the last versions of a config file in a config-history directory next
+// to it. Versions are full copies of the file, named by the UTC time they were recorded.
+type ConfigHistory struct {
+	dir         string
+	maxVersions int
+}
+
+// NewConfigHistory returns the history of configFile, or nil when maxVersions is negative or
+// configFile is empty. A maxVersions of 0 keeps DefaultConfigHistoryVersions.
+func NewConfigHistory(configFile string, maxVersions int) *ConfigHistory {
+	if maxVersions < 0 || strings.TrimSpace(configFile) == "" {
+		return nil
+	}
+	if maxVersions == 0 {
+		maxVersions = DefaultConfigHistoryVersions
+	}
+	return &ConfigHistory{dir: filepath.Join(filepath.Dir(configFile), configHistoryDirName), maxVersions: maxVersions}
+}
+
+// Record stores data as a new version unless it equals the latest one, then prunes versions
+// beyond the configured maximum. It reports whether a version was added.
+func (h *ConfigHistory) Record(data []byte) (ConfigVersion, bool, error) {
+	if h == nil {
+		return ConfigVersion{}, false, nil
+	}
+	configHistoryMu.Lock()
+	defer configHistoryMu.Unlock()
+
+	ids, err := h.ids()
+	if err != nil {
+		return ConfigVersion{}, false, err
+	}
+	if len(ids) > 0 {
+		latest, errRead := os.ReadFile(h.path(ids[len(ids)-1]))
+		if errRead == nil && bytes.Equal(latest, data) {
+			return ConfigVersion{}, false, nil
+		}
+	}
+	if err = os.MkdirAll(h.dir, 0o700); err != nil {
+		return ConfigVersion{}, false, fmt.Errorf("config history: create directory: %w", err)
+	}
+	now := time.Now().UTC()
+	id := now.Format(configVersionLayout)
+	for len(ids) > 0 && id <= ids[len(ids)-1] {
+		now = now.Add(time.Nanosecond)
+		id = now.Format(configVersionLayout)
+	}
+	if err = os.WriteFile(h.path(id), data, 0o600); err != nil {
+		return ConfigVersion{}, false, fmt.Errorf("config history: write version: %w", err)
+	}
+	ids = append(ids, id)
+	for len(ids) > h.maxVersions {
+		if errRemove := os.Remove(h.path(ids[0])); errRemove != nil && !os.IsNotExist(errRemove) {
+			return ConfigVersion{}, true, fmt.Errorf("config history: prune version: %w", errRemove)
+		}
+		ids = ids[1:]
+	}
+	return describeConfigVersion(id, data), true, nil
+}
+
+// List returns the stored versions, newest first.
+func (h *ConfigHistory) List() ([]ConfigVersion, error) {
+	if h == nil {
+		return nil, nil
+	}
+	ids, err := h.ids()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ConfigVersion, 0, len(ids))
+	for i := len(ids) - 1; i >= 0; i-- {
+		data, errRead := os.ReadFile(h.path(ids[i]))
+		if errRead != nil {
+			continue
+		}
+		out = append(out, describeConfigVersion(ids[i], data))
+	}
+	return out, nil
+}
+
+// Read returns the content of version id. It returns an error satisfying os.IsNotExist when
+// the version does not exist.
+func (h *ConfigHistory) Read(id string) ([]byte, error) {
+	if h == nil {
+		return nil, os.ErrNotExist
+	}
+	if _, err := time.Parse(configVersionLayout, id); err != nil {
+		return nil, os.ErrNotExist
+	}
+	return os.ReadFile(h.path(id))
+}
+
+func (h *ConfigHistory) path(id string) string {
+	return filepath.Join(h.dir, id+".yaml")
+}
+
+// ids returns the stored version IDs, oldest first.
+func (h *ConfigHistory) ids() ([]string, error) {
+	entries, err := os.ReadDir(h.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("config history: read directory: %w", err)
+	}
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".yaml")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if _, errParse := time.Parse(configVersionLayout, id); errParse == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func describeConfigVersion(id string, data []byte) ConfigVersion {
+	createdAt, _ := time.Parse(configVersionLayout, id)
+	sum := sha256.Sum256(data)
+	return ConfigVersion{ID: id, CreatedAt: createdAt, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:])}
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigHistoryRecordDedupsAndPrunes(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	history := NewConfigHistory(configFile, 2)
+
+	for _, content := range []string{"debug: true\n", "debug: true\n", "debug: false\n", "port: 8318\n"} {
+		if _, _, err := history.Record([]byte(content)); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+	versions, err := history.List()
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("expected 2 versions after pruning, got %d", len(versions))
+	}
+	newest, err := history.Read(versions[0].ID)
+	if err != nil || string(newest) != "port: 8318\n" {
+		t.Fatalf("newest version = %q, %v", newest, err)
+	}
+	oldest, err := history.Read(versions[1].ID)
+	if err != nil || string(oldest) != "debug: false\n" {
+		t.Fatalf("oldest version = %q, %v", oldest, err)
+	}
+	if _, err = history.Read("../config"); !os.IsNotExist(err) {
+		t.Fatalf("expected a not-exist error for an invalid id, got %v", err)
+	}
+}
+
+func TestNewConfigHistoryDisabled(t *testing.T) {
+	if NewConfigHistory(filepath.Join(t.TempDir(), "config.yaml"), -1) != nil {
+		t.Fatal("expected a negative version count to disable the history")
+	}
+}
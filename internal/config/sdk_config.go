@@ -4,6 +4,12 @@
 // debug settings, proxy configuration, and API keys.
 package config
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 // SDKConfig represents the application's configuration, loaded from a YAML file.
 type SDKConfig struct {
 	// ProxyURL is the URL of an optional proxy server to use for outbound requests.
@@ -20,6 +26,9 @@ type SDKConfig struct {
 	// APIKeys is a list of keys for authenticating clients to this proxy server.
 	APIKeys []string `yaml:"api-keys" json:"api-keys"`
 
+	// ClientKeys lists client API keys with optional per-key access policies.
+	ClientKeys []ClientKey `yaml:"client-keys,omitempty" json:"client-keys,omitempty"`
+
 	// Access holds request authentication provider configuration.
 	Access AccessConfig `yaml:"auth,omitempty" json:"auth,omitempty"`
 
@@ -29,6 +38,148 @@ type SDKConfig struct {
 	// NonStreamKeepAliveInterval controls how often blank lines are emitted for non-streaming responses.
 	// <= 0 disables keep-alives. Value is in seconds.
 	NonStreamKeepAliveInterval int `yaml:"nonstream-keepalive-interval,omitempty" json:"nonstream-keepalive-interval,omitempty"`
+
+	// ResponsesStore configures persistence of /v1/responses results used to honour previous_response_id.
+	ResponsesStore ResponsesStoreConfig `yaml:"responses-store,omitempty" json:"responses-store,omitempty"`
+
+	// SessionAffinity pins the turns of one conversation to the credential that served it,
+	// so upstream prompt caches keep hitting.
+	SessionAffinity SessionAffinityConfig `yaml:"session-affinity,omitempty" json:"session-affinity,omitempty"`
+
+	// TrafficSplits route weighted shares of a model's traffic to variant models, e.g. to trial
+	// a new model or credential prefix on a small fraction of requests.
+	TrafficSplits []TrafficSplit `yaml:"traffic-split,omitempty" json:"traffic-split,omitempty"`
+}
+
+// Traffic split bucketing keys.
+const (
+	TrafficSplitByClientKey = "client-key"
+	TrafficSplitBySession   = "session"
+)
+
+// TrafficSplit divides the requests for Model between weighted variants. Requests are bucketed
+// deterministically so one client key (or session) keeps getting the same variant.
+type TrafficSplit struct {
+	// Model is the requested model name the split applies to (without thinking suffix).
+	Model string `yaml:"model" json:"model"`
+
+	// BucketBy selects the bucketing key: "client-key" (default) or "session". When the key is
+	// unavailable the other one is used, and requests with neither are assigned at random.
+	BucketBy string `yaml:"bucket-by,omitempty" json:"bucket-by,omitempty"`
+
+	// Variants lists the models that receive a share of the traffic.
+	Variants []TrafficSplitVariant `yaml:"variants" json:"variants"`
+}
+
+// TrafficSplitVariant is one weighted target of a traffic split.
+type TrafficSplitVariant struct {
+	// Name labels the variant in usage records. Defaults to Model.
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+
+	// Model is the model requests are routed to. It may carry a credential prefix
+	// (e.g. "canary/claude-sonnet-4") to send the share to prefixed credentials.
+	Model string `yaml:"model" json:"model"`
+
+	// Weight is the variant's relative share of the traffic.
+	Weight int `yaml:"weight" json:"weight"`
+}
+
+// SessionAffinityConfig controls conversation-to-credential pinning.
+// The session key is taken from the configured header, Claude metadata.user_id, the
+// Responses prompt_cache_key, or else a hash of the system prompt and first user message.
+type SessionAffinityConfig struct {
+	// Enable turns session affinity on.
+	Enable bool `yaml:"enable" json:"enable"`
+
+	// TTLSeconds is how long an idle session stays pinned. <= 0 uses the default of 3600 seconds.
+	TTLSeconds int `yaml:"ttl-seconds,omitempty" json:"ttl-seconds,omitempty"`
+
+	// Header optionally names a request header carrying an explicit session identifier.
+	Header string `yaml:"header,omitempty" json:"header,omitempty"`
+}
+
+// ResponsesStoreConfig controls the built-in store for OpenAI Responses API results.
+type ResponsesStoreConfig struct {
+	// Disable turns persistence off; previous_response_id is then passed through untouched.
+	Disable bool `yaml:"disable,omitempty" json:"disable,omitempty"`
+
+	// Driver selects the backend: "file" (default, one JSON document per response) or
+	// "sqlite" (a responses.db database inside Dir).
+	Driver string `yaml:"driver,omitempty" json:"driver,omitempty"`
+
+	// Dir is the directory holding stored responses. Defaults to "responses" under the
+	// writable path, or the working directory when no writable path is set.
+	Dir string `yaml:"dir,omitempty" json:"dir,omitempty"`
+
+	// TTLHours controls how long stored responses are kept. <= 0 uses the default of 720 hours.
+	TTLHours int `yaml:"ttl-hours,omitempty" json:"ttl-hours,omitempty"`
+}
+
+// ClientKey describes a client API key together with the policy enforced for it.
+// Zero-valued limits and empty lists mean unrestricted.
+type ClientKey struct {
+	// Key is the secret presented by the client.
+	Key string `yaml:"key" json:"key"`
+
+	// Subject applies this policy to the principal reported by a token-based access provider
+	// (for example a JWT subject) instead of an API key. It is never accepted as a credential.
+	Subject string `yaml:"subject,omitempty" json:"subject,omitempty"`
+
+	// Name is an optional label used in logs and error messages.
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+
+	// AllowedModels restricts the key to models matching these patterns ('*' wildcards allowed).
+	AllowedModels []string `yaml:"allowed-models,omitempty" json:"allowed-models,omitempty"`
+
+	// DeniedModels rejects models matching these patterns even when otherwise allowed.
+	DeniedModels []string `yaml:"denied-models,omitempty" json:"denied-models,omitempty"`
+
+	// AllowedProviders restricts routing to these provider identifiers (e.g., "claude", "gemini-cli").
+	AllowedProviders []string `yaml:"allowed-providers,omitempty" json:"allowed-providers,omitempty"`
+
+	// AllowedPrefixes requires requested models to carry one of these credential prefixes (e.g., "teamA").
+	AllowedPrefixes []string `yaml:"allowed-prefixes,omitempty" json:"allowed-prefixes,omitempty"`
+
+	// RPM caps requests per minute for this key.
+	RPM int `yaml:"rpm,omitempty" json:"rpm,omitempty"`
+
+	// TPM caps tokens per minute for this key.
+	TPM int `yaml:"tpm,omitempty" json:"tpm,omitempty"`
+
+	// DailyTokenBudget and MonthlyTokenBudget cap total tokens per UTC day and calendar month.
+	DailyTokenBudget   int64 `yaml:"daily-token-budget,omitempty" json:"daily-token-budget,omitempty"`
+	MonthlyTokenBudget int64 `yaml:"monthly-token-budget,omitempty" json:"monthly-token-budget,omitempty"`
+
+	// DailyCostBudget and MonthlyCostBudget cap list-price cost in USD (see pricing).
+	DailyCostBudget   float64 `yaml:"daily-cost-budget,omitempty" json:"daily-cost-budget,omitempty"`
+	MonthlyCostBudget float64 `yaml:"monthly-cost-budget,omitempty" json:"monthly-cost-budget,omitempty"`
+
+	// ExpiresAt disables the key after this time (RFC3339 or YYYY-MM-DD, UTC).
+	ExpiresAt string `yaml:"expires-at,omitempty" json:"expires-at,omitempty"`
+
+	// QueuePriority orders this key's requests in the cooldown queue; higher values are
+	// dispatched first. Keys without an entry use 0.
+	QueuePriority int `yaml:"queue-priority,omitempty" json:"queue-priority,omitempty"`
+
+	// CredentialTags restricts this key's requests to credentials carrying every listed tag.
+	// Requests may narrow the selection further with the X-CLIProxy-Tags header.
+	CredentialTags []string `yaml:"credential-tags,omitempty" json:"credential-tags,omitempty"`
+}
+
+// Expiry parses ExpiresAt. It returns the zero time when the key never expires and an
+// error when the value cannot be parsed; date-only values expire at the end of that UTC day.
+func (k ClientKey) Expiry() (time.Time, error) {
+	raw := strings.TrimSpace(k.ExpiresAt)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", raw); err == nil {
+		return t.Add(24 * time.Hour), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid expires-at %q: want RFC3339 or YYYY-MM-DD", raw)
 }
 
 // StreamingConfig holds server streaming behavior configuration.
@@ -41,6 +192,12 @@ type StreamingConfig struct {
 	// to allow auth rotation / transient recovery.
 	// <= 0 disables bootstrap retries. Default is 0.
 	BootstrapRetries int `yaml:"bootstrap-retries,omitempty" json:"bootstrap-retries,omitempty"`
+
+	// FailoverRetries controls how many times a stream that fails after bytes were sent may be resumed
+	// on another credential, with the partial output replayed as an assistant prefill.
+	// Only Claude, OpenAI Chat Completions and OpenAI Responses streams can be resumed.
+	// <= 0 disables mid-stream failover. Default is 0.
+	FailoverRetries int `yaml:"failover-retries,omitempty" json:"failover-retries,omitempty"`
 }
 
 // AccessConfig groups request authentication providers.
@@ -71,6 +228,9 @@ const (
 	// AccessProviderTypeConfigAPIKey is the built-in provider validating inline API keys.
 	AccessProviderTypeConfigAPIKey = "config-api-key"
 
+	// AccessProviderTypeJWT is the built-in provider validating JWT bearer tokens against a JWKS.
+	AccessProviderTypeJWT = "jwt"
+
 	// DefaultAccessProviderName is applied when no provider name is supplied.
 	DefaultAccessProviderName = "config-inline"
 )
@@ -91,6 +251,36 @@ func (c *SDKConfig) ConfigAPIKeyProvider() *AccessProvider {
 	return nil
 }
 
+// InlineAPIKeys returns the keys accepted by the inline API key provider: the plain
+// api-keys list followed by every client-keys entry, without duplicates.
+func (c *SDKConfig) InlineAPIKeys() []string {
+	if c == nil {
+		return nil
+	}
+	if len(c.ClientKeys) == 0 {
+		return c.APIKeys
+	}
+	seen := make(map[string]struct{}, len(c.APIKeys)+len(c.ClientKeys))
+	keys := make([]string, 0, len(c.APIKeys)+len(c.ClientKeys))
+	add := func(key string) {
+		if key == "" {
+			return
+		}
+		if _, ok := seen[key]; ok {
+			return
+		}
+		seen[key] = struct{}{}
+		keys = append(keys, key)
+	}
+	for _, key := range c.APIKeys {
+		add(key)
+	}
+	for i := range c.ClientKeys {
+		add(c.ClientKeys[i].Key)
+	}
+	return keys
+}
+
 // MakeInlineAPIKeyProvider constructs an inline API key provider configuration.
 // It returns nil when no keys are supplied.
 func MakeInlineAPIKeyProvider(keys []string) *AccessProvider {
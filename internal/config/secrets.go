@@ -0,0 +1,238 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SecretsKeyEnv names the environment variable holding the base64-encoded 32-byte AES key
+// that decrypts "enc:" secret references.
+const SecretsKeyEnv = "CONFIG_SECRETS_KEY"
+
+const (
+	secretEnvPrefix  = "${env:"
+	secretFilePrefix = "file:"
+	secretEncPrefix  = "enc:"
+)
+
+// IsSecretReference reports whether value is a secret reference rather than a literal secret.
+// Supported references are "${env:NAME}", "file:/path" and "enc:/path"; the last names a file
+// encrypted with EncryptSecret under the key in CONFIG_SECRETS_KEY.
+func IsSecretReference(value string) bool {
+	value = strings.TrimSpace(value)
+	return (strings.HasPrefix(value, secretEnvPrefix) && strings.HasSuffix(value, "}")) ||
+		strings.HasPrefix(value, secretFilePrefix) ||
+		strings.HasPrefix(value, secretEncPrefix)
+}
+
+// ResolveSecretReference returns the secret a reference points to, or value unchanged when it
+// is not a reference. Secrets read from files are trimmed of surrounding whitespace.
+func ResolveSecretReference(value string) (string, error) {
+	ref := strings.TrimSpace(value)
+	switch {
+	case strings.HasPrefix(ref, secretEnvPrefix) && strings.HasSuffix(ref, "}"):
+		name := strings.TrimSpace(ref[len(secretEnvPrefix) : len(ref)-1])
+		if name == "" {
+			return "", fmt.Errorf("secret reference %s: missing variable name", ref)
+		}
+		secret, ok := os.LookupEnv(name)
+		if !ok || strings.TrimSpace(secret) == "" {
+			return "", fmt.Errorf("secret reference %s: environment variable %s is not set", ref, name)
+		}
+		return strings.TrimSpace(secret), nil
+	case strings.HasPrefix(ref, secretFilePrefix):
+		data, err := os.ReadFile(strings.TrimSpace(strings.TrimPrefix(ref, secretFilePrefix)))
+		if err != nil {
+			return "", fmt.Errorf("secret reference %s: %w", ref, err)
+		}
+		return nonEmptySecret(ref, string(data))
+	case strings.HasPrefix(ref, secretEncPrefix):
+		data, err := os.ReadFile(strings.TrimSpace(strings.TrimPrefix(ref, secretEncPrefix)))
+		if err != nil {
+			return "", fmt.Errorf("secret reference %s: %w", ref, err)
+		}
+		key, err := SecretsKeyFromEnv()
+		if err != nil {
+			return "", fmt.Errorf("secret reference %s: %w", ref, err)
+		}
+		plaintext, err := DecryptSecret(key, string(data))
+		if err != nil {
+			return "", fmt.Errorf("secret reference %s: %w", ref, err)
+		}
+		return nonEmptySecret(ref, string(plaintext))
+	}
+	return value, nil
+}
+
+func nonEmptySecret(ref, secret string) (string, error) {
+	secret = strings.TrimSpace(secret)
+	if secret == "" {
+		return "", fmt.Errorf("secret reference %s: secret is empty", ref)
+	}
+	return secret, nil
+}
+
+// SecretsKeyFromEnv returns the AES key configured in CONFIG_SECRETS_KEY.
+func SecretsKeyFromEnv() ([]byte, error) {
+	raw := strings.TrimSpace(os.Getenv(SecretsKeyEnv))
+	if raw == "" {
+		return nil, fmt.Errorf("%s is not set", SecretsKeyEnv)
+	}
+	key, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("%s must be a base64-encoded 32-byte key", SecretsKeyEnv)
+	}
+	return key, nil
+}
+
+// EncryptSecret encrypts plaintext with AES-256-GCM and returns the base64 text stored in
+// files referenced as "enc:/path".
+func EncryptSecret(key, plaintext []byte) (string, error) {
+	gcm, err := newSecretCipher(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret reverses EncryptSecret.
+func DecryptSecret(key []byte, encoded string) ([]byte, error) {
+	gcm, err := newSecretCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted secret: %w", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid encrypted secret: too short")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt secret: wrong key or corrupted file")
+	}
+	return plaintext, nil
+}
+
+func newSecretCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ResolveSecrets replaces secret references in API key fields with the secrets they point to
+// and remembers each reference, so the config is persisted and shown with the reference
+// instead of the secret. Values that are already resolved are left alone.
+func (cfg *Config) ResolveSecrets() error {
+	if cfg == nil {
+		return nil
+	}
+	refs := make(map[string]string, len(cfg.secretRefs))
+	for secret, ref := range cfg.secretRefs {
+		refs[secret] = ref
+	}
+	resolve := func(path string, value *string) error {
+		if !IsSecretReference(*value) {
+			return nil
+		}
+		secret, err := ResolveSecretReference(*value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		refs[secret] = strings.TrimSpace(*value)
+		*value = secret
+		return nil
+	}
+	for i := range cfg.GeminiKey {
+		if err := resolve(fmt.Sprintf("gemini-api-key[%d].api-key", i), &cfg.GeminiKey[i].APIKey); err != nil {
+			return err
+		}
+	}
+	for i := range cfg.CodexKey {
+		if err := resolve(fmt.Sprintf("codex-api-key[%d].api-key", i), &cfg.CodexKey[i].APIKey); err != nil {
+			return err
+		}
+	}
+	for i := range cfg.ClaudeKey {
+		if err := resolve(fmt.Sprintf("claude-api-key[%d].api-key", i), &cfg.ClaudeKey[i].APIKey); err != nil {
+			return err
+		}
+	}
+	for i := range cfg.VertexCompatAPIKey {
+		if err := resolve(fmt.Sprintf("vertex-api-key[%d].api-key", i), &cfg.VertexCompatAPIKey[i].APIKey); err != nil {
+			return err
+		}
+	}
+	for i := range cfg.OpenAICompatibility {
+		entries := cfg.OpenAICompatibility[i].APIKeyEntries
+		for j := range entries {
+			if err := resolve(fmt.Sprintf("openai-compatibility[%d].api-key-entries[%d].api-key", i, j), &entries[j].APIKey); err != nil {
+				return err
+			}
+		}
+	}
+	cfg.secretRefs = refs
+	return nil
+}
+
+// SecretReference returns the reference value was resolved from, or value itself when it did
+// not come from a reference.
+func (cfg *Config) SecretReference(value string) string {
+	if cfg != nil {
+		if ref, ok := cfg.secretRefs[value]; ok {
+			return ref
+		}
+	}
+	return value
+}
+
+// WithSecretReferences returns a copy of cfg whose API key fields hold the secret references
+// they were resolved from. Management responses and persisted files use it so resolved
+// secrets never leave the process.
+func (cfg *Config) WithSecretReferences() *Config {
+	if cfg == nil {
+		return nil
+	}
+	clone := *cfg
+	if len(cfg.secretRefs) == 0 {
+		return &clone
+	}
+	clone.GeminiKey = append([]GeminiKey(nil), cfg.GeminiKey...)
+	for i := range clone.GeminiKey {
+		clone.GeminiKey[i].APIKey = cfg.SecretReference(clone.GeminiKey[i].APIKey)
+	}
+	clone.CodexKey = append([]CodexKey(nil), cfg.CodexKey...)
+	for i := range clone.CodexKey {
+		clone.CodexKey[i].APIKey = cfg.SecretReference(clone.CodexKey[i].APIKey)
+	}
+	clone.ClaudeKey = append([]ClaudeKey(nil), cfg.ClaudeKey...)
+	for i := range clone.ClaudeKey {
+		clone.ClaudeKey[i].APIKey = cfg.SecretReference(clone.ClaudeKey[i].APIKey)
+	}
+	clone.VertexCompatAPIKey = append([]VertexCompatKey(nil), cfg.VertexCompatAPIKey...)
+	for i := range clone.VertexCompatAPIKey {
+		clone.VertexCompatAPIKey[i].APIKey = cfg.SecretReference(clone.VertexCompatAPIKey[i].APIKey)
+	}
+	clone.OpenAICompatibility = append([]OpenAICompatibility(nil), cfg.OpenAICompatibility...)
+	for i := range clone.OpenAICompatibility {
+		entries := append([]OpenAICompatibilityAPIKey(nil), clone.OpenAICompatibility[i].APIKeyEntries...)
+		for j := range entries {
+			entries[j].APIKey = cfg.SecretReference(entries[j].APIKey)
+		}
+		clone.OpenAICompatibility[i].APIKeyEntries = entries
+	}
+	return &clone
+}
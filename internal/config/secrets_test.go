@@ -0,0 +1,96 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigResolvesSecretReferences(t *testing.T) {
+	dir := t.TempDir()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	t.Setenv(SecretsKeyEnv, base64.StdEncoding.EncodeToString(key))
+	t.Setenv("TEST_CLAUDE_KEY", "sk-from-env")
+	if err := os.WriteFile(filepath.Join(dir, "codex"), []byte("sk-from-file\n"), 0o600); err != nil {
+		t.Fatalf("write secret file: %v", err)
+	}
+	encrypted, err := EncryptSecret(key, []byte("sk-encrypted"))
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "compat"), []byte(encrypted), 0o600); err != nil {
+		t.Fatalf("write encrypted file: %v", err)
+	}
+
+	configFile := filepath.Join(dir, "config.yaml")
+	content := `port: 8317
+claude-api-key:
+  - api-key: "${env:TEST_CLAUDE_KEY}"
+codex-api-key:
+  - api-key: "file:` + filepath.Join(dir, "codex") + `"
+    base-url: https://codex.example.com
+openai-compatibility:
+  - name: compat
+    base-url: https://compat.example.com
+    api-key-entries:
+      - api-key: "enc:` + filepath.Join(dir, "compat") + `"
+`
+	if err = os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if got := cfg.ClaudeKey[0].APIKey; got != "sk-from-env" {
+		t.Fatalf("claude key = %q", got)
+	}
+	if got := cfg.CodexKey[0].APIKey; got != "sk-from-file" {
+		t.Fatalf("codex key = %q", got)
+	}
+	if got := cfg.OpenAICompatibility[0].APIKeyEntries[0].APIKey; got != "sk-encrypted" {
+		t.Fatalf("openai-compatibility key = %q", got)
+	}
+
+	shown := cfg.WithSecretReferences()
+	if got := shown.ClaudeKey[0].APIKey; got != "${env:TEST_CLAUDE_KEY}" {
+		t.Fatalf("expected the reference to be shown, got %q", got)
+	}
+	if cfg.ClaudeKey[0].APIKey != "sk-from-env" {
+		t.Fatal("WithSecretReferences modified the original config")
+	}
+
+	cfg.Debug = true
+	if err = SaveConfigPreserveComments(configFile, cfg); err != nil {
+		t.Fatalf("save config: %v", err)
+	}
+	saved, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatalf("read saved config: %v", err)
+	}
+	for _, secret := range []string{"sk-from-env", "sk-from-file", "sk-encrypted"} {
+		if strings.Contains(string(saved), secret) {
+			t.Fatalf("saved config contains resolved secret %q:\n%s", secret, saved)
+		}
+	}
+	if !strings.Contains(string(saved), "${env:TEST_CLAUDE_KEY}") {
+		t.Fatalf("saved config lost the reference:\n%s", saved)
+	}
+}
+
+func TestLoadConfigFailsOnUnresolvableReference(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := "claude-api-key:\n  - api-key: \"${env:TEST_MISSING_SECRET}\"\n"
+	if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, err := LoadConfig(configFile); err == nil || !strings.Contains(err.Error(), "TEST_MISSING_SECRET") {
+		t.Fatalf("expected an error naming the missing variable, got %v", err)
+	}
+}
@@ -0,0 +1,381 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Validation issue severities. Errors make a config unfit to deploy; warnings flag settings
+// that load but are likely mistakes.
+const (
+	ValidationError   = "error"
+	ValidationWarning = "warning"
+)
+
+// ValidationIssue is a problem found in a config file.
+type ValidationIssue struct {
+	Severity string `json:"severity"`
+	// Path locates the setting, e.g. "claude-api-key[0].proxy-url", or "line N" for keys
+	// reported by the YAML decoder.
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationReport lists the issues found in a config file.
+type ValidationReport struct {
+	File   string            `json:"file"`
+	Issues []ValidationIssue `json:"issues"`
+}
+
+// Errorf records an error at path.
+func (r *ValidationReport) Errorf(path, format string, args ...any) {
+	r.Issues = append(r.Issues, ValidationIssue{Severity: ValidationError, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Warnf records a warning at path.
+func (r *ValidationReport) Warnf(path, format string, args ...any) {
+	r.Issues = append(r.Issues, ValidationIssue{Severity: ValidationWarning, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Count returns the number of issues with severity.
+func (r *ValidationReport) Count(severity string) int {
+	n := 0
+	for _, issue := range r.Issues {
+		if issue.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+// HasErrors reports whether the report contains an error.
+func (r *ValidationReport) HasErrors() bool {
+	return r.Count(ValidationError) > 0
+}
+
+// legacyConfigKeys are keys no longer part of Config that LoadConfig still migrates.
+var legacyConfigKeys = map[string]string{
+	"generative-language-api-key":          "gemini-api-key",
+	"amp-upstream-url":                     "ampcode.upstream-url",
+	"amp-upstream-api-key":                 "ampcode.upstream-api-key",
+	"amp-restrict-management-to-localhost": "ampcode.restrict-management-to-localhost",
+	"amp-model-mappings":                   "ampcode.model-mappings",
+	"oauth-model-mappings":                 "oauth-model-alias",
+	"api-keys":                             "api-key-entries",
+}
+
+// unknownFieldPattern matches the errors yaml.v3 reports for keys without a struct field.
+var unknownFieldPattern = regexp.MustCompile(`^line (\d+): field (.+) not found in type (\S+)$`)
+
+// ValidateConfigFile checks the config file without modifying it. The file is loaded through
+// LoadConfig from a temporary copy, so migrations and secret hashing do not touch the original.
+// The returned config is nil when the file cannot be loaded.
+func ValidateConfigFile(configFile string) (*Config, *ValidationReport) {
+	report := &ValidationReport{File: configFile}
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		report.Errorf("", "cannot read config file: %v", err)
+		return nil, report
+	}
+
+	tmpFile, err := os.CreateTemp("", "config-validate-*.yaml")
+	if err != nil {
+		report.Errorf("", "cannot create temporary copy: %v", err)
+		return nil, report
+	}
+	tempPath := tmpFile.Name()
+	defer func() {
+		_ = os.Remove(tempPath)
+	}()
+	_, errWrite := tmpFile.Write(data)
+	if errClose := tmpFile.Close(); errWrite == nil {
+		errWrite = errClose
+	}
+	if errWrite != nil {
+		report.Errorf("", "cannot create temporary copy: %v", errWrite)
+		return nil, report
+	}
+	cfg, err := LoadConfig(tempPath)
+	if err != nil {
+		report.Errorf("", "%v", err)
+		return nil, report
+	}
+
+	checkUnknownKeys(report, data)
+	// The remaining checks run on the file as written: LoadConfig silently drops or normalizes
+	// many of the settings they report on.
+	var raw Config
+	if err = yaml.Unmarshal(data, &raw); err == nil {
+		checkProviderKeys(report, &raw)
+		checkOAuthSettings(report, &raw)
+		checkPayloadRules(report, &raw)
+		checkProxyURL(report, "proxy-url", raw.ProxyURL)
+	}
+	return cfg, report
+}
+
+func checkUnknownKeys(report *ValidationReport, data []byte) {
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	decoder.KnownFields(true)
+	var strict Config
+	err := decoder.Decode(&strict)
+	var typeErr *yaml.TypeError
+	if err == nil || !errors.As(err, &typeErr) {
+		return
+	}
+	for _, msg := range typeErr.Errors {
+		match := unknownFieldPattern.FindStringSubmatch(msg)
+		if match == nil {
+			continue
+		}
+		path, key := "line "+match[1], match[2]
+		if replacement, ok := legacyConfigKeys[key]; ok {
+			report.Warnf(path, "deprecated key %q is migrated on load; use %s instead", key, replacement)
+			continue
+		}
+		report.Errorf(path, "unknown key %q", key)
+	}
+}
+
+// providerEntry is the part of an API key entry the validator inspects.
+type providerEntry struct {
+	path           string
+	prefix         string
+	proxyURLs      map[string]string
+	excludedModels []string
+	models         []modelAliasPair
+}
+
+type modelAliasPair struct {
+	name  string
+	alias string
+}
+
+func aliasPairs[T interface {
+	GetName() string
+	GetAlias() string
+}](models []T) []modelAliasPair {
+	out := make([]modelAliasPair, 0, len(models))
+	for _, model := range models {
+		out = append(out, modelAliasPair{name: model.GetName(), alias: model.GetAlias()})
+	}
+	return out
+}
+
+func collectProviderEntries(cfg *Config) []providerEntry {
+	var entries []providerEntry
+	for i, entry := range cfg.GeminiKey {
+		path := fmt.Sprintf("gemini-api-key[%d]", i)
+		entries = append(entries, providerEntry{path: path, prefix: entry.Prefix, proxyURLs: map[string]string{path + ".proxy-url": entry.ProxyURL}, excludedModels: entry.ExcludedModels, models: aliasPairs(entry.Models)})
+	}
+	for i, entry := range cfg.CodexKey {
+		path := fmt.Sprintf("codex-api-key[%d]", i)
+		entries = append(entries, providerEntry{path: path, prefix: entry.Prefix, proxyURLs: map[string]string{path + ".proxy-url": entry.ProxyURL}, excludedModels: entry.ExcludedModels, models: aliasPairs(entry.Models)})
+	}
+	for i, entry := range cfg.ClaudeKey {
+		path := fmt.Sprintf("claude-api-key[%d]", i)
+		entries = append(entries, providerEntry{path: path, prefix: entry.Prefix, proxyURLs: map[string]string{path + ".proxy-url": entry.ProxyURL}, excludedModels: entry.ExcludedModels, models: aliasPairs(entry.Models)})
+	}
+	for i, entry := range cfg.VertexCompatAPIKey {
+		path := fmt.Sprintf("vertex-api-key[%d]", i)
+		entries = append(entries, providerEntry{path: path, prefix: entry.Prefix, proxyURLs: map[string]string{path + ".proxy-url": entry.ProxyURL}, models: aliasPairs(entry.Models)})
+	}
+	for i, entry := range cfg.OpenAICompatibility {
+		path := fmt.Sprintf("openai-compatibility[%d]", i)
+		proxyURLs := make(map[string]string, len(entry.APIKeyEntries))
+		for j, key := range entry.APIKeyEntries {
+			proxyURLs[fmt.Sprintf("%s.api-key-entries[%d].proxy-url", path, j)] = key.ProxyURL
+		}
+		entries = append(entries, providerEntry{path: path, prefix: entry.Prefix, proxyURLs: proxyURLs, models: aliasPairs(entry.Models)})
+	}
+	return entries
+}
+
+func checkProviderKeys(report *ValidationReport, cfg *Config) {
+	entries := collectProviderEntries(cfg)
+	prefixes := make(map[string]string)
+	for _, entry := range entries {
+		paths := make([]string, 0, len(entry.proxyURLs))
+		for path := range entry.proxyURLs {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			checkProxyURL(report, path, entry.proxyURLs[path])
+		}
+		for j, pattern := range entry.excludedModels {
+			checkModelPattern(report, fmt.Sprintf("%s.excluded-models[%d]", entry.path, j), pattern)
+		}
+		checkDuplicateAliases(report, entry.path+".models", entry.models)
+
+		prefix := strings.Trim(strings.TrimSpace(entry.prefix), "/")
+		if prefix == "" {
+			continue
+		}
+		if strings.Contains(prefix, "/") {
+			report.Errorf(entry.path+".prefix", "prefix %q must be a single segment without '/'; it would be ignored", entry.prefix)
+			continue
+		}
+		if _, ok := prefixes[prefix]; !ok {
+			prefixes[prefix] = entry.path
+		}
+	}
+	checkPrefixConflicts(report, cfg, entries, prefixes)
+}
+
+// checkPrefixConflicts reports prefixes that only differ in case (prefix matching is case
+// sensitive), model aliases that start with another credential's prefix, and client key
+// allowed-prefixes that no credential declares.
+func checkPrefixConflicts(report *ValidationReport, cfg *Config, entries []providerEntry, prefixes map[string]string) {
+	names := make([]string, 0, len(prefixes))
+	for prefix := range prefixes {
+		names = append(names, prefix)
+	}
+	sort.Strings(names)
+	byFold := make(map[string]string, len(names))
+	for _, prefix := range names {
+		folded := strings.ToLower(prefix)
+		if other, ok := byFold[folded]; ok {
+			report.Warnf(prefixes[prefix]+".prefix", "prefix %q differs from %q (%s) only in case; prefixes are matched case-sensitively", prefix, other, prefixes[other])
+			continue
+		}
+		byFold[folded] = prefix
+	}
+	for _, entry := range entries {
+		for j, model := range entry.models {
+			alias := strings.TrimSpace(model.alias)
+			head, _, found := strings.Cut(alias, "/")
+			if !found {
+				continue
+			}
+			if owner, ok := prefixes[head]; ok && owner != entry.path {
+				report.Errorf(fmt.Sprintf("%s.models[%d].alias", entry.path, j), "alias %q collides with the %q prefix of %s", alias, head, owner)
+			}
+		}
+	}
+	for i, key := range cfg.ClientKeys {
+		for j, prefix := range key.AllowedPrefixes {
+			prefix = strings.Trim(strings.TrimSpace(prefix), "/")
+			if _, ok := prefixes[prefix]; prefix != "" && !ok {
+				report.Warnf(fmt.Sprintf("client-keys[%d].allowed-prefixes[%d]", i, j), "no credential declares the prefix %q", prefix)
+			}
+		}
+	}
+}
+
+func checkOAuthSettings(report *ValidationReport, cfg *Config) {
+	channels := make([]string, 0, len(cfg.OAuthExcludedModels))
+	for channel := range cfg.OAuthExcludedModels {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	for _, channel := range channels {
+		for j, pattern := range cfg.OAuthExcludedModels[channel] {
+			checkModelPattern(report, fmt.Sprintf("oauth-excluded-models.%s[%d]", channel, j), pattern)
+		}
+	}
+
+	channels = channels[:0]
+	for channel := range cfg.OAuthModelAlias {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	for _, channel := range channels {
+		pairs := make([]modelAliasPair, 0, len(cfg.OAuthModelAlias[channel]))
+		for _, entry := range cfg.OAuthModelAlias[channel] {
+			pairs = append(pairs, modelAliasPair{name: entry.Name, alias: entry.Alias})
+		}
+		checkDuplicateAliases(report, "oauth-model-alias."+channel, pairs)
+	}
+}
+
+func checkPayloadRules(report *ValidationReport, cfg *Config) {
+	sections := []struct {
+		name  string
+		rules []PayloadRule
+	}{
+		{"default", cfg.Payload.Default},
+		{"default-raw", cfg.Payload.DefaultRaw},
+		{"override", cfg.Payload.Override},
+		{"override-raw", cfg.Payload.OverrideRaw},
+	}
+	for _, section := range sections {
+		for i, rule := range section.rules {
+			for j, model := range rule.Models {
+				checkModelPattern(report, fmt.Sprintf("payload.%s[%d].models[%d].name", section.name, i, j), model.Name)
+			}
+		}
+	}
+	for i, rule := range cfg.Payload.Filter {
+		for j, model := range rule.Models {
+			checkModelPattern(report, fmt.Sprintf("payload.filter[%d].models[%d].name", i, j), model.Name)
+		}
+	}
+}
+
+// checkModelPattern reports model patterns the '*'-only wildcard matcher cannot honour.
+func checkModelPattern(report *ValidationReport, path, pattern string) {
+	trimmed := strings.TrimSpace(pattern)
+	if trimmed == "" {
+		report.Errorf(path, "empty model pattern")
+		return
+	}
+	if i := strings.IndexAny(trimmed, "?[]{}()|^$\\+"); i >= 0 {
+		report.Errorf(path, "pattern %q uses %q; only '*' wildcards are supported", trimmed, trimmed[i:i+1])
+		return
+	}
+	if strings.Contains(trimmed, "**") {
+		report.Warnf(path, "pattern %q contains a repeated '*'", trimmed)
+	}
+}
+
+// checkDuplicateAliases reports client-facing model names defined more than once in a list.
+func checkDuplicateAliases(report *ValidationReport, path string, models []modelAliasPair) {
+	seen := make(map[string]int, len(models))
+	for i, model := range models {
+		alias := strings.TrimSpace(model.alias)
+		if alias == "" {
+			continue
+		}
+		key := strings.ToLower(alias)
+		if first, ok := seen[key]; ok {
+			report.Errorf(fmt.Sprintf("%s[%d].alias", path, i), "alias %q is already defined at %s[%d]", alias, path, first)
+			continue
+		}
+		seen[key] = i
+	}
+}
+
+// checkProxyURL reports proxy URLs the HTTP clients would silently ignore.
+func checkProxyURL(report *ValidationReport, path, raw string) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return
+	}
+	// Messages leave out the URL itself, which may carry proxy credentials.
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		report.Errorf(path, "malformed proxy URL: %v", err)
+		return
+	}
+	switch parsed.Scheme {
+	case "http", "https", "socks5":
+	default:
+		report.Errorf(path, "unsupported proxy scheme %q; use http, https or socks5", parsed.Scheme)
+		return
+	}
+	if parsed.Hostname() == "" {
+		report.Errorf(path, "proxy URL has no host")
+	}
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateConfigFile(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := `port: 8317
+debugg: true
+proxy-url: "ftp://proxy:1080"
+claude-api-key:
+  - api-key: sk-1
+    prefix: team/a
+    excluded-models: ["claude-[34]*"]
+    models:
+      - name: claude-sonnet-4
+        alias: sonnet
+      - name: claude-opus-4
+        alias: Sonnet
+codex-api-key:
+  - api-key: c
+    base-url: https://c.example.com
+    prefix: teamB
+openai-compatibility:
+  - name: x
+    base-url: https://x.example.com
+    api-key-entries:
+      - api-key: k
+        proxy-url: "socks5://"
+    models:
+      - name: m
+        alias: teamB/m
+payload:
+  filter:
+    - models:
+        - name: "gpt-?"
+      params: ["a"]
+`
+	if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, report := ValidateConfigFile(configFile)
+	if cfg == nil {
+		t.Fatalf("expected the config to load, got %+v", report.Issues)
+	}
+	want := map[string]string{
+		"line 2":                                               "unknown key",
+		"proxy-url":                                            "unsupported proxy scheme",
+		"claude-api-key[0].prefix":                             "single segment",
+		"claude-api-key[0].excluded-models[0]":                 "only '*' wildcards",
+		"claude-api-key[0].models[1].alias":                    "already defined",
+		"openai-compatibility[0].models[0].alias":              "collides with the \"teamB\" prefix",
+		"openai-compatibility[0].api-key-entries[0].proxy-url": "no host",
+		"payload.filter[0].models[0].name":                     "only '*' wildcards",
+	}
+	for _, issue := range report.Issues {
+		if fragment, ok := want[issue.Path]; ok && issue.Severity == ValidationError && strings.Contains(issue.Message, fragment) {
+			delete(want, issue.Path)
+		}
+	}
+	if len(want) > 0 {
+		t.Fatalf("missing issues %v in report %+v", want, report.Issues)
+	}
+
+	after, err := os.ReadFile(configFile)
+	if err != nil || string(after) != content {
+		t.Fatalf("validation modified the config file: %v", err)
+	}
+}
+
+func TestValidateConfigFileClean(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configFile, []byte("port: 8317\nproxy-url: socks5://127.0.0.1:1080\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, report := ValidateConfigFile(configFile); report.HasErrors() {
+		t.Fatalf("expected no errors, got %+v", report.Issues)
+	}
+}
+
+func TestValidateConfigFileRejectsInvalidKeyExpiry(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := "client-keys:\n  - key: a\n    expires-at: \"next tuesday\"\n"
+	if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, report := ValidateConfigFile(configFile)
+	if cfg != nil || !report.HasErrors() || !strings.Contains(report.Issues[0].Message, "client-keys[0]: invalid expires-at") {
+		t.Fatalf("expected the invalid expiry to be rejected, got %+v", report.Issues)
+	}
+}
@@ -17,6 +17,22 @@ type VertexCompatKey struct {
 	// Higher values are preferred; defaults to 0.
 	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
 
+	// MaxConcurrency caps in-flight requests sent with this credential; 0 means unlimited.
+	MaxConcurrency int `yaml:"max-concurrency,omitempty" json:"max-concurrency,omitempty"`
+
+	// RPM caps requests per minute sent with this credential; 0 means unlimited.
+	RPM int `yaml:"rpm,omitempty" json:"rpm,omitempty"`
+
+	// TPM caps tokens per minute consumed by this credential; 0 means unlimited.
+	TPM int `yaml:"tpm,omitempty" json:"tpm,omitempty"`
+
+	// Availability optionally restricts the times this credential serves proxy traffic.
+	Availability *AvailabilitySchedule `yaml:"availability,omitempty" json:"availability,omitempty"`
+
+	// Tags label this credential for tag-based routing (see client-keys credential-tags and the
+	// X-CLIProxy-Tags header). Unlike Prefix they do not change model names.
+	Tags []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+
 	// Prefix optionally namespaces model aliases for this credential (e.g., "teamA/vertex-pro").
 	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
 
@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"context"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func init() {
+	coreusage.RegisterPlugin(NewPlugin())
+}
+
+// Hook records conductor results into the default collector.
+// It implements coreauth.Hook.
+type Hook struct {
+	coreauth.NoopHook
+	collector *Collector
+}
+
+// NewHook constructs a conductor hook wired to the default collector.
+func NewHook() *Hook { return &Hook{collector: defaultCollector} }
+
+var defaultHook = NewHook()
+
+// DefaultHook returns the shared hook wired to the default collector, so adding it to a
+// manager more than once does not count results twice.
+func DefaultHook() *Hook { return defaultHook }
+
+// OnResult implements coreauth.Hook.
+func (h *Hook) OnResult(_ context.Context, result coreauth.Result) {
+	if h == nil || !Enabled() {
+		return
+	}
+	h.collector.ObserveResult(result)
+}
+
+// Plugin records token usage into the default collector.
+// It implements coreusage.Plugin.
+type Plugin struct {
+	collector *Collector
+}
+
+// NewPlugin constructs a usage plugin wired to the default collector.
+func NewPlugin() *Plugin { return &Plugin{collector: defaultCollector} }
+
+// HandleUsage implements coreusage.Plugin.
+func (p *Plugin) HandleUsage(_ context.Context, record coreusage.Record) {
+	if p == nil || !Enabled() {
+		return
+	}
+	d := record.Detail
+	p.collector.AddTokens(record.Provider, record.Model, d.InputTokens, d.OutputTokens, d.ReasoningTokens, d.CachedTokens)
+}
@@ -0,0 +1,322 @@
+// Package metrics exposes proxy telemetry in the Prometheus text exposition format.
+// Request outcomes are collected through a conductor hook, token usage through a
+// usage plugin, and credential state is sampled from the auth manager at scrape time.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+var enabled atomic.Bool
+
+// SetEnabled toggles metric collection and the /metrics endpoint.
+func SetEnabled(value bool) { enabled.Store(value) }
+
+// Enabled reports whether metric collection is active.
+func Enabled() bool { return enabled.Load() }
+
+// latencyBuckets are the histogram upper bounds in seconds shared by TTFT and total latency.
+var latencyBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+type requestKey struct {
+	provider string
+	model    string
+	status   string
+}
+
+type modelKey struct {
+	provider string
+	model    string
+}
+
+type tokenKey struct {
+	provider string
+	model    string
+	kind     string
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	total  uint64
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make([]uint64, len(latencyBuckets))}
+}
+
+func (h *histogram) observe(value float64) {
+	for i, bound := range latencyBuckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.total++
+}
+
+// Collector accumulates counters and histograms between scrapes.
+type Collector struct {
+	mu       sync.Mutex
+	requests map[requestKey]uint64
+	retries  map[modelKey]uint64
+	latency  map[modelKey]*histogram
+	ttft     map[modelKey]*histogram
+	tokens   map[tokenKey]uint64
+}
+
+var defaultCollector = NewCollector()
+
+// NewCollector constructs an empty collector.
+func NewCollector() *Collector {
+	return &Collector{
+		requests: make(map[requestKey]uint64),
+		retries:  make(map[modelKey]uint64),
+		latency:  make(map[modelKey]*histogram),
+		ttft:     make(map[modelKey]*histogram),
+		tokens:   make(map[tokenKey]uint64),
+	}
+}
+
+// Default returns the process-wide collector fed by the hook and usage plugin.
+func Default() *Collector { return defaultCollector }
+
+// ObserveResult records one upstream attempt reported by the conductor.
+func (c *Collector) ObserveResult(result coreauth.Result) {
+	if c == nil {
+		return
+	}
+	mk := modelKey{provider: result.Provider, model: result.Model}
+	status := "200"
+	if !result.Success {
+		status = "error"
+		if result.Error != nil && result.Error.HTTPStatus > 0 {
+			status = strconv.Itoa(result.Error.HTTPStatus)
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests[requestKey{provider: mk.provider, model: mk.model, status: status}]++
+	if result.Retry {
+		c.retries[mk]++
+	}
+	if result.Latency > 0 {
+		h := c.latency[mk]
+		if h == nil {
+			h = newHistogram()
+			c.latency[mk] = h
+		}
+		h.observe(result.Latency.Seconds())
+	}
+	if result.Success && result.FirstByteLatency > 0 {
+		h := c.ttft[mk]
+		if h == nil {
+			h = newHistogram()
+			c.ttft[mk] = h
+		}
+		h.observe(result.FirstByteLatency.Seconds())
+	}
+}
+
+// AddTokens records token consumption for provider and model.
+func (c *Collector) AddTokens(provider, model string, input, output, reasoning, cached int64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for kind, value := range map[string]int64{"input": input, "output": output, "reasoning": reasoning, "cached": cached} {
+		if value > 0 {
+			c.tokens[tokenKey{provider: provider, model: model, kind: kind}] += uint64(value)
+		}
+	}
+}
+
+// WritePrometheus renders all collected metrics plus credential gauges derived from auths.
+func (c *Collector) WritePrometheus(w io.Writer, auths []*coreauth.Auth, now time.Time) error {
+	ew := &errWriter{w: w}
+	c.mu.Lock()
+	c.writeRequests(ew)
+	c.writeHistogram(ew, "cliproxy_request_duration_seconds", "Total upstream request latency in seconds.", c.latency)
+	c.writeHistogram(ew, "cliproxy_time_to_first_byte_seconds", "Time until the first upstream response chunk in seconds.", c.ttft)
+	c.writeTokens(ew)
+	c.mu.Unlock()
+	writeAuthGauges(ew, auths, now)
+	return ew.err
+}
+
+func (c *Collector) writeRequests(w *errWriter) {
+	writeHeader(w, "cliproxy_requests_total", "Upstream requests by provider, model and status.", "counter")
+	keys := make([]requestKey, 0, len(c.requests))
+	for k := range c.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].provider != keys[j].provider {
+			return keys[i].provider < keys[j].provider
+		}
+		if keys[i].model != keys[j].model {
+			return keys[i].model < keys[j].model
+		}
+		return keys[i].status < keys[j].status
+	})
+	for _, k := range keys {
+		w.printf("cliproxy_requests_total%s %d\n", labels("provider", k.provider, "model", k.model, "status", k.status), c.requests[k])
+	}
+
+	writeHeader(w, "cliproxy_retries_total", "Upstream attempts that retried an earlier failure.", "counter")
+	for _, k := range sortedModelKeys(c.retries) {
+		w.printf("cliproxy_retries_total%s %d\n", labels("provider", k.provider, "model", k.model), c.retries[k])
+	}
+}
+
+func (c *Collector) writeHistogram(w *errWriter, name, help string, series map[modelKey]*histogram) {
+	writeHeader(w, name, help, "histogram")
+	for _, k := range sortedModelKeys(series) {
+		h := series[k]
+		for i, bound := range latencyBuckets {
+			w.printf("%s_bucket%s %d\n", name, labels("provider", k.provider, "model", k.model, "le", formatFloat(bound)), h.counts[i])
+		}
+		w.printf("%s_bucket%s %d\n", name, labels("provider", k.provider, "model", k.model, "le", "+Inf"), h.total)
+		w.printf("%s_sum%s %s\n", name, labels("provider", k.provider, "model", k.model), formatFloat(h.sum))
+		w.printf("%s_count%s %d\n", name, labels("provider", k.provider, "model", k.model), h.total)
+	}
+}
+
+func (c *Collector) writeTokens(w *errWriter) {
+	writeHeader(w, "cliproxy_tokens_total", "Tokens consumed by provider, model and type.", "counter")
+	keys := make([]tokenKey, 0, len(c.tokens))
+	for k := range c.tokens {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].provider != keys[j].provider {
+			return keys[i].provider < keys[j].provider
+		}
+		if keys[i].model != keys[j].model {
+			return keys[i].model < keys[j].model
+		}
+		return keys[i].kind < keys[j].kind
+	})
+	for _, k := range keys {
+		w.printf("cliproxy_tokens_total%s %d\n", labels("provider", k.provider, "model", k.model, "type", k.kind), c.tokens[k])
+	}
+}
+
+func writeAuthGauges(w *errWriter, auths []*coreauth.Auth, now time.Time) {
+	sort.Slice(auths, func(i, j int) bool { return auths[i].ID < auths[j].ID })
+
+	writeHeader(w, "cliproxy_auth_status", "Current status of each credential (1 for the active status label).", "gauge")
+	for _, a := range auths {
+		w.printf("cliproxy_auth_status%s 1\n", labels("auth", a.ID, "provider", a.Provider, "status", string(a.Status)))
+	}
+	writeHeader(w, "cliproxy_auth_unavailable", "Whether the credential is temporarily unavailable.", "gauge")
+	for _, a := range auths {
+		w.printf("cliproxy_auth_unavailable%s %d\n", labels("auth", a.ID, "provider", a.Provider), boolValue(a.Unavailable))
+		for _, model := range sortedModels(a) {
+			w.printf("cliproxy_auth_unavailable%s %d\n", labels("auth", a.ID, "provider", a.Provider, "model", model), boolValue(a.ModelStates[model].Unavailable))
+		}
+	}
+	writeHeader(w, "cliproxy_auth_quota_exceeded", "Whether the credential has exceeded its quota.", "gauge")
+	for _, a := range auths {
+		w.printf("cliproxy_auth_quota_exceeded%s %d\n", labels("auth", a.ID, "provider", a.Provider), boolValue(a.Quota.Exceeded))
+		for _, model := range sortedModels(a) {
+			w.printf("cliproxy_auth_quota_exceeded%s %d\n", labels("auth", a.ID, "provider", a.Provider, "model", model), boolValue(a.ModelStates[model].Quota.Exceeded))
+		}
+	}
+	writeHeader(w, "cliproxy_auth_next_retry_seconds", "Seconds until the credential leaves cooldown; 0 when available.", "gauge")
+	for _, a := range auths {
+		w.printf("cliproxy_auth_next_retry_seconds%s %s\n", labels("auth", a.ID, "provider", a.Provider), formatFloat(secondsUntil(a.NextRetryAfter, now)))
+		for _, model := range sortedModels(a) {
+			w.printf("cliproxy_auth_next_retry_seconds%s %s\n", labels("auth", a.ID, "provider", a.Provider, "model", model), formatFloat(secondsUntil(a.ModelStates[model].NextRetryAfter, now)))
+		}
+	}
+}
+
+func sortedModels(a *coreauth.Auth) []string {
+	models := make([]string, 0, len(a.ModelStates))
+	for model, state := range a.ModelStates {
+		if state != nil {
+			models = append(models, model)
+		}
+	}
+	sort.Strings(models)
+	return models
+}
+
+func sortedModelKeys[V any](m map[modelKey]V) []modelKey {
+	keys := make([]modelKey, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].provider != keys[j].provider {
+			return keys[i].provider < keys[j].provider
+		}
+		return keys[i].model < keys[j].model
+	})
+	return keys
+}
+
+func secondsUntil(t time.Time, now time.Time) float64 {
+	if t.IsZero() || !t.After(now) {
+		return 0
+	}
+	return math.Ceil(t.Sub(now).Seconds())
+}
+
+func boolValue(v bool) int {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+func writeHeader(w *errWriter, name, help, kind string) {
+	w.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// labels renders alternating name/value pairs as a Prometheus label set.
+func labels(pairs ...string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pairs[i])
+		b.WriteString(`="`)
+		b.WriteString(escapeLabel(pairs[i+1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string { return labelEscaper.Replace(value) }
+
+func formatFloat(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) printf(format string, args ...any) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = fmt.Fprintf(e.w, format, args...)
+}
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+func TestCollectorWritePrometheus(t *testing.T) {
+	c := NewCollector()
+	c.ObserveResult(coreauth.Result{Provider: "gemini", Model: "gemini-2.5-pro", Success: true, Latency: 2 * time.Second, FirstByteLatency: 300 * time.Millisecond})
+	c.ObserveResult(coreauth.Result{Provider: "gemini", Model: "gemini-2.5-pro", Error: &coreauth.Error{HTTPStatus: 429}, Latency: time.Second, Retry: true})
+	c.AddTokens("gemini", "gemini-2.5-pro", 10, 20, 0, 5)
+
+	now := time.Now()
+	auths := []*coreauth.Auth{{
+		ID:             "a\"1",
+		Provider:       "gemini",
+		Status:         coreauth.StatusError,
+		Unavailable:    true,
+		NextRetryAfter: now.Add(30 * time.Second),
+		Quota:          coreauth.QuotaState{Exceeded: true},
+	}}
+
+	var buf bytes.Buffer
+	if err := c.WritePrometheus(&buf, auths, now); err != nil {
+		t.Fatalf("WritePrometheus() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`cliproxy_requests_total{provider="gemini",model="gemini-2.5-pro",status="200"} 1`,
+		`cliproxy_requests_total{provider="gemini",model="gemini-2.5-pro",status="429"} 1`,
+		`cliproxy_retries_total{provider="gemini",model="gemini-2.5-pro"} 1`,
+		`cliproxy_request_duration_seconds_bucket{provider="gemini",model="gemini-2.5-pro",le="1"} 1`,
+		`cliproxy_request_duration_seconds_count{provider="gemini",model="gemini-2.5-pro"} 2`,
+		`cliproxy_time_to_first_byte_seconds_bucket{provider="gemini",model="gemini-2.5-pro",le="0.5"} 1`,
+		`cliproxy_tokens_total{provider="gemini",model="gemini-2.5-pro",type="cached"} 5`,
+		`cliproxy_tokens_total{provider="gemini",model="gemini-2.5-pro",type="output"} 20`,
+		`cliproxy_auth_status{auth="a\"1",provider="gemini",status="error"} 1`,
+		`cliproxy_auth_unavailable{auth="a\"1",provider="gemini"} 1`,
+		`cliproxy_auth_quota_exceeded{auth="a\"1",provider="gemini"} 1`,
+		`cliproxy_auth_next_retry_seconds{auth="a\"1",provider="gemini"} 30`,
+		"# TYPE cliproxy_request_duration_seconds histogram",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, `type="reasoning"`) {
+		t.Errorf("zero token counts should be omitted\n%s", out)
+	}
+}
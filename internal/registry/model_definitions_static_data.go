@@ -203,6 +203,33 @@ func GetGeminiModels() []*ModelInfo {
 			SupportedGenerationMethods: []string{"generateContent", "countTokens", "createCachedContent", "batchGenerateContent"},
 			Thinking:                   &ThinkingSupport{Min: 128, Max: 32768, ZeroAllowed: false, DynamicAllowed: true, Levels: []string{"low", "high"}},
 		},
+		// Text embedding models - served through /v1/embeddings and embedContent
+		{
+			ID:                         "gemini-embedding-001",
+			Object:                     "model",
+			Created:                    1752019200,
+			OwnedBy:                    "google",
+			Type:                       "gemini",
+			Name:                       "models/gemini-embedding-001",
+			Version:                    "001",
+			DisplayName:                "Gemini Embedding 001",
+			Description:                "Gemini text embedding model",
+			InputTokenLimit:            2048,
+			SupportedGenerationMethods: []string{"embedContent", "batchEmbedContents"},
+		},
+		{
+			ID:                         "text-embedding-004",
+			Object:                     "model",
+			Created:                    1715644800,
+			OwnedBy:                    "google",
+			Type:                       "gemini",
+			Name:                       "models/text-embedding-004",
+			Version:                    "004",
+			DisplayName:                "Text Embedding 004",
+			Description:                "Obtain a distributed representation of a text.",
+			InputTokenLimit:            2048,
+			SupportedGenerationMethods: []string{"embedContent"},
+		},
 	}
 }
 
@@ -359,6 +386,33 @@ func GetGeminiVertexModels() []*ModelInfo {
 			Description:                "Imagen 4.0 fast image generation model",
 			SupportedGenerationMethods: []string{"predict"},
 		},
+		// Text embedding models - use :predict action
+		{
+			ID:                         "gemini-embedding-001",
+			Object:                     "model",
+			Created:                    1752019200,
+			OwnedBy:                    "google",
+			Type:                       "gemini",
+			Name:                       "models/gemini-embedding-001",
+			Version:                    "001",
+			DisplayName:                "Gemini Embedding 001",
+			Description:                "Gemini text embedding model",
+			InputTokenLimit:            2048,
+			SupportedGenerationMethods: []string{"predict"},
+		},
+		{
+			ID:                         "text-embedding-005",
+			Object:                     "model",
+			Created:                    1715644800,
+			OwnedBy:                    "google",
+			Type:                       "gemini",
+			Name:                       "models/text-embedding-005",
+			Version:                    "005",
+			DisplayName:                "Text Embedding 005",
+			Description:                "Text embedding model specialised for English and code",
+			InputTokenLimit:            2048,
+			SupportedGenerationMethods: []string{"predict"},
+		},
 	}
 }
 
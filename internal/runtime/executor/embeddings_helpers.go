@@ -0,0 +1,50 @@
+package executor
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	geminiembeddings "github.com/router-for-me/CLIProxyAPI/v6/internal/translator/gemini/openai/embeddings"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// embeddingsAlt is the execution alt used by handlers to request an embeddings call
+// instead of a generation call.
+const embeddingsAlt = "embeddings"
+
+// buildGeminiEmbeddingsRequest converts an embeddings payload into a Gemini request body
+// and returns the Gemini method that accepts it. OpenAI requests always map to
+// batchEmbedContents; Gemini-native requests keep the method implied by their shape.
+func buildGeminiEmbeddingsRequest(from sdktranslator.Format, baseModel string, payload []byte) ([]byte, string, error) {
+	if from == sdktranslator.FormatOpenAI {
+		if geminiembeddings.HasTokenInput(payload) {
+			return nil, "", statusErr{code: http.StatusBadRequest, msg: "token array input is not supported for Gemini embeddings"}
+		}
+		return geminiembeddings.ConvertOpenAIEmbeddingsRequestToGemini(baseModel, payload), "batchEmbedContents", nil
+	}
+
+	requests := gjson.GetBytes(payload, "requests")
+	if !requests.IsArray() {
+		body, _ := sjson.DeleteBytes(payload, "model")
+		return body, "embedContent", nil
+	}
+	// Gemini requires every batch entry to name the model addressed by the URL.
+	body := payload
+	model := "models/" + strings.TrimPrefix(baseModel, "models/")
+	for i := range requests.Array() {
+		body, _ = sjson.SetBytes(body, fmt.Sprintf("requests.%d.model", i), model)
+	}
+	return body, "batchEmbedContents", nil
+}
+
+// translateGeminiEmbeddingsResponse converts a Gemini embeddings response back into the
+// source format of the request.
+func translateGeminiEmbeddingsResponse(from sdktranslator.Format, model string, originalRequest, data []byte) []byte {
+	if from == sdktranslator.FormatOpenAI {
+		return geminiembeddings.ConvertGeminiEmbeddingsResponseToOpenAI(model, originalRequest, data)
+	}
+	return data
+}
@@ -22,6 +22,10 @@ type usageReporter struct {
 	authIndex   string
 	apiKey      string
 	source      string
+	affinity    string
+	hedge       string
+	variant     string
+	shadow      bool
 	requestedAt time.Time
 	once        sync.Once
 }
@@ -34,6 +38,10 @@ func newUsageReporter(ctx context.Context, provider, model string, auth *cliprox
 		requestedAt: time.Now(),
 		apiKey:      apiKey,
 		source:      resolveUsageSource(auth, apiKey),
+		affinity:    usage.SessionAffinityFromContext(ctx),
+		hedge:       usage.HedgeRoleFromContext(ctx),
+		variant:     usage.TrafficVariantFromContext(ctx),
+		shadow:      usage.IsShadow(ctx),
 	}
 	if auth != nil {
 		reporter.authID = auth.ID
@@ -69,20 +77,24 @@ func (r *usageReporter) publishWithOutcome(ctx context.Context, detail usage.Det
 			detail.TotalTokens = total
 		}
 	}
-	if detail.InputTokens == 0 && detail.OutputTokens == 0 && detail.ReasoningTokens == 0 && detail.CachedTokens == 0 && detail.TotalTokens == 0 && !failed {
+	if detail.InputTokens == 0 && detail.OutputTokens == 0 && detail.ReasoningTokens == 0 && detail.CachedTokens == 0 && detail.CacheWriteTokens == 0 && detail.TotalTokens == 0 && !failed {
 		return
 	}
 	r.once.Do(func() {
 		usage.PublishRecord(ctx, usage.Record{
-			Provider:    r.provider,
-			Model:       r.model,
-			Source:      r.source,
-			APIKey:      r.apiKey,
-			AuthID:      r.authID,
-			AuthIndex:   r.authIndex,
-			RequestedAt: r.requestedAt,
-			Failed:      failed,
-			Detail:      detail,
+			Provider:        r.provider,
+			Model:           r.model,
+			Source:          r.source,
+			APIKey:          r.apiKey,
+			AuthID:          r.authID,
+			AuthIndex:       r.authIndex,
+			RequestedAt:     r.requestedAt,
+			SessionAffinity: r.affinity,
+			Hedge:           r.hedge,
+			Variant:         r.variant,
+			Shadow:          r.shadow,
+			Failed:          failed,
+			Detail:          detail,
 		})
 	})
 }
@@ -97,15 +109,19 @@ func (r *usageReporter) ensurePublished(ctx context.Context) {
 	}
 	r.once.Do(func() {
 		usage.PublishRecord(ctx, usage.Record{
-			Provider:    r.provider,
-			Model:       r.model,
-			Source:      r.source,
-			APIKey:      r.apiKey,
-			AuthID:      r.authID,
-			AuthIndex:   r.authIndex,
-			RequestedAt: r.requestedAt,
-			Failed:      false,
-			Detail:      usage.Detail{},
+			Provider:        r.provider,
+			Model:           r.model,
+			Source:          r.source,
+			APIKey:          r.apiKey,
+			AuthID:          r.authID,
+			AuthIndex:       r.authIndex,
+			RequestedAt:     r.requestedAt,
+			SessionAffinity: r.affinity,
+			Hedge:           r.hedge,
+			Variant:         r.variant,
+			Shadow:          r.shadow,
+			Failed:          false,
+			Detail:          usage.Detail{},
 		})
 	})
 }
@@ -116,7 +132,8 @@ func apiKeyFromContext(ctx context.Context) string {
 	}
 	ginCtx, ok := ctx.Value("gin").(*gin.Context)
 	if !ok || ginCtx == nil {
-		return ""
+		// Detached requests such as shadow traffic carry the client key without a Gin context.
+		return cliproxyauth.ClientKeyFromContext(ctx)
 	}
 	if v, exists := ginCtx.Get("apiKey"); exists {
 		switch value := v.(type) {
@@ -258,13 +275,10 @@ func parseClaudeUsage(data []byte) usage.Detail {
 		return usage.Detail{}
 	}
 	detail := usage.Detail{
-		InputTokens:  usageNode.Get("input_tokens").Int(),
-		OutputTokens: usageNode.Get("output_tokens").Int(),
-		CachedTokens: usageNode.Get("cache_read_input_tokens").Int(),
-	}
-	if detail.CachedTokens == 0 {
-		// fall back to creation tokens when read tokens are absent
-		detail.CachedTokens = usageNode.Get("cache_creation_input_tokens").Int()
+		InputTokens:      usageNode.Get("input_tokens").Int(),
+		OutputTokens:     usageNode.Get("output_tokens").Int(),
+		CachedTokens:     usageNode.Get("cache_read_input_tokens").Int(),
+		CacheWriteTokens: usageNode.Get("cache_creation_input_tokens").Int(),
 	}
 	detail.TotalTokens = detail.InputTokens + detail.OutputTokens
 	return detail
@@ -280,12 +294,10 @@ func parseClaudeStreamUsage(line []byte) (usage.Detail, bool) {
 		return usage.Detail{}, false
 	}
 	detail := usage.Detail{
-		InputTokens:  usageNode.Get("input_tokens").Int(),
-		OutputTokens: usageNode.Get("output_tokens").Int(),
-		CachedTokens: usageNode.Get("cache_read_input_tokens").Int(),
-	}
-	if detail.CachedTokens == 0 {
-		detail.CachedTokens = usageNode.Get("cache_creation_input_tokens").Int()
+		InputTokens:      usageNode.Get("input_tokens").Int(),
+		OutputTokens:     usageNode.Get("output_tokens").Int(),
+		CachedTokens:     usageNode.Get("cache_read_input_tokens").Int(),
+		CacheWriteTokens: usageNode.Get("cache_creation_input_tokens").Int(),
 	}
 	detail.TotalTokens = detail.InputTokens + detail.OutputTokens
 	return detail, true
@@ -165,6 +165,14 @@ func (s *PostgresStore) ConfigPath() string {
 	return s.configPath
 }
 
+// DB exposes the underlying connection so other subsystems can share it.
+func (s *PostgresStore) DB() *sql.DB {
+	if s == nil {
+		return nil
+	}
+	return s.db
+}
+
 // AuthDir returns the local directory containing mirrored auth files.
 func (s *PostgresStore) AuthDir() string {
 	if s == nil {
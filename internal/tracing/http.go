@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TraceparentHeader is the W3C trace context propagation header.
+const TraceparentHeader = "traceparent"
+
+// Middleware starts a server span for every inbound request, continuing any trace
+// announced through the traceparent header.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if currentProvider() == nil {
+			c.Next()
+			return
+		}
+		ctx := c.Request.Context()
+		if sc, ok := ParseTraceparent(c.GetHeader(TraceparentHeader)); ok {
+			ctx = ContextWithRemoteParent(ctx, sc)
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		ctx, span := StartKind(ctx, c.Request.Method+" "+route, SpanKindServer)
+		// The context also carries the decision not to sample, so handlers must see it either way.
+		c.Request = c.Request.WithContext(ctx)
+		if span == nil {
+			c.Next()
+			return
+		}
+		span.SetAttribute("http.method", c.Request.Method)
+		span.SetAttribute("http.route", route)
+		span.SetAttribute("http.target", c.Request.URL.Path)
+		c.Next()
+		status := c.Writer.Status()
+		span.SetAttribute("http.status_code", status)
+		if status >= http.StatusInternalServerError {
+			span.RecordError(errorStatus(status))
+		}
+		span.End()
+	}
+}
+
+type errorStatus int
+
+func (e errorStatus) Error() string { return http.StatusText(int(e)) }
+
+// Transport wraps base so every upstream round trip is recorded as a client span.
+// The traceparent header is not forwarded to upstream providers.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if _, ok := base.(*tracingTransport); ok {
+		return base
+	}
+	return &tracingTransport{base: base}
+}
+
+type tracingTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	_, span := StartKind(req.Context(), "HTTP "+req.Method+" "+req.URL.Host, SpanKindClient)
+	if span == nil {
+		return t.base.RoundTrip(req)
+	}
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.url", req.URL.Scheme+"://"+req.URL.Host+req.URL.Path)
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+	} else {
+		span.SetAttribute("http.status_code", resp.StatusCode)
+		if resp.StatusCode >= http.StatusBadRequest {
+			span.RecordError(errorStatus(resp.StatusCode))
+		}
+	}
+	span.End()
+	return resp, err
+}
@@ -0,0 +1,308 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultServiceName  = "cli-proxy-api"
+	defaultOTLPEndpoint = "http://localhost:4318/v1/traces"
+	queueSize           = 4096
+	batchSize           = 512
+	flushInterval       = 2 * time.Second
+)
+
+// exporter writes a batch of spans encoded as an OTLP/JSON ExportTraceServiceRequest.
+type exporter interface {
+	export(payload []byte) error
+	close() error
+}
+
+type provider struct {
+	serviceName string
+	sampleRatio float64
+	exporter    exporter
+	queue       chan *Span
+	done        chan struct{}
+	stopped     chan struct{}
+	stopOnce    sync.Once
+}
+
+var active atomic.Pointer[provider]
+
+func currentProvider() *provider { return active.Load() }
+
+// Configure applies tracing settings, replacing any previously running exporter.
+// defaultFile is used by the file exporter when no file path is configured.
+func Configure(cfg config.TracingConfig, defaultFile string) error {
+	if !cfg.Enable {
+		if previous := active.Swap(nil); previous != nil {
+			previous.stop()
+		}
+		return nil
+	}
+	exp, err := newExporter(cfg, defaultFile)
+	if err != nil {
+		return err
+	}
+	ratio := cfg.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+	serviceName := strings.TrimSpace(cfg.ServiceName)
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+	p := &provider{
+		serviceName: serviceName,
+		sampleRatio: ratio,
+		exporter:    exp,
+		queue:       make(chan *Span, queueSize),
+		done:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+	go p.run()
+	if previous := active.Swap(p); previous != nil {
+		previous.stop()
+	}
+	return nil
+}
+
+// Shutdown flushes pending spans and disables tracing.
+func Shutdown() {
+	if previous := active.Swap(nil); previous != nil {
+		previous.stop()
+	}
+}
+
+func newExporter(cfg config.TracingConfig, defaultFile string) (exporter, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Exporter)) {
+	case "", "file":
+		path := strings.TrimSpace(cfg.File)
+		if path == "" {
+			path = defaultFile
+		}
+		if path == "" {
+			path = "traces.jsonl"
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, fmt.Errorf("tracing: create trace directory: %w", err)
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("tracing: open trace file: %w", err)
+		}
+		return &writerExporter{w: f, closer: f}, nil
+	case "stdout":
+		return &writerExporter{w: os.Stdout}, nil
+	case "otlp", "otlp-http", "otlphttp":
+		endpoint := strings.TrimSpace(cfg.Endpoint)
+		if endpoint == "" {
+			endpoint = defaultOTLPEndpoint
+		}
+		return &otlpHTTPExporter{endpoint: endpoint, headers: cfg.Headers, client: &http.Client{Timeout: 10 * time.Second}}, nil
+	default:
+		return nil, fmt.Errorf("tracing: unsupported exporter %q", cfg.Exporter)
+	}
+}
+
+func (p *provider) sample() bool {
+	return p.sampleRatio >= 1 || rand.Float64() < p.sampleRatio
+}
+
+func (p *provider) enqueue(span *Span) {
+	if p == nil {
+		return
+	}
+	select {
+	case <-p.done:
+	case p.queue <- span:
+	default:
+		// Drop spans rather than block request handling when the exporter falls behind.
+	}
+}
+
+// stop signals the run loop to flush pending spans and waits until it exits.
+func (p *provider) stop() {
+	p.stopOnce.Do(func() {
+		close(p.done)
+	})
+	<-p.stopped
+}
+
+func (p *provider) run() {
+	defer close(p.stopped)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	batch := make([]*Span, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := p.exporter.export(p.encode(batch)); err != nil {
+			log.Debugf("tracing: export failed: %v", err)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case span := <-p.queue:
+			batch = append(batch, span)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-p.done:
+			for {
+				select {
+				case span := <-p.queue:
+					batch = append(batch, span)
+				default:
+					flush()
+					if err := p.exporter.close(); err != nil {
+						log.Debugf("tracing: close exporter failed: %v", err)
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+type otlpAttribute struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+func otlpValue(v any) map[string]any {
+	switch val := v.(type) {
+	case bool:
+		return map[string]any{"boolValue": val}
+	case int:
+		return map[string]any{"intValue": strconv.Itoa(val)}
+	case int64:
+		return map[string]any{"intValue": strconv.FormatInt(val, 10)}
+	case float64:
+		return map[string]any{"doubleValue": val}
+	case time.Duration:
+		return map[string]any{"intValue": strconv.FormatInt(val.Milliseconds(), 10)}
+	case string:
+		return map[string]any{"stringValue": val}
+	default:
+		return map[string]any{"stringValue": fmt.Sprint(val)}
+	}
+}
+
+func (p *provider) encode(spans []*Span) []byte {
+	encoded := make([]map[string]any, 0, len(spans))
+	for _, s := range spans {
+		s.mu.Lock()
+		attrs := make([]otlpAttribute, 0, len(s.attributes))
+		for k, v := range s.attributes {
+			attrs = append(attrs, otlpAttribute{Key: k, Value: otlpValue(v)})
+		}
+		item := map[string]any{
+			"traceId":           hex.EncodeToString(s.sc.TraceID[:]),
+			"spanId":            hex.EncodeToString(s.sc.SpanID[:]),
+			"name":              s.name,
+			"kind":              int(s.kind),
+			"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.end.UnixNano(), 10),
+			"attributes":        attrs,
+		}
+		if s.parent != [8]byte{} {
+			item["parentSpanId"] = hex.EncodeToString(s.parent[:])
+		}
+		if s.errMessage != "" {
+			item["status"] = map[string]any{"code": 2, "message": s.errMessage}
+		}
+		s.mu.Unlock()
+		encoded = append(encoded, item)
+	}
+	payload := map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{
+				"attributes": []otlpAttribute{{Key: "service.name", Value: otlpValue(p.serviceName)}},
+			},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": "github.com/router-for-me/CLIProxyAPI"},
+				"spans": encoded,
+			}},
+		}},
+	}
+	data, _ := json.Marshal(payload)
+	return data
+}
+
+type writerExporter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+func (e *writerExporter) export(payload []byte) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, err := e.w.Write(append(payload, '\n')); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (e *writerExporter) close() error {
+	if e.closer == nil {
+		return nil
+	}
+	return e.closer.Close()
+}
+
+type otlpHTTPExporter struct {
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+}
+
+func (e *otlpHTTPExporter) export(payload []byte) error {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, e.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			log.Debugf("tracing: close response body: %v", errClose)
+		}
+	}()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("collector returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (e *otlpHTTPExporter) close() error { return nil }
@@ -0,0 +1,220 @@
+// Package tracing provides lightweight distributed tracing with W3C traceparent propagation.
+// Finished spans are batched and written as OTLP/JSON either to a local file, stdout, or an
+// OTLP/HTTP collector endpoint.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SpanKind mirrors the OTLP span kind enumeration.
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+// SpanContext identifies a span within a trace.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+}
+
+// IsValid reports whether both trace and span identifiers are non-zero.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// Traceparent renders the span context as a W3C traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", hex.EncodeToString(sc.TraceID[:]), hex.EncodeToString(sc.SpanID[:]), flags)
+}
+
+// ParseTraceparent decodes a W3C traceparent header value.
+func ParseTraceparent(value string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return SpanContext{}, false
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return SpanContext{}, false
+	}
+	var sc SpanContext
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return SpanContext{}, false
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return SpanContext{}, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return SpanContext{}, false
+	}
+	var flags [1]byte
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return SpanContext{}, false
+	}
+	sc.Sampled = flags[0]&0x01 == 0x01
+	if !sc.IsValid() {
+		return SpanContext{}, false
+	}
+	return sc, true
+}
+
+// Span records a timed operation. All methods are safe on a nil receiver so call sites
+// do not need to check whether tracing is enabled.
+type Span struct {
+	mu         sync.Mutex
+	name       string
+	kind       SpanKind
+	sc         SpanContext
+	parent     [8]byte
+	start      time.Time
+	end        time.Time
+	attributes map[string]any
+	errMessage string
+	ended      bool
+	provider   *provider
+}
+
+// SpanContext returns the identifiers of s.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// SetAttribute records a key/value attribute on the span.
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.attributes == nil {
+		s.attributes = make(map[string]any)
+	}
+	s.attributes[key] = value
+	s.mu.Unlock()
+}
+
+// RecordError marks the span as failed with err's message.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.errMessage = err.Error()
+	s.mu.Unlock()
+}
+
+// End finishes the span and queues it for export. Subsequent calls are no-ops.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+	s.provider.enqueue(s)
+}
+
+type spanContextKey struct{}
+
+type remoteParentKey struct{}
+
+// SpanFromContext returns the active span stored in ctx, if any.
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// ContextWithRemoteParent marks sc as the parent for spans started from the returned context.
+func ContextWithRemoteParent(ctx context.Context, sc SpanContext) context.Context {
+	if !sc.IsValid() {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteParentKey{}, sc)
+}
+
+// WithSpanFrom copies the active span of src into dst so work detached from the inbound
+// request context still joins the same trace. When src only carries a trace that is not
+// sampled, that decision is copied instead so the detached work is not sampled on its own.
+func WithSpanFrom(dst, src context.Context) context.Context {
+	if SpanFromContext(dst) != nil {
+		return dst
+	}
+	if span := SpanFromContext(src); span != nil {
+		return context.WithValue(dst, spanContextKey{}, span)
+	}
+	if src == nil || dst == nil {
+		return dst
+	}
+	if remote, ok := src.Value(remoteParentKey{}).(SpanContext); ok && !remote.Sampled {
+		if _, set := dst.Value(remoteParentKey{}).(SpanContext); !set {
+			return context.WithValue(dst, remoteParentKey{}, remote)
+		}
+	}
+	return dst
+}
+
+// Start begins a span named name as a child of the span in ctx. When tracing is disabled it
+// returns ctx unchanged and a nil span. When the trace is not sampled it also returns a nil
+// span, and a root span records that decision in the returned context so its children are
+// not sampled into fragments of their own.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	return StartKind(ctx, name, SpanKindInternal)
+}
+
+// StartKind is Start with an explicit span kind.
+func StartKind(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	p := currentProvider()
+	if p == nil {
+		return ctx, nil
+	}
+	span := &Span{name: name, kind: kind, start: time.Now(), provider: p}
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.sc.TraceID = parent.sc.TraceID
+		span.parent = parent.sc.SpanID
+		span.sc.Sampled = parent.sc.Sampled
+	} else if remote, ok := ctx.Value(remoteParentKey{}).(SpanContext); ok {
+		span.sc.TraceID = remote.TraceID
+		span.parent = remote.SpanID
+		span.sc.Sampled = remote.Sampled
+	} else {
+		_, _ = rand.Read(span.sc.TraceID[:])
+		span.sc.Sampled = p.sample()
+	}
+	_, _ = rand.Read(span.sc.SpanID[:])
+	if !span.sc.Sampled {
+		if span.parent == [8]byte{} {
+			// Descendants continue the unsampled trace like one announced by a remote caller.
+			return context.WithValue(ctx, remoteParentKey{}, span.sc), nil
+		}
+		return ctx, nil
+	}
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
@@ -0,0 +1,157 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+type bufferExporter struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (e *bufferExporter) export(payload []byte) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.buf.Write(payload)
+	e.buf.WriteByte('\n')
+	return nil
+}
+
+func (e *bufferExporter) close() error { return nil }
+
+func (e *bufferExporter) String() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.buf.String()
+}
+
+func installTestProvider(t *testing.T) *bufferExporter {
+	t.Helper()
+	exp := &bufferExporter{}
+	p := &provider{
+		serviceName: "test",
+		sampleRatio: 1,
+		exporter:    exp,
+		queue:       make(chan *Span, queueSize),
+		done:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+	go p.run()
+	active.Store(p)
+	t.Cleanup(flushTestProvider)
+	return exp
+}
+
+func flushTestProvider() {
+	if p := active.Swap(nil); p != nil {
+		p.stop()
+	}
+}
+
+func TestParseTraceparentRoundTrip(t *testing.T) {
+	const header = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, ok := ParseTraceparent(header)
+	if !ok {
+		t.Fatalf("ParseTraceparent(%q) failed", header)
+	}
+	if !sc.Sampled {
+		t.Fatalf("expected sampled flag")
+	}
+	if got := sc.Traceparent(); got != header {
+		t.Fatalf("Traceparent() = %q, want %q", got, header)
+	}
+	for _, invalid := range []string{"", "00-abc-def-01", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "00-00000000000000000000000000000000-00f067aa0ba902b7-01"} {
+		if _, ok := ParseTraceparent(invalid); ok {
+			t.Errorf("ParseTraceparent(%q) should fail", invalid)
+		}
+	}
+}
+
+func TestStartDisabledReturnsNilSpan(t *testing.T) {
+	active.Store(nil)
+	ctx := context.Background()
+	got, span := Start(ctx, "noop")
+	if span != nil || got != ctx {
+		t.Fatalf("expected no span when tracing is disabled")
+	}
+	span.SetAttribute("k", "v")
+	span.RecordError(errors.New("ignored"))
+	span.End()
+}
+
+func TestMiddlewarePropagatesTraceparent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	exp := installTestProvider(t)
+
+	var child SpanContext
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/v1/models", func(c *gin.Context) {
+		_, span := Start(c.Request.Context(), "inner")
+		child = span.SpanContext()
+		span.End()
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	req.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	flushTestProvider()
+
+	if got := hex.EncodeToString(child.TraceID[:]); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("child trace id = %s, want inbound trace id", got)
+	}
+
+	var server gjson.Result
+	for _, line := range strings.Split(strings.TrimSpace(exp.String()), "\n") {
+		gjson.Get(line, "resourceSpans.0.scopeSpans.0.spans").ForEach(func(_, span gjson.Result) bool {
+			if span.Get("name").String() == "GET /v1/models" {
+				server = span
+			}
+			return true
+		})
+	}
+	if !server.Exists() {
+		t.Fatalf("server span not exported: %s", exp.String())
+	}
+	if got := server.Get("parentSpanId").String(); got != "00f067aa0ba902b7" {
+		t.Fatalf("server parentSpanId = %q, want inbound span id", got)
+	}
+	if got := server.Get("kind").Int(); got != int64(SpanKindServer) {
+		t.Fatalf("server kind = %d, want %d", got, SpanKindServer)
+	}
+}
+
+func TestStartUnsampledRootKeepsChildrenUnsampled(t *testing.T) {
+	exp := installTestProvider(t)
+	p := currentProvider()
+
+	p.sampleRatio = 0
+	ctx, root := Start(context.Background(), "root")
+	if root != nil {
+		t.Fatalf("expected no span for an unsampled root")
+	}
+	p.sampleRatio = 1
+	childCtx, child := Start(ctx, "child")
+	if child != nil {
+		t.Fatalf("expected the child of an unsampled root to stay unsampled")
+	}
+	if _, detached := Start(WithSpanFrom(context.Background(), childCtx), "detached"); detached != nil {
+		t.Fatalf("expected detached work to inherit the unsampled decision")
+	}
+	flushTestProvider()
+	if out := exp.String(); out != "" {
+		t.Fatalf("expected no exported spans, got %s", out)
+	}
+}
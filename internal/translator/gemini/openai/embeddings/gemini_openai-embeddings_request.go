@@ -0,0 +1,74 @@
+// Package embeddings translates OpenAI embeddings requests and responses to and from
+// the Gemini batchEmbedContents API. The embeddings endpoint has no streaming variant
+// and is not registered with the translator registry; executors call these helpers
+// directly when the request is dispatched with the "embeddings" alt.
+package embeddings
+
+import (
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ConvertOpenAIEmbeddingsRequestToGemini converts an OpenAI /v1/embeddings request into
+// a Gemini batchEmbedContents request. Both a single string and an array of strings are
+// accepted as input; every input becomes one entry in the "requests" array so the
+// response order matches the input order.
+//
+// Parameters:
+//   - modelName: The upstream Gemini model name
+//   - inputRawJSON: The raw OpenAI embeddings request
+//
+// Returns:
+//   - []byte: The Gemini batchEmbedContents request body
+func ConvertOpenAIEmbeddingsRequestToGemini(modelName string, inputRawJSON []byte) []byte {
+	root := gjson.ParseBytes(inputRawJSON)
+	model := "models/" + strings.TrimPrefix(modelName, "models/")
+
+	out := `{"requests":[]}`
+	for _, text := range inputTexts(root.Get("input")) {
+		item := `{"model":"","content":{"parts":[{"text":""}]}}`
+		item, _ = sjson.Set(item, "model", model)
+		item, _ = sjson.Set(item, "content.parts.0.text", text)
+		if dims := root.Get("dimensions"); dims.Exists() && dims.Int() > 0 {
+			item, _ = sjson.Set(item, "outputDimensionality", dims.Int())
+		}
+		out, _ = sjson.SetRaw(out, "requests.-1", item)
+	}
+	return []byte(out)
+}
+
+// HasTokenInput reports whether the OpenAI embeddings request carries pre-tokenized
+// input (an array of integers or an array of integer arrays). Gemini only accepts text,
+// so callers reject such requests before they reach the upstream.
+func HasTokenInput(inputRawJSON []byte) bool {
+	input := gjson.GetBytes(inputRawJSON, "input")
+	if !input.IsArray() {
+		return false
+	}
+	hasTokens := false
+	input.ForEach(func(_, value gjson.Result) bool {
+		if value.Type == gjson.Number || value.IsArray() {
+			hasTokens = true
+			return false
+		}
+		return true
+	})
+	return hasTokens
+}
+
+func inputTexts(input gjson.Result) []string {
+	if !input.Exists() {
+		return nil
+	}
+	if !input.IsArray() {
+		return []string{input.String()}
+	}
+	var texts []string
+	input.ForEach(func(_, value gjson.Result) bool {
+		texts = append(texts, value.String())
+		return true
+	})
+	return texts
+}
@@ -0,0 +1,67 @@
+package embeddings
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ConvertGeminiEmbeddingsResponseToOpenAI converts a Gemini embedContent or
+// batchEmbedContents response into the OpenAI embeddings list format. When the original
+// request asked for "encoding_format": "base64", the vectors are emitted as base64
+// encoded little-endian float32 arrays, matching the OpenAI wire format.
+//
+// Parameters:
+//   - modelName: The model name reported back to the client
+//   - originalRequestRawJSON: The original OpenAI embeddings request
+//   - rawJSON: The raw Gemini response
+//
+// Returns:
+//   - []byte: The OpenAI embeddings response body
+func ConvertGeminiEmbeddingsResponseToOpenAI(modelName string, originalRequestRawJSON, rawJSON []byte) []byte {
+	root := gjson.ParseBytes(rawJSON)
+	useBase64 := gjson.GetBytes(originalRequestRawJSON, "encoding_format").String() == "base64"
+
+	var vectors []gjson.Result
+	if embeddings := root.Get("embeddings"); embeddings.IsArray() {
+		embeddings.ForEach(func(_, value gjson.Result) bool {
+			vectors = append(vectors, value.Get("values"))
+			return true
+		})
+	} else if single := root.Get("embedding.values"); single.Exists() {
+		vectors = append(vectors, single)
+	}
+
+	out := `{"object":"list","data":[],"model":"","usage":{"prompt_tokens":0,"total_tokens":0}}`
+	out, _ = sjson.Set(out, "model", modelName)
+	for i, values := range vectors {
+		item := `{"object":"embedding","index":0,"embedding":[]}`
+		item, _ = sjson.Set(item, "index", i)
+		if useBase64 {
+			item, _ = sjson.Set(item, "embedding", encodeFloat32Base64(values))
+		} else if values.IsArray() {
+			item, _ = sjson.SetRaw(item, "embedding", values.Raw)
+		}
+		out, _ = sjson.SetRaw(out, "data.-1", item)
+	}
+	if promptTokens := root.Get("usageMetadata.promptTokenCount"); promptTokens.Exists() {
+		out, _ = sjson.Set(out, "usage.prompt_tokens", promptTokens.Int())
+		out, _ = sjson.Set(out, "usage.total_tokens", promptTokens.Int())
+	}
+	return []byte(out)
+}
+
+func encodeFloat32Base64(values gjson.Result) string {
+	var buf bytes.Buffer
+	values.ForEach(func(_, value gjson.Result) bool {
+		var b [4]byte
+		binary.LittleEndian.PutUint32(b[:], math.Float32bits(float32(value.Float())))
+		buf.Write(b[:])
+		return true
+	})
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
@@ -0,0 +1,71 @@
+package embeddings
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestConvertOpenAIEmbeddingsRequestToGemini_ArrayInput(t *testing.T) {
+	in := []byte(`{"model":"gemini-embedding-001","input":["hello","world"],"dimensions":256}`)
+	out := gjson.ParseBytes(ConvertOpenAIEmbeddingsRequestToGemini("gemini-embedding-001", in))
+
+	requests := out.Get("requests").Array()
+	if len(requests) != 2 {
+		t.Fatalf("requests = %d, want 2: %s", len(requests), out.Raw)
+	}
+	if got := requests[0].Get("model").String(); got != "models/gemini-embedding-001" {
+		t.Fatalf("model = %q, want models/gemini-embedding-001", got)
+	}
+	if got := requests[1].Get("content.parts.0.text").String(); got != "world" {
+		t.Fatalf("second text = %q, want world", got)
+	}
+	if got := requests[0].Get("outputDimensionality").Int(); got != 256 {
+		t.Fatalf("outputDimensionality = %d, want 256", got)
+	}
+}
+
+func TestHasTokenInput(t *testing.T) {
+	cases := map[string]bool{
+		`{"input":"hello"}`:          false,
+		`{"input":["a","b"]}`:        false,
+		`{"input":[1,2,3]}`:          true,
+		`{"input":[[1,2],[3]]}`:      true,
+		`{"model":"no-input-field"}`: false,
+	}
+	for raw, want := range cases {
+		if got := HasTokenInput([]byte(raw)); got != want {
+			t.Fatalf("HasTokenInput(%s) = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestConvertGeminiEmbeddingsResponseToOpenAI(t *testing.T) {
+	raw := []byte(`{"embeddings":[{"values":[0.5,-1]},{"values":[0.25]}]}`)
+
+	out := gjson.ParseBytes(ConvertGeminiEmbeddingsResponseToOpenAI("gemini-embedding-001", []byte(`{}`), raw))
+	if got := out.Get("object").String(); got != "list" {
+		t.Fatalf("object = %q, want list", got)
+	}
+	if got := out.Get("data.1.index").Int(); got != 1 {
+		t.Fatalf("data.1.index = %d, want 1", got)
+	}
+	if got := out.Get("data.0.embedding.1").Float(); got != -1 {
+		t.Fatalf("data.0.embedding.1 = %v, want -1", got)
+	}
+
+	out = gjson.ParseBytes(ConvertGeminiEmbeddingsResponseToOpenAI("gemini-embedding-001", []byte(`{"encoding_format":"base64"}`), raw))
+	decoded, err := base64.StdEncoding.DecodeString(out.Get("data.0.embedding").String())
+	if err != nil {
+		t.Fatalf("decode base64 embedding: %v", err)
+	}
+	if len(decoded) != 8 {
+		t.Fatalf("decoded length = %d, want 8", len(decoded))
+	}
+	if got := math.Float32frombits(binary.LittleEndian.Uint32(decoded[4:])); got != -1 {
+		t.Fatalf("decoded second value = %v, want -1", got)
+	}
+}
@@ -0,0 +1,48 @@
+// Package embeddings translates Gemini embedContent and batchEmbedContents requests and
+// responses to and from the OpenAI embeddings API. It is used by OpenAI-compatible
+// executors when a Gemini-native embeddings request is routed to them; the helpers are
+// called directly rather than through the translator registry.
+package embeddings
+
+import (
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ConvertGeminiEmbeddingsRequestToOpenAI converts a Gemini embedContent request, or a
+// batchEmbedContents request carrying a "requests" array, into an OpenAI embeddings
+// request. The text parts of each content are joined with newlines to form one input.
+//
+// Parameters:
+//   - modelName: The upstream model name
+//   - inputRawJSON: The raw Gemini request
+//
+// Returns:
+//   - []byte: The OpenAI embeddings request body
+func ConvertGeminiEmbeddingsRequestToOpenAI(modelName string, inputRawJSON []byte) []byte {
+	root := gjson.ParseBytes(inputRawJSON)
+
+	entries := []gjson.Result{root}
+	if requests := root.Get("requests"); requests.IsArray() {
+		entries = requests.Array()
+	}
+
+	out := `{"model":"","input":[]}`
+	out, _ = sjson.Set(out, "model", modelName)
+	for _, entry := range entries {
+		var parts []string
+		entry.Get("content.parts").ForEach(func(_, part gjson.Result) bool {
+			if text := part.Get("text"); text.Exists() {
+				parts = append(parts, text.String())
+			}
+			return true
+		})
+		out, _ = sjson.Set(out, "input.-1", strings.Join(parts, "\n"))
+		if dims := entry.Get("outputDimensionality"); dims.Exists() && dims.Int() > 0 {
+			out, _ = sjson.Set(out, "dimensions", dims.Int())
+		}
+	}
+	return []byte(out)
+}
@@ -0,0 +1,40 @@
+package embeddings
+
+import (
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ConvertOpenAIEmbeddingsResponseToGemini converts an OpenAI embeddings response into the
+// Gemini format. A batchEmbedContents request (one carrying "requests") yields an
+// "embeddings" array; a single embedContent request yields one "embedding" object.
+//
+// Parameters:
+//   - originalRequestRawJSON: The original Gemini request
+//   - rawJSON: The raw OpenAI embeddings response
+//
+// Returns:
+//   - []byte: The Gemini embeddings response body
+func ConvertOpenAIEmbeddingsResponseToGemini(originalRequestRawJSON, rawJSON []byte) []byte {
+	data := gjson.GetBytes(rawJSON, "data")
+	batch := gjson.GetBytes(originalRequestRawJSON, "requests").IsArray()
+
+	if !batch {
+		out := `{"embedding":{"values":[]}}`
+		if values := data.Get("0.embedding"); values.IsArray() {
+			out, _ = sjson.SetRaw(out, "embedding.values", values.Raw)
+		}
+		return []byte(out)
+	}
+
+	out := `{"embeddings":[]}`
+	data.ForEach(func(_, item gjson.Result) bool {
+		entry := `{"values":[]}`
+		if values := item.Get("embedding"); values.IsArray() {
+			entry, _ = sjson.SetRaw(entry, "values", values.Raw)
+		}
+		out, _ = sjson.SetRaw(out, "embeddings.-1", entry)
+		return true
+	})
+	return []byte(out)
+}
@@ -64,6 +64,14 @@ type RequestStatistics struct {
 	successCount  int64
 	failureCount  int64
 	totalTokens   int64
+	totalCost     float64
+
+	affinityHits   int64
+	affinityMisses int64
+	hedgeAttempts  int64
+	shadowRequests int64
+	shadowTokens   int64
+	shadowCost     float64
 
 	apis map[string]*apiStats
 
@@ -77,6 +85,7 @@ type RequestStatistics struct {
 type apiStats struct {
 	TotalRequests int64
 	TotalTokens   int64
+	TotalCost     float64
 	Models        map[string]*modelStats
 }
 
@@ -84,33 +93,54 @@ type apiStats struct {
 type modelStats struct {
 	TotalRequests int64
 	TotalTokens   int64
+	TotalCost     float64
 	Details       []RequestDetail
 }
 
-// RequestDetail stores the timestamp and token usage for a single request.
+// RequestDetail stores the timestamp, token usage, list-price cost, session affinity
+// outcome, hedge role, traffic split variant and shadow flag for a single request.
 type RequestDetail struct {
-	Timestamp time.Time  `json:"timestamp"`
-	Source    string     `json:"source"`
-	AuthIndex string     `json:"auth_index"`
-	Tokens    TokenStats `json:"tokens"`
-	Failed    bool       `json:"failed"`
+	Timestamp       time.Time  `json:"timestamp"`
+	Source          string     `json:"source"`
+	AuthIndex       string     `json:"auth_index"`
+	Tokens          TokenStats `json:"tokens"`
+	Cost            float64    `json:"cost"`
+	Failed          bool       `json:"failed"`
+	SessionAffinity string     `json:"session_affinity,omitempty"`
+	Hedge           string     `json:"hedge,omitempty"`
+	Variant         string     `json:"variant,omitempty"`
+	Shadow          bool       `json:"shadow,omitempty"`
 }
 
 // TokenStats captures the token usage breakdown for a request.
 type TokenStats struct {
-	InputTokens     int64 `json:"input_tokens"`
-	OutputTokens    int64 `json:"output_tokens"`
-	ReasoningTokens int64 `json:"reasoning_tokens"`
-	CachedTokens    int64 `json:"cached_tokens"`
-	TotalTokens     int64 `json:"total_tokens"`
+	InputTokens      int64 `json:"input_tokens"`
+	OutputTokens     int64 `json:"output_tokens"`
+	ReasoningTokens  int64 `json:"reasoning_tokens"`
+	CachedTokens     int64 `json:"cached_tokens"`
+	CacheWriteTokens int64 `json:"cache_write_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
 }
 
 // StatisticsSnapshot represents an immutable view of the aggregated metrics.
 type StatisticsSnapshot struct {
-	TotalRequests int64 `json:"total_requests"`
-	SuccessCount  int64 `json:"success_count"`
-	FailureCount  int64 `json:"failure_count"`
-	TotalTokens   int64 `json:"total_tokens"`
+	TotalRequests int64   `json:"total_requests"`
+	SuccessCount  int64   `json:"success_count"`
+	FailureCount  int64   `json:"failure_count"`
+	TotalTokens   int64   `json:"total_tokens"`
+	TotalCost     float64 `json:"total_cost"`
+
+	// AffinityHits and AffinityMisses count requests routed by session affinity.
+	AffinityHits   int64 `json:"affinity_hits"`
+	AffinityMisses int64 `json:"affinity_misses"`
+	// HedgeAttempts counts second attempts launched by hedging policies.
+	HedgeAttempts int64 `json:"hedge_attempts"`
+	// ShadowRequests counts requests mirrored by shadow traffic policies. Mirrored requests
+	// are listed in the request details but kept out of every other total; ShadowTokens and
+	// ShadowCost hold their tokens and cost instead.
+	ShadowRequests int64   `json:"shadow_requests"`
+	ShadowTokens   int64   `json:"shadow_tokens"`
+	ShadowCost     float64 `json:"shadow_cost"`
 
 	APIs map[string]APISnapshot `json:"apis"`
 
@@ -124,6 +154,7 @@ type StatisticsSnapshot struct {
 type APISnapshot struct {
 	TotalRequests int64                    `json:"total_requests"`
 	TotalTokens   int64                    `json:"total_tokens"`
+	TotalCost     float64                  `json:"total_cost"`
 	Models        map[string]ModelSnapshot `json:"models"`
 }
 
@@ -131,6 +162,7 @@ type APISnapshot struct {
 type ModelSnapshot struct {
 	TotalRequests int64           `json:"total_requests"`
 	TotalTokens   int64           `json:"total_tokens"`
+	TotalCost     float64         `json:"total_cost"`
 	Details       []RequestDetail `json:"details"`
 }
 
@@ -177,49 +209,70 @@ func (s *RequestStatistics) Record(ctx context.Context, record coreusage.Record)
 	if modelName == "" {
 		modelName = "unknown"
 	}
+	cost := CostFor(record.Provider, modelName, detail)
 	dayKey := timestamp.Format("2006-01-02")
 	hourKey := timestamp.Hour()
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.totalRequests++
-	if success {
-		s.successCount++
-	} else {
-		s.failureCount++
-	}
-	s.totalTokens += totalTokens
-
 	stats, ok := s.apis[statsKey]
 	if !ok {
 		stats = &apiStats{Models: make(map[string]*modelStats)}
 		s.apis[statsKey] = stats
 	}
+	if record.Shadow {
+		s.shadowRequests++
+		s.shadowTokens += totalTokens
+		s.shadowCost += cost
+	} else {
+		s.totalRequests++
+		if success {
+			s.successCount++
+		} else {
+			s.failureCount++
+		}
+		s.totalTokens += totalTokens
+		s.totalCost += cost
+		s.countAffinity(record.SessionAffinity)
+		s.countHedge(record.Hedge)
+		s.requestsByDay[dayKey]++
+		s.requestsByHour[hourKey]++
+		s.tokensByDay[dayKey] += totalTokens
+		s.tokensByHour[hourKey] += totalTokens
+	}
 	s.updateAPIStats(stats, modelName, RequestDetail{
-		Timestamp: timestamp,
-		Source:    record.Source,
-		AuthIndex: record.AuthIndex,
-		Tokens:    detail,
-		Failed:    failed,
+		Timestamp:       timestamp,
+		Source:          record.Source,
+		AuthIndex:       record.AuthIndex,
+		Tokens:          detail,
+		Cost:            cost,
+		Failed:          failed,
+		SessionAffinity: record.SessionAffinity,
+		Hedge:           record.Hedge,
+		Variant:         record.Variant,
+		Shadow:          record.Shadow,
 	})
-
-	s.requestsByDay[dayKey]++
-	s.requestsByHour[hourKey]++
-	s.tokensByDay[dayKey] += totalTokens
-	s.tokensByHour[hourKey] += totalTokens
 }
 
+// updateAPIStats appends detail to the model's request details. Shadow requests are not
+// added to the API and model totals.
 func (s *RequestStatistics) updateAPIStats(stats *apiStats, model string, detail RequestDetail) {
-	stats.TotalRequests++
-	stats.TotalTokens += detail.Tokens.TotalTokens
 	modelStatsValue, ok := stats.Models[model]
 	if !ok {
 		modelStatsValue = &modelStats{}
 		stats.Models[model] = modelStatsValue
 	}
+	if detail.Shadow {
+		modelStatsValue.Details = append(modelStatsValue.Details, detail)
+		return
+	}
+	stats.TotalRequests++
+	stats.TotalTokens += detail.Tokens.TotalTokens
+	stats.TotalCost += detail.Cost
 	modelStatsValue.TotalRequests++
 	modelStatsValue.TotalTokens += detail.Tokens.TotalTokens
+	modelStatsValue.TotalCost += detail.Cost
 	modelStatsValue.Details = append(modelStatsValue.Details, detail)
 }
 
@@ -237,12 +290,20 @@ func (s *RequestStatistics) Snapshot() StatisticsSnapshot {
 	result.SuccessCount = s.successCount
 	result.FailureCount = s.failureCount
 	result.TotalTokens = s.totalTokens
+	result.TotalCost = s.totalCost
+	result.AffinityHits = s.affinityHits
+	result.AffinityMisses = s.affinityMisses
+	result.HedgeAttempts = s.hedgeAttempts
+	result.ShadowRequests = s.shadowRequests
+	result.ShadowTokens = s.shadowTokens
+	result.ShadowCost = s.shadowCost
 
 	result.APIs = make(map[string]APISnapshot, len(s.apis))
 	for apiName, stats := range s.apis {
 		apiSnapshot := APISnapshot{
 			TotalRequests: stats.TotalRequests,
 			TotalTokens:   stats.TotalTokens,
+			TotalCost:     stats.TotalCost,
 			Models:        make(map[string]ModelSnapshot, len(stats.Models)),
 		}
 		for modelName, modelStatsValue := range stats.Models {
@@ -251,6 +312,7 @@ func (s *RequestStatistics) Snapshot() StatisticsSnapshot {
 			apiSnapshot.Models[modelName] = ModelSnapshot{
 				TotalRequests: modelStatsValue.TotalRequests,
 				TotalTokens:   modelStatsValue.TotalTokens,
+				TotalCost:     modelStatsValue.TotalCost,
 				Details:       requestDetails,
 			}
 		}
@@ -356,6 +418,14 @@ func (s *RequestStatistics) recordImported(apiName, modelName string, stats *api
 		totalTokens = 0
 	}
 
+	s.updateAPIStats(stats, modelName, detail)
+	if detail.Shadow {
+		s.shadowRequests++
+		s.shadowTokens += totalTokens
+		s.shadowCost += detail.Cost
+		return
+	}
+
 	s.totalRequests++
 	if detail.Failed {
 		s.failureCount++
@@ -363,8 +433,9 @@ func (s *RequestStatistics) recordImported(apiName, modelName string, stats *api
 		s.successCount++
 	}
 	s.totalTokens += totalTokens
-
-	s.updateAPIStats(stats, modelName, detail)
+	s.totalCost += detail.Cost
+	s.countAffinity(detail.SessionAffinity)
+	s.countHedge(detail.Hedge)
 
 	dayKey := detail.Timestamp.Format("2006-01-02")
 	hourKey := detail.Timestamp.Hour()
@@ -375,6 +446,23 @@ func (s *RequestStatistics) recordImported(apiName, modelName string, stats *api
 	s.tokensByHour[hourKey] += totalTokens
 }
 
+// countAffinity tallies a session affinity outcome; callers must hold s.mu.
+func (s *RequestStatistics) countAffinity(outcome string) {
+	switch outcome {
+	case coreusage.AffinityHit:
+		s.affinityHits++
+	case coreusage.AffinityMiss:
+		s.affinityMisses++
+	}
+}
+
+// countHedge tallies hedge attempts; callers must hold s.mu.
+func (s *RequestStatistics) countHedge(role string) {
+	if role == coreusage.HedgeSecondary {
+		s.hedgeAttempts++
+	}
+}
+
 func dedupKey(apiName, modelName string, detail RequestDetail) string {
 	timestamp := detail.Timestamp.UTC().Format(time.RFC3339Nano)
 	tokens := normaliseTokenStats(detail.Tokens)
@@ -438,11 +526,12 @@ const httpStatusBadRequest = 400
 
 func normaliseDetail(detail coreusage.Detail) TokenStats {
 	tokens := TokenStats{
-		InputTokens:     detail.InputTokens,
-		OutputTokens:    detail.OutputTokens,
-		ReasoningTokens: detail.ReasoningTokens,
-		CachedTokens:    detail.CachedTokens,
-		TotalTokens:     detail.TotalTokens,
+		InputTokens:      detail.InputTokens,
+		OutputTokens:     detail.OutputTokens,
+		ReasoningTokens:  detail.ReasoningTokens,
+		CachedTokens:     detail.CachedTokens,
+		CacheWriteTokens: detail.CacheWriteTokens,
+		TotalTokens:      detail.TotalTokens,
 	}
 	if tokens.TotalTokens == 0 {
 		tokens.TotalTokens = detail.InputTokens + detail.OutputTokens + detail.ReasoningTokens
@@ -0,0 +1,32 @@
+package usage
+
+import (
+	"context"
+	"testing"
+
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func TestShadowRecordsStayOutOfTotals(t *testing.T) {
+	stats := NewRequestStatistics()
+	stats.Record(context.Background(), coreusage.Record{Model: "gpt-5", APIKey: "team-a", Detail: coreusage.Detail{InputTokens: 10, OutputTokens: 5}})
+	stats.Record(context.Background(), coreusage.Record{Model: "gpt-5", APIKey: "team-a", Shadow: true, Detail: coreusage.Detail{InputTokens: 20, OutputTokens: 20}})
+
+	snapshot := stats.Snapshot()
+	if snapshot.TotalRequests != 1 || snapshot.TotalTokens != 15 {
+		t.Fatalf("totals = %d requests / %d tokens, want 1 / 15", snapshot.TotalRequests, snapshot.TotalTokens)
+	}
+	if snapshot.ShadowRequests != 1 || snapshot.ShadowTokens != 40 {
+		t.Fatalf("shadow totals = %d requests / %d tokens, want 1 / 40", snapshot.ShadowRequests, snapshot.ShadowTokens)
+	}
+	model := snapshot.APIs["team-a"].Models["gpt-5"]
+	if model.TotalRequests != 1 || model.TotalTokens != 15 || len(model.Details) != 2 {
+		t.Fatalf("model stats = %+v, want the shadow request listed but not counted", model)
+	}
+
+	imported := NewRequestStatistics()
+	imported.MergeSnapshot(snapshot)
+	if got := imported.Snapshot(); got.TotalRequests != 1 || got.ShadowRequests != 1 || got.ShadowTokens != 40 {
+		t.Fatalf("imported totals = %d requests, %d shadow requests, %d shadow tokens", got.TotalRequests, got.ShadowRequests, got.ShadowTokens)
+	}
+}
@@ -0,0 +1,422 @@
+package usage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultUsageTable      = "usage_records"
+	persistQueueSize       = 8192
+	persistBatchSize       = 256
+	persistFlushInterval   = time.Second
+	retentionSweepInterval = time.Hour
+)
+
+// Group-by dimensions accepted by RecordStore.Summarize.
+const (
+	GroupByAPIKey    = "api-key"
+	GroupByAuthIndex = "auth-index"
+	GroupByProvider  = "provider"
+	GroupByModel     = "model"
+	GroupByDay       = "day"
+	GroupByHour      = "hour"
+)
+
+// StoredRecord is a single usage record as kept by the persistent store.
+type StoredRecord struct {
+	ID        int64      `json:"id,omitempty"`
+	Timestamp time.Time  `json:"timestamp"`
+	APIKey    string     `json:"api_key"`
+	AuthID    string     `json:"auth_id"`
+	AuthIndex string     `json:"auth_index"`
+	Provider  string     `json:"provider"`
+	Model     string     `json:"model"`
+	Source    string     `json:"source"`
+	Failed    bool       `json:"failed"`
+	Tokens    TokenStats `json:"tokens"`
+	Cost      float64    `json:"cost"`
+}
+
+// Query filters persisted usage records. Zero values match everything.
+type Query struct {
+	From      time.Time
+	To        time.Time
+	APIKey    string
+	AuthIndex string
+	Provider  string
+	Model     string
+	// Limit caps the number of records returned by RecordStore.Query; <= 0 means no limit.
+	Limit int
+}
+
+// Aggregate sums the usage of all records sharing the same group key.
+type Aggregate struct {
+	Key      string     `json:"key"`
+	Requests int64      `json:"requests"`
+	Failures int64      `json:"failures"`
+	Tokens   TokenStats `json:"tokens"`
+	Cost     float64    `json:"cost"`
+}
+
+// RecordStore persists usage records and answers range queries over them.
+type RecordStore interface {
+	// Insert appends records to the store.
+	Insert(ctx context.Context, records []StoredRecord) error
+	// Query returns matching records, newest first.
+	Query(ctx context.Context, q Query) ([]StoredRecord, error)
+	// Summarize aggregates matching records by one of the GroupBy* dimensions.
+	Summarize(ctx context.Context, q Query, groupBy string) ([]Aggregate, error)
+	// Prune deletes records older than before and reports how many were removed.
+	Prune(ctx context.Context, before time.Time) (int64, error)
+	// Close releases resources held by the store.
+	Close() error
+}
+
+// ValidGroupBy reports whether groupBy is a supported aggregation dimension.
+func ValidGroupBy(groupBy string) bool {
+	switch groupBy {
+	case GroupByAPIKey, GroupByAuthIndex, GroupByProvider, GroupByModel, GroupByDay, GroupByHour:
+		return true
+	default:
+		return false
+	}
+}
+
+var (
+	persistentSink = &PersistentPlugin{}
+
+	sharedPostgresMu     sync.RWMutex
+	sharedPostgresDB     *sql.DB
+	sharedPostgresSchema string
+)
+
+func init() {
+	coreusage.RegisterPlugin(persistentSink)
+}
+
+// SetSharedPostgres registers the connection of the Postgres-backed config store so the
+// persistent usage store can reuse it when no dedicated DSN is configured.
+func SetSharedPostgres(db *sql.DB, schema string) {
+	sharedPostgresMu.Lock()
+	sharedPostgresDB = db
+	sharedPostgresSchema = strings.TrimSpace(schema)
+	sharedPostgresMu.Unlock()
+}
+
+func sharedPostgres() (*sql.DB, string) {
+	sharedPostgresMu.RLock()
+	defer sharedPostgresMu.RUnlock()
+	return sharedPostgresDB, sharedPostgresSchema
+}
+
+// ConfigurePersistence applies the usage-store settings, replacing any previously opened store.
+// defaultDir is where the local backends keep their file when no path is configured.
+func ConfigurePersistence(ctx context.Context, cfg config.UsageStoreConfig, defaultDir string) error {
+	if !cfg.Enable {
+		persistentSink.swap(nil)
+		return nil
+	}
+	store, err := openRecordStore(ctx, cfg, defaultDir)
+	if err != nil {
+		return err
+	}
+	retention := time.Duration(0)
+	if cfg.RetentionDays > 0 {
+		retention = time.Duration(cfg.RetentionDays) * 24 * time.Hour
+	}
+	persistentSink.swap(newPersistentWriter(store, retention))
+	return nil
+}
+
+// ClosePersistence flushes queued records and closes the persistent store.
+func ClosePersistence() { persistentSink.swap(nil) }
+
+// PersistentStore returns the active persistent store, or nil when persistence is disabled.
+func PersistentStore() RecordStore {
+	if w := persistentSink.active.Load(); w != nil {
+		return w.store
+	}
+	return nil
+}
+
+func openRecordStore(ctx context.Context, cfg config.UsageStoreConfig, defaultDir string) (RecordStore, error) {
+	table := strings.TrimSpace(cfg.Table)
+	if table == "" {
+		table = defaultUsageTable
+	}
+	driver := strings.ToLower(strings.TrimSpace(cfg.Driver))
+	if driver == "" {
+		driver = "sqlite"
+	}
+	switch driver {
+	case "sqlite", "sqlite3":
+		path := strings.TrimSpace(cfg.Path)
+		if path == "" {
+			path = filepath.Join(defaultDir, "usage.db")
+		}
+		return openSQLiteRecordStore(ctx, path, table)
+	case "postgres", "postgresql", "pg":
+		if dsn := strings.TrimSpace(cfg.DSN); dsn != "" {
+			return openPostgresRecordStore(ctx, dsn, table)
+		}
+		db, schema := sharedPostgres()
+		if db == nil {
+			return nil, fmt.Errorf("usage store: postgres driver requires a dsn or PGSTORE_DSN")
+		}
+		return newSQLRecordStore(ctx, db, dialectPostgres, schema, table, false)
+	case "file", "jsonl":
+		path := strings.TrimSpace(cfg.Path)
+		if path == "" {
+			path = filepath.Join(defaultDir, "usage.jsonl")
+		}
+		return openFileRecordStore(path)
+	default:
+		return nil, fmt.Errorf("usage store: unsupported driver %q", cfg.Driver)
+	}
+}
+
+// PersistentPlugin writes every usage record to the configured RecordStore.
+// It implements coreusage.Plugin and stays idle until ConfigurePersistence enables it.
+type PersistentPlugin struct {
+	mu     sync.Mutex
+	active atomic.Pointer[persistentWriter]
+}
+
+// HandleUsage implements coreusage.Plugin.
+func (p *PersistentPlugin) HandleUsage(ctx context.Context, record coreusage.Record) {
+	if p == nil {
+		return
+	}
+	w := p.active.Load()
+	if w == nil {
+		return
+	}
+	w.enqueue(newStoredRecord(ctx, record))
+}
+
+func (p *PersistentPlugin) swap(next *persistentWriter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if previous := p.active.Swap(next); previous != nil {
+		previous.stop()
+	}
+}
+
+func newStoredRecord(ctx context.Context, record coreusage.Record) StoredRecord {
+	timestamp := record.RequestedAt
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	failed := record.Failed
+	if !failed {
+		failed = !resolveSuccess(ctx)
+	}
+	model := record.Model
+	if model == "" {
+		model = "unknown"
+	}
+	tokens := normaliseDetail(record.Detail)
+	return StoredRecord{
+		Timestamp: timestamp.UTC(),
+		APIKey:    record.APIKey,
+		AuthID:    record.AuthID,
+		AuthIndex: record.AuthIndex,
+		Provider:  record.Provider,
+		Model:     model,
+		Source:    record.Source,
+		Failed:    failed,
+		Tokens:    tokens,
+		Cost:      CostFor(record.Provider, model, tokens),
+	}
+}
+
+// persistentWriter batches records in the background so request handling never waits on storage.
+type persistentWriter struct {
+	store     RecordStore
+	retention time.Duration
+	queue     chan StoredRecord
+	done      chan struct{}
+	stopped   chan struct{}
+	stopOnce  sync.Once
+}
+
+func newPersistentWriter(store RecordStore, retention time.Duration) *persistentWriter {
+	w := &persistentWriter{
+		store:     store,
+		retention: retention,
+		queue:     make(chan StoredRecord, persistQueueSize),
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *persistentWriter) enqueue(record StoredRecord) {
+	select {
+	case <-w.done:
+	case w.queue <- record:
+	default:
+		log.Warn("usage store: queue full, dropping usage record")
+	}
+}
+
+// stop flushes pending records, closes the store and waits for the writer to exit.
+func (w *persistentWriter) stop() {
+	w.stopOnce.Do(func() {
+		close(w.done)
+	})
+	<-w.stopped
+}
+
+func (w *persistentWriter) run() {
+	defer close(w.stopped)
+	flushTicker := time.NewTicker(persistFlushInterval)
+	defer flushTicker.Stop()
+	retentionTicker := time.NewTicker(retentionSweepInterval)
+	defer retentionTicker.Stop()
+	w.prune()
+
+	batch := make([]StoredRecord, 0, persistBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		if err := w.store.Insert(ctx, batch); err != nil {
+			log.Errorf("usage store: failed to persist %d records: %v", len(batch), err)
+		}
+		cancel()
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case record := <-w.queue:
+			batch = append(batch, record)
+			if len(batch) >= persistBatchSize {
+				flush()
+			}
+		case <-flushTicker.C:
+			flush()
+		case <-retentionTicker.C:
+			w.prune()
+		case <-w.done:
+			for {
+				select {
+				case record := <-w.queue:
+					batch = append(batch, record)
+					if len(batch) >= persistBatchSize {
+						flush()
+					}
+				default:
+					flush()
+					if err := w.store.Close(); err != nil {
+						log.Errorf("usage store: close failed: %v", err)
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+func (w *persistentWriter) prune() {
+	if w.retention <= 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	removed, err := w.store.Prune(ctx, time.Now().Add(-w.retention))
+	if err != nil {
+		log.Errorf("usage store: retention cleanup failed: %v", err)
+		return
+	}
+	if removed > 0 {
+		log.Debugf("usage store: removed %d expired records", removed)
+	}
+}
+
+func (q Query) matches(record StoredRecord) bool {
+	if !q.From.IsZero() && record.Timestamp.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !record.Timestamp.Before(q.To) {
+		return false
+	}
+	if q.APIKey != "" && record.APIKey != q.APIKey {
+		return false
+	}
+	if q.AuthIndex != "" && record.AuthIndex != q.AuthIndex {
+		return false
+	}
+	if q.Provider != "" && !strings.EqualFold(record.Provider, q.Provider) {
+		return false
+	}
+	if q.Model != "" && record.Model != q.Model {
+		return false
+	}
+	return true
+}
+
+func groupKey(record StoredRecord, groupBy string) string {
+	switch groupBy {
+	case GroupByAPIKey:
+		return record.APIKey
+	case GroupByAuthIndex:
+		return record.AuthIndex
+	case GroupByProvider:
+		return record.Provider
+	case GroupByDay:
+		return record.Timestamp.UTC().Format("2006-01-02")
+	case GroupByHour:
+		return record.Timestamp.UTC().Format("2006-01-02T15:00Z")
+	default:
+		return record.Model
+	}
+}
+
+// aggregateRecords groups records in memory; used by stores without a query engine.
+func aggregateRecords(records []StoredRecord, groupBy string) []Aggregate {
+	groups := make(map[string]*Aggregate)
+	for _, record := range records {
+		key := groupKey(record, groupBy)
+		agg, ok := groups[key]
+		if !ok {
+			agg = &Aggregate{Key: key}
+			groups[key] = agg
+		}
+		agg.add(record)
+	}
+	out := make([]Aggregate, 0, len(groups))
+	for _, agg := range groups {
+		out = append(out, *agg)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+func (a *Aggregate) add(record StoredRecord) {
+	a.Requests++
+	if record.Failed {
+		a.Failures++
+	}
+	a.Tokens.InputTokens += record.Tokens.InputTokens
+	a.Tokens.OutputTokens += record.Tokens.OutputTokens
+	a.Tokens.ReasoningTokens += record.Tokens.ReasoningTokens
+	a.Tokens.CachedTokens += record.Tokens.CachedTokens
+	a.Tokens.CacheWriteTokens += record.Tokens.CacheWriteTokens
+	a.Tokens.TotalTokens += record.Tokens.TotalTokens
+	a.Cost += record.Cost
+}
@@ -0,0 +1,196 @@
+package usage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// fileRecordStore keeps usage records as JSON lines on disk and mirrors them in memory
+// for queries. It suits deployments that prefer plain files to a database.
+type fileRecordStore struct {
+	mu      sync.RWMutex
+	path    string
+	file    *os.File
+	records []StoredRecord
+	nextID  int64
+}
+
+func openFileRecordStore(path string) (*fileRecordStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("usage store: create directory: %w", err)
+	}
+	s := &fileRecordStore{path: path, nextID: 1}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("usage store: open %s: %w", path, err)
+	}
+	s.file = f
+	return s, nil
+}
+
+func (s *fileRecordStore) load() error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("usage store: open %s: %w", s.path, err)
+	}
+	defer func() { _ = f.Close() }()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var record StoredRecord
+		if errUnmarshal := json.Unmarshal(line, &record); errUnmarshal != nil {
+			log.Warnf("usage store: skipping malformed record in %s: %v", s.path, errUnmarshal)
+			continue
+		}
+		if record.ID >= s.nextID {
+			s.nextID = record.ID + 1
+		}
+		s.records = append(s.records, record)
+	}
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("usage store: read %s: %w", s.path, err)
+	}
+	sort.SliceStable(s.records, func(i, j int) bool { return s.records[i].Timestamp.Before(s.records[j].Timestamp) })
+	return nil
+}
+
+// Insert implements RecordStore.
+func (s *fileRecordStore) Insert(_ context.Context, records []StoredRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return fmt.Errorf("usage store: closed")
+	}
+	buf := make([]byte, 0, 256*len(records))
+	stored := make([]StoredRecord, 0, len(records))
+	for _, record := range records {
+		record.ID = s.nextID
+		s.nextID++
+		line, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("usage store: encode record: %w", err)
+		}
+		buf = append(append(buf, line...), '\n')
+		stored = append(stored, record)
+	}
+	if _, err := s.file.Write(buf); err != nil {
+		return fmt.Errorf("usage store: write %s: %w", s.path, err)
+	}
+	s.records = append(s.records, stored...)
+	return nil
+}
+
+// Query implements RecordStore.
+func (s *fileRecordStore) Query(_ context.Context, q Query) ([]StoredRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []StoredRecord
+	for i := len(s.records) - 1; i >= 0; i-- {
+		if !q.matches(s.records[i]) {
+			continue
+		}
+		out = append(out, s.records[i])
+		if q.Limit > 0 && len(out) >= q.Limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+// Summarize implements RecordStore.
+func (s *fileRecordStore) Summarize(ctx context.Context, q Query, groupBy string) ([]Aggregate, error) {
+	q.Limit = 0
+	records, err := s.Query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return aggregateRecords(records, groupBy), nil
+}
+
+// Prune implements RecordStore by rewriting the file without expired records.
+func (s *fileRecordStore) Prune(_ context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := make([]StoredRecord, 0, len(s.records))
+	for _, record := range s.records {
+		if !record.Timestamp.Before(before) {
+			kept = append(kept, record)
+		}
+	}
+	removed := int64(len(s.records) - len(kept))
+	if removed == 0 || s.file == nil {
+		return 0, nil
+	}
+
+	tmpPath := s.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return 0, fmt.Errorf("usage store: create %s: %w", tmpPath, err)
+	}
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for _, record := range kept {
+		if err = encoder.Encode(record); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if errClose := tmp.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return 0, fmt.Errorf("usage store: rewrite %s: %w", s.path, err)
+	}
+	_ = s.file.Close()
+	if err = os.Rename(tmpPath, s.path); err != nil {
+		_ = os.Remove(tmpPath)
+	}
+	f, errOpen := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if errOpen != nil {
+		s.file = nil
+		return 0, fmt.Errorf("usage store: reopen %s: %w", s.path, errOpen)
+	}
+	s.file = f
+	if err != nil {
+		return 0, fmt.Errorf("usage store: replace %s: %w", s.path, err)
+	}
+	s.records = kept
+	return removed, nil
+}
+
+// Close implements RecordStore.
+func (s *fileRecordStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
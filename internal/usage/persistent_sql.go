@@ -0,0 +1,317 @@
+package usage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+type sqlDialect int
+
+const (
+	dialectPostgres sqlDialect = iota
+	dialectSQLite
+)
+
+const (
+	msPerHour = int64(time.Hour / time.Millisecond)
+	msPerDay  = 24 * msPerHour
+)
+
+// sqlRecordStore keeps usage records in a single table. Timestamps are stored as Unix
+// milliseconds so the same statements work on Postgres and SQLite.
+type sqlRecordStore struct {
+	db      *sql.DB
+	dialect sqlDialect
+	table   string
+	ownsDB  bool
+}
+
+func openPostgresRecordStore(ctx context.Context, dsn, table string) (*sqlRecordStore, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("usage store: open postgres connection: %w", err)
+	}
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("usage store: ping postgres: %w", err)
+	}
+	return newSQLRecordStore(ctx, db, dialectPostgres, "", table, true)
+}
+
+// openSQLiteRecordStore opens path with the pure-Go modernc.org/sqlite driver.
+func openSQLiteRecordStore(ctx context.Context, path, table string) (*sqlRecordStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("usage store: create directory: %w", err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("usage store: open sqlite database: %w", err)
+	}
+	// SQLite allows a single writer; serialising connections avoids SQLITE_BUSY errors.
+	db.SetMaxOpenConns(1)
+	return newSQLRecordStore(ctx, db, dialectSQLite, "", table, true)
+}
+
+func newSQLRecordStore(ctx context.Context, db *sql.DB, dialect sqlDialect, schema, table string, ownsDB bool) (*sqlRecordStore, error) {
+	s := &sqlRecordStore{db: db, dialect: dialect, table: quoteIdentifier(table), ownsDB: ownsDB}
+	if schema != "" {
+		s.table = quoteIdentifier(schema) + "." + quoteIdentifier(table)
+	}
+	if err := s.ensureSchema(ctx, table); err != nil {
+		if ownsDB {
+			_ = db.Close()
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *sqlRecordStore) ensureSchema(ctx context.Context, baseTable string) error {
+	idColumn := "id BIGSERIAL PRIMARY KEY"
+	failedColumn := "failed BOOLEAN NOT NULL DEFAULT FALSE"
+	if s.dialect == dialectSQLite {
+		idColumn = "id INTEGER PRIMARY KEY AUTOINCREMENT"
+		failedColumn = "failed INTEGER NOT NULL DEFAULT 0"
+	}
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			%s,
+			requested_at BIGINT NOT NULL,
+			api_key TEXT NOT NULL DEFAULT '',
+			auth_id TEXT NOT NULL DEFAULT '',
+			auth_index TEXT NOT NULL DEFAULT '',
+			provider TEXT NOT NULL DEFAULT '',
+			model TEXT NOT NULL DEFAULT '',
+			source TEXT NOT NULL DEFAULT '',
+			%s,
+			input_tokens BIGINT NOT NULL DEFAULT 0,
+			output_tokens BIGINT NOT NULL DEFAULT 0,
+			reasoning_tokens BIGINT NOT NULL DEFAULT 0,
+			cached_tokens BIGINT NOT NULL DEFAULT 0,
+			cache_write_tokens BIGINT NOT NULL DEFAULT 0,
+			total_tokens BIGINT NOT NULL DEFAULT 0,
+			cost DOUBLE PRECISION NOT NULL DEFAULT 0
+		)
+	`, s.table, idColumn, failedColumn)); err != nil {
+		return fmt.Errorf("usage store: create table: %w", err)
+	}
+	index := quoteIdentifier(baseTable + "_requested_at_idx")
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (requested_at)", index, s.table)); err != nil {
+		return fmt.Errorf("usage store: create index: %w", err)
+	}
+	return nil
+}
+
+func (s *sqlRecordStore) placeholder(n int) string {
+	if s.dialect == dialectPostgres {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// Insert implements RecordStore.
+func (s *sqlRecordStore) Insert(ctx context.Context, records []StoredRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	placeholders := make([]string, 15)
+	for i := range placeholders {
+		placeholders[i] = s.placeholder(i + 1)
+	}
+	query := fmt.Sprintf(`INSERT INTO %s (requested_at, api_key, auth_id, auth_index, provider, model, source, failed,
+		input_tokens, output_tokens, reasoning_tokens, cached_tokens, cache_write_tokens, total_tokens, cost) VALUES (%s)`,
+		s.table, strings.Join(placeholders, ", "))
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("usage store: begin transaction: %w", err)
+	}
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("usage store: prepare insert: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+	for _, r := range records {
+		if _, err = stmt.ExecContext(ctx, r.Timestamp.UnixMilli(), r.APIKey, r.AuthID, r.AuthIndex, r.Provider, r.Model, r.Source,
+			s.boolValue(r.Failed), r.Tokens.InputTokens, r.Tokens.OutputTokens, r.Tokens.ReasoningTokens,
+			r.Tokens.CachedTokens, r.Tokens.CacheWriteTokens, r.Tokens.TotalTokens, r.Cost); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("usage store: insert record: %w", err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("usage store: commit: %w", err)
+	}
+	return nil
+}
+
+func (s *sqlRecordStore) boolValue(v bool) any {
+	if s.dialect == dialectSQLite {
+		if v {
+			return 1
+		}
+		return 0
+	}
+	return v
+}
+
+func (s *sqlRecordStore) where(q Query) (string, []any) {
+	var clauses []string
+	var args []any
+	add := func(expr string, value any) {
+		args = append(args, value)
+		clauses = append(clauses, fmt.Sprintf(expr, s.placeholder(len(args))))
+	}
+	if !q.From.IsZero() {
+		add("requested_at >= %s", q.From.UnixMilli())
+	}
+	if !q.To.IsZero() {
+		add("requested_at < %s", q.To.UnixMilli())
+	}
+	if q.APIKey != "" {
+		add("api_key = %s", q.APIKey)
+	}
+	if q.AuthIndex != "" {
+		add("auth_index = %s", q.AuthIndex)
+	}
+	if q.Provider != "" {
+		add("LOWER(provider) = %s", strings.ToLower(q.Provider))
+	}
+	if q.Model != "" {
+		add("model = %s", q.Model)
+	}
+	if len(clauses) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(clauses, " AND "), args
+}
+
+// Query implements RecordStore.
+func (s *sqlRecordStore) Query(ctx context.Context, q Query) ([]StoredRecord, error) {
+	where, args := s.where(q)
+	query := fmt.Sprintf(`SELECT id, requested_at, api_key, auth_id, auth_index, provider, model, source, failed,
+		input_tokens, output_tokens, reasoning_tokens, cached_tokens, cache_write_tokens, total_tokens, cost
+		FROM %s%s ORDER BY requested_at DESC, id DESC`,
+		s.table, where)
+	if q.Limit > 0 {
+		query += " LIMIT " + strconv.Itoa(q.Limit)
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("usage store: query records: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var out []StoredRecord
+	for rows.Next() {
+		var (
+			r           StoredRecord
+			requestedAt int64
+		)
+		if err = rows.Scan(&r.ID, &requestedAt, &r.APIKey, &r.AuthID, &r.AuthIndex, &r.Provider, &r.Model, &r.Source, &r.Failed,
+			&r.Tokens.InputTokens, &r.Tokens.OutputTokens, &r.Tokens.ReasoningTokens, &r.Tokens.CachedTokens, &r.Tokens.CacheWriteTokens,
+			&r.Tokens.TotalTokens, &r.Cost); err != nil {
+			return nil, fmt.Errorf("usage store: scan record: %w", err)
+		}
+		r.Timestamp = time.UnixMilli(requestedAt).UTC()
+		out = append(out, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("usage store: iterate records: %w", err)
+	}
+	return out, nil
+}
+
+// Summarize implements RecordStore.
+func (s *sqlRecordStore) Summarize(ctx context.Context, q Query, groupBy string) ([]Aggregate, error) {
+	var keyExpr string
+	bucket := int64(0)
+	switch groupBy {
+	case GroupByAPIKey:
+		keyExpr = "api_key"
+	case GroupByAuthIndex:
+		keyExpr = "auth_index"
+	case GroupByProvider:
+		keyExpr = "provider"
+	case GroupByDay:
+		bucket = msPerDay
+	case GroupByHour:
+		bucket = msPerHour
+	default:
+		keyExpr = "model"
+	}
+	if bucket > 0 {
+		keyExpr = fmt.Sprintf("requested_at / %d", bucket)
+	}
+	failedExpr := "CASE WHEN failed THEN 1 ELSE 0 END"
+	if s.dialect == dialectSQLite {
+		failedExpr = "failed"
+	}
+	where, args := s.where(q)
+	query := fmt.Sprintf(`SELECT %s AS group_key, COUNT(*), COALESCE(SUM(%s), 0),
+		COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0), COALESCE(SUM(reasoning_tokens), 0),
+		COALESCE(SUM(cached_tokens), 0), COALESCE(SUM(cache_write_tokens), 0), COALESCE(SUM(total_tokens), 0), COALESCE(SUM(cost), 0)
+		FROM %s%s GROUP BY group_key ORDER BY group_key`, keyExpr, failedExpr, s.table, where)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("usage store: summarize records: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var out []Aggregate
+	for rows.Next() {
+		var (
+			agg       Aggregate
+			bucketKey int64
+			dest      any = &agg.Key
+		)
+		if bucket > 0 {
+			dest = &bucketKey
+		}
+		if err = rows.Scan(dest, &agg.Requests, &agg.Failures, &agg.Tokens.InputTokens, &agg.Tokens.OutputTokens,
+			&agg.Tokens.ReasoningTokens, &agg.Tokens.CachedTokens, &agg.Tokens.CacheWriteTokens, &agg.Tokens.TotalTokens, &agg.Cost); err != nil {
+			return nil, fmt.Errorf("usage store: scan summary: %w", err)
+		}
+		if bucket > 0 {
+			agg.Key = groupKey(StoredRecord{Timestamp: time.UnixMilli(bucketKey * bucket)}, groupBy)
+		}
+		out = append(out, agg)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("usage store: iterate summary: %w", err)
+	}
+	return out, nil
+}
+
+// Prune implements RecordStore.
+func (s *sqlRecordStore) Prune(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE requested_at < %s", s.table, s.placeholder(1)), before.UnixMilli())
+	if err != nil {
+		return 0, fmt.Errorf("usage store: prune records: %w", err)
+	}
+	removed, _ := result.RowsAffected()
+	return removed, nil
+}
+
+// Close implements RecordStore. Shared connections are left open for their owner.
+func (s *sqlRecordStore) Close() error {
+	if s == nil || s.db == nil || !s.ownsDB {
+		return nil
+	}
+	return s.db.Close()
+}
+
+func quoteIdentifier(identifier string) string {
+	replaced := strings.ReplaceAll(identifier, "\"", "\"\"")
+	return "\"" + replaced + "\""
+}
@@ -0,0 +1,112 @@
+package usage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func TestFileRecordStoreQuerySummarizePrune(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.jsonl")
+	testRecordStore(t, func() (RecordStore, error) { return openFileRecordStore(path) })
+}
+
+func TestSQLiteRecordStoreQuerySummarizePrune(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.db")
+	testRecordStore(t, func() (RecordStore, error) {
+		return openSQLiteRecordStore(context.Background(), path, defaultUsageTable)
+	})
+}
+
+// testRecordStore checks a store opened by open, closing and reopening it to verify that
+// records and IDs survive a restart.
+func testRecordStore(t *testing.T, open func() (RecordStore, error)) {
+	t.Helper()
+	ctx := context.Background()
+	store, err := open()
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	records := []StoredRecord{
+		{Timestamp: base.Add(-48 * time.Hour), APIKey: "k1", Provider: "claude", Model: "claude-sonnet", Tokens: TokenStats{TotalTokens: 10}},
+		{Timestamp: base, APIKey: "k1", Provider: "gemini", Model: "gemini-2.5-pro", Tokens: TokenStats{TotalTokens: 20}},
+		{Timestamp: base.Add(time.Hour), APIKey: "k2", AuthIndex: "a1", Provider: "gemini", Model: "gemini-2.5-pro", Failed: true, Tokens: TokenStats{TotalTokens: 5}},
+	}
+	if err = store.Insert(ctx, records); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+
+	got, err := store.Query(ctx, Query{From: base, Provider: "GEMINI"})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(got) != 2 || got[0].APIKey != "k2" || got[0].ID != 3 {
+		t.Fatalf("Query returned %+v, want the two gemini records newest first", got)
+	}
+
+	summary, err := store.Summarize(ctx, Query{}, GroupByModel)
+	if err != nil {
+		t.Fatalf("Summarize: %v", err)
+	}
+	if len(summary) != 2 || summary[1].Key != "gemini-2.5-pro" || summary[1].Requests != 2 || summary[1].Failures != 1 || summary[1].Tokens.TotalTokens != 25 {
+		t.Fatalf("Summarize returned %+v", summary)
+	}
+
+	removed, err := store.Prune(ctx, base.Add(-time.Hour))
+	if err != nil || removed != 1 {
+		t.Fatalf("Prune = %d, %v; want 1 removed", removed, err)
+	}
+	if err = store.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened, err := open()
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer func() { _ = reopened.Close() }()
+	got, _ = reopened.Query(ctx, Query{})
+	if len(got) != 2 {
+		t.Fatalf("reopened store has %d records, want 2", len(got))
+	}
+	if err = reopened.Insert(ctx, []StoredRecord{{Timestamp: base, Model: "m"}}); err != nil {
+		t.Fatalf("Insert after reopen: %v", err)
+	}
+	got, _ = reopened.Query(ctx, Query{Model: "m"})
+	if len(got) != 1 || got[0].ID != 4 {
+		t.Fatalf("record inserted after reopen has id %d, want 4", got[0].ID)
+	}
+}
+
+func TestPersistentPluginWritesRecords(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.UsageStoreConfig{Enable: true}
+	if err := ConfigurePersistence(context.Background(), cfg, dir); err != nil {
+		t.Fatalf("ConfigurePersistence: %v", err)
+	}
+	persistentSink.HandleUsage(context.Background(), coreusage.Record{
+		Provider:  "codex",
+		Model:     "gpt-5",
+		APIKey:    "client-key",
+		AuthIndex: "idx",
+		Detail:    coreusage.Detail{InputTokens: 3, OutputTokens: 4},
+	})
+	ClosePersistence()
+
+	// SQLite is the default backend.
+	store, err := openSQLiteRecordStore(context.Background(), filepath.Join(dir, "usage.db"), defaultUsageTable)
+	if err != nil {
+		t.Fatalf("openSQLiteRecordStore: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	got, _ := store.Query(context.Background(), Query{APIKey: "client-key"})
+	if len(got) != 1 || got[0].Tokens.TotalTokens != 7 || got[0].AuthIndex != "idx" {
+		t.Fatalf("persisted records = %+v", got)
+	}
+}
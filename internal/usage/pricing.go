@@ -0,0 +1,123 @@
+package usage
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+const tokensPerPriceUnit = 1_000_000
+
+type priceTable struct {
+	exact    map[string]config.ModelPrice
+	patterns []config.ModelPrice
+}
+
+var activePricing atomic.Pointer[priceTable]
+
+// SetPricing replaces the model price table used to compute request cost.
+func SetPricing(prices []config.ModelPrice) {
+	if len(prices) == 0 {
+		activePricing.Store(nil)
+		return
+	}
+	table := &priceTable{exact: make(map[string]config.ModelPrice, len(prices))}
+	for _, price := range prices {
+		name := strings.ToLower(strings.TrimSpace(price.Model))
+		if name == "" {
+			continue
+		}
+		price.Model = name
+		if strings.Contains(name, "*") {
+			table.patterns = append(table.patterns, price)
+			continue
+		}
+		if _, exists := table.exact[name]; !exists {
+			table.exact[name] = price
+		}
+	}
+	activePricing.Store(table)
+}
+
+// PriceFor returns the price entry for model. Exact names win over wildcard patterns,
+// which are tried in configuration order.
+func PriceFor(model string) (config.ModelPrice, bool) {
+	table := activePricing.Load()
+	if table == nil {
+		return config.ModelPrice{}, false
+	}
+	model = strings.ToLower(strings.TrimSpace(model))
+	if price, ok := table.exact[model]; ok {
+		return price, true
+	}
+	for _, price := range table.patterns {
+		if util.MatchWildcard(price.Model, model) {
+			return price, true
+		}
+	}
+	return config.ModelPrice{}, false
+}
+
+// CostFor returns the list-price cost in USD of a request, or 0 when model has no price.
+// Providers report token buckets differently, so provider decides whether cached input
+// and reasoning tokens are already contained in the input and output counts.
+func CostFor(provider, model string, tokens TokenStats) float64 {
+	price, ok := PriceFor(model)
+	if !ok {
+		return 0
+	}
+	cachedRead := price.CachedRead
+	if cachedRead == 0 {
+		cachedRead = price.Input
+	}
+	cacheWrite := price.CacheWrite
+	if cacheWrite == 0 {
+		cacheWrite = price.Input
+	}
+	reasoning := price.Reasoning
+	if reasoning == 0 {
+		reasoning = price.Output
+	}
+
+	input := tokens.InputTokens
+	if !inputExcludesCache(provider) {
+		input -= tokens.CachedTokens + tokens.CacheWriteTokens
+	}
+	output := tokens.OutputTokens
+	if !outputExcludesReasoning(provider) {
+		output -= tokens.ReasoningTokens
+	}
+	input = max(input, 0)
+	output = max(output, 0)
+
+	cost := float64(input)*price.Input +
+		float64(tokens.CachedTokens)*cachedRead +
+		float64(tokens.CacheWriteTokens)*cacheWrite +
+		float64(output)*price.Output +
+		float64(tokens.ReasoningTokens)*reasoning
+	return cost / tokensPerPriceUnit
+}
+
+// RecordTotals returns the normalised total token count and list-price cost of record.
+func RecordTotals(record coreusage.Record) (int64, float64) {
+	tokens := normaliseDetail(record.Detail)
+	return tokens.TotalTokens, CostFor(record.Provider, record.Model, tokens)
+}
+
+// inputExcludesCache reports whether the provider's input count omits cache reads and writes.
+func inputExcludesCache(provider string) bool {
+	return strings.EqualFold(provider, "claude")
+}
+
+// outputExcludesReasoning reports whether the provider's output count omits thinking tokens.
+func outputExcludesReasoning(provider string) bool {
+	switch strings.ToLower(provider) {
+	case "gemini", "gemini-cli", "vertex", "aistudio", "antigravity":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,79 @@
+package usage
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func almostEqual(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestCostForMatchesExactBeforeWildcard(t *testing.T) {
+	SetPricing([]config.ModelPrice{
+		{Model: "gpt-5*", Input: 1, Output: 10},
+		{Model: "GPT-5-mini", Input: 0.25, Output: 2},
+	})
+	t.Cleanup(func() { SetPricing(nil) })
+
+	if price, ok := PriceFor("gpt-5-mini"); !ok || price.Input != 0.25 {
+		t.Fatalf("PriceFor(gpt-5-mini) = %+v, %v; want exact entry", price, ok)
+	}
+	if price, ok := PriceFor("gpt-5-codex"); !ok || price.Input != 1 {
+		t.Fatalf("PriceFor(gpt-5-codex) = %+v, %v; want wildcard entry", price, ok)
+	}
+	if _, ok := PriceFor("claude-sonnet-4-5"); ok {
+		t.Fatalf("PriceFor(claude-sonnet-4-5) should not match")
+	}
+}
+
+func TestCostForProviderTokenSemantics(t *testing.T) {
+	SetPricing([]config.ModelPrice{{Model: "m", Input: 2, Output: 10, CachedRead: 1, CacheWrite: 4, Reasoning: 20}})
+	t.Cleanup(func() { SetPricing(nil) })
+
+	// OpenAI-style counts include cached input in input and reasoning in output.
+	openai := CostFor("codex", "m", TokenStats{InputTokens: 1_000_000, CachedTokens: 400_000, OutputTokens: 500_000, ReasoningTokens: 100_000})
+	if want := 0.6*2 + 0.4*1 + 0.4*10 + 0.1*20; !almostEqual(openai, want) {
+		t.Fatalf("codex cost = %v, want %v", openai, want)
+	}
+	// Claude reports cache reads and writes separately from input.
+	claude := CostFor("claude", "m", TokenStats{InputTokens: 1_000_000, CachedTokens: 400_000, CacheWriteTokens: 100_000, OutputTokens: 500_000})
+	if want := 1*2 + 0.4*1 + 0.1*4 + 0.5*10; !almostEqual(claude, want) {
+		t.Fatalf("claude cost = %v, want %v", claude, want)
+	}
+	// Gemini reports thinking tokens separately from candidates.
+	gemini := CostFor("gemini", "m", TokenStats{InputTokens: 1_000_000, OutputTokens: 500_000, ReasoningTokens: 100_000})
+	if want := 1*2 + 0.5*10 + 0.1*20; !almostEqual(gemini, want) {
+		t.Fatalf("gemini cost = %v, want %v", gemini, want)
+	}
+}
+
+func TestSnapshotIncludesCostTotals(t *testing.T) {
+	SetPricing([]config.ModelPrice{{Model: "gpt-*", Input: 1, Output: 2}})
+	t.Cleanup(func() { SetPricing(nil) })
+
+	stats := NewRequestStatistics()
+	record := coreusage.Record{Provider: "codex", Model: "gpt-5", APIKey: "team-a", Detail: coreusage.Detail{InputTokens: 1_000_000, OutputTokens: 1_000_000}}
+	stats.Record(context.Background(), record)
+	stats.Record(context.Background(), record)
+
+	snapshot := stats.Snapshot()
+	if !almostEqual(snapshot.TotalCost, 6) {
+		t.Fatalf("TotalCost = %v, want 6", snapshot.TotalCost)
+	}
+	api := snapshot.APIs["team-a"]
+	if !almostEqual(api.TotalCost, 6) || !almostEqual(api.Models["gpt-5"].TotalCost, 6) {
+		t.Fatalf("per-key cost = %v, per-model cost = %v; want 6", api.TotalCost, api.Models["gpt-5"].TotalCost)
+	}
+	if !almostEqual(api.Models["gpt-5"].Details[0].Cost, 3) {
+		t.Fatalf("detail cost = %v, want 3", api.Models["gpt-5"].Details[0].Cost)
+	}
+
+	imported := NewRequestStatistics()
+	imported.MergeSnapshot(snapshot)
+	if got := imported.Snapshot().APIs["team-a"].TotalCost; !almostEqual(got, 6) {
+		t.Fatalf("imported cost = %v, want 6", got)
+	}
+}
@@ -0,0 +1,34 @@
+package util
+
+// MatchWildcard reports whether value matches pattern, where '*' matches any run of
+// characters, including none, and every other character matches itself. Matching is
+// case-sensitive; callers normalise case and whitespace first. Examples:
+//
+//	"*-5" matches "gpt-5"
+//	"gpt-*" matches "gpt-5" and "gpt-4"
+//	"gemini-*-pro" matches "gemini-2.5-pro" and "gemini-3-pro".
+func MatchWildcard(pattern, value string) bool {
+	pi, vi := 0, 0
+	starIdx, matchIdx := -1, 0
+	for vi < len(value) {
+		switch {
+		case pi < len(pattern) && pattern[pi] != '*' && pattern[pi] == value[vi]:
+			pi++
+			vi++
+		case pi < len(pattern) && pattern[pi] == '*':
+			starIdx, matchIdx = pi, vi
+			pi++
+		case starIdx != -1:
+			// Let the last star absorb one more character and retry.
+			pi = starIdx + 1
+			matchIdx++
+			vi = matchIdx
+		default:
+			return false
+		}
+	}
+	for pi < len(pattern) && pattern[pi] == '*' {
+		pi++
+	}
+	return pi == len(pattern)
+}
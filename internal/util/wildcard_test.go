@@ -0,0 +1,29 @@
+package util
+
+import "testing"
+
+func TestMatchWildcard(t *testing.T) {
+	cases := []struct {
+		pattern, value string
+		want           bool
+	}{
+		{"claude-*", "claude-sonnet-4", true},
+		{"*-pro", "gemini-2.5-pro", true},
+		{"gemini-*-flash", "gemini-2.5-flash", true},
+		{"gemini-*-flash", "gemini-2.5-pro", false},
+		{"gpt-5", "gpt-5-mini", false},
+		{"gpt-5", "gpt-5", true},
+		{"*", "", true},
+		{"", "", true},
+		{"", "gpt-5", false},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "axxcyyb", false},
+		{"*a*a", "aaa", true},
+		{"Claude-*", "claude-sonnet-4", false},
+	}
+	for _, tc := range cases {
+		if got := MatchWildcard(tc.pattern, tc.value); got != tc.want {
+			t.Errorf("MatchWildcard(%q, %q) = %v, want %v", tc.pattern, tc.value, got, tc.want)
+		}
+	}
+}
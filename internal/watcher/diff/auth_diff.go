@@ -9,9 +9,9 @@ import (
 )
 
 // BuildAuthChangeDetails computes a redacted, human-readable list of auth field changes.
-// Only prefix, proxy_url, and disabled fields are tracked; sensitive data is never printed.
+// Only prefix, proxy_url, disabled and tags fields are tracked; sensitive data is never printed.
 func BuildAuthChangeDetails(oldAuth, newAuth *coreauth.Auth) []string {
-	changes := make([]string, 0, 3)
+	changes := make([]string, 0, 4)
 
 	// Handle nil cases by using empty Auth as default
 	if oldAuth == nil {
@@ -40,5 +40,12 @@ func BuildAuthChangeDetails(oldAuth, newAuth *coreauth.Auth) []string {
 		changes = append(changes, fmt.Sprintf("disabled: %t -> %t", oldAuth.Disabled, newAuth.Disabled))
 	}
 
+	// Compare routing tags
+	oldTags := strings.Join(oldAuth.Tags(), ",")
+	newTags := strings.Join(newAuth.Tags(), ",")
+	if oldTags != newTags {
+		changes = append(changes, fmt.Sprintf("tags: %s -> %s", oldTags, newTags))
+	}
+
 	return changes
 }
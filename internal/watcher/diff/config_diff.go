@@ -78,9 +78,50 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 		changes = append(changes, fmt.Sprintf("quota-exceeded.switch-preview-model: %t -> %t", oldCfg.QuotaExceeded.SwitchPreviewModel, newCfg.QuotaExceeded.SwitchPreviewModel))
 	}
 
+	if oldCfg.CircuitBreaker != newCfg.CircuitBreaker {
+		o, n := oldCfg.CircuitBreaker, newCfg.CircuitBreaker
+		changes = append(changes, fmt.Sprintf("circuit-breaker: enable=%t failure-threshold=%d auth-failure-threshold=%d window-seconds=%d open-seconds=%d -> enable=%t failure-threshold=%d auth-failure-threshold=%d window-seconds=%d open-seconds=%d",
+			o.Enable, o.FailureThreshold, o.AuthFailureThreshold, o.WindowSeconds, o.OpenSeconds,
+			n.Enable, n.FailureThreshold, n.AuthFailureThreshold, n.WindowSeconds, n.OpenSeconds))
+	}
+
+	if oldCfg.CooldownQueue != newCfg.CooldownQueue {
+		changes = append(changes, fmt.Sprintf("cooldown-queue: enable=%t max-wait-seconds=%d max-depth=%d -> enable=%t max-wait-seconds=%d max-depth=%d",
+			oldCfg.CooldownQueue.Enable, oldCfg.CooldownQueue.MaxWaitSeconds, oldCfg.CooldownQueue.MaxDepth,
+			newCfg.CooldownQueue.Enable, newCfg.CooldownQueue.MaxWaitSeconds, newCfg.CooldownQueue.MaxDepth))
+	}
+
 	if oldCfg.Routing.Strategy != newCfg.Routing.Strategy {
 		changes = append(changes, fmt.Sprintf("routing.strategy: %s -> %s", oldCfg.Routing.Strategy, newCfg.Routing.Strategy))
 	}
+	if oldCfg.Routing.LimitWaitSeconds != newCfg.Routing.LimitWaitSeconds {
+		changes = append(changes, fmt.Sprintf("routing.limit-wait-seconds: %d -> %d", oldCfg.Routing.LimitWaitSeconds, newCfg.Routing.LimitWaitSeconds))
+	}
+	if oldCfg.SessionAffinity != newCfg.SessionAffinity {
+		changes = append(changes, fmt.Sprintf("session-affinity: enable=%t ttl-seconds=%d header=%q -> enable=%t ttl-seconds=%d header=%q",
+			oldCfg.SessionAffinity.Enable, oldCfg.SessionAffinity.TTLSeconds, oldCfg.SessionAffinity.Header,
+			newCfg.SessionAffinity.Enable, newCfg.SessionAffinity.TTLSeconds, newCfg.SessionAffinity.Header))
+	}
+	if len(oldCfg.TrafficSplits) != len(newCfg.TrafficSplits) {
+		changes = append(changes, fmt.Sprintf("traffic-split count: %d -> %d", len(oldCfg.TrafficSplits), len(newCfg.TrafficSplits)))
+	} else if !reflect.DeepEqual(oldCfg.TrafficSplits, newCfg.TrafficSplits) {
+		changes = append(changes, "traffic-split: updated")
+	}
+	if len(oldCfg.VirtualModels) != len(newCfg.VirtualModels) {
+		changes = append(changes, fmt.Sprintf("virtual-models count: %d -> %d", len(oldCfg.VirtualModels), len(newCfg.VirtualModels)))
+	} else if !reflect.DeepEqual(oldCfg.VirtualModels, newCfg.VirtualModels) {
+		changes = append(changes, "virtual-models: updated")
+	}
+	if len(oldCfg.Hedging) != len(newCfg.Hedging) {
+		changes = append(changes, fmt.Sprintf("hedging count: %d -> %d", len(oldCfg.Hedging), len(newCfg.Hedging)))
+	} else if !reflect.DeepEqual(oldCfg.Hedging, newCfg.Hedging) {
+		changes = append(changes, "hedging: updated")
+	}
+	if len(oldCfg.ShadowTraffic) != len(newCfg.ShadowTraffic) {
+		changes = append(changes, fmt.Sprintf("shadow-traffic count: %d -> %d", len(oldCfg.ShadowTraffic), len(newCfg.ShadowTraffic)))
+	} else if !reflect.DeepEqual(oldCfg.ShadowTraffic, newCfg.ShadowTraffic) {
+		changes = append(changes, "shadow-traffic: updated")
+	}
 
 	// API keys (redacted) and counts
 	if len(oldCfg.APIKeys) != len(newCfg.APIKeys) {
@@ -88,6 +129,11 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	} else if !reflect.DeepEqual(trimStrings(oldCfg.APIKeys), trimStrings(newCfg.APIKeys)) {
 		changes = append(changes, "api-keys: values updated (count unchanged, redacted)")
 	}
+	if len(oldCfg.ClientKeys) != len(newCfg.ClientKeys) {
+		changes = append(changes, fmt.Sprintf("client-keys count: %d -> %d", len(oldCfg.ClientKeys), len(newCfg.ClientKeys)))
+	} else if !reflect.DeepEqual(oldCfg.ClientKeys, newCfg.ClientKeys) {
+		changes = append(changes, "client-keys: policies updated (count unchanged, redacted)")
+	}
 	if len(oldCfg.GeminiKey) != len(newCfg.GeminiKey) {
 		changes = append(changes, fmt.Sprintf("gemini-api-key count: %d -> %d", len(oldCfg.GeminiKey), len(newCfg.GeminiKey)))
 	} else {
@@ -265,6 +311,12 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 			changes = append(changes, "remote-management.secret-key: updated")
 		}
 	}
+	if !reflect.DeepEqual(oldCfg.RemoteManagement.Tokens, newCfg.RemoteManagement.Tokens) {
+		changes = append(changes, fmt.Sprintf("remote-management.tokens: updated (%d -> %d)", len(oldCfg.RemoteManagement.Tokens), len(newCfg.RemoteManagement.Tokens)))
+	}
+	if oldCfg.RemoteManagement.ConfigHistory != newCfg.RemoteManagement.ConfigHistory {
+		changes = append(changes, fmt.Sprintf("remote-management.config-history: %d -> %d", oldCfg.RemoteManagement.ConfigHistory, newCfg.RemoteManagement.ConfigHistory))
+	}
 
 	// OpenAI compatibility providers (summarized)
 	if compat := DiffOpenAICompatibility(oldCfg.OpenAICompatibility, newCfg.OpenAICompatibility); len(compat) > 0 {
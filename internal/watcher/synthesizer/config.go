@@ -63,6 +63,9 @@ func (s *ConfigSynthesizer) synthesizeGeminiKeys(ctx *SynthesisContext) []*corea
 		if entry.Priority != 0 {
 			attrs["priority"] = strconv.Itoa(entry.Priority)
 		}
+		addLimitAttrs(attrs, entry.MaxConcurrency, entry.RPM, entry.TPM)
+		addAvailabilityAttrs(attrs, entry.Availability)
+		addTagAttrs(attrs, entry.Tags)
 		if base != "" {
 			attrs["base_url"] = base
 		}
@@ -110,6 +113,9 @@ func (s *ConfigSynthesizer) synthesizeClaudeKeys(ctx *SynthesisContext) []*corea
 		if ck.Priority != 0 {
 			attrs["priority"] = strconv.Itoa(ck.Priority)
 		}
+		addLimitAttrs(attrs, ck.MaxConcurrency, ck.RPM, ck.TPM)
+		addAvailabilityAttrs(attrs, ck.Availability)
+		addTagAttrs(attrs, ck.Tags)
 		if base != "" {
 			attrs["base_url"] = base
 		}
@@ -157,6 +163,9 @@ func (s *ConfigSynthesizer) synthesizeCodexKeys(ctx *SynthesisContext) []*coreau
 		if ck.Priority != 0 {
 			attrs["priority"] = strconv.Itoa(ck.Priority)
 		}
+		addLimitAttrs(attrs, ck.MaxConcurrency, ck.RPM, ck.TPM)
+		addAvailabilityAttrs(attrs, ck.Availability)
+		addTagAttrs(attrs, ck.Tags)
 		if ck.BaseURL != "" {
 			attrs["base_url"] = ck.BaseURL
 		}
@@ -215,6 +224,9 @@ func (s *ConfigSynthesizer) synthesizeOpenAICompat(ctx *SynthesisContext) []*cor
 			if compat.Priority != 0 {
 				attrs["priority"] = strconv.Itoa(compat.Priority)
 			}
+			addLimitAttrs(attrs, entry.MaxConcurrency, entry.RPM, entry.TPM)
+			addAvailabilityAttrs(attrs, entry.Availability)
+			addTagAttrs(attrs, entry.Tags)
 			if key != "" {
 				attrs["api_key"] = key
 			}
@@ -294,6 +306,9 @@ func (s *ConfigSynthesizer) synthesizeVertexCompat(ctx *SynthesisContext) []*cor
 		if compat.Priority != 0 {
 			attrs["priority"] = strconv.Itoa(compat.Priority)
 		}
+		addLimitAttrs(attrs, compat.MaxConcurrency, compat.RPM, compat.TPM)
+		addAvailabilityAttrs(attrs, compat.Availability)
+		addTagAttrs(attrs, compat.Tags)
 		if key != "" {
 			attrs["api_key"] = key
 		}
@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
@@ -108,3 +109,93 @@ func addConfigHeadersToAttrs(headers map[string]string, attrs map[string]string)
 		attrs["header:"+key] = val
 	}
 }
+
+// addLimitAttrs records per-credential concurrency and rate limits in auth attributes.
+// Zero or negative values mean unlimited and are omitted.
+func addLimitAttrs(attrs map[string]string, maxConcurrency, rpm, tpm int) {
+	if attrs == nil {
+		return
+	}
+	if maxConcurrency > 0 {
+		attrs["max_concurrency"] = strconv.Itoa(maxConcurrency)
+	}
+	if rpm > 0 {
+		attrs["rpm"] = strconv.Itoa(rpm)
+	}
+	if tpm > 0 {
+		attrs["tpm"] = strconv.Itoa(tpm)
+	}
+}
+
+// addAvailabilityAttrs records a credential's availability schedule in auth attributes.
+// Windows are joined with ';' and parsed by the auth manager at selection time.
+func addAvailabilityAttrs(attrs map[string]string, schedule *config.AvailabilitySchedule) {
+	if attrs == nil || schedule == nil {
+		return
+	}
+	windows := make([]string, 0, len(schedule.Windows))
+	for _, window := range schedule.Windows {
+		if window = strings.TrimSpace(window); window != "" {
+			windows = append(windows, window)
+		}
+	}
+	if len(windows) == 0 {
+		return
+	}
+	attrs["availability"] = strings.Join(windows, ";")
+	if tz := strings.TrimSpace(schedule.Timezone); tz != "" {
+		attrs["availability_timezone"] = tz
+	}
+}
+
+// addLimitAttrsFromMetadata copies limits declared in an auth file into auth attributes.
+// Both snake_case and kebab-case keys are accepted.
+func addLimitAttrsFromMetadata(attrs map[string]string, metadata map[string]any) {
+	if attrs == nil || len(metadata) == 0 {
+		return
+	}
+	addLimitAttrs(attrs,
+		intFromMetadata(metadata, "max_concurrency", "max-concurrency"),
+		intFromMetadata(metadata, "rpm"),
+		intFromMetadata(metadata, "tpm"),
+	)
+}
+
+func intFromMetadata(metadata map[string]any, keys ...string) int {
+	for _, key := range keys {
+		raw, ok := metadata[key]
+		if !ok || raw == nil {
+			continue
+		}
+		switch v := raw.(type) {
+		case float64:
+			return int(v)
+		case int:
+			return v
+		case int64:
+			return int(v)
+		case string:
+			if parsed, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+				return parsed
+			}
+		}
+	}
+	return 0
+}
+
+// addTagAttrs records a credential's routing tags in auth attributes as a lowercase,
+// comma-separated list.
+func addTagAttrs(attrs map[string]string, tags []string) {
+	if attrs == nil {
+		return
+	}
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			normalized = append(normalized, tag)
+		}
+	}
+	if len(normalized) > 0 {
+		attrs["tags"] = strings.Join(normalized, ",")
+	}
+}
@@ -62,20 +62,24 @@ func BuildProviders(root *config.SDKConfig) ([]Provider, error) {
 	if root == nil {
 		return nil, nil
 	}
-	providers := make([]Provider, 0, len(root.Access.Providers))
+	providers := make([]Provider, 0, len(root.Access.Providers)+1)
+	hasInline := false
 	for i := range root.Access.Providers {
 		providerCfg := &root.Access.Providers[i]
 		if providerCfg.Type == "" {
 			continue
 		}
+		if providerCfg.Type == config.AccessProviderTypeConfigAPIKey {
+			hasInline = true
+		}
 		provider, err := BuildProvider(providerCfg, root)
 		if err != nil {
 			return nil, err
 		}
 		providers = append(providers, provider)
 	}
-	if len(providers) == 0 {
-		if inline := config.MakeInlineAPIKeyProvider(root.APIKeys); inline != nil {
+	if !hasInline {
+		if inline := config.MakeInlineAPIKeyProvider(root.InlineAPIKeys()); inline != nil {
 			provider, err := BuildProvider(inline, root)
 			if err != nil {
 				return nil, err
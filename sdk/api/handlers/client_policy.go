@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/access/policy"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// credentialTagsHeader carries a comma-separated list of tags that credentials serving the
+// request must carry, e.g. "team-a,eu".
+const credentialTagsHeader = "X-CLIProxy-Tags"
+
+// clientAPIKey returns the client API key stored on the gin context by the auth middleware.
+func clientAPIKey(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil {
+		return ""
+	}
+	if v, exists := ginCtx.Get("apiKey"); exists {
+		if key, okKey := v.(string); okKey {
+			return key
+		}
+	}
+	return ""
+}
+
+// withCredentialTags restricts credential selection to the tags required by the client key
+// policy and by the X-CLIProxy-Tags header. Required tags accumulate, so the header can narrow
+// but never widen the credentials the key's policy allows.
+func withCredentialTags(ctx context.Context) context.Context {
+	ctx = coreauth.WithRequiredTags(ctx, policy.Default().CredentialTags(clientAPIKey(ctx))...)
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil && ginCtx.Request != nil {
+		ctx = coreauth.WithRequiredTags(ctx, coreauth.ParseTags(ginCtx.GetHeader(credentialTagsHeader))...)
+	}
+	return ctx
+}
+
+// resolveClientRequest enforces the client key policy around traffic splitting and provider
+// resolution. The request is admitted for the model the client asked for, then routed to a
+// traffic split variant the key may use; the resolved providers are narrowed to those the key
+// may use, and only a request passing every check counts against the key's RPM. Virtual models
+// are admitted by name and their targets are checked one by one as the fallback chain runs.
+// The returned context records the chosen variant and is sampled for shadow traffic only once,
+// however often the request is retried.
+func (h *BaseAPIHandler) resolveClientRequest(ctx context.Context, handlerType, modelName string, rawJSON []byte) (context.Context, []string, string, *interfaces.ErrorMessage) {
+	if errMsg := admitClientRequest(ctx, handlerType, h.admissionModel(modelName)); errMsg != nil {
+		return ctx, nil, "", errMsg
+	}
+	ctx, modelName = h.applyTrafficSplit(ctx, modelName, rawJSON, func(variant string) bool {
+		return h.clientMayUse(ctx, variant)
+	})
+	providers, normalizedModel, errMsg := h.getRequestDetails(modelName)
+	if errMsg != nil {
+		return ctx, nil, "", errMsg
+	}
+	if isVirtualModel(providers) {
+		ctx = coreauth.WithTargetFilter(ctx, func(model string, targetProviders []string) []string {
+			return clientTargetProviders(ctx, model, targetProviders)
+		})
+	} else if providers, errMsg = filterClientProviders(ctx, handlerType, normalizedModel, providers); errMsg != nil {
+		return ctx, nil, "", errMsg
+	}
+	if v := policy.Default().CountRequest(clientAPIKey(ctx)); v != nil {
+		return ctx, nil, "", policyErrorMessage(handlerType, v)
+	}
+	return coreauth.WithShadowOnce(ctx), providers, normalizedModel, nil
+}
+
+// clientMayUse reports whether the client key may call model through at least one of the
+// providers serving it. Virtual models are usable; their targets are checked when they run.
+func (h *BaseAPIHandler) clientMayUse(ctx context.Context, model string) bool {
+	providers, normalizedModel, errMsg := h.getRequestDetails(model)
+	if errMsg != nil {
+		return false
+	}
+	if isVirtualModel(providers) {
+		return true
+	}
+	return len(clientTargetProviders(ctx, normalizedModel, providers)) > 0
+}
+
+// clientTargetProviders returns the providers through which the client key may call model,
+// or nil when the key may not call model at all.
+func clientTargetProviders(ctx context.Context, model string, providers []string) []string {
+	apiKey := clientAPIKey(ctx)
+	if !policy.Default().AllowsModel(apiKey, model) {
+		return nil
+	}
+	filtered, v := policy.Default().FilterProviders(apiKey, model, providers)
+	if v != nil {
+		return nil
+	}
+	return filtered
+}
+
+// admissionModel returns the model whose rules admission checks: none for virtual models,
+// whose targets are checked instead.
+func (h *BaseAPIHandler) admissionModel(model string) string {
+	if providers, _, errMsg := h.getRequestDetails(model); errMsg == nil && isVirtualModel(providers) {
+		return ""
+	}
+	return model
+}
+
+// isVirtualModel reports whether providers resolve a virtual model rather than a real one.
+func isVirtualModel(providers []string) bool {
+	return len(providers) == 1 && providers[0] == coreauth.VirtualModelProvider
+}
+
+// admitClientRequest applies the client key policy before the request is routed.
+func admitClientRequest(ctx context.Context, handlerType, modelName string) *interfaces.ErrorMessage {
+	if v := policy.Default().Admit(clientAPIKey(ctx), modelName); v != nil {
+		return policyErrorMessage(handlerType, v)
+	}
+	return nil
+}
+
+// filterClientProviders drops providers the client key may not route to.
+func filterClientProviders(ctx context.Context, handlerType, modelName string, providers []string) ([]string, *interfaces.ErrorMessage) {
+	filtered, v := policy.Default().FilterProviders(clientAPIKey(ctx), modelName, providers)
+	if v != nil {
+		return nil, policyErrorMessage(handlerType, v)
+	}
+	return filtered, nil
+}
+
+// policyErrorMessage renders a policy violation in the error format native to the client API.
+// WriteErrorResponse passes JSON error texts through unchanged.
+func policyErrorMessage(handlerType string, v *policy.Violation) *interfaces.ErrorMessage {
+	msg := &interfaces.ErrorMessage{
+		StatusCode: v.Status,
+		Error:      errors.New(string(buildPolicyErrorBody(handlerType, v))),
+	}
+	if v.RetryAfter > 0 {
+		msg.Addon = http.Header{}
+		msg.Addon.Set("Retry-After", strconv.Itoa(int(math.Ceil(v.RetryAfter.Seconds()))))
+	}
+	return msg
+}
+
+func buildPolicyErrorBody(handlerType string, v *policy.Violation) []byte {
+	var payload any
+	switch handlerType {
+	case "claude":
+		errType := "permission_error"
+		if v.Status == http.StatusTooManyRequests {
+			errType = "rate_limit_error"
+		}
+		payload = map[string]any{
+			"type":  "error",
+			"error": map[string]string{"type": errType, "message": v.Message},
+		}
+	case "gemini", "gemini-cli":
+		status := "PERMISSION_DENIED"
+		if v.Status == http.StatusTooManyRequests {
+			status = "RESOURCE_EXHAUSTED"
+		}
+		payload = map[string]any{
+			"error": map[string]any{"code": v.Status, "message": v.Message, "status": status},
+		}
+	default:
+		errType := "permission_error"
+		switch v.Code {
+		case policy.CodeBudgetExceeded:
+			errType = "insufficient_quota"
+		case policy.CodeRateLimitExceeded:
+			errType = "rate_limit_error"
+		}
+		payload = ErrorResponse{Error: ErrorDetail{Message: v.Message, Type: errType, Code: v.Code}}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return []byte(fmt.Sprintf(`{"error":{"message":%q}}`, v.Message))
+	}
+	return body
+}
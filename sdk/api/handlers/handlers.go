@@ -17,6 +17,7 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tracing"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
@@ -140,19 +141,35 @@ func StreamingBootstrapRetries(cfg *config.SDKConfig) int {
 	return retries
 }
 
-func requestExecutionMetadata(ctx context.Context) map[string]any {
+// StreamingFailoverRetries returns how many times a stream may be resumed on another credential after bytes were sent.
+func StreamingFailoverRetries(cfg *config.SDKConfig) int {
+	if cfg == nil || cfg.Streaming.FailoverRetries < 0 {
+		return 0
+	}
+	return cfg.Streaming.FailoverRetries
+}
+
+func (h *BaseAPIHandler) requestExecutionMetadata(ctx context.Context) map[string]any {
 	// Idempotency-Key is an optional client-supplied header used to correlate retries.
 	// It is forwarded as execution metadata; when absent we generate a UUID.
 	key := ""
+	sessionID := ""
 	if ctx != nil {
 		if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil && ginCtx.Request != nil {
 			key = strings.TrimSpace(ginCtx.GetHeader("Idempotency-Key"))
+			if h != nil && h.Cfg != nil && h.Cfg.SessionAffinity.Header != "" {
+				sessionID = strings.TrimSpace(ginCtx.GetHeader(h.Cfg.SessionAffinity.Header))
+			}
 		}
 	}
 	if key == "" {
 		key = uuid.NewString()
 	}
-	return map[string]any{idempotencyKeyMetadataKey: key}
+	meta := map[string]any{idempotencyKeyMetadataKey: key}
+	if sessionID != "" {
+		meta[coreexecutor.SessionIDMetadataKey] = sessionID
+	}
+	return meta
 }
 
 // BaseAPIHandler contains the handlers for API endpoints.
@@ -241,6 +258,9 @@ func (h *BaseAPIHandler) GetContextWithCancel(handler interfaces.APIHandler, c *
 			parentCtx = logging.WithRequestID(parentCtx, requestID)
 		}
 	}
+	if requestCtx != nil {
+		parentCtx = tracing.WithSpanFrom(parentCtx, requestCtx)
+	}
 	newCtx, cancel := context.WithCancel(parentCtx)
 	if requestCtx != nil && requestCtx != parentCtx {
 		go func() {
@@ -371,11 +391,16 @@ func appendAPIResponse(c *gin.Context, data []byte) {
 // ExecuteWithAuthManager executes a non-streaming request via the core auth manager.
 // This path is the only supported execution route.
 func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) ([]byte, *interfaces.ErrorMessage) {
-	providers, normalizedModel, errMsg := h.getRequestDetails(modelName)
+	ctx, span := startExecuteSpan(ctx, handlerType, modelName, false)
+	defer span.End()
+	ctx, providers, normalizedModel, errMsg := h.resolveClientRequest(ctx, handlerType, modelName, rawJSON)
 	if errMsg != nil {
+		span.RecordError(errMsg.Error)
 		return nil, errMsg
 	}
-	reqMeta := requestExecutionMetadata(ctx)
+	ctx = coreauth.WithClientKey(ctx, clientAPIKey(ctx))
+	ctx = withCredentialTags(ctx)
+	reqMeta := h.requestExecutionMetadata(ctx)
 	reqMeta[coreexecutor.RequestedModelMetadataKey] = normalizedModel
 	payload := rawJSON
 	if len(payload) == 0 {
@@ -406,6 +431,7 @@ func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType
 				addon = hdr.Clone()
 			}
 		}
+		span.RecordError(err)
 		return nil, &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
 	}
 	return resp.Payload, nil
@@ -414,11 +440,16 @@ func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType
 // ExecuteCountWithAuthManager executes a non-streaming request via the core auth manager.
 // This path is the only supported execution route.
 func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) ([]byte, *interfaces.ErrorMessage) {
-	providers, normalizedModel, errMsg := h.getRequestDetails(modelName)
+	ctx, span := startExecuteSpan(ctx, handlerType, modelName, false)
+	defer span.End()
+	ctx, providers, normalizedModel, errMsg := h.resolveClientRequest(ctx, handlerType, modelName, rawJSON)
 	if errMsg != nil {
+		span.RecordError(errMsg.Error)
 		return nil, errMsg
 	}
-	reqMeta := requestExecutionMetadata(ctx)
+	ctx = coreauth.WithClientKey(ctx, clientAPIKey(ctx))
+	ctx = withCredentialTags(ctx)
+	reqMeta := h.requestExecutionMetadata(ctx)
 	reqMeta[coreexecutor.RequestedModelMetadataKey] = normalizedModel
 	payload := rawJSON
 	if len(payload) == 0 {
@@ -449,6 +480,7 @@ func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handle
 				addon = hdr.Clone()
 			}
 		}
+		span.RecordError(err)
 		return nil, &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
 	}
 	return resp.Payload, nil
@@ -457,14 +489,19 @@ func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handle
 // ExecuteStreamWithAuthManager executes a streaming request via the core auth manager.
 // This path is the only supported execution route.
 func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) (<-chan []byte, <-chan *interfaces.ErrorMessage) {
-	providers, normalizedModel, errMsg := h.getRequestDetails(modelName)
+	ctx, span := startExecuteSpan(ctx, handlerType, modelName, true)
+	ctx, providers, normalizedModel, errMsg := h.resolveClientRequest(ctx, handlerType, modelName, rawJSON)
 	if errMsg != nil {
+		span.RecordError(errMsg.Error)
+		span.End()
 		errChan := make(chan *interfaces.ErrorMessage, 1)
 		errChan <- errMsg
 		close(errChan)
 		return nil, errChan
 	}
-	reqMeta := requestExecutionMetadata(ctx)
+	ctx = coreauth.WithClientKey(ctx, clientAPIKey(ctx))
+	ctx = withCredentialTags(ctx)
+	reqMeta := h.requestExecutionMetadata(ctx)
 	reqMeta[coreexecutor.RequestedModelMetadataKey] = normalizedModel
 	payload := rawJSON
 	if len(payload) == 0 {
@@ -481,7 +518,23 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 		SourceFormat:    sdktranslator.FromString(handlerType),
 	}
 	opts.Metadata = reqMeta
-	chunks, err := h.AuthManager.ExecuteStream(ctx, providers, req, opts)
+	maxFailovers := StreamingFailoverRetries(h.Cfg)
+	var resumer streamResumer
+	if maxFailovers > 0 {
+		resumer = newStreamResumer(handlerType)
+	}
+	// usedAuths collects the credentials that served this stream so a mid-stream failover can avoid them.
+	var usedAuths []string
+	var usedAuthsMu sync.Mutex
+	execCtx := ctx
+	if resumer != nil {
+		execCtx = coreauth.WithSelectionObserver(ctx, func(authID string) {
+			usedAuthsMu.Lock()
+			usedAuths = append(usedAuths, authID)
+			usedAuthsMu.Unlock()
+		})
+	}
+	chunks, err := h.AuthManager.ExecuteStream(execCtx, providers, req, opts)
 	if err != nil {
 		errChan := make(chan *interfaces.ErrorMessage, 1)
 		status := http.StatusInternalServerError
@@ -498,6 +551,8 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 		}
 		errChan <- &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
 		close(errChan)
+		span.RecordError(err)
+		span.End()
 		return nil, errChan
 	}
 	dataChan := make(chan []byte)
@@ -507,6 +562,14 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 		defer close(errChan)
 		sentPayload := false
 		bootstrapRetries := 0
+		failovers := 0
+		defer func() {
+			span.SetAttribute("cliproxy.bootstrap_retries", bootstrapRetries)
+			if failovers > 0 {
+				span.SetAttribute("cliproxy.stream_failovers", failovers)
+			}
+			span.End()
+		}()
 		maxBootstrapRetries := StreamingBootstrapRetries(h.Cfg)
 
 		sendErr := func(msg *interfaces.ErrorMessage) bool {
@@ -573,7 +636,7 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 					if !sentPayload {
 						if bootstrapRetries < maxBootstrapRetries && bootstrapEligible(streamErr) {
 							bootstrapRetries++
-							retryChunks, retryErr := h.AuthManager.ExecuteStream(ctx, providers, req, opts)
+							retryChunks, retryErr := h.AuthManager.ExecuteStream(execCtx, providers, req, opts)
 							if retryErr == nil {
 								chunks = retryChunks
 								continue outer
@@ -581,6 +644,25 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 							streamErr = retryErr
 						}
 					}
+					// Mid-stream failover: once bytes were sent, continue the answer on another credential
+					// with the partial output as a prefill and splice the continuation into this stream.
+					if sentPayload && resumer != nil && failovers < maxFailovers && bootstrapEligible(streamErr) && resumer.resumable() {
+						failovers++
+						usedAuthsMu.Lock()
+						excluded := append([]string(nil), usedAuths...)
+						usedAuthsMu.Unlock()
+						resumedChunks, resumeErr := h.resumeStream(execCtx, providers, req, opts, resumer, excluded)
+						if resumeErr == nil {
+							chunks = resumedChunks
+							if prelude := resumer.begin(); len(prelude) > 0 {
+								resumer.observe(prelude)
+								if okSendData := sendData(prelude); !okSendData {
+									return
+								}
+							}
+							continue outer
+						}
+					}
 
 					status := http.StatusInternalServerError
 					if se, ok := streamErr.(interface{ StatusCode() int }); ok && se != nil {
@@ -594,12 +676,23 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 							addon = hdr.Clone()
 						}
 					}
+					span.RecordError(streamErr)
 					_ = sendErr(&interfaces.ErrorMessage{StatusCode: status, Error: streamErr, Addon: addon})
 					return
 				}
 				if len(chunk.Payload) > 0 {
+					payload := cloneBytes(chunk.Payload)
+					if resumer != nil {
+						if failovers > 0 {
+							payload = resumer.splice(payload)
+							if len(payload) == 0 {
+								continue
+							}
+						}
+						resumer.observe(payload)
+					}
 					sentPayload = true
-					if okSendData := sendData(cloneBytes(chunk.Payload)); !okSendData {
+					if okSendData := sendData(payload); !okSendData {
 						return
 					}
 				}
@@ -609,6 +702,32 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 	return dataChan, errChan
 }
 
+// resumeStream re-issues a stream that failed part-way through as a continuation of the output the
+// client already received. It prefers credentials that have not served this stream yet and falls
+// back to any available credential when none is left.
+func (h *BaseAPIHandler) resumeStream(ctx context.Context, providers []string, req coreexecutor.Request, opts coreexecutor.Options, resumer streamResumer, usedAuths []string) (<-chan coreexecutor.StreamChunk, error) {
+	payload, err := resumer.continuation(opts.OriginalRequest)
+	if err != nil {
+		return nil, err
+	}
+	req.Payload = payload
+	opts.OriginalRequest = payload
+	chunks, err := h.AuthManager.ExecuteStream(coreauth.WithExcludedAuths(ctx, usedAuths...), providers, req, opts)
+	if err == nil {
+		return chunks, nil
+	}
+	return h.AuthManager.ExecuteStream(ctx, providers, req, opts)
+}
+
+// startExecuteSpan opens the span covering one dispatch through the auth manager.
+func startExecuteSpan(ctx context.Context, handlerType, modelName string, stream bool) (context.Context, *tracing.Span) {
+	ctx, span := tracing.Start(ctx, "cliproxy.execute")
+	span.SetAttribute("cliproxy.handler", handlerType)
+	span.SetAttribute("cliproxy.model", modelName)
+	span.SetAttribute("cliproxy.stream", stream)
+	return ctx, span
+}
+
 func statusFromError(err error) int {
 	if err == nil {
 		return 0
@@ -0,0 +1,299 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/responsestore"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// GetResponse handles GET /v1/responses/{id} by returning a stored response object.
+//
+// Parameters:
+//   - c: The Gin context containing the HTTP request and response
+func (h *OpenAIResponsesAPIHandler) GetResponse(c *gin.Context) {
+	record, ok := loadStoredResponse(c)
+	if !ok {
+		return
+	}
+	c.Data(http.StatusOK, "application/json", record.Response)
+}
+
+// DeleteResponse handles DELETE /v1/responses/{id} by removing a stored response.
+//
+// Parameters:
+//   - c: The Gin context containing the HTTP request and response
+func (h *OpenAIResponsesAPIHandler) DeleteResponse(c *gin.Context) {
+	id := c.Param("id")
+	if _, ok := loadStoredResponse(c); !ok {
+		return
+	}
+	if err := responsestore.GetStore().Delete(c.Request.Context(), id); err != nil {
+		if errors.Is(err, responsestore.ErrNotFound) {
+			writeResponseNotFound(c, id)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{
+				Message: fmt.Sprintf("Failed to delete response: %v", err),
+				Type:    "server_error",
+			},
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":      id,
+		"object":  "response",
+		"deleted": true,
+	})
+}
+
+// ListInputItems handles GET /v1/responses/{id}/input_items.
+// It supports the "order" (asc|desc, default desc), "limit" (1-100, default 20) and
+// "after" (item ID cursor) query parameters.
+//
+// Parameters:
+//   - c: The Gin context containing the HTTP request and response
+func (h *OpenAIResponsesAPIHandler) ListInputItems(c *gin.Context) {
+	record, ok := loadStoredResponse(c)
+	if !ok {
+		return
+	}
+
+	items := gjson.ParseBytes(record.Input).Array()
+	data := make([]string, 0, len(items))
+	for i, item := range items {
+		raw := item.Raw
+		if !item.Get("id").Exists() {
+			raw, _ = sjson.Set(raw, "id", fmt.Sprintf("item_%s_%d", strings.TrimPrefix(record.ID, "resp_"), i))
+		}
+		data = append(data, raw)
+	}
+	if c.DefaultQuery("order", "desc") != "asc" {
+		for i, j := 0, len(data)-1; i < j; i, j = i+1, j-1 {
+			data[i], data[j] = data[j], data[i]
+		}
+	}
+	if after := c.Query("after"); after != "" {
+		for i := range data {
+			if gjson.Get(data[i], "id").String() == after {
+				data = data[i+1:]
+				break
+			}
+		}
+	}
+	limit := 20
+	if v, err := strconv.Atoi(c.Query("limit")); err == nil && v > 0 {
+		limit = min(v, 100)
+	}
+	hasMore := len(data) > limit
+	if hasMore {
+		data = data[:limit]
+	}
+
+	out := `{"object":"list","data":[],"first_id":null,"last_id":null,"has_more":false}`
+	for _, item := range data {
+		out, _ = sjson.SetRaw(out, "data.-1", item)
+	}
+	if len(data) > 0 {
+		out, _ = sjson.Set(out, "first_id", gjson.Get(data[0], "id").String())
+		out, _ = sjson.Set(out, "last_id", gjson.Get(data[len(data)-1], "id").String())
+	}
+	out, _ = sjson.Set(out, "has_more", hasMore)
+	c.Data(http.StatusOK, "application/json", []byte(out))
+}
+
+// responseOwner returns the OwnerKey of the client that authenticated the request.
+func responseOwner(c *gin.Context) string {
+	if v, exists := c.Get("apiKey"); exists {
+		if key, ok := v.(string); ok {
+			return responsestore.OwnerKey(key)
+		}
+	}
+	return ""
+}
+
+// loadStoredResponse loads the response named by the id path parameter. Responses created by
+// another client are reported as not found.
+func loadStoredResponse(c *gin.Context) (*responsestore.Record, bool) {
+	id := c.Param("id")
+	store := responsestore.GetStore()
+	if store == nil {
+		writeResponseNotFound(c, id)
+		return nil, false
+	}
+	record, err := store.Load(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, responsestore.ErrNotFound) {
+			writeResponseNotFound(c, id)
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{
+				Message: fmt.Sprintf("Failed to load response: %v", err),
+				Type:    "server_error",
+			},
+		})
+		return nil, false
+	}
+	if !record.OwnedBy(responseOwner(c)) {
+		writeResponseNotFound(c, id)
+		return nil, false
+	}
+	return record, true
+}
+
+func writeResponseNotFound(c *gin.Context, id string) {
+	c.JSON(http.StatusNotFound, handlers.ErrorResponse{
+		Error: handlers.ErrorDetail{
+			Message: fmt.Sprintf("No response found with id '%s'.", id),
+			Type:    "invalid_request_error",
+		},
+	})
+}
+
+// expandPreviousResponse rewrites the request input so it carries the full conversation.
+// When previous_response_id names a stored response, that response's input items and
+// replayable output items are prepended to the current input. Only responses created by
+// owner may be continued. previous_response_id itself is left in place so translators can
+// echo it back. The returned input is the array that will be stored alongside the new response.
+func expandPreviousResponse(ctx context.Context, owner string, rawJSON []byte) ([]byte, json.RawMessage, *handlers.ErrorResponse, int) {
+	current := normalizeResponsesInput(gjson.GetBytes(rawJSON, "input"))
+	store := responsestore.GetStore()
+	previousID := strings.TrimSpace(gjson.GetBytes(rawJSON, "previous_response_id").String())
+	if store == nil || previousID == "" {
+		return rawJSON, json.RawMessage(current), nil, 0
+	}
+
+	record, err := store.Load(ctx, previousID)
+	if err == nil && !record.OwnedBy(owner) {
+		err = responsestore.ErrNotFound
+	}
+	if err != nil {
+		if errors.Is(err, responsestore.ErrNotFound) {
+			return nil, nil, &handlers.ErrorResponse{
+				Error: handlers.ErrorDetail{
+					Message: fmt.Sprintf("Previous response with id '%s' not found.", previousID),
+					Type:    "invalid_request_error",
+					Code:    "previous_response_not_found",
+				},
+			}, http.StatusBadRequest
+		}
+		return nil, nil, &handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{
+				Message: fmt.Sprintf("Failed to load previous response: %v", err),
+				Type:    "server_error",
+			},
+		}, http.StatusInternalServerError
+	}
+
+	combined := "[]"
+	gjson.ParseBytes(record.Input).ForEach(func(_, item gjson.Result) bool {
+		combined, _ = sjson.SetRaw(combined, "-1", item.Raw)
+		return true
+	})
+	gjson.GetBytes(record.Response, "output").ForEach(func(_, item gjson.Result) bool {
+		if replay, ok := replayableOutputItem(item); ok {
+			combined, _ = sjson.SetRaw(combined, "-1", replay)
+		}
+		return true
+	})
+	gjson.Parse(current).ForEach(func(_, item gjson.Result) bool {
+		combined, _ = sjson.SetRaw(combined, "-1", item.Raw)
+		return true
+	})
+
+	expanded, errSet := sjson.SetRawBytes(rawJSON, "input", []byte(combined))
+	if errSet != nil {
+		return rawJSON, json.RawMessage(current), nil, 0
+	}
+	return expanded, json.RawMessage(combined), nil, 0
+}
+
+// normalizeResponsesInput converts the request input into an item array. A plain string
+// becomes a single user message.
+func normalizeResponsesInput(input gjson.Result) string {
+	switch {
+	case input.IsArray():
+		return input.Raw
+	case input.Type == gjson.String:
+		item := `{"type":"message","role":"user","content":[{"type":"input_text","text":""}]}`
+		item, _ = sjson.Set(item, "content.0.text", input.String())
+		return "[" + item + "]"
+	default:
+		return "[]"
+	}
+}
+
+// replayableOutputItem prepares a stored output item for use as input. Item IDs are
+// dropped because backends running with store disabled reject references to items they
+// never persisted; reasoning items are only kept when they carry encrypted content.
+func replayableOutputItem(item gjson.Result) (string, bool) {
+	if item.Get("type").String() == "reasoning" && item.Get("encrypted_content").String() == "" {
+		return "", false
+	}
+	out, err := sjson.Delete(item.Raw, "id")
+	if err != nil {
+		return item.Raw, true
+	}
+	return out, true
+}
+
+// saveResponse stores a completed response for owner unless the client opted out with
+// "store": false.
+func saveResponse(ctx context.Context, owner string, rawJSON []byte, input json.RawMessage, response []byte) {
+	store := responsestore.GetStore()
+	if store == nil || gjson.GetBytes(rawJSON, "store").Type == gjson.False {
+		return
+	}
+	id := gjson.GetBytes(response, "id").String()
+	if id == "" || !gjson.ValidBytes(response) {
+		return
+	}
+	record := &responsestore.Record{
+		ID:       id,
+		Owner:    owner,
+		Model:    gjson.GetBytes(rawJSON, "model").String(),
+		Input:    input,
+		Response: append(json.RawMessage(nil), response...),
+	}
+	if err := store.Save(ctx, record); err != nil {
+		log.Warnf("failed to store response %s: %v", id, err)
+	}
+}
+
+// completedResponseFromChunk extracts the response object from a response.completed
+// SSE event contained in chunk.
+func completedResponseFromChunk(chunk []byte) ([]byte, bool) {
+	if !bytes.Contains(chunk, []byte("response.completed")) {
+		return nil, false
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(chunk))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(chunk)+1)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+		payload := bytes.TrimSpace(line[len("data:"):])
+		if gjson.GetBytes(payload, "type").String() != "response.completed" {
+			continue
+		}
+		if resp := gjson.GetBytes(payload, "response"); resp.IsObject() {
+			return []byte(resp.Raw), true
+		}
+	}
+	return nil, false
+}
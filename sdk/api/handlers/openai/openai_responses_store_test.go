@@ -0,0 +1,132 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/responsestore"
+	"github.com/tidwall/gjson"
+)
+
+func TestExpandPreviousResponse(t *testing.T) {
+	store := responsestore.NewFileStore(t.TempDir(), time.Hour)
+	responsestore.RegisterStore(store)
+	t.Cleanup(func() { responsestore.RegisterStore(nil) })
+
+	owner := responsestore.OwnerKey("client-a")
+	record := &responsestore.Record{
+		ID:    "resp_prev",
+		Owner: owner,
+		Input: json.RawMessage(`[{"type":"message","role":"user","content":[{"type":"input_text","text":"first"}]}]`),
+		Response: json.RawMessage(`{"id":"resp_prev","output":[` +
+			`{"type":"reasoning","id":"rs_1","summary":[]},` +
+			`{"type":"message","id":"msg_1","role":"assistant","content":[{"type":"output_text","text":"answer"}]}]}`),
+	}
+	if err := store.Save(context.Background(), record); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	raw := []byte(`{"model":"test-model","previous_response_id":"resp_prev","input":"second"}`)
+	expanded, input, errResp, _ := expandPreviousResponse(context.Background(), owner, raw)
+	if errResp != nil {
+		t.Fatalf("unexpected error: %+v", errResp)
+	}
+	items := gjson.GetBytes(expanded, "input").Array()
+	if len(items) != 3 {
+		t.Fatalf("input items = %d, want 3: %s", len(items), expanded)
+	}
+	if got := items[1].Get("content.0.text").String(); got != "answer" {
+		t.Fatalf("replayed output text = %q, want answer", got)
+	}
+	if items[1].Get("id").Exists() {
+		t.Fatalf("replayed output item kept its id: %s", items[1].Raw)
+	}
+	if got := items[2].Get("content.0.text").String(); got != "second" {
+		t.Fatalf("current input text = %q, want second", got)
+	}
+	if gjson.GetBytes(expanded, "previous_response_id").String() != "resp_prev" {
+		t.Fatalf("previous_response_id should be preserved for echoing")
+	}
+	if string(input) != gjson.GetBytes(expanded, "input").Raw {
+		t.Fatalf("returned input does not match expanded payload")
+	}
+
+	_, _, errResp, status := expandPreviousResponse(context.Background(), owner, []byte(`{"previous_response_id":"resp_missing"}`))
+	if errResp == nil || status != http.StatusBadRequest {
+		t.Fatalf("missing previous response: status = %d, err = %+v", status, errResp)
+	}
+
+	_, _, errResp, status = expandPreviousResponse(context.Background(), responsestore.OwnerKey("client-b"), raw)
+	if errResp == nil || status != http.StatusBadRequest {
+		t.Fatalf("previous response of another client: status = %d, err = %+v", status, errResp)
+	}
+}
+
+func TestResponsesStoreEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := responsestore.NewFileStore(t.TempDir(), time.Hour)
+	responsestore.RegisterStore(store)
+	t.Cleanup(func() { responsestore.RegisterStore(nil) })
+
+	saveResponse(context.Background(), responsestore.OwnerKey("client-a"),
+		[]byte(`{"model":"test-model"}`),
+		json.RawMessage(`[{"type":"message","role":"user","content":"a"},{"type":"message","role":"user","content":"b"}]`),
+		[]byte(`{"id":"resp_abc","object":"response","output":[]}`))
+
+	h := &OpenAIResponsesAPIHandler{}
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("apiKey", c.GetHeader("Authorization"))
+	})
+	router.GET("/v1/responses/:id", h.GetResponse)
+	router.DELETE("/v1/responses/:id", h.DeleteResponse)
+	router.GET("/v1/responses/:id/input_items", h.ListInputItems)
+
+	serve := func(method, path, client string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", client)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	if resp := serve(http.MethodGet, "/v1/responses/resp_abc", "client-b"); resp.Code != http.StatusNotFound {
+		t.Fatalf("get by another client: status = %d, want 404", resp.Code)
+	}
+	if resp := serve(http.MethodDelete, "/v1/responses/resp_abc", "client-b"); resp.Code != http.StatusNotFound {
+		t.Fatalf("delete by another client: status = %d, want 404", resp.Code)
+	}
+	if resp := serve(http.MethodGet, "/v1/responses/resp_abc/input_items", "client-b"); resp.Code != http.StatusNotFound {
+		t.Fatalf("input_items by another client: status = %d, want 404", resp.Code)
+	}
+
+	resp := serve(http.MethodGet, "/v1/responses/resp_abc", "client-a")
+	if resp.Code != http.StatusOK || gjson.Get(resp.Body.String(), "id").String() != "resp_abc" {
+		t.Fatalf("get: status = %d, body = %s", resp.Code, resp.Body.String())
+	}
+
+	resp = serve(http.MethodGet, "/v1/responses/resp_abc/input_items?order=asc&limit=1", "client-a")
+	body := resp.Body.String()
+	if resp.Code != http.StatusOK || gjson.Get(body, "data.#").Int() != 1 || !gjson.Get(body, "has_more").Bool() {
+		t.Fatalf("input_items: status = %d, body = %s", resp.Code, body)
+	}
+	if got := gjson.Get(body, "data.0.content").String(); got != "a" {
+		t.Fatalf("first ascending item = %q, want a", got)
+	}
+
+	resp = serve(http.MethodDelete, "/v1/responses/resp_abc", "client-a")
+	if resp.Code != http.StatusOK || !strings.Contains(resp.Body.String(), `"deleted":true`) {
+		t.Fatalf("delete: status = %d, body = %s", resp.Code, resp.Body.String())
+	}
+
+	resp = serve(http.MethodGet, "/v1/responses/resp_abc", "client-a")
+	if resp.Code != http.StatusNotFound {
+		t.Fatalf("get after delete: status = %d, want 404", resp.Code)
+	}
+}
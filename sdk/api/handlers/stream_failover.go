@@ -0,0 +1,464 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// streamResumer tracks what a client stream has received so that a stream whose upstream fails
+// part-way through can be re-issued on another credential with the partial answer as an
+// assistant prefill, and the continuation spliced into the same client response.
+type streamResumer interface {
+	// observe records a chunk that was forwarded to the client.
+	observe(chunk []byte)
+	// resumable reports whether the forwarded output can be reproduced as a prefill.
+	resumable() bool
+	// continuation returns the client request extended with the partial assistant output.
+	continuation(raw []byte) ([]byte, error)
+	// begin prepares splicing of a new continuation stream and returns the events that must be
+	// sent first to close any content left open by the failed stream.
+	begin() []byte
+	// splice rewrites a continuation chunk so it extends the client stream. It returns nil when
+	// the chunk only repeats framing the client has already received.
+	splice(chunk []byte) []byte
+}
+
+// newStreamResumer returns the resumer for a client format, or nil when the format cannot be resumed.
+func newStreamResumer(handlerType string) streamResumer {
+	switch handlerType {
+	case constant.Claude:
+		return &claudeStreamResumer{open: -1}
+	case constant.OpenAI:
+		return &openAIChatStreamResumer{}
+	case constant.OpenaiResponse:
+		return &responsesStreamResumer{items: make(map[int]string)}
+	default:
+		return nil
+	}
+}
+
+// rewriteStreamChunk applies fn to every JSON event of a stream chunk, which is either a bare JSON
+// document or one or more SSE frames. Events dropped by fn are removed together with their event
+// line; nil is returned when no event is left.
+func rewriteStreamChunk(chunk []byte, fn func(data []byte) ([]byte, bool)) []byte {
+	trimmed := bytes.TrimSpace(chunk)
+	if len(trimmed) == 0 {
+		return chunk
+	}
+	if trimmed[0] == '{' {
+		out, keep := fn(trimmed)
+		if !keep {
+			return nil
+		}
+		return out
+	}
+	lines := bytes.Split(chunk, []byte("\n"))
+	out := make([][]byte, 0, len(lines))
+	kept := false
+	dropBlank := false
+	for _, line := range lines {
+		if dropBlank && len(bytes.TrimSpace(line)) == 0 {
+			dropBlank = false
+			continue
+		}
+		dropBlank = false
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			out = append(out, line)
+			continue
+		}
+		data := bytes.TrimSpace(line[len("data:"):])
+		if len(data) == 0 || data[0] != '{' {
+			out = append(out, line)
+			kept = true
+			continue
+		}
+		rewritten, keep := fn(data)
+		if !keep {
+			if n := len(out); n > 0 && bytes.HasPrefix(out[n-1], []byte("event:")) {
+				out = out[:n-1]
+			}
+			dropBlank = true
+			continue
+		}
+		out = append(out, append([]byte("data: "), rewritten...))
+		kept = true
+	}
+	if !kept {
+		return nil
+	}
+	return bytes.Join(out, []byte("\n"))
+}
+
+// forEachStreamEvent calls fn for every JSON event of a stream chunk.
+func forEachStreamEvent(chunk []byte, fn func(event gjson.Result)) {
+	rewriteStreamChunk(chunk, func(data []byte) ([]byte, bool) {
+		fn(gjson.ParseBytes(data))
+		return data, true
+	})
+}
+
+// appendChatPrefill appends text as a trailing assistant message of a messages-based request,
+// extending a trailing assistant message the client already supplied as its own prefill.
+func appendChatPrefill(raw []byte, text string) ([]byte, error) {
+	messages := gjson.GetBytes(raw, "messages")
+	if !messages.IsArray() {
+		return nil, errors.New("request has no messages to continue")
+	}
+	items := messages.Array()
+	if n := len(items); n > 0 && items[n-1].Get("role").String() == "assistant" {
+		content := items[n-1].Get("content")
+		path := fmt.Sprintf("messages.%d.content", n-1)
+		switch {
+		case content.Type == gjson.String:
+			return sjson.SetBytes(raw, path, content.String()+text)
+		case content.IsArray():
+			return sjson.SetBytes(raw, path+".-1", map[string]any{"type": "text", "text": text})
+		}
+	}
+	return sjson.SetBytes(raw, "messages.-1", map[string]any{"role": "assistant", "content": text})
+}
+
+func sseEvent(name string, payload any) string {
+	data, _ := json.Marshal(payload)
+	return "event: " + name + "\ndata: " + string(data)
+}
+
+// claudeStreamResumer resumes Anthropic Messages streams. The block left open by the failed
+// stream is closed and the continuation's blocks are renumbered after it; thinking blocks of
+// the continuation are dropped because the client already received the reasoning.
+type claudeStreamResumer struct {
+	text     strings.Builder
+	blocks   int
+	open     int
+	blocked  bool
+	finished bool
+
+	next     int
+	indexMap map[int]int
+	trimLead bool
+}
+
+func (r *claudeStreamResumer) observe(chunk []byte) {
+	forEachStreamEvent(chunk, func(event gjson.Result) {
+		index := int(event.Get("index").Int())
+		switch event.Get("type").String() {
+		case "content_block_start":
+			if index+1 > r.blocks {
+				r.blocks = index + 1
+			}
+			r.open = index
+			switch event.Get("content_block.type").String() {
+			case "text", "thinking", "redacted_thinking":
+			default:
+				r.blocked = true
+			}
+		case "content_block_delta":
+			if event.Get("delta.type").String() == "text_delta" {
+				r.text.WriteString(event.Get("delta.text").String())
+			}
+		case "content_block_stop":
+			if index == r.open {
+				r.open = -1
+			}
+		case "message_delta":
+			if event.Get("delta.stop_reason").String() != "" {
+				r.finished = true
+			}
+		case "message_stop":
+			r.finished = true
+		}
+	})
+}
+
+func (r *claudeStreamResumer) resumable() bool {
+	return !r.blocked && !r.finished && strings.TrimSpace(r.text.String()) != ""
+}
+
+// continuation trims trailing whitespace because Anthropic rejects a final assistant turn
+// ending in whitespace; the first continuation delta is left-trimmed to match.
+func (r *claudeStreamResumer) continuation(raw []byte) ([]byte, error) {
+	text := r.text.String()
+	prefill := strings.TrimRight(text, " \t\r\n")
+	r.trimLead = len(prefill) != len(text)
+	return appendChatPrefill(raw, prefill)
+}
+
+func (r *claudeStreamResumer) begin() []byte {
+	r.indexMap = make(map[int]int)
+	r.next = r.blocks
+	if r.open < 0 {
+		return nil
+	}
+	return []byte(sseEvent("content_block_stop", map[string]any{"type": "content_block_stop", "index": r.open}) + "\n\n")
+}
+
+func (r *claudeStreamResumer) splice(chunk []byte) []byte {
+	return rewriteStreamChunk(chunk, func(data []byte) ([]byte, bool) {
+		event := gjson.ParseBytes(data)
+		index := int(event.Get("index").Int())
+		switch event.Get("type").String() {
+		case "message_start", "ping":
+			return nil, false
+		case "content_block_start":
+			switch event.Get("content_block.type").String() {
+			case "thinking", "redacted_thinking":
+				r.indexMap[index] = -1
+				return nil, false
+			}
+			r.indexMap[index] = r.next
+			r.next++
+		case "content_block_delta", "content_block_stop":
+			mapped, ok := r.indexMap[index]
+			if !ok || mapped < 0 {
+				return nil, false
+			}
+			if r.trimLead && event.Get("delta.type").String() == "text_delta" {
+				r.trimLead = false
+				data, _ = sjson.SetBytes(data, "delta.text", strings.TrimLeft(event.Get("delta.text").String(), " \t\r\n"))
+			}
+		default:
+			return data, true
+		}
+		data, _ = sjson.SetBytes(data, "index", r.indexMap[index])
+		return data, true
+	})
+}
+
+// openAIChatStreamResumer resumes Chat Completions streams by keeping the original completion
+// id and dropping the role and reasoning deltas the continuation repeats.
+type openAIChatStreamResumer struct {
+	text     strings.Builder
+	id       string
+	created  string
+	blocked  bool
+	finished bool
+}
+
+func (r *openAIChatStreamResumer) observe(chunk []byte) {
+	forEachStreamEvent(chunk, func(event gjson.Result) {
+		if r.id == "" {
+			r.id = event.Get("id").String()
+			r.created = event.Get("created").Raw
+		}
+		for _, choice := range event.Get("choices").Array() {
+			if choice.Get("index").Int() != 0 || choice.Get("delta.tool_calls").Exists() || choice.Get("delta.function_call").Exists() {
+				r.blocked = true
+			}
+			r.text.WriteString(choice.Get("delta.content").String())
+			if choice.Get("finish_reason").String() != "" {
+				r.finished = true
+			}
+		}
+	})
+}
+
+func (r *openAIChatStreamResumer) resumable() bool {
+	return !r.blocked && !r.finished && strings.TrimSpace(r.text.String()) != ""
+}
+
+func (r *openAIChatStreamResumer) continuation(raw []byte) ([]byte, error) {
+	return appendChatPrefill(raw, r.text.String())
+}
+
+func (r *openAIChatStreamResumer) begin() []byte { return nil }
+
+func (r *openAIChatStreamResumer) splice(chunk []byte) []byte {
+	return rewriteStreamChunk(chunk, func(data []byte) ([]byte, bool) {
+		if r.id != "" {
+			data, _ = sjson.SetBytes(data, "id", r.id)
+		}
+		if r.created != "" {
+			data, _ = sjson.SetRawBytes(data, "created", []byte(r.created))
+		}
+		data, _ = sjson.DeleteBytes(data, "choices.0.delta.role")
+		data, _ = sjson.DeleteBytes(data, "choices.0.delta.reasoning_content")
+		event := gjson.ParseBytes(data)
+		choice := event.Get("choices.0")
+		if choice.Exists() && choice.Get("delta").Raw == "{}" && choice.Get("finish_reason").String() == "" && !event.Get("usage").Exists() {
+			return nil, false
+		}
+		return data, true
+	})
+}
+
+// responsesStreamResumer resumes OpenAI Responses streams. The message item left open by the
+// failed stream is completed, continuation items are appended after it under the original
+// response id, sequence numbers stay monotonic and the final response lists every item.
+type responsesStreamResumer struct {
+	text       strings.Builder
+	responseID string
+	lastSeq    int64
+	outputs    int
+	items      map[int]string
+	blocked    bool
+	finished   bool
+
+	message  responsesOpenMessage
+	base     int
+	next     int
+	indexMap map[int]int
+}
+
+// responsesOpenMessage is the message output item currently streaming on the client response.
+type responsesOpenMessage struct {
+	id      string
+	index   int
+	content int
+	text    strings.Builder
+	open    bool
+}
+
+func (r *responsesStreamResumer) observe(chunk []byte) {
+	forEachStreamEvent(chunk, func(event gjson.Result) {
+		if seq := event.Get("sequence_number"); seq.Exists() && seq.Int() > r.lastSeq {
+			r.lastSeq = seq.Int()
+		}
+		index := int(event.Get("output_index").Int())
+		switch event.Get("type").String() {
+		case "response.created":
+			r.responseID = event.Get("response.id").String()
+		case "response.output_item.added":
+			if index+1 > r.outputs {
+				r.outputs = index + 1
+			}
+			switch event.Get("item.type").String() {
+			case "message":
+				r.message.id = event.Get("item.id").String()
+				r.message.index = index
+				r.message.content = 0
+				r.message.text.Reset()
+				r.message.open = true
+			case "reasoning":
+			default:
+				r.blocked = true
+			}
+		case "response.content_part.added":
+			if event.Get("item_id").String() == r.message.id {
+				r.message.content = int(event.Get("content_index").Int())
+			}
+		case "response.output_text.delta":
+			delta := event.Get("delta").String()
+			r.text.WriteString(delta)
+			if event.Get("item_id").String() == r.message.id {
+				r.message.text.WriteString(delta)
+			}
+		case "response.output_item.done":
+			r.items[index] = event.Get("item").Raw
+			if event.Get("item.id").String() == r.message.id {
+				r.message.open = false
+			}
+		case "response.completed", "response.incomplete", "response.failed":
+			r.finished = true
+		}
+	})
+}
+
+func (r *responsesStreamResumer) resumable() bool {
+	return !r.blocked && !r.finished && r.responseID != "" && strings.TrimSpace(r.text.String()) != ""
+}
+
+func (r *responsesStreamResumer) continuation(raw []byte) ([]byte, error) {
+	input := gjson.GetBytes(raw, "input")
+	var err error
+	switch {
+	case input.Type == gjson.String:
+		raw, err = sjson.SetBytes(raw, "input", []any{map[string]any{"type": "message", "role": "user", "content": input.String()}})
+		if err != nil {
+			return nil, err
+		}
+	case !input.IsArray():
+		return nil, errors.New("request has no input to continue")
+	}
+	return sjson.SetBytes(raw, "input.-1", map[string]any{
+		"type":    "message",
+		"role":    "assistant",
+		"content": []any{map[string]any{"type": "output_text", "text": r.text.String()}},
+	})
+}
+
+func (r *responsesStreamResumer) nextSeq() int64 {
+	r.lastSeq++
+	return r.lastSeq
+}
+
+func (r *responsesStreamResumer) begin() []byte {
+	r.indexMap = make(map[int]int)
+	r.base = r.outputs
+	r.next = r.outputs
+	if !r.message.open {
+		return nil
+	}
+	msg := &r.message
+	part := map[string]any{"type": "output_text", "annotations": []any{}, "text": msg.text.String()}
+	events := []string{
+		sseEvent("response.output_text.done", map[string]any{
+			"type": "response.output_text.done", "sequence_number": r.nextSeq(),
+			"item_id": msg.id, "output_index": msg.index, "content_index": msg.content, "text": msg.text.String(),
+		}),
+		sseEvent("response.content_part.done", map[string]any{
+			"type": "response.content_part.done", "sequence_number": r.nextSeq(),
+			"item_id": msg.id, "output_index": msg.index, "content_index": msg.content, "part": part,
+		}),
+		sseEvent("response.output_item.done", map[string]any{
+			"type": "response.output_item.done", "sequence_number": r.nextSeq(), "output_index": msg.index,
+			"item": map[string]any{"id": msg.id, "type": "message", "status": "completed", "role": "assistant", "content": []any{part}},
+		}),
+	}
+	return []byte(strings.Join(events, "\n\n"))
+}
+
+func (r *responsesStreamResumer) splice(chunk []byte) []byte {
+	return rewriteStreamChunk(chunk, func(data []byte) ([]byte, bool) {
+		event := gjson.ParseBytes(data)
+		switch event.Get("type").String() {
+		case "response.created", "response.in_progress":
+			return nil, false
+		}
+		if index := event.Get("output_index"); index.Exists() {
+			mapped, ok := r.indexMap[int(index.Int())]
+			if !ok {
+				mapped = r.next
+				r.next++
+				r.indexMap[int(index.Int())] = mapped
+			}
+			data, _ = sjson.SetBytes(data, "output_index", mapped)
+		}
+		if event.Get("response").IsObject() {
+			data, _ = sjson.SetBytes(data, "response.id", r.responseID)
+			if output := event.Get("response.output"); output.IsArray() {
+				data, _ = sjson.SetRawBytes(data, "response.output", r.mergedOutput(output))
+			}
+		}
+		if event.Get("sequence_number").Exists() {
+			data, _ = sjson.SetBytes(data, "sequence_number", r.nextSeq())
+		}
+		return data, true
+	})
+}
+
+// mergedOutput prepends the items completed before the failover to the continuation's output.
+func (r *responsesStreamResumer) mergedOutput(continued gjson.Result) []byte {
+	indexes := make([]int, 0, len(r.items))
+	for index := range r.items {
+		if index < r.base {
+			indexes = append(indexes, index)
+		}
+	}
+	sort.Ints(indexes)
+	parts := make([]string, 0, len(indexes)+len(continued.Array()))
+	for _, index := range indexes {
+		parts = append(parts, r.items[index])
+	}
+	for _, item := range continued.Array() {
+		parts = append(parts, item.Raw)
+	}
+	return []byte("[" + strings.Join(parts, ",") + "]")
+}
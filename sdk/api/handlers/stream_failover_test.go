@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+type midStreamFailExecutor struct {
+	mu       sync.Mutex
+	auths    []string
+	payloads [][]byte
+}
+
+func (e *midStreamFailExecutor) Identifier() string { return "codex" }
+
+func (e *midStreamFailExecutor) Execute(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, &coreauth.Error{Code: "not_implemented", Message: "Execute not implemented"}
+}
+
+func (e *midStreamFailExecutor) ExecuteStream(_ context.Context, auth *coreauth.Auth, req coreexecutor.Request, _ coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	e.mu.Lock()
+	e.auths = append(e.auths, auth.ID)
+	e.payloads = append(e.payloads, req.Payload)
+	call := len(e.auths)
+	e.mu.Unlock()
+
+	ch := make(chan coreexecutor.StreamChunk, 3)
+	if call == 1 {
+		ch <- coreexecutor.StreamChunk{Payload: []byte(`{"id":"chatcmpl-1","created":1,"choices":[{"index":0,"delta":{"role":"assistant","content":"Hello, "},"finish_reason":null}]}`)}
+		ch <- coreexecutor.StreamChunk{Err: &coreauth.Error{Code: "upstream_closed", Message: "upstream closed", HTTPStatus: http.StatusBadGateway}}
+	} else {
+		ch <- coreexecutor.StreamChunk{Payload: []byte(`{"id":"chatcmpl-2","created":2,"choices":[{"index":0,"delta":{"role":"assistant","content":"world"},"finish_reason":null}]}`)}
+		ch <- coreexecutor.StreamChunk{Payload: []byte(`{"id":"chatcmpl-2","created":2,"choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`)}
+	}
+	close(ch)
+	return ch, nil
+}
+
+func (e *midStreamFailExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *midStreamFailExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, &coreauth.Error{Code: "not_implemented", Message: "CountTokens not implemented"}
+}
+
+func (e *midStreamFailExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, &coreauth.Error{Code: "not_implemented", Message: "HttpRequest not implemented", HTTPStatus: http.StatusNotImplemented}
+}
+
+func TestExecuteStreamWithAuthManager_FailsOverMidStream(t *testing.T) {
+	executor := &midStreamFailExecutor{}
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+	for _, id := range []string{"failover-auth1", "failover-auth2"} {
+		if _, err := manager.Register(context.Background(), &coreauth.Auth{ID: id, Provider: "codex", Status: coreauth.StatusActive}); err != nil {
+			t.Fatalf("manager.Register(%s): %v", id, err)
+		}
+		registry.GetGlobalRegistry().RegisterClient(id, "codex", []*registry.ModelInfo{{ID: "failover-model"}})
+		authID := id
+		t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(authID) })
+	}
+
+	handler := NewBaseAPIHandlers(&sdkconfig.SDKConfig{
+		Streaming: sdkconfig.StreamingConfig{FailoverRetries: 1},
+	}, manager)
+	raw := []byte(`{"model":"failover-model","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	dataChan, errChan := handler.ExecuteStreamWithAuthManager(context.Background(), "openai", "failover-model", raw, "")
+
+	var chunks []string
+	for chunk := range dataChan {
+		chunks = append(chunks, string(chunk))
+	}
+	for msg := range errChan {
+		if msg != nil {
+			t.Fatalf("unexpected error: %+v", msg)
+		}
+	}
+
+	if len(chunks) != 3 {
+		t.Fatalf("expected 3 chunks, got %d: %v", len(chunks), chunks)
+	}
+	for _, chunk := range chunks {
+		if id := gjson.Get(chunk, "id").String(); id != "chatcmpl-1" {
+			t.Fatalf("expected spliced chunks to keep the original id, got %s", chunk)
+		}
+	}
+	if gjson.Get(chunks[1], "choices.0.delta.role").Exists() {
+		t.Fatalf("expected continuation role delta to be dropped, got %s", chunks[1])
+	}
+	if got := gjson.Get(chunks[1], "choices.0.delta.content").String(); got != "world" {
+		t.Fatalf("expected continuation content, got %q", got)
+	}
+	if len(executor.auths) != 2 || executor.auths[0] == executor.auths[1] {
+		t.Fatalf("expected the continuation on a different credential, got %v", executor.auths)
+	}
+	prefill := gjson.GetBytes(executor.payloads[1], "messages.1")
+	if prefill.Get("role").String() != "assistant" || prefill.Get("content").String() != "Hello, " {
+		t.Fatalf("expected assistant prefill in continuation request, got %s", executor.payloads[1])
+	}
+}
+
+func TestClaudeStreamResumer_SplicesContinuation(t *testing.T) {
+	r := newStreamResumer("claude")
+	r.observe([]byte("event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\"}}\n\n"))
+	r.observe([]byte("event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n"))
+	r.observe([]byte("event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"The answer is \"}}\n\n"))
+	if !r.resumable() {
+		t.Fatalf("expected partial text stream to be resumable")
+	}
+
+	payload, err := r.continuation([]byte(`{"messages":[{"role":"user","content":"q"}]}`))
+	if err != nil {
+		t.Fatalf("continuation: %v", err)
+	}
+	if got := gjson.GetBytes(payload, "messages.1.content").String(); got != "The answer is" {
+		t.Fatalf("expected whitespace-trimmed prefill, got %q", got)
+	}
+
+	prelude := string(r.begin())
+	if !strings.Contains(prelude, `{"index":0,"type":"content_block_stop"}`) {
+		t.Fatalf("expected prelude to close the open block, got %q", prelude)
+	}
+	r.observe([]byte(prelude))
+
+	if got := r.splice([]byte("event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_2\"}}\n\n")); got != nil {
+		t.Fatalf("expected continuation message_start to be dropped, got %q", got)
+	}
+	start := string(r.splice([]byte("event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n")))
+	if got := gjson.Get(strings.TrimPrefix(strings.SplitN(start, "\n", 2)[1], "data: "), "index").Int(); got != 1 {
+		t.Fatalf("expected continuation block renumbered to 1, got %q", start)
+	}
+	delta := string(r.splice([]byte("event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\" 42\"}}\n\n")))
+	data := strings.TrimPrefix(strings.SplitN(delta, "\n", 3)[1], "data: ")
+	if gjson.Get(data, "index").Int() != 1 || gjson.Get(data, "delta.text").String() != "42" {
+		t.Fatalf("unexpected spliced delta %q", delta)
+	}
+}
+
+func TestResponsesStreamResumer_KeepsResponseIdentity(t *testing.T) {
+	r := newStreamResumer("openai-response")
+	r.observe([]byte(`event: response.created` + "\n" + `data: {"type":"response.created","sequence_number":0,"response":{"id":"resp_1"}}`))
+	r.observe([]byte(`event: response.output_item.added` + "\n" + `data: {"type":"response.output_item.added","sequence_number":1,"output_index":0,"item":{"id":"msg_1","type":"message"}}`))
+	r.observe([]byte(`event: response.output_text.delta` + "\n" + `data: {"type":"response.output_text.delta","sequence_number":2,"item_id":"msg_1","output_index":0,"content_index":0,"delta":"partial"}`))
+
+	payload, err := r.continuation([]byte(`{"input":"question"}`))
+	if err != nil {
+		t.Fatalf("continuation: %v", err)
+	}
+	if gjson.GetBytes(payload, "input.0.content").String() != "question" || gjson.GetBytes(payload, "input.1.content.0.text").String() != "partial" {
+		t.Fatalf("unexpected continuation input %s", payload)
+	}
+
+	prelude := r.begin()
+	r.observe(prelude)
+	if !strings.Contains(string(prelude), "response.output_item.done") {
+		t.Fatalf("expected prelude to complete the open message, got %q", prelude)
+	}
+	if got := r.splice([]byte(`event: response.created` + "\n" + `data: {"type":"response.created","sequence_number":0,"response":{"id":"resp_2"}}`)); got != nil {
+		t.Fatalf("expected response.created to be dropped, got %q", got)
+	}
+	completed := string(r.splice([]byte(`event: response.completed` + "\n" + `data: {"type":"response.completed","sequence_number":5,"response":{"id":"resp_2","output":[{"id":"msg_2","type":"message"}]}}`)))
+	data := strings.TrimPrefix(strings.SplitN(completed, "\n", 2)[1], "data: ")
+	if gjson.Get(data, "response.id").String() != "resp_1" {
+		t.Fatalf("expected original response id, got %s", data)
+	}
+	if gjson.Get(data, "sequence_number").Int() != 6 {
+		t.Fatalf("expected sequence to continue after the prelude, got %s", data)
+	}
+	if ids := gjson.Get(data, "response.output.#.id").String(); ids != `["msg_1","msg_2"]` {
+		t.Fatalf("expected merged output items, got %s", ids)
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"hash/fnv"
+	"math/rand/v2"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+// applyTrafficSplit routes the request to a variant of the first traffic split matching
+// modelName. Only variants whose model passes usable are considered (nil admits all); when none
+// does, the request keeps modelName. The returned context records the chosen variant for usage
+// reporting; the requested thinking suffix is carried over to the variant model.
+func (h *BaseAPIHandler) applyTrafficSplit(ctx context.Context, modelName string, rawJSON []byte, usable func(model string) bool) (context.Context, string) {
+	if h == nil || h.Cfg == nil || len(h.Cfg.TrafficSplits) == 0 {
+		return ctx, modelName
+	}
+	requested := thinking.ParseSuffix(modelName)
+	base := strings.TrimSpace(requested.ModelName)
+	for i := range h.Cfg.TrafficSplits {
+		rule := &h.Cfg.TrafficSplits[i]
+		if !strings.EqualFold(rule.Model, base) {
+			continue
+		}
+		targetOf := func(variant *config.TrafficSplitVariant) string {
+			target := variant.Model
+			if requested.HasSuffix && requested.RawSuffix != "" && !thinking.ParseSuffix(target).HasSuffix {
+				target += "(" + requested.RawSuffix + ")"
+			}
+			return target
+		}
+		variant := pickTrafficVariant(rule, h.trafficSplitKey(ctx, rule.BucketBy, rawJSON), func(variant *config.TrafficSplitVariant) bool {
+			return usable == nil || usable(targetOf(variant))
+		})
+		if variant == nil {
+			return ctx, modelName
+		}
+		target := targetOf(variant)
+		name := variant.Name
+		if name == "" {
+			name = variant.Model
+		}
+		return usage.WithTrafficVariant(ctx, name), target
+	}
+	return ctx, modelName
+}
+
+// trafficSplitKey returns the identity a request is bucketed by, falling back from the
+// configured key to the other one.
+func (h *BaseAPIHandler) trafficSplitKey(ctx context.Context, bucketBy string, rawJSON []byte) string {
+	clientKey := clientAPIKey(ctx)
+	if bucketBy != config.TrafficSplitBySession && clientKey != "" {
+		return "key:" + clientKey
+	}
+	meta := map[string]any{}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil && ginCtx.Request != nil && h.Cfg.SessionAffinity.Header != "" {
+		if sessionID := strings.TrimSpace(ginCtx.GetHeader(h.Cfg.SessionAffinity.Header)); sessionID != "" {
+			meta[coreexecutor.SessionIDMetadataKey] = sessionID
+		}
+	}
+	if session := coreauth.SessionKey(coreexecutor.Options{OriginalRequest: rawJSON, Metadata: meta}); session != "" {
+		return "session:" + session
+	}
+	if clientKey != "" {
+		return "key:" + clientKey
+	}
+	return ""
+}
+
+// pickTrafficVariant maps key onto the weighted variants of rule that pass usable. The same key
+// always lands on the same variant while the rule and the usable variants are unchanged; an
+// empty key picks a variant at random.
+func pickTrafficVariant(rule *config.TrafficSplit, key string, usable func(*config.TrafficSplitVariant) bool) *config.TrafficSplitVariant {
+	candidates := make([]*config.TrafficSplitVariant, 0, len(rule.Variants))
+	total := 0
+	for i := range rule.Variants {
+		variant := &rule.Variants[i]
+		if variant.Weight > 0 && usable(variant) {
+			candidates = append(candidates, variant)
+			total += variant.Weight
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+	var bucket int
+	if key == "" {
+		bucket = rand.IntN(total)
+	} else {
+		hasher := fnv.New64a()
+		_, _ = hasher.Write([]byte(strings.ToLower(rule.Model) + "\x00" + key))
+		bucket = int(hasher.Sum64() % uint64(total))
+	}
+	for _, variant := range candidates {
+		if bucket < variant.Weight {
+			return variant
+		}
+		bucket -= variant.Weight
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+func trafficSplitContext(apiKey string) context.Context {
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Request = httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	if apiKey != "" {
+		ginCtx.Set("apiKey", apiKey)
+	}
+	return context.WithValue(context.Background(), "gin", ginCtx)
+}
+
+func TestApplyTrafficSplit_BucketsDeterministically(t *testing.T) {
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{TrafficSplits: []sdkconfig.TrafficSplit{{
+		Model: "model-a",
+		Variants: []sdkconfig.TrafficSplitVariant{
+			{Name: "control", Model: "model-a", Weight: 90},
+			{Name: "canary", Model: "model-b", Weight: 10},
+		},
+	}}}, nil)
+
+	canary := 0
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("client-%d", i)
+		ctx, model := h.applyTrafficSplit(trafficSplitContext(key), "model-a", nil, nil)
+		for j := 0; j < 3; j++ {
+			_, again := h.applyTrafficSplit(trafficSplitContext(key), "model-a", nil, nil)
+			if again != model {
+				t.Fatalf("expected key %s to stay on %s, got %s", key, model, again)
+			}
+		}
+		variant := usage.TrafficVariantFromContext(ctx)
+		switch {
+		case model == "model-b" && variant == "canary":
+			canary++
+		case model == "model-a" && variant == "control":
+		default:
+			t.Fatalf("unexpected variant %q for model %q", variant, model)
+		}
+	}
+	if canary < 50 || canary > 150 {
+		t.Fatalf("expected roughly 10%% of keys on the canary, got %d of 1000", canary)
+	}
+}
+
+func TestApplyTrafficSplit_CarriesSuffixAndIgnoresOtherModels(t *testing.T) {
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{TrafficSplits: []sdkconfig.TrafficSplit{{
+		Model:    "model-a",
+		Variants: []sdkconfig.TrafficSplitVariant{{Name: "canary", Model: "model-b", Weight: 1}},
+	}}}, nil)
+
+	if _, model := h.applyTrafficSplit(trafficSplitContext("key"), "Model-A(high)", nil, nil); model != "model-b(high)" {
+		t.Fatalf("expected the thinking suffix to carry over, got %q", model)
+	}
+	ctx, model := h.applyTrafficSplit(trafficSplitContext("key"), "model-c", nil, nil)
+	if model != "model-c" || usage.TrafficVariantFromContext(ctx) != "" {
+		t.Fatalf("expected unmatched models to pass through, got %q", model)
+	}
+}
+
+func TestApplyTrafficSplit_SkipsUnusableVariants(t *testing.T) {
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{TrafficSplits: []sdkconfig.TrafficSplit{{
+		Model: "model-a",
+		Variants: []sdkconfig.TrafficSplitVariant{
+			{Name: "control", Model: "model-a", Weight: 1},
+			{Name: "canary", Model: "model-b", Weight: 99},
+		},
+	}}}, nil)
+	onlyControl := func(model string) bool { return model == "model-a" }
+
+	for i := 0; i < 100; i++ {
+		ctx, model := h.applyTrafficSplit(trafficSplitContext(fmt.Sprintf("client-%d", i)), "model-a", nil, onlyControl)
+		if model != "model-a" || usage.TrafficVariantFromContext(ctx) != "control" {
+			t.Fatalf("expected keys limited to model-a to stay on the control, got %q", model)
+		}
+	}
+	ctx, model := h.applyTrafficSplit(trafficSplitContext("key"), "model-a", nil, func(string) bool { return false })
+	if model != "model-a" || usage.TrafficVariantFromContext(ctx) != "" {
+		t.Fatalf("expected the requested model when no variant is usable, got %q", model)
+	}
+}
@@ -0,0 +1,245 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	defaultAffinityTTL = time.Hour
+	// affinitySweepInterval bounds how often expired pins are purged.
+	affinitySweepInterval = time.Minute
+)
+
+// sessionAffinity remembers which auth served a conversation so later turns can reuse it.
+// Pins expire after the configured TTL of inactivity.
+type sessionAffinity struct {
+	mu        sync.Mutex
+	pins      map[string]affinityPin
+	lastSweep time.Time
+}
+
+type affinityPin struct {
+	authID  string
+	expires time.Time
+}
+
+func newSessionAffinity() *sessionAffinity {
+	return &sessionAffinity{pins: make(map[string]affinityPin)}
+}
+
+// lookup returns the auth pinned to key, if the pin has not expired.
+func (a *sessionAffinity) lookup(key string, now time.Time) string {
+	if a == nil || key == "" {
+		return ""
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	pin, ok := a.pins[key]
+	if !ok {
+		return ""
+	}
+	if !now.Before(pin.expires) {
+		delete(a.pins, key)
+		return ""
+	}
+	return pin.authID
+}
+
+// bind pins key to authID for ttl, replacing any previous pin.
+func (a *sessionAffinity) bind(key, authID string, ttl time.Duration, now time.Time) {
+	if a == nil || key == "" || authID == "" {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pins[key] = affinityPin{authID: authID, expires: now.Add(ttl)}
+	if now.Sub(a.lastSweep) < affinitySweepInterval {
+		return
+	}
+	a.lastSweep = now
+	for k, pin := range a.pins {
+		if !now.Before(pin.expires) {
+			delete(a.pins, k)
+		}
+	}
+}
+
+// affinitySettings returns whether session affinity is enabled and the pin TTL.
+func (m *Manager) affinitySettings() (bool, time.Duration) {
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	if cfg == nil || !cfg.SessionAffinity.Enable {
+		return false, 0
+	}
+	ttl := time.Duration(cfg.SessionAffinity.TTLSeconds) * time.Second
+	if ttl <= 0 {
+		ttl = defaultAffinityTTL
+	}
+	return true, ttl
+}
+
+// sessionAffinityKey derives the affinity key for a request, or "" when affinity is disabled
+// or the request carries nothing that identifies a conversation.
+func (m *Manager) sessionAffinityKey(model string, opts cliproxyexecutor.Options) string {
+	if enabled, _ := m.affinitySettings(); !enabled {
+		return ""
+	}
+	session := sessionKeyFromRequest(opts)
+	if session == "" {
+		return ""
+	}
+	return session + "|" + strings.ToLower(thinking.ParseSuffix(model).ModelName)
+}
+
+// SessionKey identifies the conversation of a request the same way session affinity does.
+// It returns "" when the request carries nothing to identify the conversation by.
+func SessionKey(opts cliproxyexecutor.Options) string {
+	return sessionKeyFromRequest(opts)
+}
+
+// sessionKeyFromRequest identifies the conversation of a request. It prefers an explicit
+// session header, then Claude metadata.user_id, then the Responses prompt_cache_key, and
+// finally hashes the system prompt together with the first user message.
+func sessionKeyFromRequest(opts cliproxyexecutor.Options) string {
+	if raw, ok := opts.Metadata[cliproxyexecutor.SessionIDMetadataKey].(string); ok && strings.TrimSpace(raw) != "" {
+		return "header:" + strings.TrimSpace(raw)
+	}
+	payload := opts.OriginalRequest
+	if len(payload) == 0 || !gjson.ValidBytes(payload) {
+		return ""
+	}
+	root := gjson.ParseBytes(payload)
+	if userID := strings.TrimSpace(root.Get("metadata.user_id").String()); userID != "" {
+		return "user:" + userID
+	}
+	if cacheKey := strings.TrimSpace(root.Get("prompt_cache_key").String()); cacheKey != "" {
+		return "cache:" + cacheKey
+	}
+	system, firstUser := conversationAnchor(root)
+	if system == "" && firstUser == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(system + "\x00" + firstUser))
+	return "prompt:" + hex.EncodeToString(sum[:16])
+}
+
+// conversationAnchor extracts the raw system prompt and first user message across the
+// Claude, OpenAI Chat, OpenAI Responses and Gemini request formats.
+func conversationAnchor(root gjson.Result) (system, firstUser string) {
+	for _, path := range []string{"system", "instructions", "systemInstruction", "system_instruction"} {
+		if value := root.Get(path); value.Exists() {
+			system = value.Raw
+			break
+		}
+	}
+	if messages := root.Get("messages"); messages.IsArray() {
+		for _, message := range messages.Array() {
+			switch message.Get("role").String() {
+			case "system", "developer":
+				if system == "" {
+					system = message.Get("content").Raw
+				}
+			case "user":
+				if firstUser == "" {
+					firstUser = message.Get("content").Raw
+				}
+			}
+		}
+		return system, firstUser
+	}
+	if input := root.Get("input"); input.Exists() {
+		if input.Type == gjson.String {
+			return system, input.Raw
+		}
+		if input.IsArray() {
+			for _, item := range input.Array() {
+				if item.Get("role").String() == "user" {
+					return system, item.Get("content").Raw
+				}
+			}
+		}
+		return system, ""
+	}
+	if contents := root.Get("contents"); contents.IsArray() {
+		for _, content := range contents.Array() {
+			if role := content.Get("role").String(); role == "" || role == "user" {
+				return system, content.Get("parts").Raw
+			}
+		}
+	}
+	return system, firstUser
+}
+
+type pinnedAuthContextKey struct{}
+
+// withPinnedAuth asks the pick functions to prefer authID when it is an available candidate.
+func withPinnedAuth(ctx context.Context, authID string) context.Context {
+	if authID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, pinnedAuthContextKey{}, authID)
+}
+
+// pinnedCandidate returns the pinned auth from candidates when it can serve model right now.
+func pinnedCandidate(ctx context.Context, candidates []*Auth, model string, now time.Time) *Auth {
+	if ctx == nil {
+		return nil
+	}
+	authID, _ := ctx.Value(pinnedAuthContextKey{}).(string)
+	if authID == "" {
+		return nil
+	}
+	for _, candidate := range candidates {
+		if candidate == nil || candidate.ID != authID {
+			continue
+		}
+		if blocked, _, _ := isAuthBlockedForModel(candidate, model, now); blocked {
+			return nil
+		}
+		return candidate
+	}
+	return nil
+}
+
+// pickWithAffinity prepares one selection attempt for affinityKey. It returns the context to
+// pick with, which carries the pinned auth when one exists, and the pinned auth ID.
+func (m *Manager) pickWithAffinity(ctx context.Context, affinityKey string) (context.Context, string) {
+	if affinityKey == "" {
+		return ctx, ""
+	}
+	pinned := m.affinity.lookup(affinityKey, time.Now())
+	return withPinnedAuth(ctx, pinned), pinned
+}
+
+// affinityExecContext records the affinity outcome of the selected auth for usage reporting.
+func affinityExecContext(ctx context.Context, affinityKey, pinned, selected string) context.Context {
+	if affinityKey == "" {
+		return ctx
+	}
+	if pinned != "" && pinned == selected {
+		return usage.WithSessionAffinity(ctx, usage.AffinityHit)
+	}
+	return usage.WithSessionAffinity(ctx, usage.AffinityMiss)
+}
+
+// bindAffinity pins affinityKey to the auth that just served it successfully.
+func (m *Manager) bindAffinity(affinityKey, authID string) {
+	if affinityKey == "" {
+		return
+	}
+	_, ttl := m.affinitySettings()
+	if ttl <= 0 {
+		return
+	}
+	m.affinity.bind(affinityKey, authID, ttl, time.Now())
+}
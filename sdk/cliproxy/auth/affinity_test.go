@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+type affinityTestExecutor struct {
+	mu       sync.Mutex
+	calls    []string
+	outcomes []string
+}
+
+func (e *affinityTestExecutor) Identifier() string { return "affinity-test" }
+
+func (e *affinityTestExecutor) Execute(ctx context.Context, auth *Auth, _ cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls = append(e.calls, auth.ID)
+	e.outcomes = append(e.outcomes, usage.SessionAffinityFromContext(ctx))
+	return cliproxyexecutor.Response{Payload: []byte(`{}`)}, nil
+}
+
+func (e *affinityTestExecutor) ExecuteStream(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *affinityTestExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) {
+	return auth, nil
+}
+
+func (e *affinityTestExecutor) CountTokens(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e *affinityTestExecutor) HttpRequest(context.Context, *Auth, *http.Request) (*http.Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestSessionKeyFromRequest(t *testing.T) {
+	claude := cliproxyexecutor.Options{OriginalRequest: []byte(`{"metadata":{"user_id":"user_abc_session_1"},"messages":[{"role":"user","content":"hi"}]}`)}
+	if got := sessionKeyFromRequest(claude); got != "user:user_abc_session_1" {
+		t.Fatalf("claude key = %q", got)
+	}
+	responses := cliproxyexecutor.Options{OriginalRequest: []byte(`{"prompt_cache_key":"conv-9","input":"hi"}`)}
+	if got := sessionKeyFromRequest(responses); got != "cache:conv-9" {
+		t.Fatalf("responses key = %q", got)
+	}
+	header := cliproxyexecutor.Options{
+		OriginalRequest: []byte(`{"prompt_cache_key":"conv-9"}`),
+		Metadata:        map[string]any{cliproxyexecutor.SessionIDMetadataKey: "s-1"},
+	}
+	if got := sessionKeyFromRequest(header); got != "header:s-1" {
+		t.Fatalf("header key = %q", got)
+	}
+
+	turn1 := cliproxyexecutor.Options{OriginalRequest: []byte(`{"messages":[{"role":"system","content":"be brief"},{"role":"user","content":"hello"}]}`)}
+	turn2 := cliproxyexecutor.Options{OriginalRequest: []byte(`{"messages":[{"role":"system","content":"be brief"},{"role":"user","content":"hello"},{"role":"assistant","content":"hi"},{"role":"user","content":"more"}]}`)}
+	other := cliproxyexecutor.Options{OriginalRequest: []byte(`{"messages":[{"role":"system","content":"be brief"},{"role":"user","content":"different"}]}`)}
+	key1, key2 := sessionKeyFromRequest(turn1), sessionKeyFromRequest(turn2)
+	if key1 == "" || key1 != key2 {
+		t.Fatalf("expected turns of one conversation to share a key, got %q and %q", key1, key2)
+	}
+	if key1 == sessionKeyFromRequest(other) {
+		t.Fatalf("expected different conversations to get different keys")
+	}
+}
+
+func TestManagerExecute_SessionAffinityPinsAndFallsBack(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	m.SetConfig(&internalconfig.Config{SDKConfig: internalconfig.SDKConfig{SessionAffinity: internalconfig.SessionAffinityConfig{Enable: true}}})
+	executor := &affinityTestExecutor{}
+	m.RegisterExecutor(executor)
+
+	const model = "affinity-model"
+	reg := registry.GetGlobalRegistry()
+	for _, id := range []string{"affinity-a", "affinity-b"} {
+		if _, err := m.Register(context.Background(), &Auth{ID: id, Provider: "affinity-test"}); err != nil {
+			t.Fatalf("register auth: %v", err)
+		}
+		reg.RegisterClient(id, "affinity-test", []*registry.ModelInfo{{ID: model}})
+		authID := id
+		t.Cleanup(func() { reg.UnregisterClient(authID) })
+	}
+
+	opts := cliproxyexecutor.Options{OriginalRequest: []byte(`{"metadata":{"user_id":"session-1"}}`)}
+	req := cliproxyexecutor.Request{Model: model}
+	for i := 0; i < 3; i++ {
+		if _, err := m.Execute(context.Background(), []string{"affinity-test"}, req, opts); err != nil {
+			t.Fatalf("Execute %d: %v", i, err)
+		}
+	}
+	pinned := executor.calls[0]
+	for i, id := range executor.calls {
+		if id != pinned {
+			t.Fatalf("call %d went to %s, want pinned %s", i, id, pinned)
+		}
+	}
+	if want := []string{usage.AffinityMiss, usage.AffinityHit, usage.AffinityHit}; !equalStrings(executor.outcomes, want) {
+		t.Fatalf("outcomes = %v, want %v", executor.outcomes, want)
+	}
+
+	// Put the pinned auth into cooldown; the session must move to the other auth and stick there.
+	m.mu.Lock()
+	m.auths[pinned].ModelStates = map[string]*ModelState{model: {
+		Unavailable:    true,
+		NextRetryAfter: time.Now().Add(time.Hour),
+		Quota:          QuotaState{Exceeded: true},
+	}}
+	m.mu.Unlock()
+	for i := 0; i < 2; i++ {
+		if _, err := m.Execute(context.Background(), []string{"affinity-test"}, req, opts); err != nil {
+			t.Fatalf("Execute after cooldown %d: %v", i, err)
+		}
+	}
+	moved := executor.calls[3]
+	if moved == pinned || executor.calls[4] != moved {
+		t.Fatalf("expected session to move off %s and stick, got %v", pinned, executor.calls[3:])
+	}
+	if want := []string{usage.AffinityMiss, usage.AffinityHit}; !equalStrings(executor.outcomes[3:], want) {
+		t.Fatalf("outcomes after fallback = %v, want %v", executor.outcomes[3:], want)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,359 @@
+package auth
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultBreakerFailureThreshold = 5
+	defaultBreakerWindow           = time.Minute
+	defaultBreakerOpenDuration     = 30 * time.Second
+)
+
+// Circuit breaker states reported through CircuitStatus.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// CircuitStatus is a snapshot of one circuit breaker.
+type CircuitStatus struct {
+	// State is CircuitClosed, CircuitOpen or CircuitHalfOpen.
+	State string
+	// Failures is the number of failures counted in the current window.
+	Failures int
+	// OpenedAt is when the circuit last opened; zero while closed.
+	OpenedAt time.Time
+	// ProbeAt is when an open circuit admits its probe request; zero unless open.
+	ProbeAt time.Time
+}
+
+// AuthCircuit is the circuit breaker state of a credential and of its individual models.
+type AuthCircuit struct {
+	CircuitStatus
+	Models map[string]CircuitStatus
+}
+
+// breakerSettings are the resolved circuit breaker parameters.
+type breakerSettings struct {
+	threshold     int
+	authThreshold int
+	window        time.Duration
+	open          time.Duration
+}
+
+func resolveBreakerSettings(cfg internalconfig.CircuitBreakerConfig) breakerSettings {
+	s := breakerSettings{
+		threshold:     cfg.FailureThreshold,
+		authThreshold: cfg.AuthFailureThreshold,
+		window:        time.Duration(cfg.WindowSeconds) * time.Second,
+		open:          time.Duration(cfg.OpenSeconds) * time.Second,
+	}
+	if s.threshold <= 0 {
+		s.threshold = defaultBreakerFailureThreshold
+	}
+	if s.authThreshold <= 0 {
+		s.authThreshold = 2 * s.threshold
+	}
+	if s.window <= 0 {
+		s.window = defaultBreakerWindow
+	}
+	if s.open <= 0 {
+		s.open = defaultBreakerOpenDuration
+	}
+	return s
+}
+
+// breakerOutcome classifies an execution result for the circuit breaker.
+type breakerOutcome int
+
+const (
+	// breakerNeutral results, such as quota or auth errors, are handled by the cooldowns in
+	// MarkResult and neither trip nor close a circuit.
+	breakerNeutral breakerOutcome = iota
+	breakerSuccess
+	breakerFailure
+)
+
+func breakerOutcomeOf(result Result) breakerOutcome {
+	if result.Success {
+		return breakerSuccess
+	}
+	status := statusCodeFromResult(result.Error)
+	if status == 0 || status == 408 || status >= 500 {
+		return breakerFailure
+	}
+	return breakerNeutral
+}
+
+// breakerKey identifies a circuit; an empty model denotes the circuit of the whole credential.
+type breakerKey struct {
+	authID string
+	model  string
+}
+
+func (k breakerKey) String() string {
+	if k.model == "" {
+		return "auth " + k.authID
+	}
+	return "auth " + k.authID + " model " + k.model
+}
+
+type breakerState struct {
+	state    string
+	failures []time.Time
+	openedAt time.Time
+	// probing is set while the half-open probe request is in flight.
+	probing bool
+	probeAt time.Time
+}
+
+func (s *breakerState) prune(now time.Time, window time.Duration) {
+	cutoff := now.Add(-window)
+	i := 0
+	for i < len(s.failures) && !s.failures[i].After(cutoff) {
+		i++
+	}
+	s.failures = s.failures[i:]
+}
+
+// advance moves an open circuit to half-open once the open period elapsed and expires a probe
+// whose result never arrived, e.g. because the client went away.
+func (s *breakerState) advance(now time.Time, settings breakerSettings) {
+	switch s.state {
+	case CircuitOpen:
+		if !now.Before(s.openedAt.Add(settings.open)) {
+			s.state = CircuitHalfOpen
+			s.probing = false
+		}
+	case CircuitHalfOpen:
+		if s.probing && !now.Before(s.probeAt.Add(settings.open)) {
+			s.probing = false
+		}
+	}
+}
+
+// rejects reports whether the circuit turns requests away at now.
+func (s *breakerState) rejects() bool {
+	return s.state == CircuitOpen || (s.state == CircuitHalfOpen && s.probing)
+}
+
+// circuitBreaker tracks closed/open/half-open circuits per auth ID and per auth ID and model.
+type circuitBreaker struct {
+	mu     sync.Mutex
+	states map[breakerKey]*breakerState
+}
+
+func newCircuitBreaker() *circuitBreaker {
+	return &circuitBreaker{states: make(map[breakerKey]*breakerState)}
+}
+
+func (b *circuitBreaker) keys(authID, model string) []breakerKey {
+	if model == "" {
+		return []breakerKey{{authID: authID}}
+	}
+	return []breakerKey{{authID: authID}, {authID: authID, model: model}}
+}
+
+// blocked reports whether the circuit of the auth or of the auth and model rejects requests.
+func (b *circuitBreaker) blocked(authID, model string, settings breakerSettings, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, key := range b.keys(authID, model) {
+		if state := b.states[key]; state != nil {
+			state.advance(now, settings)
+			if state.rejects() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// acquire claims the probe of every half-open circuit on the path of a selected auth.
+// It returns false when a circuit rejects the request, i.e. another request took the probe.
+func (b *circuitBreaker) acquire(authID, model string, settings breakerSettings, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	keys := b.keys(authID, model)
+	for _, key := range keys {
+		if state := b.states[key]; state != nil {
+			state.advance(now, settings)
+			if state.rejects() {
+				return false
+			}
+		}
+	}
+	for _, key := range keys {
+		if state := b.states[key]; state != nil && state.state == CircuitHalfOpen {
+			state.probing = true
+			state.probeAt = now
+		}
+	}
+	return true
+}
+
+// record applies an execution outcome to the circuits of the auth and of the auth and model.
+func (b *circuitBreaker) record(authID, model string, outcome breakerOutcome, settings breakerSettings, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, key := range b.keys(authID, model) {
+		threshold := settings.threshold
+		if key.model == "" {
+			threshold = settings.authThreshold
+		}
+		state := b.states[key]
+		switch outcome {
+		case breakerSuccess:
+			if state == nil {
+				continue
+			}
+			if state.state == CircuitHalfOpen {
+				log.Infof("circuit breaker closed for %s after a successful probe", key)
+				delete(b.states, key)
+				continue
+			}
+			if state.state == CircuitClosed {
+				state.prune(now, settings.window)
+				if len(state.failures) == 0 {
+					delete(b.states, key)
+				}
+			}
+		case breakerFailure:
+			if state == nil {
+				state = &breakerState{state: CircuitClosed}
+				b.states[key] = state
+			}
+			switch state.state {
+			case CircuitClosed:
+				state.prune(now, settings.window)
+				state.failures = append(state.failures, now)
+				if len(state.failures) >= threshold {
+					state.state = CircuitOpen
+					state.openedAt = now
+					log.Warnf("circuit breaker opened for %s after %d failures within %s", key, len(state.failures), settings.window)
+				}
+			case CircuitHalfOpen:
+				state.state = CircuitOpen
+				state.openedAt = now
+				state.probing = false
+				log.Warnf("circuit breaker re-opened for %s after a failed probe", key)
+			}
+		default:
+			if state != nil && state.state == CircuitHalfOpen {
+				state.probing = false
+			}
+		}
+	}
+}
+
+// status returns the circuits tracked for authID, or false when none are.
+func (b *circuitBreaker) status(authID string, settings breakerSettings, now time.Time) (AuthCircuit, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := AuthCircuit{CircuitStatus: CircuitStatus{State: CircuitClosed}}
+	found := false
+	for key, state := range b.states {
+		if key.authID != authID {
+			continue
+		}
+		state.advance(now, settings)
+		state.prune(now, settings.window)
+		if state.state == CircuitClosed && len(state.failures) == 0 {
+			delete(b.states, key)
+			continue
+		}
+		found = true
+		snapshot := CircuitStatus{State: state.state, Failures: len(state.failures)}
+		if state.state != CircuitClosed {
+			snapshot.OpenedAt = state.openedAt
+		}
+		if state.state == CircuitOpen {
+			snapshot.ProbeAt = state.openedAt.Add(settings.open)
+		}
+		if key.model == "" {
+			out.CircuitStatus = snapshot
+			continue
+		}
+		if out.Models == nil {
+			out.Models = make(map[string]CircuitStatus)
+		}
+		out.Models[key.model] = snapshot
+	}
+	return out, found
+}
+
+// breakerSettings returns the resolved circuit breaker parameters and whether breakers are enabled.
+func (m *Manager) breakerSettings() (breakerSettings, bool) {
+	if m == nil || m.breaker == nil {
+		return breakerSettings{}, false
+	}
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	if cfg == nil || !cfg.CircuitBreaker.Enable {
+		return breakerSettings{}, false
+	}
+	return resolveBreakerSettings(cfg.CircuitBreaker), true
+}
+
+// circuitBlocked reports whether an open circuit keeps authID from serving model.
+func (m *Manager) circuitBlocked(authID, model string, now time.Time) bool {
+	settings, ok := m.breakerSettings()
+	return ok && m.breaker.blocked(authID, baseModelKey(model), settings, now)
+}
+
+// acquireCircuit claims the half-open probe for a selected auth, if its circuit is half-open.
+func (m *Manager) acquireCircuit(authID, model string, now time.Time) bool {
+	settings, ok := m.breakerSettings()
+	return !ok || m.breaker.acquire(authID, baseModelKey(model), settings, now)
+}
+
+// recordCircuitResult feeds an execution result into the circuit breakers.
+func (m *Manager) recordCircuitResult(result Result) {
+	settings, ok := m.breakerSettings()
+	if !ok {
+		return
+	}
+	m.breaker.record(result.AuthID, baseModelKey(result.Model), breakerOutcomeOf(result), settings, time.Now())
+}
+
+// releaseCircuit gives up the half-open probe an attempt may hold without recording an outcome.
+func (m *Manager) releaseCircuit(authID, model string) {
+	settings, ok := m.breakerSettings()
+	if !ok {
+		return
+	}
+	m.breaker.record(authID, baseModelKey(model), breakerNeutral, settings, time.Now())
+}
+
+// CircuitBreakerStatus returns the circuit breaker state of an auth and of its models. It returns
+// false when circuit breakers are disabled or no circuit of the auth has recorded failures.
+func (m *Manager) CircuitBreakerStatus(authID string) (AuthCircuit, bool) {
+	settings, ok := m.breakerSettings()
+	if !ok || authID == "" {
+		return AuthCircuit{}, false
+	}
+	return m.breaker.status(authID, settings, time.Now())
+}
+
+// baseModelKey returns model without a thinking suffix.
+func baseModelKey(model string) string {
+	model = strings.TrimSpace(model)
+	if model == "" {
+		return ""
+	}
+	if parsed := thinking.ParseSuffix(model); parsed.ModelName != "" {
+		model = strings.TrimSpace(parsed.ModelName)
+	}
+	return model
+}
+
+func newCircuitOpenError() *Error {
+	return &Error{Code: "auth_circuit_open", Message: "all credentials are temporarily skipped after repeated upstream failures", Retryable: true, HTTPStatus: 503}
+}
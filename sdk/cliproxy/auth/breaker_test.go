@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func TestCircuitBreaker_OpensAndProbes(t *testing.T) {
+	settings := resolveBreakerSettings(internalconfig.CircuitBreakerConfig{FailureThreshold: 2, WindowSeconds: 60, OpenSeconds: 10})
+	b := newCircuitBreaker()
+	now := time.Now()
+
+	b.record("a", "m", breakerFailure, settings, now)
+	if b.blocked("a", "m", settings, now) {
+		t.Fatalf("expected circuit to stay closed below the threshold")
+	}
+	b.record("a", "m", breakerFailure, settings, now.Add(time.Second))
+	if !b.blocked("a", "m", settings, now.Add(2*time.Second)) {
+		t.Fatalf("expected circuit to open at the threshold")
+	}
+	if b.blocked("a", "other", settings, now.Add(2*time.Second)) {
+		t.Fatalf("expected other models of the auth to stay available")
+	}
+
+	probeAt := now.Add(12 * time.Second)
+	if b.blocked("a", "m", settings, probeAt) {
+		t.Fatalf("expected circuit to admit a probe after the open period")
+	}
+	if !b.acquire("a", "m", settings, probeAt) {
+		t.Fatalf("expected the first request to take the probe")
+	}
+	if !b.blocked("a", "m", settings, probeAt) || b.acquire("a", "m", settings, probeAt) {
+		t.Fatalf("expected a single half-open probe")
+	}
+
+	b.record("a", "m", breakerFailure, settings, probeAt)
+	if !b.blocked("a", "m", settings, probeAt.Add(5*time.Second)) {
+		t.Fatalf("expected a failed probe to re-open the circuit")
+	}
+
+	probeAt = probeAt.Add(11 * time.Second)
+	if !b.acquire("a", "m", settings, probeAt) {
+		t.Fatalf("expected a second probe after the open period")
+	}
+	b.record("a", "m", breakerSuccess, settings, probeAt)
+	if b.blocked("a", "m", settings, probeAt) {
+		t.Fatalf("expected a successful probe to close the circuit")
+	}
+	if status, _ := b.status("a", settings, probeAt); len(status.Models) != 0 {
+		t.Fatalf("expected the closed model circuit to be forgotten, got %+v", status)
+	}
+}
+
+func TestCircuitBreaker_NeutralResultReleasesProbe(t *testing.T) {
+	settings := resolveBreakerSettings(internalconfig.CircuitBreakerConfig{FailureThreshold: 1, OpenSeconds: 10})
+	b := newCircuitBreaker()
+	now := time.Now()
+	b.record("a", "m", breakerFailure, settings, now)
+
+	probeAt := now.Add(10 * time.Second)
+	if !b.acquire("a", "m", settings, probeAt) {
+		t.Fatalf("expected the probe to be admitted")
+	}
+	b.record("a", "m", breakerNeutral, settings, probeAt)
+	if !b.acquire("a", "m", settings, probeAt) {
+		t.Fatalf("expected a neutral result to release the probe")
+	}
+}
+
+func TestBreakerOutcomeOf(t *testing.T) {
+	cases := []struct {
+		result Result
+		want   breakerOutcome
+	}{
+		{Result{Success: true}, breakerSuccess},
+		{Result{Error: &Error{Message: "timeout"}}, breakerFailure},
+		{Result{Error: &Error{HTTPStatus: http.StatusBadGateway}}, breakerFailure},
+		{Result{Error: &Error{HTTPStatus: http.StatusRequestTimeout}}, breakerFailure},
+		{Result{Error: &Error{HTTPStatus: http.StatusTooManyRequests}}, breakerNeutral},
+		{Result{Error: &Error{HTTPStatus: http.StatusUnauthorized}}, breakerNeutral},
+	}
+	for _, tc := range cases {
+		if got := breakerOutcomeOf(tc.result); got != tc.want {
+			t.Errorf("breakerOutcomeOf(%+v) = %v, want %v", tc.result, got, tc.want)
+		}
+	}
+}
+
+// breakerTestExecutor fails every request served by the "cb-bad" auth without a status code.
+type breakerTestExecutor struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (e *breakerTestExecutor) Identifier() string { return "breaker-test" }
+
+func (e *breakerTestExecutor) Execute(_ context.Context, auth *Auth, _ cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	e.mu.Lock()
+	e.calls[auth.ID]++
+	e.mu.Unlock()
+	if auth.ID == "cb-bad" {
+		return cliproxyexecutor.Response{}, errors.New("upstream timeout")
+	}
+	return cliproxyexecutor.Response{Payload: []byte(auth.ID)}, nil
+}
+
+func (e *breakerTestExecutor) ExecuteStream(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *breakerTestExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) { return auth, nil }
+
+func (e *breakerTestExecutor) CountTokens(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e *breakerTestExecutor) HttpRequest(context.Context, *Auth, *http.Request) (*http.Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestManagerExecute_CircuitBreakerSkipsFailingAuth(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	m.SetConfig(&internalconfig.Config{CircuitBreaker: internalconfig.CircuitBreakerConfig{Enable: true, FailureThreshold: 2, OpenSeconds: 60}})
+	executor := &breakerTestExecutor{calls: make(map[string]int)}
+	m.RegisterExecutor(executor)
+	reg := registry.GetGlobalRegistry()
+	for _, id := range []string{"cb-bad", "cb-good"} {
+		if _, err := m.Register(context.Background(), &Auth{ID: id, Provider: "breaker-test"}); err != nil {
+			t.Fatalf("register auth: %v", err)
+		}
+		reg.RegisterClient(id, "breaker-test", []*registry.ModelInfo{{ID: "breaker-model"}})
+		authID := id
+		t.Cleanup(func() { reg.UnregisterClient(authID) })
+	}
+
+	for i := 0; i < 8; i++ {
+		resp, err := m.Execute(context.Background(), []string{"breaker-test"}, cliproxyexecutor.Request{Model: "breaker-model"}, cliproxyexecutor.Options{})
+		if err != nil {
+			t.Fatalf("Execute %d: %v", i, err)
+		}
+		if string(resp.Payload) != "cb-good" {
+			t.Fatalf("expected the healthy auth to answer, got %q", resp.Payload)
+		}
+	}
+	if calls := executor.calls["cb-bad"]; calls != 2 {
+		t.Fatalf("expected the failing auth to be skipped after 2 failures, got %d calls", calls)
+	}
+
+	circuit, ok := m.CircuitBreakerStatus("cb-bad")
+	if !ok || circuit.Models["breaker-model"].State != CircuitOpen || circuit.Models["breaker-model"].ProbeAt.IsZero() {
+		t.Fatalf("expected an open model circuit, got %+v (ok=%v)", circuit, ok)
+	}
+	if circuit.State != CircuitClosed || circuit.Failures != 2 {
+		t.Fatalf("expected the auth circuit to count failures below its threshold, got %+v", circuit.CircuitStatus)
+	}
+	if _, ok := m.CircuitBreakerStatus("cb-good"); ok {
+		t.Fatalf("expected no circuit state for the healthy auth")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"path/filepath"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
@@ -19,8 +20,10 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tracing"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -84,6 +87,16 @@ type Result struct {
 	RetryAfter *time.Duration
 	// Error describes the failure when Success is false.
 	Error *Error
+	// Latency is the wall-clock duration of the attempt (the whole stream for streaming calls).
+	Latency time.Duration
+	// FirstByteLatency is the time until the first response chunk; zero when not measured.
+	FirstByteLatency time.Duration
+	// Retry marks attempts that follow an earlier failed attempt for the same request.
+	Retry bool
+	// Abandoned marks attempts the conductor cancelled itself, such as the losing attempt of a
+	// hedged request. They are reported to hooks but leave auth state untouched, as do attempts
+	// of requests mirrored by shadow traffic policies.
+	Abandoned bool
 }
 
 // Selector chooses an auth candidate for execution.
@@ -113,6 +126,30 @@ func (NoopHook) OnAuthUpdated(context.Context, *Auth) {}
 // OnResult implements Hook.
 func (NoopHook) OnResult(context.Context, Result) {}
 
+// hookChain fans callbacks out to several hooks in registration order.
+type hookChain []Hook
+
+// OnAuthRegistered implements Hook.
+func (c hookChain) OnAuthRegistered(ctx context.Context, auth *Auth) {
+	for _, hook := range c {
+		hook.OnAuthRegistered(ctx, auth)
+	}
+}
+
+// OnAuthUpdated implements Hook.
+func (c hookChain) OnAuthUpdated(ctx context.Context, auth *Auth) {
+	for _, hook := range c {
+		hook.OnAuthUpdated(ctx, auth)
+	}
+}
+
+// OnResult implements Hook.
+func (c hookChain) OnResult(ctx context.Context, result Result) {
+	for _, hook := range c {
+		hook.OnResult(ctx, result)
+	}
+}
+
 // Manager orchestrates auth lifecycle, selection, execution, and persistence.
 type Manager struct {
 	store     Store
@@ -135,6 +172,15 @@ type Manager struct {
 	// Keyed by auth.ID, value is alias(lower) -> upstream model (including suffix).
 	apiKeyModelAlias atomic.Value
 
+	// virtualModels caches the virtual model fallback chains compiled from the runtime config.
+	virtualModels atomic.Value
+
+	// hedgePolicies caches the hedged-request policies compiled from the runtime config.
+	hedgePolicies atomic.Value
+
+	// shadowPolicies caches the shadow traffic policies compiled from the runtime config.
+	shadowPolicies atomic.Value
+
 	// runtimeConfig stores the latest application config for request-time decisions.
 	// It is initialized in NewManager; never Load() before first Store().
 	runtimeConfig atomic.Value
@@ -142,6 +188,21 @@ type Manager struct {
 	// Optional HTTP RoundTripper provider injected by host.
 	rtProvider RoundTripperProvider
 
+	// shadowRecorder receives the outcome of requests mirrored by shadow traffic policies.
+	shadowRecorder ShadowRecorder
+
+	// limiter enforces per-auth max-concurrency, RPM and TPM limits during selection.
+	limiter *authLimiter
+
+	// breaker takes auths that keep failing out of selection when circuit breakers are enabled.
+	breaker *circuitBreaker
+
+	// queue parks requests while every credential for their model is cooling down.
+	queue *cooldownQueue
+
+	// affinity pins conversations to the auth that served them when session affinity is enabled.
+	affinity *sessionAffinity
+
 	// Auto refresh state
 	refreshCancel context.CancelFunc
 }
@@ -161,13 +222,47 @@ func NewManager(store Store, selector Selector, hook Hook) *Manager {
 		hook:            hook,
 		auths:           make(map[string]*Auth),
 		providerOffsets: make(map[string]int),
+		limiter:         newAuthLimiter(),
+		breaker:         newCircuitBreaker(),
+		queue:           newCooldownQueue(),
+		affinity:        newSessionAffinity(),
 	}
 	// atomic.Value requires non-nil initial value.
 	manager.runtimeConfig.Store(&internalconfig.Config{})
 	manager.apiKeyModelAlias.Store(apiKeyModelAliasTable(nil))
+	manager.virtualModels.Store(virtualModelTable(nil))
+	manager.hedgePolicies.Store(hedgePolicyTable(nil))
+	manager.shadowPolicies.Store(shadowPolicyTable(nil))
 	return manager
 }
 
+// AddHook registers hook next to the hook passed to NewManager. Adding a hook that is already
+// registered has no effect. Hooks must be added before the manager starts serving requests.
+func (m *Manager) AddHook(hook Hook) {
+	if m == nil || hook == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	chain, ok := m.hook.(hookChain)
+	if !ok {
+		chain = hookChain{m.hook}
+	}
+	for _, existing := range chain {
+		if sameHook(existing, hook) {
+			return
+		}
+	}
+	m.hook = append(chain[:len(chain):len(chain)], hook)
+}
+
+// sameHook reports whether a and b are the same hook without panicking on hooks whose
+// dynamic type is not comparable.
+func sameHook(a, b Hook) bool {
+	ta := reflect.TypeOf(a)
+	return ta == reflect.TypeOf(b) && ta.Comparable() && a == b
+}
+
 func (m *Manager) SetSelector(selector Selector) {
 	if m == nil {
 		return
@@ -204,6 +299,9 @@ func (m *Manager) SetConfig(cfg *internalconfig.Config) {
 		cfg = &internalconfig.Config{}
 	}
 	m.runtimeConfig.Store(cfg)
+	m.virtualModels.Store(compileVirtualModelTable(cfg.VirtualModels))
+	m.hedgePolicies.Store(compileHedgePolicies(cfg.Hedging))
+	m.shadowPolicies.Store(compileShadowPolicies(cfg.ShadowTraffic))
 	m.rebuildAPIKeyModelAliasFromRuntimeConfig()
 }
 
@@ -414,11 +512,14 @@ func (m *Manager) Register(ctx context.Context, auth *Auth) (*Auth, error) {
 		auth.ID = uuid.NewString()
 	}
 	auth.EnsureIndex()
+	stored := auth.Clone()
+	stored.cacheSchedule()
 	m.mu.Lock()
-	m.auths[auth.ID] = auth.Clone()
+	m.auths[auth.ID] = stored
 	m.mu.Unlock()
 	m.rebuildAPIKeyModelAliasFromRuntimeConfig()
 	_ = m.persist(ctx, auth)
+	m.queue.signal()
 	m.hook.OnAuthRegistered(ctx, auth.Clone())
 	return auth.Clone(), nil
 }
@@ -434,14 +535,30 @@ func (m *Manager) Update(ctx context.Context, auth *Auth) (*Auth, error) {
 		auth.indexAssigned = existing.indexAssigned
 	}
 	auth.EnsureIndex()
-	m.auths[auth.ID] = auth.Clone()
+	stored := auth.Clone()
+	stored.cacheSchedule()
+	m.auths[auth.ID] = stored
 	m.mu.Unlock()
+	if auth.Disabled {
+		m.forgetAuth(auth.ID)
+	}
 	m.rebuildAPIKeyModelAliasFromRuntimeConfig()
 	_ = m.persist(ctx, auth)
 	m.hook.OnAuthUpdated(ctx, auth.Clone())
 	return auth.Clone(), nil
 }
 
+// forgetAuth drops the per-auth state kept for scheduling once an auth is disabled or removed.
+func (m *Manager) forgetAuth(authID string) {
+	m.mu.RLock()
+	forgetter, _ := m.selector.(AuthForgetter)
+	m.mu.RUnlock()
+	if forgetter != nil {
+		forgetter.ForgetAuth(authID)
+	}
+	m.limiter.forget(authID)
+}
+
 // Load resets manager state from the backing store.
 func (m *Manager) Load(ctx context.Context) error {
 	m.mu.Lock()
@@ -459,7 +576,9 @@ func (m *Manager) Load(ctx context.Context) error {
 			continue
 		}
 		auth.EnsureIndex()
-		m.auths[auth.ID] = auth.Clone()
+		stored := auth.Clone()
+		stored.cacheSchedule()
+		m.auths[auth.ID] = stored
 	}
 	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
 	if cfg == nil {
@@ -471,7 +590,24 @@ func (m *Manager) Load(ctx context.Context) error {
 
 // Execute performs a non-streaming execution using the configured selector and executor.
 // It supports multiple providers for the same model and round-robins the starting provider per model.
+// Virtual models are resolved to their target chain before provider routing, and models with a
+// hedging policy race a second attempt when the first is slow to respond.
 func (m *Manager) Execute(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	m.mirrorShadow(ctx, req, opts, false)
+	if vm := m.lookupVirtualModel(req.Model); vm != nil {
+		resp, err := executeVirtualModel(ctx, m, vm, req, opts, m.execute)
+		if err != nil {
+			return resp, err
+		}
+		return rewriteVirtualModelResponse(resp, vm.name), nil
+	}
+	if policy := m.lookupHedgePolicy(req.Model); policy != nil {
+		return executeHedged(ctx, m, policy, providers, req, opts, m.execute, nil, nil)
+	}
+	return m.execute(ctx, providers, req, opts)
+}
+
+func (m *Manager) execute(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
 	normalized := m.normalizeProviders(providers)
 	if len(normalized) == 0 {
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "no provider supplied"}
@@ -480,14 +616,22 @@ func (m *Manager) Execute(ctx context.Context, providers []string, req cliproxye
 	_, maxWait := m.retrySettings()
 
 	var lastErr error
+	var queued *queueWaiter
 	for attempt := 0; ; attempt++ {
-		resp, errExec := m.executeMixedOnce(ctx, normalized, req, opts)
+		resp, errExec := m.executeMixedOnce(ctx, normalized, req, opts, attempt)
 		if errExec == nil {
 			return resp, nil
 		}
 		lastErr = errExec
 		wait, shouldRetry := m.shouldRetryAfterError(errExec, attempt, normalized, req.Model, maxWait)
 		if !shouldRetry {
+			requeued, errQueue := m.awaitCooldownQueue(ctx, &queued, normalized, req.Model, errExec)
+			if errQueue != nil {
+				return cliproxyexecutor.Response{}, errQueue
+			}
+			if requeued {
+				continue
+			}
 			break
 		}
 		if errWait := waitForCooldown(ctx, wait); errWait != nil {
@@ -502,7 +646,15 @@ func (m *Manager) Execute(ctx context.Context, providers []string, req cliproxye
 
 // ExecuteCount performs a non-streaming execution using the configured selector and executor.
 // It supports multiple providers for the same model and round-robins the starting provider per model.
+// Virtual models are resolved to their target chain before provider routing.
 func (m *Manager) ExecuteCount(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	if vm := m.lookupVirtualModel(req.Model); vm != nil {
+		return executeVirtualModel(ctx, m, vm, req, opts, m.executeCount)
+	}
+	return m.executeCount(ctx, providers, req, opts)
+}
+
+func (m *Manager) executeCount(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
 	normalized := m.normalizeProviders(providers)
 	if len(normalized) == 0 {
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "no provider supplied"}
@@ -511,14 +663,22 @@ func (m *Manager) ExecuteCount(ctx context.Context, providers []string, req clip
 	_, maxWait := m.retrySettings()
 
 	var lastErr error
+	var queued *queueWaiter
 	for attempt := 0; ; attempt++ {
-		resp, errExec := m.executeCountMixedOnce(ctx, normalized, req, opts)
+		resp, errExec := m.executeCountMixedOnce(ctx, normalized, req, opts, attempt)
 		if errExec == nil {
 			return resp, nil
 		}
 		lastErr = errExec
 		wait, shouldRetry := m.shouldRetryAfterError(errExec, attempt, normalized, req.Model, maxWait)
 		if !shouldRetry {
+			requeued, errQueue := m.awaitCooldownQueue(ctx, &queued, normalized, req.Model, errExec)
+			if errQueue != nil {
+				return cliproxyexecutor.Response{}, errQueue
+			}
+			if requeued {
+				continue
+			}
 			break
 		}
 		if errWait := waitForCooldown(ctx, wait); errWait != nil {
@@ -533,7 +693,26 @@ func (m *Manager) ExecuteCount(ctx context.Context, providers []string, req clip
 
 // ExecuteStream performs a streaming execution using the configured selector and executor.
 // It supports multiple providers for the same model and round-robins the starting provider per model.
+// Virtual models are resolved to their target chain before provider routing, and models with a
+// hedging policy race a second attempt when the first byte is slow to arrive.
 func (m *Manager) ExecuteStream(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	m.mirrorShadow(ctx, req, opts, true)
+	if vm := m.lookupVirtualModel(req.Model); vm != nil {
+		chunks, err := executeVirtualModel(ctx, m, vm, req, opts, m.executeStream)
+		if err != nil {
+			return nil, err
+		}
+		return rewriteVirtualModelStream(ctx, chunks, vm.name), nil
+	}
+	if policy := m.lookupHedgePolicy(req.Model); policy != nil {
+		return executeHedged(ctx, m, policy, providers, req, opts, m.executeStreamFirstByte, drainStream, func(chunks <-chan cliproxyexecutor.StreamChunk, cancel func()) <-chan cliproxyexecutor.StreamChunk {
+			return cancelWhenDrained(ctx, chunks, cancel)
+		})
+	}
+	return m.executeStream(ctx, providers, req, opts)
+}
+
+func (m *Manager) executeStream(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
 	normalized := m.normalizeProviders(providers)
 	if len(normalized) == 0 {
 		return nil, &Error{Code: "provider_not_found", Message: "no provider supplied"}
@@ -542,14 +721,22 @@ func (m *Manager) ExecuteStream(ctx context.Context, providers []string, req cli
 	_, maxWait := m.retrySettings()
 
 	var lastErr error
+	var queued *queueWaiter
 	for attempt := 0; ; attempt++ {
-		chunks, errStream := m.executeStreamMixedOnce(ctx, normalized, req, opts)
+		chunks, errStream := m.executeStreamMixedOnce(ctx, normalized, req, opts, attempt)
 		if errStream == nil {
 			return chunks, nil
 		}
 		lastErr = errStream
 		wait, shouldRetry := m.shouldRetryAfterError(errStream, attempt, normalized, req.Model, maxWait)
 		if !shouldRetry {
+			requeued, errQueue := m.awaitCooldownQueue(ctx, &queued, normalized, req.Model, errStream)
+			if errQueue != nil {
+				return nil, errQueue
+			}
+			if requeued {
+				continue
+			}
 			break
 		}
 		if errWait := waitForCooldown(ctx, wait); errWait != nil {
@@ -562,17 +749,22 @@ func (m *Manager) ExecuteStream(ctx context.Context, providers []string, req cli
 	return nil, &Error{Code: "auth_not_found", Message: "no auth available"}
 }
 
-func (m *Manager) executeMixedOnce(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+func (m *Manager) executeMixedOnce(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, attempt int) (cliproxyexecutor.Response, error) {
 	if len(providers) == 0 {
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "no provider supplied"}
 	}
 	routeModel := req.Model
 	opts = ensureRequestedModelMetadata(opts, routeModel)
-	tried := make(map[string]struct{})
+	affinityKey := m.sessionAffinityKey(routeModel, opts)
+	tried := newTriedSet(ctx)
 	var lastErr error
 	for {
-		auth, executor, provider, errPick := m.pickNextMixed(ctx, providers, routeModel, opts, tried)
+		attemptCtx, span := tracing.Start(ctx, "cliproxy.attempt")
+		pickCtx, pinned := m.pickWithAffinity(attemptCtx, affinityKey)
+		auth, executor, provider, errPick := m.pickNextMixed(pickCtx, providers, routeModel, opts, tried)
 		if errPick != nil {
+			span.RecordError(errPick)
+			span.End()
 			if lastErr != nil {
 				return cliproxyexecutor.Response{}, lastErr
 			}
@@ -583,7 +775,10 @@ func (m *Manager) executeMixedOnce(ctx context.Context, providers []string, req
 		debugLogAuthSelection(entry, auth, provider, req.Model)
 
 		tried[auth.ID] = struct{}{}
-		execCtx := ctx
+		notifySelection(ctx, auth.ID)
+		retry := attempt > 0 || len(tried) > 1
+		setAttemptSpanAttributes(span, auth, provider, routeModel, retry)
+		execCtx := affinityExecContext(attemptCtx, affinityKey, pinned, auth.ID)
 		if rt := m.roundTripperFor(auth); rt != nil {
 			execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
 			execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
@@ -592,10 +787,19 @@ func (m *Manager) executeMixedOnce(ctx context.Context, providers []string, req
 		execReq.Model = rewriteModelForAuth(routeModel, auth)
 		execReq.Model = m.applyOAuthModelAlias(auth, execReq.Model)
 		execReq.Model = m.applyAPIKeyModelAlias(auth, execReq.Model)
+		started := time.Now()
 		resp, errExec := executor.Execute(execCtx, auth, execReq, opts)
-		result := Result{AuthID: auth.ID, Provider: provider, Model: routeModel, Success: errExec == nil}
+		elapsed := time.Since(started)
+		m.releaseAuth(auth.ID)
+		span.RecordError(errExec)
+		span.End()
+		result := Result{AuthID: auth.ID, Provider: provider, Model: routeModel, Success: errExec == nil, Latency: elapsed, FirstByteLatency: elapsed, Retry: retry}
 		if errExec != nil {
 			if errCtx := execCtx.Err(); errCtx != nil {
+				if attemptAbandoned(execCtx) {
+					result.Abandoned = true
+					m.MarkResult(execCtx, result)
+				}
 				return cliproxyexecutor.Response{}, errCtx
 			}
 			result.Error = &Error{Message: errExec.Error()}
@@ -611,21 +815,27 @@ func (m *Manager) executeMixedOnce(ctx context.Context, providers []string, req
 			continue
 		}
 		m.MarkResult(execCtx, result)
+		m.bindAffinity(affinityKey, auth.ID)
 		return resp, nil
 	}
 }
 
-func (m *Manager) executeCountMixedOnce(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+func (m *Manager) executeCountMixedOnce(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, attempt int) (cliproxyexecutor.Response, error) {
 	if len(providers) == 0 {
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "no provider supplied"}
 	}
 	routeModel := req.Model
 	opts = ensureRequestedModelMetadata(opts, routeModel)
-	tried := make(map[string]struct{})
+	affinityKey := m.sessionAffinityKey(routeModel, opts)
+	tried := newTriedSet(ctx)
 	var lastErr error
 	for {
-		auth, executor, provider, errPick := m.pickNextMixed(ctx, providers, routeModel, opts, tried)
+		attemptCtx, span := tracing.Start(ctx, "cliproxy.attempt")
+		pickCtx, pinned := m.pickWithAffinity(attemptCtx, affinityKey)
+		auth, executor, provider, errPick := m.pickNextMixed(pickCtx, providers, routeModel, opts, tried)
 		if errPick != nil {
+			span.RecordError(errPick)
+			span.End()
 			if lastErr != nil {
 				return cliproxyexecutor.Response{}, lastErr
 			}
@@ -636,7 +846,10 @@ func (m *Manager) executeCountMixedOnce(ctx context.Context, providers []string,
 		debugLogAuthSelection(entry, auth, provider, req.Model)
 
 		tried[auth.ID] = struct{}{}
-		execCtx := ctx
+		notifySelection(ctx, auth.ID)
+		retry := attempt > 0 || len(tried) > 1
+		setAttemptSpanAttributes(span, auth, provider, routeModel, retry)
+		execCtx := affinityExecContext(attemptCtx, affinityKey, pinned, auth.ID)
 		if rt := m.roundTripperFor(auth); rt != nil {
 			execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
 			execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
@@ -645,10 +858,19 @@ func (m *Manager) executeCountMixedOnce(ctx context.Context, providers []string,
 		execReq.Model = rewriteModelForAuth(routeModel, auth)
 		execReq.Model = m.applyOAuthModelAlias(auth, execReq.Model)
 		execReq.Model = m.applyAPIKeyModelAlias(auth, execReq.Model)
+		started := time.Now()
 		resp, errExec := executor.CountTokens(execCtx, auth, execReq, opts)
-		result := Result{AuthID: auth.ID, Provider: provider, Model: routeModel, Success: errExec == nil}
+		elapsed := time.Since(started)
+		m.releaseAuth(auth.ID)
+		span.RecordError(errExec)
+		span.End()
+		result := Result{AuthID: auth.ID, Provider: provider, Model: routeModel, Success: errExec == nil, Latency: elapsed, FirstByteLatency: elapsed, Retry: retry}
 		if errExec != nil {
 			if errCtx := execCtx.Err(); errCtx != nil {
+				if attemptAbandoned(execCtx) {
+					result.Abandoned = true
+					m.MarkResult(execCtx, result)
+				}
 				return cliproxyexecutor.Response{}, errCtx
 			}
 			result.Error = &Error{Message: errExec.Error()}
@@ -664,21 +886,27 @@ func (m *Manager) executeCountMixedOnce(ctx context.Context, providers []string,
 			continue
 		}
 		m.MarkResult(execCtx, result)
+		m.bindAffinity(affinityKey, auth.ID)
 		return resp, nil
 	}
 }
 
-func (m *Manager) executeStreamMixedOnce(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+func (m *Manager) executeStreamMixedOnce(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, attempt int) (<-chan cliproxyexecutor.StreamChunk, error) {
 	if len(providers) == 0 {
 		return nil, &Error{Code: "provider_not_found", Message: "no provider supplied"}
 	}
 	routeModel := req.Model
 	opts = ensureRequestedModelMetadata(opts, routeModel)
-	tried := make(map[string]struct{})
+	affinityKey := m.sessionAffinityKey(routeModel, opts)
+	tried := newTriedSet(ctx)
 	var lastErr error
 	for {
-		auth, executor, provider, errPick := m.pickNextMixed(ctx, providers, routeModel, opts, tried)
+		attemptCtx, span := tracing.Start(ctx, "cliproxy.attempt")
+		pickCtx, pinned := m.pickWithAffinity(attemptCtx, affinityKey)
+		auth, executor, provider, errPick := m.pickNextMixed(pickCtx, providers, routeModel, opts, tried)
 		if errPick != nil {
+			span.RecordError(errPick)
+			span.End()
 			if lastErr != nil {
 				return nil, lastErr
 			}
@@ -689,7 +917,10 @@ func (m *Manager) executeStreamMixedOnce(ctx context.Context, providers []string
 		debugLogAuthSelection(entry, auth, provider, req.Model)
 
 		tried[auth.ID] = struct{}{}
-		execCtx := ctx
+		notifySelection(ctx, auth.ID)
+		retry := attempt > 0 || len(tried) > 1
+		setAttemptSpanAttributes(span, auth, provider, routeModel, retry)
+		execCtx := affinityExecContext(attemptCtx, affinityKey, pinned, auth.ID)
 		if rt := m.roundTripperFor(auth); rt != nil {
 			execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
 			execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
@@ -698,9 +929,16 @@ func (m *Manager) executeStreamMixedOnce(ctx context.Context, providers []string
 		execReq.Model = rewriteModelForAuth(routeModel, auth)
 		execReq.Model = m.applyOAuthModelAlias(auth, execReq.Model)
 		execReq.Model = m.applyAPIKeyModelAlias(auth, execReq.Model)
+		started := time.Now()
 		chunks, errStream := executor.ExecuteStream(execCtx, auth, execReq, opts)
 		if errStream != nil {
+			m.releaseAuth(auth.ID)
+			span.RecordError(errStream)
+			span.End()
 			if errCtx := execCtx.Err(); errCtx != nil {
+				if attemptAbandoned(execCtx) {
+					m.MarkResult(execCtx, Result{AuthID: auth.ID, Provider: provider, Model: routeModel, Latency: time.Since(started), Retry: retry, Abandoned: true})
+				}
 				return nil, errCtx
 			}
 			rerr := &Error{Message: errStream.Error()}
@@ -708,7 +946,7 @@ func (m *Manager) executeStreamMixedOnce(ctx context.Context, providers []string
 			if errors.As(errStream, &se) && se != nil {
 				rerr.HTTPStatus = se.StatusCode()
 			}
-			result := Result{AuthID: auth.ID, Provider: provider, Model: routeModel, Success: false, Error: rerr}
+			result := Result{AuthID: auth.ID, Provider: provider, Model: routeModel, Success: false, Error: rerr, Latency: time.Since(started), Retry: retry}
 			result.RetryAfter = retryAfterFromError(errStream)
 			m.MarkResult(execCtx, result)
 			lastErr = errStream
@@ -717,17 +955,24 @@ func (m *Manager) executeStreamMixedOnce(ctx context.Context, providers []string
 		out := make(chan cliproxyexecutor.StreamChunk)
 		go func(streamCtx context.Context, streamAuth *Auth, streamProvider string, streamChunks <-chan cliproxyexecutor.StreamChunk) {
 			defer close(out)
+			defer m.releaseAuth(streamAuth.ID)
+			defer span.End()
 			var failed bool
+			var firstByte time.Duration
 			forward := true
 			for chunk := range streamChunks {
+				if firstByte == 0 {
+					firstByte = time.Since(started)
+				}
 				if chunk.Err != nil && !failed {
 					failed = true
+					span.RecordError(chunk.Err)
 					rerr := &Error{Message: chunk.Err.Error()}
 					var se cliproxyexecutor.StatusError
 					if errors.As(chunk.Err, &se) && se != nil {
 						rerr.HTTPStatus = se.StatusCode()
 					}
-					m.MarkResult(streamCtx, Result{AuthID: streamAuth.ID, Provider: streamProvider, Model: routeModel, Success: false, Error: rerr})
+					m.MarkResult(streamCtx, Result{AuthID: streamAuth.ID, Provider: streamProvider, Model: routeModel, Success: false, Error: rerr, Latency: time.Since(started), FirstByteLatency: firstByte, Retry: retry, Abandoned: attemptAbandoned(streamCtx)})
 				}
 				if !forward {
 					continue
@@ -743,13 +988,25 @@ func (m *Manager) executeStreamMixedOnce(ctx context.Context, providers []string
 				}
 			}
 			if !failed {
-				m.MarkResult(streamCtx, Result{AuthID: streamAuth.ID, Provider: streamProvider, Model: routeModel, Success: true})
+				if attemptAbandoned(streamCtx) {
+					m.MarkResult(streamCtx, Result{AuthID: streamAuth.ID, Provider: streamProvider, Model: routeModel, Latency: time.Since(started), FirstByteLatency: firstByte, Retry: retry, Abandoned: true})
+					return
+				}
+				m.MarkResult(streamCtx, Result{AuthID: streamAuth.ID, Provider: streamProvider, Model: routeModel, Success: true, Latency: time.Since(started), FirstByteLatency: firstByte, Retry: retry})
+				m.bindAffinity(affinityKey, streamAuth.ID)
 			}
 		}(execCtx, auth.Clone(), provider, chunks)
 		return out, nil
 	}
 }
 
+func setAttemptSpanAttributes(span *tracing.Span, auth *Auth, provider, model string, retry bool) {
+	span.SetAttribute("cliproxy.auth_id", auth.ID)
+	span.SetAttribute("cliproxy.provider", provider)
+	span.SetAttribute("cliproxy.model", model)
+	span.SetAttribute("cliproxy.retry", retry)
+}
+
 func ensureRequestedModelMetadata(opts cliproxyexecutor.Options, requestedModel string) cliproxyexecutor.Options {
 	requestedModel = strings.TrimSpace(requestedModel)
 	if requestedModel == "" {
@@ -1121,6 +1378,9 @@ func waitForCooldown(ctx context.Context, wait time.Duration) error {
 	if wait <= 0 {
 		return nil
 	}
+	_, span := tracing.Start(ctx, "cliproxy.cooldown_wait")
+	span.SetAttribute("cliproxy.wait_ms", wait.Milliseconds())
+	defer span.End()
 	timer := time.NewTimer(wait)
 	defer timer.Stop()
 	select {
@@ -1136,6 +1396,11 @@ func (m *Manager) MarkResult(ctx context.Context, result Result) {
 	if result.AuthID == "" {
 		return
 	}
+	if result.Abandoned || usage.IsShadow(ctx) {
+		m.releaseCircuit(result.AuthID, result.Model)
+		m.hook.OnResult(ctx, result)
+		return
+	}
 
 	shouldResumeModel := false
 	shouldSuspendModel := false
@@ -1236,8 +1501,16 @@ func (m *Manager) MarkResult(ctx context.Context, result Result) {
 
 		_ = m.persist(ctx, auth)
 	}
+	observer, _ := m.selector.(ResultObserver)
 	m.mu.Unlock()
 
+	m.recordCircuitResult(result)
+	if result.Success {
+		m.queue.signal()
+	}
+	if observer != nil {
+		observer.ObserveResult(result)
+	}
 	if clearModelQuota && result.Model != "" {
 		registry.GetGlobalRegistry().ClearModelQuotaExceeded(result.AuthID, result.Model)
 	}
@@ -1528,12 +1801,24 @@ func (m *Manager) GetByID(id string) (*Auth, bool) {
 	return auth.Clone(), true
 }
 
+// pickNext selects an auth for provider, queueing briefly when every candidate is at its
+// concurrency or rate limit. The caller must release the limiter slot via releaseAuth.
 func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, error) {
+	deadline := m.limitDeadline(ctx)
+	for {
+		auth, executor, wake, errPick := m.pickNextOnce(ctx, provider, model, opts, tried)
+		if !isLimitSaturatedError(errPick) || !m.waitForLimit(ctx, wake, deadline) {
+			return auth, executor, errPick
+		}
+	}
+}
+
+func (m *Manager) pickNextOnce(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, time.Time, error) {
 	m.mu.RLock()
 	executor, okExecutor := m.executors[provider]
 	if !okExecutor {
 		m.mu.RUnlock()
-		return nil, nil, &Error{Code: "executor_not_found", Message: "executor not registered"}
+		return nil, nil, time.Time{}, &Error{Code: "executor_not_found", Message: "executor not registered"}
 	}
 	candidates := make([]*Auth, 0, len(m.auths))
 	modelKey := strings.TrimSpace(model)
@@ -1545,6 +1830,10 @@ func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cli
 		}
 	}
 	registryRef := registry.GetGlobalRegistry()
+	now := time.Now()
+	requiredTags := RequiredTagsFromContext(ctx)
+	tripped := false
+	untagged := false
 	for _, candidate := range m.auths {
 		if candidate.Provider != provider || candidate.Disabled {
 			continue
@@ -1552,23 +1841,48 @@ func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cli
 		if _, used := tried[candidate.ID]; used {
 			continue
 		}
+		if !candidate.HasTags(requiredTags) {
+			untagged = true
+			continue
+		}
 		if modelKey != "" && registryRef != nil && !registryRef.ClientSupportsModel(candidate.ID, modelKey) {
 			continue
 		}
+		if m.circuitBlocked(candidate.ID, modelKey, now) {
+			tripped = true
+			continue
+		}
 		candidates = append(candidates, candidate)
 	}
 	if len(candidates) == 0 {
 		m.mu.RUnlock()
-		return nil, nil, &Error{Code: "auth_not_found", Message: "no auth available"}
+		if tripped {
+			return nil, nil, time.Time{}, newCircuitOpenError()
+		}
+		if untagged {
+			return nil, nil, time.Time{}, newTagMismatchError(requiredTags)
+		}
+		return nil, nil, time.Time{}, &Error{Code: "auth_not_found", Message: "no auth available"}
 	}
-	selected, errPick := m.selector.Pick(ctx, provider, model, opts, candidates)
+	selected, wake, errPick := m.limiter.reserve(candidates, now, func(available []*Auth) (*Auth, error) {
+		if pinned := pinnedCandidate(ctx, available, model, now); pinned != nil {
+			return pinned, nil
+		}
+		return m.selector.Pick(ctx, provider, model, opts, available)
+	})
 	if errPick != nil {
 		m.mu.RUnlock()
-		return nil, nil, errPick
+		return nil, nil, wake, errPick
 	}
 	if selected == nil {
 		m.mu.RUnlock()
-		return nil, nil, &Error{Code: "auth_not_found", Message: "selector returned no auth"}
+		return nil, nil, time.Time{}, &Error{Code: "auth_not_found", Message: "selector returned no auth"}
+	}
+	if !m.acquireCircuit(selected.ID, modelKey, now) {
+		// Another request took the half-open probe since the candidates were filtered.
+		m.limiter.cancel(selected.ID, now)
+		m.mu.RUnlock()
+		return nil, nil, now, newLimitSaturatedError()
 	}
 	authCopy := selected.Clone()
 	m.mu.RUnlock()
@@ -1580,10 +1894,22 @@ func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cli
 		}
 		m.mu.Unlock()
 	}
-	return authCopy, executor, nil
+	return authCopy, executor, time.Time{}, nil
 }
 
+// pickNextMixed selects an auth across providers, queueing briefly when every candidate is at
+// its concurrency or rate limit. The caller must release the limiter slot via releaseAuth.
 func (m *Manager) pickNextMixed(ctx context.Context, providers []string, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, string, error) {
+	deadline := m.limitDeadline(ctx)
+	for {
+		auth, executor, provider, wake, errPick := m.pickNextMixedOnce(ctx, providers, model, opts, tried)
+		if !isLimitSaturatedError(errPick) || !m.waitForLimit(ctx, wake, deadline) {
+			return auth, executor, provider, errPick
+		}
+	}
+}
+
+func (m *Manager) pickNextMixedOnce(ctx context.Context, providers []string, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, string, time.Time, error) {
 	providerSet := make(map[string]struct{}, len(providers))
 	for _, provider := range providers {
 		p := strings.TrimSpace(strings.ToLower(provider))
@@ -1593,7 +1919,7 @@ func (m *Manager) pickNextMixed(ctx context.Context, providers []string, model s
 		providerSet[p] = struct{}{}
 	}
 	if len(providerSet) == 0 {
-		return nil, nil, "", &Error{Code: "provider_not_found", Message: "no provider supplied"}
+		return nil, nil, "", time.Time{}, &Error{Code: "provider_not_found", Message: "no provider supplied"}
 	}
 
 	m.mu.RLock()
@@ -1607,6 +1933,10 @@ func (m *Manager) pickNextMixed(ctx context.Context, providers []string, model s
 		}
 	}
 	registryRef := registry.GetGlobalRegistry()
+	now := time.Now()
+	requiredTags := RequiredTagsFromContext(ctx)
+	tripped := false
+	untagged := false
 	for _, candidate := range m.auths {
 		if candidate == nil || candidate.Disabled {
 			continue
@@ -1621,32 +1951,58 @@ func (m *Manager) pickNextMixed(ctx context.Context, providers []string, model s
 		if _, used := tried[candidate.ID]; used {
 			continue
 		}
+		if !candidate.HasTags(requiredTags) {
+			untagged = true
+			continue
+		}
 		if _, ok := m.executors[providerKey]; !ok {
 			continue
 		}
 		if modelKey != "" && registryRef != nil && !registryRef.ClientSupportsModel(candidate.ID, modelKey) {
 			continue
 		}
+		if m.circuitBlocked(candidate.ID, modelKey, now) {
+			tripped = true
+			continue
+		}
 		candidates = append(candidates, candidate)
 	}
 	if len(candidates) == 0 {
 		m.mu.RUnlock()
-		return nil, nil, "", &Error{Code: "auth_not_found", Message: "no auth available"}
+		if tripped {
+			return nil, nil, "", time.Time{}, newCircuitOpenError()
+		}
+		if untagged {
+			return nil, nil, "", time.Time{}, newTagMismatchError(requiredTags)
+		}
+		return nil, nil, "", time.Time{}, &Error{Code: "auth_not_found", Message: "no auth available"}
 	}
-	selected, errPick := m.selector.Pick(ctx, "mixed", model, opts, candidates)
+	selected, wake, errPick := m.limiter.reserve(candidates, now, func(available []*Auth) (*Auth, error) {
+		if pinned := pinnedCandidate(ctx, available, model, now); pinned != nil {
+			return pinned, nil
+		}
+		return m.selector.Pick(ctx, "mixed", model, opts, available)
+	})
 	if errPick != nil {
 		m.mu.RUnlock()
-		return nil, nil, "", errPick
+		return nil, nil, "", wake, errPick
 	}
 	if selected == nil {
 		m.mu.RUnlock()
-		return nil, nil, "", &Error{Code: "auth_not_found", Message: "selector returned no auth"}
+		return nil, nil, "", time.Time{}, &Error{Code: "auth_not_found", Message: "selector returned no auth"}
 	}
 	providerKey := strings.TrimSpace(strings.ToLower(selected.Provider))
 	executor, okExecutor := m.executors[providerKey]
 	if !okExecutor {
+		m.limiter.cancel(selected.ID, now)
+		m.mu.RUnlock()
+		return nil, nil, "", time.Time{}, &Error{Code: "executor_not_found", Message: "executor not registered"}
+	}
+	if !m.acquireCircuit(selected.ID, modelKey, now) {
+		// Another request took the half-open probe since the candidates were filtered.
+		m.limiter.cancel(selected.ID, now)
 		m.mu.RUnlock()
-		return nil, nil, "", &Error{Code: "executor_not_found", Message: "executor not registered"}
+		return nil, nil, "", now, newLimitSaturatedError()
 	}
 	authCopy := selected.Clone()
 	m.mu.RUnlock()
@@ -1658,7 +2014,7 @@ func (m *Manager) pickNextMixed(ctx context.Context, providers []string, model s
 		}
 		m.mu.Unlock()
 	}
-	return authCopy, executor, providerKey, nil
+	return authCopy, executor, providerKey, time.Time{}, nil
 }
 
 func (m *Manager) persist(ctx context.Context, auth *Auth) error {
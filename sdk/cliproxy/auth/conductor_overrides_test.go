@@ -95,3 +95,23 @@ func TestManager_MarkResult_RespectsAuthDisableCoolingOverride(t *testing.T) {
 		t.Fatalf("expected NextRetryAfter to be zero when disable_cooling=true, got %v", state.NextRetryAfter)
 	}
 }
+
+type countingHook struct {
+	NoopHook
+	results *int
+}
+
+func (h *countingHook) OnResult(context.Context, Result) { *h.results++ }
+
+func TestManager_AddHook_NotifiesOnceAlongsideConstructorHook(t *testing.T) {
+	var first, added int
+	m := NewManager(nil, nil, &countingHook{results: &first})
+	hook := &countingHook{results: &added}
+	m.AddHook(hook)
+	m.AddHook(hook)
+
+	m.MarkResult(context.Background(), Result{AuthID: "missing", Provider: "claude", Model: "m", Success: true})
+	if first != 1 || added != 1 {
+		t.Fatalf("expected each hook to see the result once, got constructor=%d added=%d", first, added)
+	}
+}
@@ -0,0 +1,259 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+// errHedgeLost is the cancellation cause of the losing attempt of a hedged request.
+var errHedgeLost = errors.New("hedged attempt lost the race")
+
+// attemptAbandoned reports whether ctx was cancelled because the conductor gave up on the attempt.
+func attemptAbandoned(ctx context.Context) bool {
+	return ctx != nil && errors.Is(context.Cause(ctx), errHedgeLost)
+}
+
+type hedgePolicy struct {
+	patterns []string
+	delay    time.Duration
+	fallback string
+}
+
+// hedgePolicyTable holds the hedging policies compiled from the runtime config, in config order.
+type hedgePolicyTable []*hedgePolicy
+
+func compileHedgePolicies(entries []internalconfig.HedgePolicy) hedgePolicyTable {
+	out := make(hedgePolicyTable, 0, len(entries))
+	for _, entry := range entries {
+		if entry.DelayMS <= 0 {
+			continue
+		}
+		policy := &hedgePolicy{delay: time.Duration(entry.DelayMS) * time.Millisecond, fallback: strings.TrimSpace(entry.FallbackModel)}
+		for _, pattern := range entry.Models {
+			if pattern = strings.ToLower(strings.TrimSpace(pattern)); pattern != "" {
+				policy.patterns = append(policy.patterns, pattern)
+			}
+		}
+		if len(policy.patterns) > 0 {
+			out = append(out, policy)
+		}
+	}
+	return out
+}
+
+// lookupHedgePolicy returns the first policy matching model (ignoring a thinking suffix), or nil.
+func (m *Manager) lookupHedgePolicy(model string) *hedgePolicy {
+	if m == nil {
+		return nil
+	}
+	table, _ := m.hedgePolicies.Load().(hedgePolicyTable)
+	if len(table) == 0 {
+		return nil
+	}
+	key := strings.ToLower(strings.TrimSpace(thinking.ParseSuffix(model).ModelName))
+	for _, policy := range table {
+		for _, pattern := range policy.patterns {
+			if util.MatchWildcard(pattern, key) {
+				return policy
+			}
+		}
+	}
+	return nil
+}
+
+type hedgeOutcome[T any] struct {
+	value T
+	err   error
+	hedge bool
+}
+
+// executeHedged runs req through run and, when the attempt has not responded after the policy
+// delay, races a second attempt on a different credential or against the policy's fallback
+// model. The first successful attempt wins and the other is cancelled and reported as abandoned;
+// discard releases the value of an attempt that succeeded after the race was decided. The
+// winner's context is cancelled once its value is returned, unless settle takes over: settle
+// receives the winning value and its cancel function, e.g. to cancel when a stream drains.
+func executeHedged[T any](ctx context.Context, m *Manager, policy *hedgePolicy, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, run func(context.Context, []string, cliproxyexecutor.Request, cliproxyexecutor.Options) (T, error), discard func(T), settle func(T, func()) T) (T, error) {
+	var zero T
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	outcomes := make(chan hedgeOutcome[T], 2)
+	cancels := make(map[bool]context.CancelCauseFunc, 2)
+	defer func() {
+		for _, cancel := range cancels {
+			cancel(nil)
+		}
+	}()
+
+	var mu sync.Mutex
+	var primaryAuths []string
+	primaryCtx, cancelPrimary := context.WithCancelCause(ctx)
+	cancels[false] = cancelPrimary
+	primaryCtx = WithSelectionObserver(usage.WithHedgeRole(primaryCtx, usage.HedgePrimary), func(authID string) {
+		mu.Lock()
+		primaryAuths = append(primaryAuths, authID)
+		mu.Unlock()
+	})
+	go func() {
+		value, err := run(primaryCtx, providers, req, opts)
+		outcomes <- hedgeOutcome[T]{value: value, err: err}
+	}()
+	pending := 1
+
+	timer := time.NewTimer(policy.delay)
+	defer timer.Stop()
+	hedgeC := timer.C
+	var primaryErr, hedgeErr error
+	for {
+		select {
+		case <-ctx.Done():
+			for _, cancel := range cancels {
+				cancel(context.Cause(ctx))
+			}
+			go drainHedgeOutcomes(outcomes, pending, discard)
+			return zero, ctx.Err()
+		case <-hedgeC:
+			hedgeC = nil
+			hedgeProviders, hedgeReq, hedgeOpts := providers, req, opts
+			hedgeCtx, cancelHedge := context.WithCancelCause(ctx)
+			if policy.fallback != "" {
+				hedgeReq.Model = applyRequestedSuffix(req.Model, policy.fallback)
+				hedgeProviders = util.GetProviderName(thinking.ParseSuffix(hedgeReq.Model).ModelName)
+				hedgeOpts = withRequestedModel(opts, hedgeReq.Model)
+				if len(hedgeProviders) == 0 {
+					cancelHedge(nil)
+					logEntryWithRequestID(ctx).Debugf("hedge for %s skipped: unknown provider for fallback model %s", req.Model, hedgeReq.Model)
+					continue
+				}
+			} else {
+				mu.Lock()
+				hedgeCtx = WithExcludedAuths(hedgeCtx, primaryAuths...)
+				mu.Unlock()
+			}
+			cancels[true] = cancelHedge
+			pending++
+			logEntryWithRequestID(ctx).Debugf("hedging %s with %s after %s without a response", req.Model, hedgeReq.Model, policy.delay)
+			go func(attemptCtx context.Context) {
+				value, err := run(usage.WithHedgeRole(attemptCtx, usage.HedgeSecondary), hedgeProviders, hedgeReq, hedgeOpts)
+				outcomes <- hedgeOutcome[T]{value: value, err: err, hedge: true}
+			}(hedgeCtx)
+		case outcome := <-outcomes:
+			pending--
+			if outcome.err == nil {
+				if cancelLoser, ok := cancels[!outcome.hedge]; ok {
+					cancelLoser(errHedgeLost)
+				}
+				go drainHedgeOutcomes(outcomes, pending, discard)
+				if settle == nil {
+					return outcome.value, nil
+				}
+				cancelWinner := cancels[outcome.hedge]
+				delete(cancels, outcome.hedge)
+				return settle(outcome.value, func() { cancelWinner(nil) }), nil
+			}
+			if outcome.hedge {
+				hedgeErr = outcome.err
+			} else {
+				primaryErr = outcome.err
+			}
+			if pending > 0 {
+				continue
+			}
+			if primaryErr != nil {
+				return zero, primaryErr
+			}
+			return zero, hedgeErr
+		}
+	}
+}
+
+// drainHedgeOutcomes collects the attempts still running after a hedged request was decided.
+func drainHedgeOutcomes[T any](outcomes <-chan hedgeOutcome[T], pending int, discard func(T)) {
+	for ; pending > 0; pending-- {
+		outcome := <-outcomes
+		if outcome.err == nil && discard != nil {
+			discard(outcome.value)
+		}
+	}
+}
+
+// executeStreamFirstByte runs a stream attempt and waits for its first chunk so hedged attempts
+// race on time to first byte. A stream whose first chunk is an error fails the attempt.
+func (m *Manager) executeStreamFirstByte(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	chunks, err := m.executeStream(ctx, providers, req, opts)
+	if err != nil {
+		return nil, err
+	}
+	var first cliproxyexecutor.StreamChunk
+	var ok bool
+	select {
+	case <-ctx.Done():
+		go drainStream(chunks)
+		return nil, ctx.Err()
+	case first, ok = <-chunks:
+	}
+	out := make(chan cliproxyexecutor.StreamChunk)
+	if !ok {
+		close(out)
+		return out, nil
+	}
+	if first.Err != nil {
+		go drainStream(chunks)
+		return nil, first.Err
+	}
+	go func() {
+		defer close(out)
+		select {
+		case <-ctx.Done():
+			drainStream(chunks)
+			return
+		case out <- first:
+		}
+		forward := true
+		for chunk := range chunks {
+			if !forward {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				forward = false
+			case out <- chunk:
+			}
+		}
+	}()
+	return out, nil
+}
+
+// cancelWhenDrained forwards the chunks of a winning hedged stream and calls cancel once the
+// stream ends or ctx is done, releasing the context of the attempt.
+func cancelWhenDrained(ctx context.Context, chunks <-chan cliproxyexecutor.StreamChunk, cancel func()) <-chan cliproxyexecutor.StreamChunk {
+	out := make(chan cliproxyexecutor.StreamChunk)
+	go func() {
+		defer close(out)
+		defer cancel()
+		for chunk := range chunks {
+			select {
+			case <-ctx.Done():
+				go drainStream(chunks)
+				return
+			case out <- chunk:
+			}
+		}
+	}()
+	return out
+}
+
+func drainStream(chunks <-chan cliproxyexecutor.StreamChunk) {
+	for range chunks {
+	}
+}
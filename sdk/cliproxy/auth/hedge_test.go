@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+// hedgeTestExecutor stalls the first attempt until it is cancelled and answers later attempts at once.
+type hedgeTestExecutor struct {
+	mu        sync.Mutex
+	auths     []string
+	roles     []string
+	contexts  []context.Context
+	cancelled chan struct{}
+}
+
+func (e *hedgeTestExecutor) Identifier() string { return "hedge-test" }
+
+func (e *hedgeTestExecutor) begin(ctx context.Context, auth *Auth) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.auths = append(e.auths, auth.ID)
+	e.roles = append(e.roles, usage.HedgeRoleFromContext(ctx))
+	e.contexts = append(e.contexts, ctx)
+	return len(e.auths)
+}
+
+func (e *hedgeTestExecutor) stall(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		close(e.cancelled)
+		return ctx.Err()
+	case <-time.After(5 * time.Second):
+		return errors.New("primary was not cancelled")
+	}
+}
+
+func (e *hedgeTestExecutor) Execute(ctx context.Context, auth *Auth, _ cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	if e.begin(ctx, auth) == 1 {
+		return cliproxyexecutor.Response{}, e.stall(ctx)
+	}
+	return cliproxyexecutor.Response{Payload: []byte(auth.ID)}, nil
+}
+
+func (e *hedgeTestExecutor) ExecuteStream(ctx context.Context, auth *Auth, _ cliproxyexecutor.Request, _ cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	call := e.begin(ctx, auth)
+	ch := make(chan cliproxyexecutor.StreamChunk, 1)
+	go func() {
+		defer close(ch)
+		if call == 1 {
+			ch <- cliproxyexecutor.StreamChunk{Err: e.stall(ctx)}
+			return
+		}
+		ch <- cliproxyexecutor.StreamChunk{Payload: []byte(auth.ID)}
+	}()
+	return ch, nil
+}
+
+func (e *hedgeTestExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) { return auth, nil }
+
+func (e *hedgeTestExecutor) CountTokens(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e *hedgeTestExecutor) HttpRequest(context.Context, *Auth, *http.Request) (*http.Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+type resultRecorder struct {
+	NoopHook
+	mu      sync.Mutex
+	results []Result
+}
+
+func (h *resultRecorder) OnResult(_ context.Context, result Result) {
+	h.mu.Lock()
+	h.results = append(h.results, result)
+	h.mu.Unlock()
+}
+
+func (h *resultRecorder) waitFor(t *testing.T, n int) []Result {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		h.mu.Lock()
+		results := append([]Result(nil), h.results...)
+		h.mu.Unlock()
+		if len(results) >= n || time.Now().After(deadline) {
+			return results
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func newHedgeTestManager(t *testing.T) (*Manager, *hedgeTestExecutor, *resultRecorder) {
+	t.Helper()
+	hook := &resultRecorder{}
+	m := NewManager(nil, nil, hook)
+	m.SetConfig(&internalconfig.Config{Hedging: []internalconfig.HedgePolicy{{Models: []string{"hedge-*"}, DelayMS: 20}}})
+	executor := &hedgeTestExecutor{cancelled: make(chan struct{})}
+	m.RegisterExecutor(executor)
+	reg := registry.GetGlobalRegistry()
+	for _, id := range []string{"hedge-a", "hedge-b"} {
+		if _, err := m.Register(context.Background(), &Auth{ID: id, Provider: "hedge-test"}); err != nil {
+			t.Fatalf("register auth: %v", err)
+		}
+		reg.RegisterClient(id, "hedge-test", []*registry.ModelInfo{{ID: "hedge-model"}})
+		authID := id
+		t.Cleanup(func() { reg.UnregisterClient(authID) })
+	}
+	return m, executor, hook
+}
+
+func assertHedgeRace(t *testing.T, executor *hedgeTestExecutor, hook *resultRecorder, winner string) {
+	t.Helper()
+	select {
+	case <-executor.cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected the slow primary attempt to be cancelled")
+	}
+	if len(executor.auths) != 2 || executor.auths[0] == executor.auths[1] {
+		t.Fatalf("expected the hedge on a different credential, got %v", executor.auths)
+	}
+	if winner != executor.auths[1] {
+		t.Fatalf("expected the hedge attempt to win, got %s", winner)
+	}
+	if executor.roles[0] != usage.HedgePrimary || executor.roles[1] != usage.HedgeSecondary {
+		t.Fatalf("unexpected hedge roles %v", executor.roles)
+	}
+	var won, abandoned bool
+	for _, result := range hook.waitFor(t, 2) {
+		switch {
+		case result.AuthID == executor.auths[1] && result.Success:
+			won = true
+		case result.AuthID == executor.auths[0] && result.Abandoned:
+			abandoned = true
+		}
+	}
+	if !won || !abandoned {
+		t.Fatalf("expected both attempts to be recorded, got %+v", hook.results)
+	}
+	if executor.contexts[1].Err() == nil {
+		t.Fatalf("expected the context of the winning attempt to be cancelled once it was consumed")
+	}
+}
+
+func TestManagerExecute_HedgesSlowAttempt(t *testing.T) {
+	m, executor, hook := newHedgeTestManager(t)
+	resp, err := m.Execute(context.Background(), []string{"hedge-test"}, cliproxyexecutor.Request{Model: "hedge-model"}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	assertHedgeRace(t, executor, hook, string(resp.Payload))
+
+	// The abandoned attempt must not cool the credential down.
+	auth, _ := m.GetByID(executor.auths[0])
+	if state := auth.ModelStates["hedge-model"]; state != nil && state.Unavailable {
+		t.Fatalf("expected abandoned attempt to leave auth state untouched, got %+v", state)
+	}
+}
+
+func TestManagerExecuteStream_HedgesSlowFirstByte(t *testing.T) {
+	m, executor, hook := newHedgeTestManager(t)
+	chunks, err := m.ExecuteStream(context.Background(), []string{"hedge-test"}, cliproxyexecutor.Request{Model: "hedge-model"}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("ExecuteStream: %v", err)
+	}
+	var got []byte
+	for chunk := range chunks {
+		if chunk.Err != nil {
+			t.Fatalf("unexpected stream error: %v", chunk.Err)
+		}
+		got = append(got, chunk.Payload...)
+	}
+	assertHedgeRace(t, executor, hook, string(got))
+}
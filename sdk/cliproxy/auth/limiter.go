@@ -0,0 +1,325 @@
+package auth
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tracing"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+const (
+	// limitWindow is the sliding window used for RPM and TPM accounting.
+	limitWindow = time.Minute
+	// defaultLimitWait bounds how long a request waits when every candidate is saturated,
+	// unless routing.limit-wait-seconds says otherwise.
+	defaultLimitWait = 5 * time.Second
+)
+
+// authLimits captures the optional per-credential limits declared through auth attributes.
+// Zero values mean unlimited.
+type authLimits struct {
+	maxConcurrency int
+	rpm            int
+	tpm            int
+}
+
+func (l authLimits) unlimited() bool {
+	return l.maxConcurrency <= 0 && l.rpm <= 0 && l.tpm <= 0
+}
+
+func limitsForAuth(auth *Auth) authLimits {
+	if auth == nil || auth.Attributes == nil {
+		return authLimits{}
+	}
+	return authLimits{
+		maxConcurrency: intAttribute(auth.Attributes, "max_concurrency"),
+		rpm:            intAttribute(auth.Attributes, "rpm"),
+		tpm:            intAttribute(auth.Attributes, "tpm"),
+	}
+}
+
+func intAttribute(attrs map[string]string, key string) int {
+	raw := strings.TrimSpace(attrs[key])
+	if raw == "" {
+		return 0
+	}
+	parsed, err := strconv.Atoi(raw)
+	if err != nil || parsed < 0 {
+		return 0
+	}
+	return parsed
+}
+
+type tokenSample struct {
+	at     time.Time
+	tokens int64
+}
+
+type limitState struct {
+	inFlight int
+	requests []time.Time
+	tokens   []tokenSample
+}
+
+func (s *limitState) prune(now time.Time) {
+	cutoff := now.Add(-limitWindow)
+	i := 0
+	for i < len(s.requests) && !s.requests[i].After(cutoff) {
+		i++
+	}
+	s.requests = s.requests[i:]
+	j := 0
+	for j < len(s.tokens) && !s.tokens[j].at.After(cutoff) {
+		j++
+	}
+	s.tokens = s.tokens[j:]
+}
+
+func (s *limitState) tokenTotal() int64 {
+	var total int64
+	for i := range s.tokens {
+		total += s.tokens[i].tokens
+	}
+	return total
+}
+
+// authLimiter enforces max-concurrency, RPM and TPM limits per auth ID.
+type authLimiter struct {
+	mu     sync.Mutex
+	states map[string]*limitState
+	// released is closed and replaced whenever capacity frees up, waking queued pickers.
+	released chan struct{}
+}
+
+func newAuthLimiter() *authLimiter {
+	return &authLimiter{
+		states:   make(map[string]*limitState),
+		released: make(chan struct{}),
+	}
+}
+
+// saturated reports whether auth has no spare capacity at now. When saturated because of a
+// rate window, wake holds the time at which the oldest sample leaves the window.
+func (l *authLimiter) saturated(auth *Auth, now time.Time) (bool, time.Time) {
+	limits := limitsForAuth(auth)
+	if limits.unlimited() {
+		return false, time.Time{}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.saturatedLocked(auth.ID, limits, now)
+}
+
+func (l *authLimiter) saturatedLocked(id string, limits authLimits, now time.Time) (bool, time.Time) {
+	state := l.states[id]
+	if state == nil {
+		return false, time.Time{}
+	}
+	state.prune(now)
+	if limits.maxConcurrency > 0 && state.inFlight >= limits.maxConcurrency {
+		return true, time.Time{}
+	}
+	if limits.rpm > 0 && len(state.requests) >= limits.rpm {
+		return true, state.requests[0].Add(limitWindow)
+	}
+	if limits.tpm > 0 && len(state.tokens) > 0 && state.tokenTotal() >= int64(limits.tpm) {
+		return true, state.tokens[0].at.Add(limitWindow)
+	}
+	return false, time.Time{}
+}
+
+// reserve picks one of candidates that still has spare capacity and reserves a concurrency
+// slot and a request for it. Capacity is checked and reserved under one lock, so a credential
+// is never handed to pick and then refused, which would advance a selector's cursor for nothing.
+// When every candidate is saturated it returns a limit error and the time at which a rate
+// window frees up, if any.
+func (l *authLimiter) reserve(candidates []*Auth, now time.Time, pick func(available []*Auth) (*Auth, error)) (*Auth, time.Time, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	available := make([]*Auth, 0, len(candidates))
+	var wake time.Time
+	for _, candidate := range candidates {
+		if blocked, next := l.saturatedLocked(candidate.ID, limitsForAuth(candidate), now); blocked {
+			wake = earliestWake(wake, next)
+			continue
+		}
+		available = append(available, candidate)
+	}
+	if len(available) == 0 {
+		return nil, wake, newLimitSaturatedError()
+	}
+	selected, err := pick(available)
+	if err != nil || selected == nil {
+		return selected, time.Time{}, err
+	}
+	if limits := limitsForAuth(selected); !limits.unlimited() {
+		state := l.states[selected.ID]
+		if state == nil {
+			state = &limitState{}
+			l.states[selected.ID] = state
+		}
+		state.inFlight++
+		state.requests = append(state.requests, now)
+	}
+	return selected, time.Time{}, nil
+}
+
+// acquire reserves a concurrency slot and records a request for auth.
+// It returns false when auth has no spare capacity.
+func (l *authLimiter) acquire(auth *Auth, now time.Time) bool {
+	selected, _, _ := l.reserve([]*Auth{auth}, now, func(available []*Auth) (*Auth, error) { return available[0], nil })
+	return selected != nil
+}
+
+// release frees the concurrency slot held by a finished request.
+func (l *authLimiter) release(authID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	state := l.states[authID]
+	if state == nil || state.inFlight <= 0 {
+		return
+	}
+	state.inFlight--
+	l.notifyLocked()
+}
+
+// cancel undoes a reservation made at reservedAt that was refused before the request was sent,
+// freeing its concurrency slot and removing its request from the RPM window.
+func (l *authLimiter) cancel(authID string, reservedAt time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	state := l.states[authID]
+	if state == nil {
+		return
+	}
+	for i := len(state.requests) - 1; i >= 0; i-- {
+		if state.requests[i].Equal(reservedAt) {
+			state.requests = append(state.requests[:i], state.requests[i+1:]...)
+			break
+		}
+	}
+	if state.inFlight > 0 {
+		state.inFlight--
+	}
+	l.notifyLocked()
+}
+
+// recordTokens adds consumed tokens to the TPM window of authID.
+func (l *authLimiter) recordTokens(authID string, tokens int64, now time.Time) {
+	if authID == "" || tokens <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	state := l.states[authID]
+	if state == nil {
+		return
+	}
+	state.prune(now)
+	state.tokens = append(state.tokens, tokenSample{at: now, tokens: tokens})
+}
+
+func (l *authLimiter) notifyLocked() {
+	close(l.released)
+	l.released = make(chan struct{})
+}
+
+// wait blocks until capacity is released, wake is reached, or deadline expires.
+// It returns false when the deadline passed or ctx was cancelled.
+func (l *authLimiter) wait(ctx context.Context, wake, deadline time.Time) bool {
+	l.mu.Lock()
+	released := l.released
+	l.mu.Unlock()
+
+	until := deadline
+	if !wake.IsZero() && wake.Before(until) {
+		until = wake
+	}
+	delay := time.Until(until)
+	if delay <= 0 {
+		return time.Now().Before(deadline)
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-released:
+		return true
+	case <-timer.C:
+		return time.Now().Before(deadline)
+	}
+}
+
+func earliestWake(current, next time.Time) time.Time {
+	if next.IsZero() {
+		return current
+	}
+	if current.IsZero() || next.Before(current) {
+		return next
+	}
+	return current
+}
+
+// limitDeadline returns when a request starting now stops waiting for saturated credentials:
+// after the configured limit wait, or earlier when ctx has an earlier deadline.
+func (m *Manager) limitDeadline(ctx context.Context) time.Time {
+	wait := defaultLimitWait
+	if cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config); cfg != nil && cfg.Routing.LimitWaitSeconds > 0 {
+		wait = time.Duration(cfg.Routing.LimitWaitSeconds) * time.Second
+	}
+	deadline := time.Now().Add(wait)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	return deadline
+}
+
+// waitForLimit queues the caller until a saturated credential frees capacity.
+func (m *Manager) waitForLimit(ctx context.Context, wake, deadline time.Time) bool {
+	_, span := tracing.Start(ctx, "cliproxy.limit_wait")
+	defer span.End()
+	return m.limiter.wait(ctx, wake, deadline)
+}
+
+// forget drops the limiter state of a disabled or removed auth. Slots still held by its
+// in-flight requests are released as a no-op.
+func (l *authLimiter) forget(authID string) {
+	l.mu.Lock()
+	delete(l.states, authID)
+	l.mu.Unlock()
+}
+
+// releaseAuth returns the limiter slot acquired when auth was picked.
+func (m *Manager) releaseAuth(authID string) {
+	if m == nil || m.limiter == nil {
+		return
+	}
+	m.limiter.release(authID)
+}
+
+// HandleUsage implements usage.Plugin so token consumption counts towards per-credential TPM limits.
+func (m *Manager) HandleUsage(ctx context.Context, record usage.Record) {
+	if m == nil || m.limiter == nil {
+		return
+	}
+	tokens := record.Detail.TotalTokens
+	if tokens == 0 {
+		tokens = record.Detail.InputTokens + record.Detail.OutputTokens + record.Detail.ReasoningTokens
+	}
+	m.limiter.recordTokens(record.AuthID, tokens, time.Now())
+}
+
+func newLimitSaturatedError() *Error {
+	return &Error{Code: "auth_limit_reached", Message: "all credentials reached their concurrency or rate limits", Retryable: true, HTTPStatus: 429}
+}
+
+func isLimitSaturatedError(err error) bool {
+	authErr, ok := err.(*Error)
+	return ok && authErr != nil && authErr.Code == "auth_limit_reached"
+}
@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+type limiterTestExecutor struct{}
+
+func (limiterTestExecutor) Identifier() string { return "limit-test" }
+
+func (limiterTestExecutor) Execute(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, nil
+}
+
+func (limiterTestExecutor) ExecuteStream(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (limiterTestExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) { return auth, nil }
+
+func (limiterTestExecutor) CountTokens(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, errors.New("not implemented")
+}
+
+func (limiterTestExecutor) HttpRequest(context.Context, *Auth, *http.Request) (*http.Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestAuthLimiter_MaxConcurrency(t *testing.T) {
+	l := newAuthLimiter()
+	auth := &Auth{ID: "a", Attributes: map[string]string{"max_concurrency": "1"}}
+	now := time.Now()
+
+	if !l.acquire(auth, now) {
+		t.Fatalf("first acquire should succeed")
+	}
+	if blocked, _ := l.saturated(auth, now); !blocked {
+		t.Fatalf("expected auth to be saturated at max concurrency")
+	}
+	if l.acquire(auth, now) {
+		t.Fatalf("second acquire should fail while slot is held")
+	}
+	l.release(auth.ID)
+	if !l.acquire(auth, now) {
+		t.Fatalf("acquire after release should succeed")
+	}
+}
+
+func TestAuthLimiter_RPMWindow(t *testing.T) {
+	l := newAuthLimiter()
+	auth := &Auth{ID: "a", Attributes: map[string]string{"rpm": "2"}}
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if !l.acquire(auth, now) {
+			t.Fatalf("acquire %d should succeed", i)
+		}
+		l.release(auth.ID)
+	}
+	blocked, wake := l.saturated(auth, now)
+	if !blocked {
+		t.Fatalf("expected auth to be saturated after rpm requests")
+	}
+	if want := now.Add(limitWindow); !wake.Equal(want) {
+		t.Fatalf("wake = %v, want %v", wake, want)
+	}
+	if blocked, _ = l.saturated(auth, now.Add(limitWindow+time.Second)); blocked {
+		t.Fatalf("expected rpm window to roll over")
+	}
+}
+
+func TestAuthLimiter_CancelReturnsRPMBudget(t *testing.T) {
+	l := newAuthLimiter()
+	auth := &Auth{ID: "a", Attributes: map[string]string{"rpm": "1", "max_concurrency": "1"}}
+	now := time.Now()
+
+	if !l.acquire(auth, now) {
+		t.Fatalf("acquire should succeed")
+	}
+	l.cancel(auth.ID, now)
+	if blocked, _ := l.saturated(auth, now); blocked {
+		t.Fatalf("a cancelled reservation should not count against rpm or concurrency")
+	}
+	if !l.acquire(auth, now) {
+		t.Fatalf("acquire after cancel should succeed")
+	}
+}
+
+func TestAuthLimiter_TPMFromUsage(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	auth := &Auth{ID: "a", Attributes: map[string]string{"tpm": "100"}}
+	now := time.Now()
+
+	if !m.limiter.acquire(auth, now) {
+		t.Fatalf("acquire should succeed")
+	}
+	m.releaseAuth(auth.ID)
+	m.HandleUsage(context.Background(), usage.Record{AuthID: "a", Detail: usage.Detail{TotalTokens: 120}})
+
+	if blocked, _ := m.limiter.saturated(auth, time.Now()); !blocked {
+		t.Fatalf("expected auth to be saturated after exceeding tpm")
+	}
+}
+
+func TestManager_PickNextMixed_SkipsSaturatedAuth(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	m.RegisterExecutor(limiterTestExecutor{})
+	limited := &Auth{ID: "limited", Provider: "limit-test", Attributes: map[string]string{"max_concurrency": "1"}}
+	free := &Auth{ID: "free", Provider: "limit-test"}
+	for _, auth := range []*Auth{limited, free} {
+		if _, errRegister := m.Register(context.Background(), auth); errRegister != nil {
+			t.Fatalf("register auth: %v", errRegister)
+		}
+	}
+	if !m.limiter.acquire(limited, time.Now()) {
+		t.Fatalf("acquire should succeed")
+	}
+
+	for i := 0; i < 3; i++ {
+		got, _, _, errPick := m.pickNextMixed(context.Background(), []string{"limit-test"}, "", cliproxyexecutor.Options{}, map[string]struct{}{})
+		if errPick != nil {
+			t.Fatalf("pickNextMixed() error = %v", errPick)
+		}
+		if got.ID != "free" {
+			t.Fatalf("pickNextMixed() auth.ID = %q, want %q", got.ID, "free")
+		}
+	}
+}
+
+func TestManager_PickNextMixed_QueuesUntilSlotReleased(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	m.RegisterExecutor(limiterTestExecutor{})
+	auth := &Auth{ID: "only", Provider: "limit-test", Attributes: map[string]string{"max_concurrency": "1"}}
+	if _, errRegister := m.Register(context.Background(), auth); errRegister != nil {
+		t.Fatalf("register auth: %v", errRegister)
+	}
+	if !m.limiter.acquire(auth, time.Now()) {
+		t.Fatalf("acquire should succeed")
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		m.releaseAuth(auth.ID)
+	}()
+
+	got, _, _, errPick := m.pickNextMixed(context.Background(), []string{"limit-test"}, "", cliproxyexecutor.Options{}, map[string]struct{}{})
+	if errPick != nil {
+		t.Fatalf("pickNextMixed() error = %v", errPick)
+	}
+	if got.ID != "only" {
+		t.Fatalf("pickNextMixed() auth.ID = %q, want %q", got.ID, "only")
+	}
+}
+
+func TestManager_PickNextMixed_SaturatedReturns429(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	m.RegisterExecutor(limiterTestExecutor{})
+	auth := &Auth{ID: "only", Provider: "limit-test", Attributes: map[string]string{"max_concurrency": "1"}}
+	if _, errRegister := m.Register(context.Background(), auth); errRegister != nil {
+		t.Fatalf("register auth: %v", errRegister)
+	}
+	if !m.limiter.acquire(auth, time.Now()) {
+		t.Fatalf("acquire should succeed")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, _, _, errPick := m.pickNextMixed(ctx, []string{"limit-test"}, "", cliproxyexecutor.Options{}, map[string]struct{}{})
+	if errPick == nil {
+		t.Fatalf("expected error when all auths are saturated")
+	}
+	var authErr *Error
+	if !errors.As(errPick, &authErr) || authErr.StatusCode() != http.StatusTooManyRequests {
+		t.Fatalf("error = %v, want 429 auth error", errPick)
+	}
+}
+
+func TestManager_LimitDeadline(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	m.SetConfig(&internalconfig.Config{Routing: internalconfig.RoutingConfig{LimitWaitSeconds: 30}})
+
+	if wait := time.Until(m.limitDeadline(context.Background())); wait < 29*time.Second || wait > 30*time.Second {
+		t.Fatalf("expected the configured 30s wait, got %s", wait)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ctxDeadline, _ := ctx.Deadline()
+	if deadline := m.limitDeadline(ctx); !deadline.Equal(ctxDeadline) {
+		t.Fatalf("expected the wait to end with the request deadline %v, got %v", ctxDeadline, deadline)
+	}
+}
+
+// recordingSelector remembers the candidates offered to each pick.
+type recordingSelector struct {
+	offered [][]string
+}
+
+func (s *recordingSelector) Pick(_ context.Context, _, _ string, _ cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
+	ids := make([]string, 0, len(auths))
+	for _, auth := range auths {
+		ids = append(ids, auth.ID)
+	}
+	s.offered = append(s.offered, ids)
+	return auths[0], nil
+}
+
+func TestManager_PickNextMixed_SelectorNeverSeesSaturatedAuth(t *testing.T) {
+	selector := &recordingSelector{}
+	m := NewManager(nil, selector, nil)
+	m.RegisterExecutor(limiterTestExecutor{})
+	auth := &Auth{ID: "only", Provider: "limit-test", Attributes: map[string]string{"max_concurrency": "1"}}
+	if _, errRegister := m.Register(context.Background(), auth); errRegister != nil {
+		t.Fatalf("register auth: %v", errRegister)
+	}
+
+	if _, _, _, _, errPick := m.pickNextMixedOnce(context.Background(), []string{"limit-test"}, "", cliproxyexecutor.Options{}, map[string]struct{}{}); errPick != nil {
+		t.Fatalf("pickNextMixedOnce() error = %v", errPick)
+	}
+	if _, _, _, _, errPick := m.pickNextMixedOnce(context.Background(), []string{"limit-test"}, "", cliproxyexecutor.Options{}, map[string]struct{}{}); !isLimitSaturatedError(errPick) {
+		t.Fatalf("pickNextMixedOnce() error = %v, want limit error", errPick)
+	}
+	if len(selector.offered) != 1 {
+		t.Fatalf("expected the selector to be consulted only while capacity was left, got %v", selector.offered)
+	}
+}
+
+func TestManager_Update_DisabledAuthDropsLimiterState(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	auth := &Auth{ID: "a", Provider: "limit-test", Attributes: map[string]string{"rpm": "10"}}
+	if _, errRegister := m.Register(context.Background(), auth); errRegister != nil {
+		t.Fatalf("register auth: %v", errRegister)
+	}
+	if !m.limiter.acquire(auth, time.Now()) {
+		t.Fatalf("acquire should succeed")
+	}
+	disabled := auth.Clone()
+	disabled.Disabled = true
+	if _, errUpdate := m.Update(context.Background(), disabled); errUpdate != nil {
+		t.Fatalf("update auth: %v", errUpdate)
+	}
+	m.releaseAuth(auth.ID)
+
+	m.limiter.mu.Lock()
+	remaining := len(m.limiter.states)
+	m.limiter.mu.Unlock()
+	if remaining != 0 {
+		t.Fatalf("expected the disabled auth's limiter state to be dropped, %d left", remaining)
+	}
+}
@@ -0,0 +1,381 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tracing"
+)
+
+const (
+	defaultCooldownQueueWait  = 2 * time.Minute
+	defaultCooldownQueueDepth = 100
+)
+
+type clientKeyContextKey struct{}
+
+type skipCooldownQueueContextKey struct{}
+
+// WithClientKey returns a derived context recording the client API key a request was made with.
+// The cooldown queue uses it to apply per-key priorities and to schedule fairly across keys.
+func WithClientKey(ctx context.Context, key string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if key = strings.TrimSpace(key); key == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, clientKeyContextKey{}, key)
+}
+
+// ClientKeyFromContext returns the client API key stored by WithClientKey, if any.
+func ClientKeyFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	key, _ := ctx.Value(clientKeyContextKey{}).(string)
+	return key
+}
+
+// withoutCooldownQueue returns a derived context whose requests fail instead of queueing.
+func withoutCooldownQueue(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipCooldownQueueContextKey{}, true)
+}
+
+func cooldownQueueSkipped(ctx context.Context) bool {
+	if ctx == nil {
+		return true
+	}
+	skip, _ := ctx.Value(skipCooldownQueueContextKey{}).(bool)
+	return skip
+}
+
+// QueueStats is a snapshot of the cooldown queue.
+type QueueStats struct {
+	// Depth is the number of requests currently queued across all models.
+	Depth int `json:"depth"`
+	// Models maps each model with queued requests to its queue depth.
+	Models map[string]int `json:"models,omitempty"`
+	// Enqueued, Dispatched, TimedOut and Rejected count queue events since start-up.
+	Enqueued   int64 `json:"enqueued"`
+	Dispatched int64 `json:"dispatched"`
+	TimedOut   int64 `json:"timed_out"`
+	Rejected   int64 `json:"rejected"`
+}
+
+// queueWaiter is a request parked in the cooldown queue. It keeps its sequence number when it
+// is requeued after losing the race for a recovered credential.
+type queueWaiter struct {
+	client   string
+	priority int
+	seq      uint64
+	deadline time.Time
+}
+
+type modelQueue struct {
+	waiters []*queueWaiter
+	// served counts dispatches per client key while the queue is non-empty, so keys of equal
+	// priority take turns.
+	served map[string]int
+}
+
+// cooldownQueue orders requests waiting for a cooling-down model by priority, then by the
+// number of dispatches their client key already received, then by arrival.
+type cooldownQueue struct {
+	mu     sync.Mutex
+	seq    uint64
+	models map[string]*modelQueue
+	// changed is closed and replaced whenever the queue or credential availability changes.
+	changed chan struct{}
+
+	enqueued   int64
+	dispatched int64
+	timedOut   int64
+	rejected   int64
+}
+
+func newCooldownQueue() *cooldownQueue {
+	return &cooldownQueue{models: make(map[string]*modelQueue), changed: make(chan struct{})}
+}
+
+// enqueue adds a waiter for model, or returns false when the model queue is full.
+func (q *cooldownQueue) enqueue(model, client string, priority, maxDepth int, deadline time.Time) (*queueWaiter, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	mq := q.models[model]
+	if mq == nil {
+		mq = &modelQueue{served: make(map[string]int)}
+		q.models[model] = mq
+	}
+	if len(mq.waiters) >= maxDepth {
+		q.rejected++
+		return nil, false
+	}
+	q.seq++
+	w := &queueWaiter{client: client, priority: priority, seq: q.seq, deadline: deadline}
+	mq.waiters = append(mq.waiters, w)
+	q.enqueued++
+	q.notifyLocked()
+	return w, true
+}
+
+// requeue puts a dispatched waiter back in line at its original position.
+func (q *cooldownQueue) requeue(model string, w *queueWaiter) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	mq := q.models[model]
+	if mq == nil {
+		mq = &modelQueue{served: make(map[string]int)}
+		q.models[model] = mq
+	}
+	mq.waiters = append(mq.waiters, w)
+	q.notifyLocked()
+}
+
+// next reports whether w is the next waiter to dispatch for model, and returns the channel
+// closed on the next queue change.
+func (q *cooldownQueue) next(model string, w *queueWaiter) (bool, <-chan struct{}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	mq := q.models[model]
+	if mq == nil {
+		return false, q.changed
+	}
+	var best *queueWaiter
+	for _, candidate := range mq.waiters {
+		if best == nil || queueWaiterBefore(candidate, best, mq.served) {
+			best = candidate
+		}
+	}
+	return best == w, q.changed
+}
+
+func queueWaiterBefore(a, b *queueWaiter, served map[string]int) bool {
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	if served[a.client] != served[b.client] {
+		return served[a.client] < served[b.client]
+	}
+	return a.seq < b.seq
+}
+
+// queueExit describes why a waiter left the queue.
+type queueExit int
+
+const (
+	queueDispatched queueExit = iota
+	queueTimedOut
+	queueCancelled
+)
+
+func (q *cooldownQueue) remove(model string, w *queueWaiter, exit queueExit) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	mq := q.models[model]
+	if mq == nil {
+		return
+	}
+	for i, candidate := range mq.waiters {
+		if candidate == w {
+			mq.waiters = append(mq.waiters[:i], mq.waiters[i+1:]...)
+			break
+		}
+	}
+	switch exit {
+	case queueDispatched:
+		mq.served[w.client]++
+		q.dispatched++
+	case queueTimedOut:
+		q.timedOut++
+	}
+	if len(mq.waiters) == 0 {
+		delete(q.models, model)
+	}
+	q.notifyLocked()
+}
+
+// signal wakes queued requests after a credential may have become available.
+func (q *cooldownQueue) signal() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.models) == 0 {
+		return
+	}
+	q.notifyLocked()
+}
+
+func (q *cooldownQueue) notifyLocked() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+func (q *cooldownQueue) stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := QueueStats{Enqueued: q.enqueued, Dispatched: q.dispatched, TimedOut: q.timedOut, Rejected: q.rejected}
+	for model, mq := range q.models {
+		if out.Models == nil {
+			out.Models = make(map[string]int, len(q.models))
+		}
+		out.Models[model] = len(mq.waiters)
+		out.Depth += len(mq.waiters)
+	}
+	return out
+}
+
+// queueSettings returns whether the cooldown queue is enabled, its maximum wait and depth.
+func (m *Manager) queueSettings() (bool, time.Duration, int) {
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	if cfg == nil || !cfg.CooldownQueue.Enable {
+		return false, 0, 0
+	}
+	maxWait := time.Duration(cfg.CooldownQueue.MaxWaitSeconds) * time.Second
+	if maxWait <= 0 {
+		maxWait = defaultCooldownQueueWait
+	}
+	maxDepth := cfg.CooldownQueue.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultCooldownQueueDepth
+	}
+	return true, maxWait, maxDepth
+}
+
+// queuePriority returns the queue priority configured for a client key or JWT subject.
+func (m *Manager) queuePriority(client string) int {
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	if cfg == nil || client == "" {
+		return 0
+	}
+	for i := range cfg.ClientKeys {
+		entry := &cfg.ClientKeys[i]
+		if entry.Key == client || entry.Subject == client {
+			return entry.QueuePriority
+		}
+	}
+	return 0
+}
+
+// awaitCooldownQueue parks a request that failed because every credential for model is cooling
+// down until its turn comes and a credential recovers. It returns true when the request should
+// be retried; *waiter keeps the request's place across retries. A non-nil error means ctx ended.
+func (m *Manager) awaitCooldownQueue(ctx context.Context, waiter **queueWaiter, providers []string, model string, err error) (bool, error) {
+	enabled, maxWait, maxDepth := m.queueSettings()
+	if !enabled || !isUnavailableError(err, statusCodeFromError(err)) {
+		return false, nil
+	}
+	if cooldownQueueSkipped(ctx) {
+		return false, nil
+	}
+	ready, wait, found := m.cooldownQueueState(providers, model, RequiredTagsFromContext(ctx))
+	if ready || !found {
+		return false, nil
+	}
+	key := baseModelKey(model)
+	now := time.Now()
+	w := *waiter
+	if w == nil {
+		client := ClientKeyFromContext(ctx)
+		var ok bool
+		if w, ok = m.queue.enqueue(key, client, m.queuePriority(client), maxDepth, now.Add(maxWait)); !ok {
+			return false, nil
+		}
+		*waiter = w
+	} else {
+		m.queue.requeue(key, w)
+	}
+	if now.Add(wait).After(w.deadline) {
+		m.queue.remove(key, w, queueTimedOut)
+		return false, nil
+	}
+
+	_, span := tracing.Start(ctx, "cliproxy.cooldown_queue")
+	defer span.End()
+	started := now
+	defer func() { span.SetAttribute("cliproxy.wait_ms", time.Since(started).Milliseconds()) }()
+	for {
+		head, changed := m.queue.next(key, w)
+		until := w.deadline
+		if head {
+			ready, wait, found = m.cooldownQueueState(providers, model, RequiredTagsFromContext(ctx))
+			if ready {
+				m.queue.remove(key, w, queueDispatched)
+				return true, nil
+			}
+			if at := time.Now().Add(wait); found && at.Before(until) {
+				until = at
+			}
+		}
+		timer := time.NewTimer(time.Until(until))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			m.queue.remove(key, w, queueCancelled)
+			return false, ctx.Err()
+		case <-changed:
+			timer.Stop()
+		case <-timer.C:
+			if !time.Now().Before(w.deadline) {
+				m.queue.remove(key, w, queueTimedOut)
+				return false, nil
+			}
+		}
+	}
+}
+
+// cooldownQueueState reports whether a credential can serve model right now and, when none can,
+// how long until the earliest cooling-down credential recovers. Only credentials carrying every
+// tag in tags are considered.
+func (m *Manager) cooldownQueueState(providers []string, model string, tags []string) (ready bool, wait time.Duration, found bool) {
+	providerSet := make(map[string]struct{}, len(providers))
+	for _, provider := range providers {
+		if key := strings.TrimSpace(strings.ToLower(provider)); key != "" {
+			providerSet[key] = struct{}{}
+		}
+	}
+	modelKey := baseModelKey(model)
+	registryRef := registry.GetGlobalRegistry()
+	now := time.Now()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, auth := range m.auths {
+		if auth == nil || auth.Disabled {
+			continue
+		}
+		if _, ok := providerSet[strings.TrimSpace(strings.ToLower(auth.Provider))]; !ok {
+			continue
+		}
+		if modelKey != "" && registryRef != nil && !registryRef.ClientSupportsModel(auth.ID, modelKey) {
+			continue
+		}
+		if !auth.HasTags(tags) {
+			continue
+		}
+		blocked, reason, next := isAuthBlockedForModel(auth, model, now)
+		if !blocked {
+			if !m.circuitBlocked(auth.ID, modelKey, now) {
+				return true, 0, false
+			}
+			continue
+		}
+		if reason == blockReasonDisabled || next.IsZero() {
+			continue
+		}
+		if remaining := next.Sub(now); !found || remaining < wait {
+			wait, found = remaining, true
+		}
+	}
+	return false, wait, found
+}
+
+// QueueStats returns a snapshot of the cooldown queue.
+func (m *Manager) QueueStats() QueueStats {
+	if m == nil || m.queue == nil {
+		return QueueStats{}
+	}
+	return m.queue.stats()
+}
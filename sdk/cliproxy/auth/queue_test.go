@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func TestCooldownQueue_OrdersByPriorityThenFairness(t *testing.T) {
+	q := newCooldownQueue()
+	deadline := time.Now().Add(time.Minute)
+	a1, _ := q.enqueue("m", "key-a", 0, 10, deadline)
+	a2, _ := q.enqueue("m", "key-a", 0, 10, deadline)
+	b1, _ := q.enqueue("m", "key-b", 0, 10, deadline)
+	vip, _ := q.enqueue("m", "key-vip", 5, 10, deadline)
+	if _, ok := q.enqueue("m", "key-c", 0, 4, deadline); ok {
+		t.Fatalf("expected a full queue to reject new requests")
+	}
+
+	var order []*queueWaiter
+	for range 4 {
+		for _, w := range []*queueWaiter{a1, a2, b1, vip} {
+			if head, _ := q.next("m", w); head {
+				order = append(order, w)
+				q.remove("m", w, queueDispatched)
+				break
+			}
+		}
+	}
+	want := []*queueWaiter{vip, a1, b1, a2}
+	for i := range want {
+		if i >= len(order) || order[i] != want[i] {
+			t.Fatalf("unexpected dispatch order at %d: got client %v", i, order)
+		}
+	}
+	stats := q.stats()
+	if stats.Depth != 0 || stats.Enqueued != 4 || stats.Dispatched != 4 || stats.Rejected != 1 {
+		t.Fatalf("unexpected queue stats %+v", stats)
+	}
+}
+
+type queueTestExecutor struct{}
+
+func (queueTestExecutor) Identifier() string { return "queue-test" }
+
+func (queueTestExecutor) Execute(_ context.Context, auth *Auth, _ cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{Payload: []byte(auth.ID)}, nil
+}
+
+func (queueTestExecutor) ExecuteStream(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (queueTestExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) { return auth, nil }
+
+func (queueTestExecutor) CountTokens(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, errors.New("not implemented")
+}
+
+func (queueTestExecutor) HttpRequest(context.Context, *Auth, *http.Request) (*http.Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+func newQueueTestManager(t *testing.T, cooldown time.Duration, maxWaitSeconds int) *Manager {
+	t.Helper()
+	m := NewManager(nil, nil, nil)
+	m.SetConfig(&internalconfig.Config{CooldownQueue: internalconfig.CooldownQueueConfig{Enable: true, MaxWaitSeconds: maxWaitSeconds}})
+	m.RegisterExecutor(queueTestExecutor{})
+	if _, err := m.Register(context.Background(), &Auth{ID: "queue-auth", Provider: "queue-test"}); err != nil {
+		t.Fatalf("register auth: %v", err)
+	}
+	reg := registry.GetGlobalRegistry()
+	reg.RegisterClient("queue-auth", "queue-test", []*registry.ModelInfo{{ID: "queue-model"}})
+	t.Cleanup(func() { reg.UnregisterClient("queue-auth") })
+	m.MarkResult(context.Background(), Result{
+		AuthID:     "queue-auth",
+		Provider:   "queue-test",
+		Model:      "queue-model",
+		Error:      &Error{HTTPStatus: http.StatusTooManyRequests, Message: "quota"},
+		RetryAfter: &cooldown,
+	})
+	return m
+}
+
+func TestManagerExecute_QueuesUntilCooldownEnds(t *testing.T) {
+	m := newQueueTestManager(t, 200*time.Millisecond, 5)
+	started := time.Now()
+	resp, err := m.Execute(WithClientKey(context.Background(), "batch"), []string{"queue-test"}, cliproxyexecutor.Request{Model: "queue-model"}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("expected the queued request to succeed after the cooldown, got %v", err)
+	}
+	if string(resp.Payload) != "queue-auth" {
+		t.Fatalf("unexpected payload %q", resp.Payload)
+	}
+	if elapsed := time.Since(started); elapsed < 150*time.Millisecond {
+		t.Fatalf("expected the request to wait for the cooldown, returned after %s", elapsed)
+	}
+	if stats := m.QueueStats(); stats.Enqueued != 1 || stats.Dispatched != 1 || stats.Depth != 0 {
+		t.Fatalf("unexpected queue stats %+v", stats)
+	}
+}
+
+func TestManagerExecute_QueueFailsWhenRecoveryExceedsMaxWait(t *testing.T) {
+	m := newQueueTestManager(t, time.Hour, 1)
+	_, err := m.Execute(context.Background(), []string{"queue-test"}, cliproxyexecutor.Request{Model: "queue-model"}, cliproxyexecutor.Options{})
+	if err == nil {
+		t.Fatalf("expected the request to fail while the credential cools down")
+	}
+	if stats := m.QueueStats(); stats.TimedOut != 1 || stats.Depth != 0 {
+		t.Fatalf("unexpected queue stats %+v", stats)
+	}
+}
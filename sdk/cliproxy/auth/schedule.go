@@ -0,0 +1,346 @@
+package auth
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const minutesPerDay = 24 * 60
+
+// AvailabilityStatus describes a credential's availability schedule at a point in time.
+type AvailabilityStatus struct {
+	// Timezone and Windows echo the configured schedule.
+	Timezone string
+	Windows  []string
+	// Available reports whether the credential may serve traffic now.
+	Available bool
+	// NextAvailableAt is when the next window opens; zero while available or when the schedule is invalid.
+	NextAvailableAt time.Time
+	// Error describes why the schedule could not be parsed. Invalid schedules keep the credential unavailable.
+	Error string
+}
+
+// availabilityWindow is a weekly window starting on each of days at start (minutes after midnight)
+// and lasting until end, which is at or before start when the window runs past midnight.
+type availabilityWindow struct {
+	days  [7]bool
+	start int
+	end   int
+}
+
+type availabilitySchedule struct {
+	location *time.Location
+	windows  []availabilityWindow
+}
+
+func (w availabilityWindow) wraps() bool {
+	return w.end <= w.start
+}
+
+// open reports whether the schedule admits traffic at now.
+func (s *availabilitySchedule) open(now time.Time) bool {
+	local := now.In(s.location)
+	weekday := local.Weekday()
+	yesterday := (weekday + 6) % 7
+	minute := local.Hour()*60 + local.Minute()
+	for _, w := range s.windows {
+		if w.wraps() {
+			if (w.days[weekday] && minute >= w.start) || (w.days[yesterday] && minute < w.end) {
+				return true
+			}
+			continue
+		}
+		if w.days[weekday] && minute >= w.start && minute < w.end {
+			return true
+		}
+	}
+	return false
+}
+
+// nextOpen returns the start of the first window opening after now.
+func (s *availabilitySchedule) nextOpen(now time.Time) time.Time {
+	local := now.In(s.location)
+	year, month, day := local.Date()
+	var next time.Time
+	for offset := 0; offset <= 7; offset++ {
+		date := time.Date(year, month, day+offset, 0, 0, 0, 0, s.location)
+		for _, w := range s.windows {
+			if !w.days[date.Weekday()] {
+				continue
+			}
+			start := time.Date(year, month, day+offset, w.start/60, w.start%60, 0, 0, s.location)
+			if start.After(now) && (next.IsZero() || start.Before(next)) {
+				next = start
+			}
+		}
+		if !next.IsZero() {
+			return next
+		}
+	}
+	return next
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseAvailabilitySchedule parses windows of the form "<days> <HH:MM-HH:MM>" in timezone.
+func parseAvailabilitySchedule(timezone string, windows []string) (*availabilitySchedule, error) {
+	location := time.Local
+	if timezone = strings.TrimSpace(timezone); timezone != "" {
+		loaded, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", timezone, err)
+		}
+		location = loaded
+	}
+	schedule := &availabilitySchedule{location: location}
+	for _, raw := range windows {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		window, err := parseAvailabilityWindow(raw)
+		if err != nil {
+			return nil, err
+		}
+		schedule.windows = append(schedule.windows, window)
+	}
+	if len(schedule.windows) == 0 {
+		return nil, fmt.Errorf("no availability windows")
+	}
+	return schedule, nil
+}
+
+func parseAvailabilityWindow(raw string) (availabilityWindow, error) {
+	window := availabilityWindow{start: 0, end: minutesPerDay}
+	var daysSet, timeSet bool
+	for _, field := range strings.Fields(strings.ToLower(raw)) {
+		if strings.Contains(field, ":") {
+			if timeSet {
+				return window, fmt.Errorf("window %q: more than one time range", raw)
+			}
+			start, end, err := parseTimeRange(field)
+			if err != nil {
+				return window, fmt.Errorf("window %q: %w", raw, err)
+			}
+			window.start, window.end, timeSet = start, end, true
+			continue
+		}
+		if daysSet {
+			return window, fmt.Errorf("window %q: more than one day list", raw)
+		}
+		days, err := parseDays(field)
+		if err != nil {
+			return window, fmt.Errorf("window %q: %w", raw, err)
+		}
+		window.days, daysSet = days, true
+	}
+	if !daysSet && !timeSet {
+		return window, fmt.Errorf("window %q: empty", raw)
+	}
+	if !daysSet {
+		for i := range window.days {
+			window.days[i] = true
+		}
+	}
+	return window, nil
+}
+
+// parseDays parses a comma-separated list of day names and ranges such as "mon-fri,sun".
+// Ranges may wrap around the week, e.g. "fri-mon"; "*" selects every day.
+func parseDays(field string) ([7]bool, error) {
+	var days [7]bool
+	if field == "*" {
+		for i := range days {
+			days[i] = true
+		}
+		return days, nil
+	}
+	for _, item := range strings.Split(field, ",") {
+		from, to, isRange := strings.Cut(item, "-")
+		first, err := parseWeekday(from)
+		if err != nil {
+			return days, err
+		}
+		last := first
+		if isRange {
+			if last, err = parseWeekday(to); err != nil {
+				return days, err
+			}
+		}
+		for day := first; ; day = (day + 1) % 7 {
+			days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	name = strings.TrimSpace(name)
+	if len(name) >= 3 {
+		if day, ok := weekdayNames[name[:3]]; ok {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown day %q", name)
+}
+
+// parseTimeRange parses "HH:MM-HH:MM" into minutes after midnight. The end may be 24:00.
+func parseTimeRange(field string) (int, int, error) {
+	from, to, ok := strings.Cut(field, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("time range %q must be HH:MM-HH:MM", field)
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return 0, 0, err
+	}
+	if start == minutesPerDay {
+		return 0, 0, fmt.Errorf("time range %q cannot start at 24:00", field)
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("time range %q is empty", field)
+	}
+	return start, end, nil
+}
+
+func parseClock(value string) (int, error) {
+	hours, minutes, ok := strings.Cut(strings.TrimSpace(value), ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	h, errH := strconv.Atoi(hours)
+	m, errM := strconv.Atoi(minutes)
+	if errH != nil || errM != nil || h < 0 || h > 24 || m < 0 || m > 59 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	return h*60 + m, nil
+}
+
+// cachedAvailability is the parsed form of an auth's availability spec, identified by key.
+type cachedAvailability struct {
+	key      string
+	schedule *availabilitySchedule
+	err      error
+}
+
+func availabilityKey(timezone string, windows []string) string {
+	return timezone + "\x00" + strings.Join(windows, "\x00")
+}
+
+// cacheSchedule parses the availability schedule of a once, so selection does not parse it for
+// every request. The manager calls it on each version of an auth it stores, which replaces the
+// schedule of the previous version.
+func (a *Auth) cacheSchedule() {
+	a.schedule = nil
+	timezone, windows, ok := authAvailabilitySpec(a)
+	if !ok {
+		return
+	}
+	schedule, err := parseAvailabilitySchedule(timezone, windows)
+	if err != nil {
+		log.Warnf("auth %s: invalid availability schedule, keeping the credential unavailable: %v", a.ID, err)
+	}
+	a.schedule = &cachedAvailability{key: availabilityKey(timezone, windows), schedule: schedule, err: err}
+}
+
+// authAvailabilitySpec returns the schedule declared for auth: the availability attributes set
+// for config API keys, or an "availability" object ({"timezone", "windows"}) in an auth file.
+func authAvailabilitySpec(auth *Auth) (timezone string, windows []string, ok bool) {
+	if auth == nil {
+		return "", nil, false
+	}
+	if raw := strings.TrimSpace(auth.Attributes["availability"]); raw != "" {
+		return strings.TrimSpace(auth.Attributes["availability_timezone"]), strings.Split(raw, ";"), true
+	}
+	switch v := auth.Metadata["availability"].(type) {
+	case map[string]any:
+		timezone, _ = v["timezone"].(string)
+		windows = stringsFromAny(v["windows"])
+	case []any, []string, string:
+		windows = stringsFromAny(v)
+	default:
+		return "", nil, false
+	}
+	return strings.TrimSpace(timezone), windows, true
+}
+
+func stringsFromAny(raw any) []string {
+	switch v := raw.(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// authSchedule returns the parsed availability schedule of auth, or nil when it has none. The
+// schedule cached by cacheSchedule is used while it still matches the auth's spec.
+func authSchedule(auth *Auth) (*availabilitySchedule, bool, error) {
+	timezone, windows, ok := authAvailabilitySpec(auth)
+	if !ok {
+		return nil, false, nil
+	}
+	if cached := auth.schedule; cached != nil && cached.key == availabilityKey(timezone, windows) {
+		return cached.schedule, true, cached.err
+	}
+	schedule, err := parseAvailabilitySchedule(timezone, windows)
+	return schedule, true, err
+}
+
+// authScheduleOpen reports whether auth's availability schedule admits traffic at now and, when
+// it does not, when the next window opens. Credentials without a schedule are always available.
+func authScheduleOpen(auth *Auth, now time.Time) (bool, time.Time) {
+	schedule, ok, err := authSchedule(auth)
+	if !ok {
+		return true, time.Time{}
+	}
+	if err != nil {
+		return false, time.Time{}
+	}
+	if schedule.open(now) {
+		return true, time.Time{}
+	}
+	return false, schedule.nextOpen(now)
+}
+
+// AvailabilityStatus returns the availability schedule state of auth at now, or false when the
+// credential has no schedule.
+func (a *Auth) AvailabilityStatus(now time.Time) (AvailabilityStatus, bool) {
+	timezone, windows, ok := authAvailabilitySpec(a)
+	if !ok {
+		return AvailabilityStatus{}, false
+	}
+	status := AvailabilityStatus{Timezone: timezone, Windows: windows}
+	schedule, _, err := authSchedule(a)
+	if err != nil {
+		status.Error = err.Error()
+		return status, true
+	}
+	status.Available = schedule.open(now)
+	if !status.Available {
+		status.NextAvailableAt = schedule.nextOpen(now)
+	}
+	return status, true
+}
@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// scheduleTime returns a UTC time in the week of Monday 2025-06-02.
+func scheduleTime(day, hour, minute int) time.Time {
+	return time.Date(2025, 6, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestAvailabilitySchedule_OpenAndNext(t *testing.T) {
+	schedule, err := parseAvailabilitySchedule("UTC", []string{"mon-fri 19:00-08:00", "sat"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	cases := []struct {
+		now  time.Time
+		open bool
+		next time.Time
+	}{
+		{scheduleTime(2, 7, 59), false, scheduleTime(2, 19, 0)}, // no window starts on Sunday
+		{scheduleTime(2, 12, 0), false, scheduleTime(2, 19, 0)},
+		{scheduleTime(2, 19, 0), true, time.Time{}},
+		{scheduleTime(3, 7, 59), true, time.Time{}}, // Monday's window runs into Tuesday
+		{scheduleTime(3, 8, 0), false, scheduleTime(3, 19, 0)},
+		{scheduleTime(7, 12, 0), true, time.Time{}},
+		{scheduleTime(8, 12, 0), false, scheduleTime(9, 19, 0)},
+	}
+	for _, tc := range cases {
+		if got := schedule.open(tc.now); got != tc.open {
+			t.Fatalf("open(%s) = %v, want %v", tc.now, got, tc.open)
+		}
+		if !tc.open {
+			if got := schedule.nextOpen(tc.now); !got.Equal(tc.next) {
+				t.Fatalf("nextOpen(%s) = %s, want %s", tc.now, got, tc.next)
+			}
+		}
+	}
+}
+
+func TestAvailabilitySchedule_Timezone(t *testing.T) {
+	schedule, err := parseAvailabilitySchedule("Asia/Tokyo", []string{"22:00-06:00"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	// 14:00 UTC is 23:00 in Tokyo.
+	if !schedule.open(scheduleTime(2, 14, 0)) || schedule.open(scheduleTime(2, 22, 0)) {
+		t.Fatalf("expected the window to be evaluated in the configured timezone")
+	}
+}
+
+func TestParseAvailabilitySchedule_Invalid(t *testing.T) {
+	for _, windows := range [][]string{
+		{"mon-fri 25:00-08:00"},
+		{"someday"},
+		{"mon 08:00-08:00"},
+		{"mon tue"},
+		{},
+	} {
+		if _, err := parseAvailabilitySchedule("", windows); err == nil {
+			t.Fatalf("expected %q to be rejected", windows)
+		}
+	}
+	if _, err := parseAvailabilitySchedule("Mars/Olympus", []string{"mon"}); err == nil {
+		t.Fatalf("expected an unknown timezone to be rejected")
+	}
+}
+
+func TestAuthAvailabilityStatus(t *testing.T) {
+	fromFile := &Auth{ID: "file", Metadata: map[string]any{
+		"availability": map[string]any{"timezone": "UTC", "windows": []any{"sat,sun"}},
+	}}
+	status, ok := fromFile.AvailabilityStatus(scheduleTime(6, 12, 0))
+	if !ok || status.Available || !status.NextAvailableAt.Equal(scheduleTime(7, 0, 0)) {
+		t.Fatalf("unexpected status for the auth file schedule: %+v (ok=%v)", status, ok)
+	}
+
+	invalid := &Auth{ID: "invalid", Attributes: map[string]string{"availability": "whenever"}}
+	status, ok = invalid.AvailabilityStatus(scheduleTime(6, 12, 0))
+	if !ok || status.Available || status.Error == "" {
+		t.Fatalf("expected an invalid schedule to report an error, got %+v", status)
+	}
+	if open, _ := authScheduleOpen(invalid, scheduleTime(6, 12, 0)); open {
+		t.Fatalf("expected an invalid schedule to keep the credential unavailable")
+	}
+	if _, ok = (&Auth{ID: "plain"}).AvailabilityStatus(time.Now()); ok {
+		t.Fatalf("expected no status for an auth without a schedule")
+	}
+}
+
+func TestManager_UpdateReplacesCachedSchedule(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	auth := &Auth{ID: "scheduled", Provider: "gemini", Attributes: map[string]string{"availability": "sat,sun", "availability_timezone": "UTC"}}
+	if _, err := m.Register(context.Background(), auth); err != nil {
+		t.Fatalf("register auth: %v", err)
+	}
+	stored, _ := m.GetByID("scheduled")
+	if stored.schedule == nil {
+		t.Fatalf("expected the registered auth to carry its parsed schedule")
+	}
+	if open, _ := authScheduleOpen(stored, scheduleTime(4, 12, 0)); open {
+		t.Fatalf("expected the weekend-only credential to be closed on Wednesday")
+	}
+
+	auth.Attributes["availability"] = "mon-fri"
+	if _, err := m.Update(context.Background(), auth); err != nil {
+		t.Fatalf("update auth: %v", err)
+	}
+	stored, _ = m.GetByID("scheduled")
+	if open, _ := authScheduleOpen(stored, scheduleTime(4, 12, 0)); !open {
+		t.Fatalf("expected the updated schedule to open the credential on Wednesday")
+	}
+
+	// A spec changed in place no longer matches the cached schedule, which is then bypassed.
+	stored.Attributes["availability"] = "sat,sun"
+	if open, _ := authScheduleOpen(stored, scheduleTime(4, 12, 0)); open {
+		t.Fatalf("expected a stale cached schedule to be ignored")
+	}
+}
+
+func TestFillFirstSelectorPick_SkipsAuthsOutsideSchedule(t *testing.T) {
+	now := time.Now().UTC()
+	closed := (now.Hour() + 12) % 24
+	auths := []*Auth{
+		{ID: "a-daytime", Attributes: map[string]string{
+			"availability":          fmt.Sprintf("%02d:00-%02d:00", closed, (closed+1)%24),
+			"availability_timezone": "UTC",
+		}},
+		{ID: "b-always"},
+	}
+	got, err := (&FillFirstSelector{}).Pick(context.Background(), "gemini", "", cliproxyexecutor.Options{}, auths)
+	if err != nil {
+		t.Fatalf("Pick: %v", err)
+	}
+	if got.ID != "b-always" {
+		t.Fatalf("expected the credential outside its window to be skipped, got %s", got.ID)
+	}
+}
+
+func TestSelectorPick_AllOutsideScheduleReturnsCooldown(t *testing.T) {
+	now := time.Now().UTC()
+	closed := (now.Hour() + 12) % 24
+	auths := []*Auth{{ID: "night", Attributes: map[string]string{
+		"availability":          fmt.Sprintf("%02d:00-%02d:00", closed, (closed+1)%24),
+		"availability_timezone": "UTC",
+	}}}
+	_, err := (&RoundRobinSelector{}).Pick(context.Background(), "gemini", "m", cliproxyexecutor.Options{}, auths)
+	var cooldownErr *modelCooldownError
+	if !errors.As(err, &cooldownErr) {
+		t.Fatalf("expected a cooldown error, got %v", err)
+	}
+	_, next := authScheduleOpen(auths[0], now)
+	if !cooldownErr.scheduled || cooldownErr.resetIn <= 0 || cooldownErr.resetIn > next.Sub(now) {
+		t.Fatalf("expected the error to wait for the window opening at %s, got %+v", next, cooldownErr)
+	}
+	if !isUnavailableError(err, cooldownErr.StatusCode()) {
+		t.Fatalf("expected the cooldown queue to treat the error as retryable")
+	}
+}
@@ -0,0 +1,69 @@
+package auth
+
+import "context"
+
+type excludedAuthsContextKey struct{}
+
+type selectionObserverContextKey struct{}
+
+// WithExcludedAuths returns a derived context that keeps the given auth IDs out of selection.
+// Callers use it to move a request to a different credential, e.g. when resuming a stream
+// whose upstream failed part-way through.
+func WithExcludedAuths(ctx context.Context, authIDs ...string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if len(authIDs) == 0 {
+		return ctx
+	}
+	ids := append([]string(nil), excludedAuthsFromContext(ctx)...)
+	ids = append(ids, authIDs...)
+	return context.WithValue(ctx, excludedAuthsContextKey{}, ids)
+}
+
+// WithSelectionObserver returns a derived context that reports every auth selected for an
+// execution attempt to fn, after any observer already attached to ctx. fn is called from the
+// selecting goroutine and may run concurrently for hedged attempts.
+func WithSelectionObserver(ctx context.Context, fn func(authID string)) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if fn == nil {
+		return ctx
+	}
+	if parent, ok := ctx.Value(selectionObserverContextKey{}).(func(string)); ok && parent != nil {
+		next := fn
+		fn = func(authID string) {
+			parent(authID)
+			next(authID)
+		}
+	}
+	return context.WithValue(ctx, selectionObserverContextKey{}, fn)
+}
+
+func excludedAuthsFromContext(ctx context.Context) []string {
+	if ctx == nil {
+		return nil
+	}
+	ids, _ := ctx.Value(excludedAuthsContextKey{}).([]string)
+	return ids
+}
+
+// newTriedSet seeds the per-attempt tried set with the auths excluded through ctx.
+func newTriedSet(ctx context.Context) map[string]struct{} {
+	excluded := excludedAuthsFromContext(ctx)
+	tried := make(map[string]struct{}, len(excluded))
+	for _, id := range excluded {
+		tried[id] = struct{}{}
+	}
+	return tried
+}
+
+func notifySelection(ctx context.Context, authID string) {
+	if ctx == nil {
+		return
+	}
+	if fn, ok := ctx.Value(selectionObserverContextKey{}).(func(string)); ok && fn != nil {
+		fn(authID)
+	}
+}
@@ -32,6 +32,7 @@ const (
 	blockReasonNone blockReason = iota
 	blockReasonCooldown
 	blockReasonDisabled
+	blockReasonSchedule
 	blockReasonOther
 )
 
@@ -39,6 +40,9 @@ type modelCooldownError struct {
 	model    string
 	resetIn  time.Duration
 	provider string
+	// scheduled is set when every credential is outside its availability schedule rather
+	// than cooling down.
+	scheduled bool
 }
 
 func newModelCooldownError(model, provider string, resetIn time.Duration) *modelCooldownError {
@@ -58,6 +62,9 @@ func (e *modelCooldownError) Error() string {
 		modelName = "requested model"
 	}
 	message := fmt.Sprintf("All credentials for model %s are cooling down", modelName)
+	if e.scheduled {
+		message = fmt.Sprintf("All credentials for model %s are outside their availability windows", modelName)
+	}
 	if e.provider != "" {
 		message = fmt.Sprintf("%s via provider %s", message, e.provider)
 	}
@@ -119,7 +126,11 @@ func authPriority(auth *Auth) int {
 	return parsed
 }
 
-func collectAvailableByPriority(auths []*Auth, model string, now time.Time) (available map[int][]*Auth, cooldownCount int, earliest time.Time) {
+// collectAvailableByPriority groups the unblocked auths by priority. Auths that will become
+// available on their own, because they are cooling down or outside their availability schedule,
+// are counted in waitCount (scheduleCount of them for the schedule), and earliest is the soonest
+// time one of them becomes available.
+func collectAvailableByPriority(auths []*Auth, model string, now time.Time) (available map[int][]*Auth, waitCount, scheduleCount int, earliest time.Time) {
 	available = make(map[int][]*Auth)
 	for i := 0; i < len(auths); i++ {
 		candidate := auths[i]
@@ -129,14 +140,17 @@ func collectAvailableByPriority(auths []*Auth, model string, now time.Time) (ava
 			available[priority] = append(available[priority], candidate)
 			continue
 		}
-		if reason == blockReasonCooldown {
-			cooldownCount++
+		if reason == blockReasonCooldown || (reason == blockReasonSchedule && !next.IsZero()) {
+			waitCount++
+			if reason == blockReasonSchedule {
+				scheduleCount++
+			}
 			if !next.IsZero() && (earliest.IsZero() || next.Before(earliest)) {
 				earliest = next
 			}
 		}
 	}
-	return available, cooldownCount, earliest
+	return available, waitCount, scheduleCount, earliest
 }
 
 func getAvailableAuths(auths []*Auth, provider, model string, now time.Time) ([]*Auth, error) {
@@ -144,9 +158,9 @@ func getAvailableAuths(auths []*Auth, provider, model string, now time.Time) ([]
 		return nil, &Error{Code: "auth_not_found", Message: "no auth candidates"}
 	}
 
-	availableByPriority, cooldownCount, earliest := collectAvailableByPriority(auths, model, now)
+	availableByPriority, waitCount, scheduleCount, earliest := collectAvailableByPriority(auths, model, now)
 	if len(availableByPriority) == 0 {
-		if cooldownCount == len(auths) && !earliest.IsZero() {
+		if waitCount == len(auths) && !earliest.IsZero() {
 			providerForError := provider
 			if providerForError == "mixed" {
 				providerForError = ""
@@ -155,7 +169,9 @@ func getAvailableAuths(auths []*Auth, provider, model string, now time.Time) ([]
 			if resetIn < 0 {
 				resetIn = 0
 			}
-			return nil, newModelCooldownError(model, providerForError, resetIn)
+			cooldownErr := newModelCooldownError(model, providerForError, resetIn)
+			cooldownErr.scheduled = scheduleCount == len(auths)
+			return nil, cooldownErr
 		}
 		return nil, &Error{Code: "auth_unavailable", Message: "no auth available"}
 	}
@@ -221,6 +237,9 @@ func isAuthBlockedForModel(auth *Auth, model string, now time.Time) (bool, block
 	if auth.Disabled || auth.Status == StatusDisabled {
 		return true, blockReasonDisabled, time.Time{}
 	}
+	if open, next := authScheduleOpen(auth, now); !open {
+		return true, blockReasonSchedule, next
+	}
 	if model != "" {
 		if len(auth.ModelStates) > 0 {
 			if state, ok := auth.ModelStates[model]; ok && state != nil {
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+const (
+	// latencyEWMAAlpha weights the newest sample in the rolling averages.
+	latencyEWMAAlpha = 0.3
+	// latencyExploreRate is the share of picks that ignore scores so slow or failing
+	// credentials are re-measured and can recover.
+	latencyExploreRate = 0.1
+	// latencyErrorPenalty scales how strongly the recent error rate inflates a score.
+	latencyErrorPenalty = 4.0
+	// latencyFailureFloor stands in for the latency of credentials that have only failed so
+	// far, so a credential that fails fast never looks faster than one that works.
+	latencyFailureFloor = float64(30 * time.Second)
+)
+
+// ResultObserver is implemented by selectors that learn from execution outcomes.
+// The manager forwards every result recorded through MarkResult to the active selector.
+type ResultObserver interface {
+	ObserveResult(result Result)
+}
+
+// AuthForgetter is implemented by selectors that keep per-auth state.
+// The manager calls ForgetAuth when an auth is disabled or removed.
+type AuthForgetter interface {
+	ForgetAuth(authID string)
+}
+
+// LatencySelector prefers the healthiest credentials for a model.
+// It keeps rolling EWMAs of time-to-first-byte, total latency and error rate per
+// auth+model and picks the lowest score, where
+//
+//	score = (ttfb + total) / 2 * (1 + latencyErrorPenalty*errorRate)
+//
+// Latencies are averaged over successful attempts only; credentials that have only failed
+// score latencyFailureFloor. Credentials without samples are tried first, and a small share of picks is spread
+// randomly across all available credentials to keep measurements fresh.
+type LatencySelector struct {
+	mu    sync.Mutex
+	stats map[string]*latencyStats
+	rng   *rand.Rand
+}
+
+type latencyStats struct {
+	ttfb      float64
+	total     float64
+	errorRate float64
+	samples   int64
+	// successes counts the samples that contributed to ttfb and total.
+	successes int64
+	updatedAt time.Time
+}
+
+func (st *latencyStats) score() float64 {
+	latency := latencyFailureFloor
+	if st.successes > 0 {
+		latency = (st.ttfb + st.total) / 2
+	}
+	return latency * (1 + latencyErrorPenalty*st.errorRate)
+}
+
+// NewLatencySelector constructs a latency-aware selector.
+func NewLatencySelector() *LatencySelector {
+	return &LatencySelector{
+		stats: make(map[string]*latencyStats),
+		rng:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func latencyStatsKey(authID, model string) string {
+	return authID + "|" + model
+}
+
+// ObserveResult folds an execution result into the rolling statistics.
+func (s *LatencySelector) ObserveResult(result Result) {
+	if s == nil || result.AuthID == "" {
+		return
+	}
+	key := latencyStatsKey(result.AuthID, result.Model)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stats == nil {
+		s.stats = make(map[string]*latencyStats)
+	}
+	st := s.stats[key]
+	if st == nil {
+		st = &latencyStats{}
+		s.stats[key] = st
+	}
+	errSample := 0.0
+	if !result.Success {
+		errSample = 1
+	}
+	ttfb := result.FirstByteLatency
+	if ttfb <= 0 {
+		ttfb = result.Latency
+	}
+	if st.samples == 0 {
+		st.errorRate = errSample
+	} else {
+		st.errorRate += latencyEWMAAlpha * (errSample - st.errorRate)
+	}
+	// Failed attempts often return quickly; only successes describe real latency.
+	if result.Success {
+		if st.successes == 0 {
+			st.ttfb = float64(ttfb)
+			st.total = float64(result.Latency)
+		} else {
+			st.ttfb += latencyEWMAAlpha * (float64(ttfb) - st.ttfb)
+			st.total += latencyEWMAAlpha * (float64(result.Latency) - st.total)
+		}
+		st.successes++
+	}
+	st.samples++
+	st.updatedAt = time.Now()
+}
+
+// ForgetAuth drops the statistics of a disabled or removed auth.
+func (s *LatencySelector) ForgetAuth(authID string) {
+	if s == nil || authID == "" {
+		return
+	}
+	prefix := latencyStatsKey(authID, "")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.stats {
+		if strings.HasPrefix(key, prefix) {
+			delete(s.stats, key)
+		}
+	}
+}
+
+// Pick selects the available auth with the best latency/error score.
+func (s *LatencySelector) Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
+	_ = ctx
+	_ = opts
+	now := time.Now()
+	available, err := getAvailableAuths(auths, provider, model, now)
+	if err != nil {
+		return nil, err
+	}
+	if len(available) == 1 {
+		return available[0], nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.rng == nil {
+		s.rng = rand.New(rand.NewSource(now.UnixNano()))
+	}
+	if s.rng.Float64() < latencyExploreRate {
+		return available[s.rng.Intn(len(available))], nil
+	}
+
+	var best *Auth
+	bestScore := 0.0
+	for _, candidate := range available {
+		st := s.stats[latencyStatsKey(candidate.ID, model)]
+		if st == nil || st.samples == 0 {
+			// Unmeasured credentials are tried before any measured one.
+			return candidate, nil
+		}
+		score := st.score()
+		if best == nil || score < bestScore {
+			best = candidate
+			bestScore = score
+		}
+	}
+	return best, nil
+}
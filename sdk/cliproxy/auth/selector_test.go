@@ -175,3 +175,110 @@ func TestRoundRobinSelectorPick_Concurrent(t *testing.T) {
 	default:
 	}
 }
+
+func TestLatencySelectorPick_PrefersFastAndHealthy(t *testing.T) {
+	t.Parallel()
+
+	selector := NewLatencySelector()
+	auths := []*Auth{{ID: "fast"}, {ID: "slow"}, {ID: "flaky"}}
+	selector.ObserveResult(Result{AuthID: "fast", Model: "m", Success: true, Latency: 2 * time.Second, FirstByteLatency: 500 * time.Millisecond})
+	selector.ObserveResult(Result{AuthID: "slow", Model: "m", Success: true, Latency: 20 * time.Second, FirstByteLatency: 5 * time.Second})
+	selector.ObserveResult(Result{AuthID: "flaky", Model: "m", Success: true, Latency: time.Second, FirstByteLatency: 200 * time.Millisecond})
+	for i := 0; i < 5; i++ {
+		selector.ObserveResult(Result{AuthID: "flaky", Model: "m", Success: false, Latency: 100 * time.Millisecond})
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 200; i++ {
+		got, err := selector.Pick(context.Background(), "gemini", "m", cliproxyexecutor.Options{}, auths)
+		if err != nil {
+			t.Fatalf("Pick() #%d error = %v", i, err)
+		}
+		counts[got.ID]++
+	}
+	if counts["fast"] < 150 {
+		t.Fatalf("fast picked %d/200 times, want at least 150 (counts=%v)", counts["fast"], counts)
+	}
+}
+
+func TestLatencySelectorPick_TriesUnmeasuredFirst(t *testing.T) {
+	t.Parallel()
+
+	selector := NewLatencySelector()
+	selector.ObserveResult(Result{AuthID: "a", Model: "m", Success: true, Latency: time.Second})
+	auths := []*Auth{{ID: "a"}, {ID: "b"}}
+
+	unmeasured := 0
+	for i := 0; i < 50; i++ {
+		got, err := selector.Pick(context.Background(), "gemini", "m", cliproxyexecutor.Options{}, auths)
+		if err != nil {
+			t.Fatalf("Pick() error = %v", err)
+		}
+		if got.ID == "b" {
+			unmeasured++
+		}
+	}
+	if unmeasured < 40 {
+		t.Fatalf("unmeasured auth picked %d/50 times, want most picks", unmeasured)
+	}
+}
+
+func TestManagerMarkResult_FeedsLatencySelector(t *testing.T) {
+	t.Parallel()
+
+	selector := NewLatencySelector()
+	manager := NewManager(nil, selector, nil)
+	if _, err := manager.Register(context.Background(), &Auth{ID: "a", Provider: "gemini"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	manager.MarkResult(context.Background(), Result{AuthID: "a", Provider: "gemini", Model: "m", Success: true, Latency: time.Second})
+
+	selector.mu.Lock()
+	st := selector.stats[latencyStatsKey("a", "m")]
+	selector.mu.Unlock()
+	if st == nil || st.samples != 1 {
+		t.Fatalf("latency stats not recorded: %+v", st)
+	}
+}
+
+func TestLatencySelectorPick_FastFailuresDoNotLookFast(t *testing.T) {
+	t.Parallel()
+
+	selector := NewLatencySelector()
+	selector.ObserveResult(Result{AuthID: "broken", Model: "m", Success: false, Latency: 10 * time.Millisecond})
+	selector.ObserveResult(Result{AuthID: "working", Model: "m", Success: true, Latency: 5 * time.Second, FirstByteLatency: time.Second})
+	auths := []*Auth{{ID: "broken"}, {ID: "working"}}
+
+	counts := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		got, err := selector.Pick(context.Background(), "gemini", "m", cliproxyexecutor.Options{}, auths)
+		if err != nil {
+			t.Fatalf("Pick() error = %v", err)
+		}
+		counts[got.ID]++
+	}
+	if counts["working"] < 80 {
+		t.Fatalf("working picked %d/100 times, want most picks (counts=%v)", counts["working"], counts)
+	}
+}
+
+func TestManagerUpdate_DisabledAuthDropsLatencyStats(t *testing.T) {
+	t.Parallel()
+
+	selector := NewLatencySelector()
+	manager := NewManager(nil, selector, nil)
+	if _, err := manager.Register(context.Background(), &Auth{ID: "a", Provider: "gemini"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	manager.MarkResult(context.Background(), Result{AuthID: "a", Provider: "gemini", Model: "m", Success: true, Latency: time.Second})
+	if _, err := manager.Update(context.Background(), &Auth{ID: "a", Provider: "gemini", Disabled: true, Status: StatusDisabled}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	selector.mu.Lock()
+	remaining := len(selector.stats)
+	selector.mu.Unlock()
+	if remaining != 0 {
+		t.Fatalf("expected the disabled auth's stats to be dropped, %d left", remaining)
+	}
+}
@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"context"
+	"math/rand/v2"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+// shadowTimeout bounds a mirrored request, which no longer follows the client's cancellation.
+const shadowTimeout = 5 * time.Minute
+
+type shadowPolicy struct {
+	patterns    []string
+	target      string
+	sampleRate  float64
+	logResponse bool
+}
+
+// shadowPolicyTable holds the shadow traffic policies compiled from the runtime config, in config order.
+type shadowPolicyTable []*shadowPolicy
+
+func compileShadowPolicies(entries []internalconfig.ShadowPolicy) shadowPolicyTable {
+	out := make(shadowPolicyTable, 0, len(entries))
+	for _, entry := range entries {
+		target := strings.TrimSpace(entry.TargetModel)
+		if target == "" || entry.SampleRate <= 0 {
+			continue
+		}
+		policy := &shadowPolicy{target: target, sampleRate: entry.SampleRate, logResponse: entry.LogResponse}
+		for _, pattern := range entry.Models {
+			if pattern = strings.ToLower(strings.TrimSpace(pattern)); pattern != "" {
+				policy.patterns = append(policy.patterns, pattern)
+			}
+		}
+		if len(policy.patterns) > 0 {
+			out = append(out, policy)
+		}
+	}
+	return out
+}
+
+// lookupShadowPolicy returns the first policy matching model (ignoring a thinking suffix), or nil.
+func (m *Manager) lookupShadowPolicy(model string) *shadowPolicy {
+	if m == nil {
+		return nil
+	}
+	table, _ := m.shadowPolicies.Load().(shadowPolicyTable)
+	if len(table) == 0 {
+		return nil
+	}
+	key := strings.ToLower(strings.TrimSpace(thinking.ParseSuffix(model).ModelName))
+	for _, policy := range table {
+		for _, pattern := range policy.patterns {
+			if util.MatchWildcard(pattern, key) {
+				return policy
+			}
+		}
+	}
+	return nil
+}
+
+// ShadowResult describes the outcome of a request mirrored by a shadow traffic policy.
+type ShadowResult struct {
+	// RequestID is the ID of the mirrored client request.
+	RequestID string
+	// Model is the model the client requested; TargetModel is the model the mirror was sent to.
+	Model       string
+	TargetModel string
+	// AuthID identifies the credential that served the mirror, if one was selected.
+	AuthID string
+	Stream bool
+	// StatusCode is 200 for a successful mirror and the upstream error status otherwise.
+	StatusCode int
+	Error      string
+	StartedAt  time.Time
+	// Latency is the time until the mirror completed; FirstByte the time to its first stream chunk.
+	Latency   time.Duration
+	FirstByte time.Duration
+	// Usage is the token usage reported by the shadow target.
+	Usage usage.Detail
+	// Request is the original client request body.
+	Request []byte
+	// LogResponse is set when the policy asks for the response to be logged, in which case
+	// Response holds the full shadow response.
+	LogResponse bool
+	Response    []byte
+}
+
+// ShadowRecorder receives the outcome of every mirrored request, e.g. to write it to the request log.
+type ShadowRecorder interface {
+	RecordShadow(result ShadowResult)
+}
+
+// SetShadowRecorder registers the recorder notified when mirrored requests complete.
+func (m *Manager) SetShadowRecorder(recorder ShadowRecorder) {
+	m.mu.Lock()
+	m.shadowRecorder = recorder
+	m.mu.Unlock()
+}
+
+type shadowOnceContextKey struct{}
+
+// WithShadowOnce returns a derived context under which a request is sampled for mirroring only
+// once, however often it is executed again, e.g. by bootstrap retries. Handlers call it once per
+// client request.
+func WithShadowOnce(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, shadowOnceContextKey{}, new(atomic.Bool))
+}
+
+// shadowSampled reports whether the client request of ctx was already considered for mirroring
+// and marks it as considered.
+func shadowSampled(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	flag, ok := ctx.Value(shadowOnceContextKey{}).(*atomic.Bool)
+	return ok && flag.Swap(true)
+}
+
+// mirrorShadow sends a sampled copy of req to the target of the matching shadow traffic policy.
+// The mirror runs in the background on a context detached from the client request, so it never
+// delays or cancels the primary request, shares its retry budget or writes to its request log.
+func (m *Manager) mirrorShadow(ctx context.Context, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, stream bool) {
+	policy := m.lookupShadowPolicy(req.Model)
+	// Requests moved to another credential, e.g. resumed streams, were mirrored when first sent.
+	if policy == nil || usage.IsShadow(ctx) || len(excludedAuthsFromContext(ctx)) > 0 || shadowSampled(ctx) || rand.Float64() >= policy.sampleRate {
+		return
+	}
+	target := applyRequestedSuffix(req.Model, policy.target)
+	providers := m.normalizeProviders(util.GetProviderName(thinking.ParseSuffix(target).ModelName))
+	if len(providers) == 0 {
+		logEntryWithRequestID(ctx).Debugf("shadow for %s skipped: unknown provider for target model %s", req.Model, target)
+		return
+	}
+	shadowCtx := context.Background()
+	requestID := logging.GetRequestID(ctx)
+	if requestID != "" {
+		shadowCtx = logging.WithRequestID(shadowCtx, requestID)
+	}
+	shadowCtx = WithClientKey(usage.WithShadow(withoutCooldownQueue(shadowCtx)), ClientKeyFromContext(ctx))
+	shadowCtx = WithRequiredTags(shadowCtx, RequiredTagsFromContext(ctx)...)
+
+	shadowReq := req
+	shadowReq.Model = target
+	shadowOpts := withRequestedModel(opts, target)
+	result := ShadowResult{
+		RequestID:   requestID,
+		Model:       req.Model,
+		TargetModel: target,
+		Stream:      stream,
+		Request:     opts.OriginalRequest,
+		LogResponse: policy.logResponse,
+	}
+	go m.runShadow(shadowCtx, providers, shadowReq, shadowOpts, result)
+}
+
+// runShadow executes a mirrored request with a single pass over the target's credentials and
+// reports the outcome. Its results reach hooks but, like abandoned attempts, never change the
+// state or circuit breakers of the credentials it used.
+func (m *Manager) runShadow(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, result ShadowResult) {
+	ctx, cancel := context.WithTimeout(ctx, shadowTimeout)
+	defer cancel()
+	// Selection happens on this goroutine, so the observer can write to result directly.
+	ctx = WithSelectionObserver(ctx, func(authID string) { result.AuthID = authID })
+
+	result.StartedAt = time.Now()
+	var err error
+	if result.Stream {
+		var chunks <-chan cliproxyexecutor.StreamChunk
+		chunks, err = m.executeStreamMixedOnce(ctx, providers, req, opts, 0)
+		if err == nil {
+			for chunk := range chunks {
+				if chunk.Err != nil {
+					err = chunk.Err
+					continue
+				}
+				if result.FirstByte == 0 {
+					result.FirstByte = time.Since(result.StartedAt)
+				}
+				if result.LogResponse {
+					result.Response = append(result.Response, chunk.Payload...)
+				}
+			}
+		}
+	} else {
+		var resp cliproxyexecutor.Response
+		resp, err = m.executeMixedOnce(ctx, providers, req, opts, 0)
+		if err == nil && result.LogResponse {
+			result.Response = resp.Payload
+		}
+	}
+	result.Latency = time.Since(result.StartedAt)
+	result.Usage = usage.ShadowUsage(ctx)
+	result.StatusCode = 200
+	if err != nil {
+		result.StatusCode = statusCodeFromError(err)
+		result.Error = err.Error()
+	}
+
+	authID := result.AuthID
+	entry := logEntryWithRequestID(ctx)
+	if err != nil {
+		entry.Infof("shadow %s -> %s failed after %s (status %d, auth %s): %v", result.Model, result.TargetModel, result.Latency, result.StatusCode, authID, err)
+	} else {
+		entry.Infof("shadow %s -> %s completed in %s (auth %s, %d input / %d output tokens)", result.Model, result.TargetModel, result.Latency, authID, result.Usage.InputTokens, result.Usage.OutputTokens)
+	}
+
+	m.mu.RLock()
+	recorder := m.shadowRecorder
+	m.mu.RUnlock()
+	if recorder != nil {
+		recorder.RecordShadow(result)
+	}
+}
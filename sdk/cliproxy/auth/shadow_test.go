@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+// shadowTestExecutor answers with the auth ID and the model it was asked for, or fails with
+// status when set, and records whether each request was a shadow.
+type shadowTestExecutor struct {
+	provider string
+	shadows  chan bool
+	status   int
+}
+
+func (e shadowTestExecutor) Identifier() string { return e.provider }
+
+func (e shadowTestExecutor) Execute(ctx context.Context, auth *Auth, req cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	e.shadows <- usage.IsShadow(ctx)
+	if e.status != 0 {
+		return cliproxyexecutor.Response{}, &Error{HTTPStatus: e.status, Message: "shadow target down"}
+	}
+	usage.PublishRecord(ctx, usage.Record{Model: req.Model, Shadow: usage.IsShadow(ctx), Detail: usage.Detail{InputTokens: 3, OutputTokens: 4, TotalTokens: 7}})
+	return cliproxyexecutor.Response{Payload: []byte(auth.ID + ":" + req.Model)}, nil
+}
+
+func (e shadowTestExecutor) ExecuteStream(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e shadowTestExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) { return auth, nil }
+
+func (e shadowTestExecutor) CountTokens(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e shadowTestExecutor) HttpRequest(context.Context, *Auth, *http.Request) (*http.Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+type shadowTestRecorder chan ShadowResult
+
+func (r shadowTestRecorder) RecordShadow(result ShadowResult) { r <- result }
+
+func TestManagerExecute_MirrorsShadowTraffic(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	m.SetConfig(&internalconfig.Config{ShadowTraffic: []internalconfig.ShadowPolicy{{
+		Models:      []string{"shadow-primary-*"},
+		TargetModel: "shadow-target-model",
+		SampleRate:  1,
+		LogResponse: true,
+	}}})
+	shadows := make(chan bool, 4)
+	recorder := make(shadowTestRecorder, 1)
+	m.SetShadowRecorder(recorder)
+	reg := registry.GetGlobalRegistry()
+	for _, entry := range []struct{ id, provider, model string }{
+		{"shadow-primary-auth", "shadow-primary", "shadow-primary-model"},
+		{"shadow-target-auth", "shadow-target", "shadow-target-model"},
+	} {
+		m.RegisterExecutor(shadowTestExecutor{provider: entry.provider, shadows: shadows})
+		if _, err := m.Register(context.Background(), &Auth{ID: entry.id, Provider: entry.provider}); err != nil {
+			t.Fatalf("register auth: %v", err)
+		}
+		reg.RegisterClient(entry.id, entry.provider, []*registry.ModelInfo{{ID: entry.model}})
+		authID := entry.id
+		t.Cleanup(func() { reg.UnregisterClient(authID) })
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	resp, err := m.Execute(ctx, []string{"shadow-primary"}, cliproxyexecutor.Request{Model: "shadow-primary-model"}, cliproxyexecutor.Options{OriginalRequest: []byte(`{"q":1}`)})
+	cancel()
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if string(resp.Payload) != "shadow-primary-auth:shadow-primary-model" {
+		t.Fatalf("expected the primary response, got %q", resp.Payload)
+	}
+
+	select {
+	case result := <-recorder:
+		if result.StatusCode != http.StatusOK || result.AuthID != "shadow-target-auth" || result.TargetModel != "shadow-target-model" {
+			t.Fatalf("unexpected shadow result %+v", result)
+		}
+		if string(result.Response) != "shadow-target-auth:shadow-target-model" || string(result.Request) != `{"q":1}` {
+			t.Fatalf("unexpected shadow payloads: request %q, response %q", result.Request, result.Response)
+		}
+		if result.Usage.InputTokens != 3 || result.Usage.OutputTokens != 4 || result.Usage.TotalTokens != 7 {
+			t.Fatalf("expected the shadow token usage only, got %+v", result.Usage)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected the shadow request to complete after the client went away")
+	}
+	var flags []bool
+	for len(flags) < 2 {
+		flags = append(flags, <-shadows)
+	}
+	if flags[0] == flags[1] {
+		t.Fatalf("expected exactly one of the two executions to be a shadow, got %v", flags)
+	}
+}
+
+func TestManagerExecute_MirrorsOncePerRequestWithoutTouchingAuthState(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	m.SetConfig(&internalconfig.Config{
+		ShadowTraffic: []internalconfig.ShadowPolicy{{
+			Models:      []string{"once-primary-model"},
+			TargetModel: "once-target-model",
+			SampleRate:  1,
+		}},
+		CircuitBreaker: internalconfig.CircuitBreakerConfig{Enable: true, FailureThreshold: 1},
+	})
+	shadows := make(chan bool, 8)
+	recorder := make(shadowTestRecorder, 4)
+	m.SetShadowRecorder(recorder)
+	reg := registry.GetGlobalRegistry()
+	for _, entry := range []struct {
+		id, provider, model string
+		status              int
+	}{
+		{"once-primary-auth", "once-primary", "once-primary-model", 0},
+		{"once-target-auth", "once-target", "once-target-model", http.StatusInternalServerError},
+	} {
+		m.RegisterExecutor(shadowTestExecutor{provider: entry.provider, shadows: shadows, status: entry.status})
+		if _, err := m.Register(context.Background(), &Auth{ID: entry.id, Provider: entry.provider}); err != nil {
+			t.Fatalf("register auth: %v", err)
+		}
+		reg.RegisterClient(entry.id, entry.provider, []*registry.ModelInfo{{ID: entry.model}})
+		authID := entry.id
+		t.Cleanup(func() { reg.UnregisterClient(authID) })
+	}
+
+	// A retried client request reuses its context and must be mirrored only once.
+	ctx := WithShadowOnce(context.Background())
+	for i := 0; i < 2; i++ {
+		if _, err := m.Execute(ctx, []string{"once-primary"}, cliproxyexecutor.Request{Model: "once-primary-model"}, cliproxyexecutor.Options{}); err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+	}
+	select {
+	case result := <-recorder:
+		if result.StatusCode != http.StatusInternalServerError {
+			t.Fatalf("expected the failed shadow to be reported, got %+v", result)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected one shadow request")
+	}
+	select {
+	case result := <-recorder:
+		t.Fatalf("expected a single shadow per client request, got another: %+v", result)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	target, ok := m.GetByID("once-target-auth")
+	if !ok {
+		t.Fatalf("target auth missing")
+	}
+	if target.Unavailable || target.Status == StatusError || len(target.ModelStates) != 0 {
+		t.Fatalf("shadow failure changed the target credential: status %s, unavailable %v, models %v", target.Status, target.Unavailable, target.ModelStates)
+	}
+	if _, tracked := m.CircuitBreakerStatus("once-target-auth"); tracked {
+		t.Fatalf("shadow failure reached the circuit breaker")
+	}
+}
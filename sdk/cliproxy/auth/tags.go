@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"strings"
+)
+
+type requiredTagsContextKey struct{}
+
+// NormalizeTags trims and lowercases tags, dropping empty and duplicate entries.
+func NormalizeTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(tags))
+	seen := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+		if _, dup := seen[tag]; dup {
+			continue
+		}
+		seen[tag] = struct{}{}
+		out = append(out, tag)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// ParseTags splits a comma-separated tag list such as "team-a,eu" and normalizes the result.
+func ParseTags(raw string) []string {
+	return NormalizeTags(strings.Split(raw, ","))
+}
+
+// Tags returns the routing tags of the auth: the "tags" attribute set for config credentials,
+// or the "tags" field of an auth file, given as a list or a comma-separated string.
+func (a *Auth) Tags() []string {
+	if a == nil {
+		return nil
+	}
+	if raw := strings.TrimSpace(a.Attributes["tags"]); raw != "" {
+		return ParseTags(raw)
+	}
+	switch v := a.Metadata["tags"].(type) {
+	case string:
+		return ParseTags(v)
+	case []string, []any:
+		return NormalizeTags(stringsFromAny(v))
+	}
+	return nil
+}
+
+// HasTags reports whether the auth carries every tag in required.
+func (a *Auth) HasTags(required []string) bool {
+	if len(required) == 0 {
+		return true
+	}
+	tags := a.Tags()
+	for _, want := range required {
+		found := false
+		for _, tag := range tags {
+			if tag == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// WithRequiredTags returns a derived context that restricts selection to credentials carrying
+// every given tag, in addition to any tags already required by ctx.
+func WithRequiredTags(ctx context.Context, tags ...string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	tags = NormalizeTags(tags)
+	if len(tags) == 0 {
+		return ctx
+	}
+	merged := NormalizeTags(append(append([]string(nil), RequiredTagsFromContext(ctx)...), tags...))
+	return context.WithValue(ctx, requiredTagsContextKey{}, merged)
+}
+
+// RequiredTagsFromContext returns the tags stored by WithRequiredTags, if any.
+func RequiredTagsFromContext(ctx context.Context) []string {
+	if ctx == nil {
+		return nil
+	}
+	tags, _ := ctx.Value(requiredTagsContextKey{}).([]string)
+	return tags
+}
+
+func newTagMismatchError(tags []string) *Error {
+	return &Error{Code: "auth_not_found", Message: "no auth available with tags " + strings.Join(tags, ",")}
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func TestAuthTags_FromAttributesAndMetadata(t *testing.T) {
+	fromConfig := &Auth{Attributes: map[string]string{"tags": "Team-A, eu"}}
+	if !fromConfig.HasTags([]string{"team-a", "eu"}) || fromConfig.HasTags([]string{"us"}) {
+		t.Fatalf("unexpected tag match for config auth with tags %v", fromConfig.Tags())
+	}
+	fromFile := &Auth{Metadata: map[string]any{"tags": []any{"EU", "team-b", 3}}}
+	if got := fromFile.Tags(); len(got) != 2 || got[0] != "eu" || got[1] != "team-b" {
+		t.Fatalf("Tags() = %v, want [eu team-b]", got)
+	}
+	if !(&Auth{}).HasTags(nil) {
+		t.Fatalf("expected an untagged auth to match when no tags are required")
+	}
+}
+
+func TestManagerExecute_RestrictsSelectionToRequiredTags(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	m.RegisterExecutor(queueTestExecutor{})
+	reg := registry.GetGlobalRegistry()
+	auths := []*Auth{
+		{ID: "tags-team-a", Provider: "queue-test", Attributes: map[string]string{"tags": "team-a,eu"}},
+		{ID: "tags-team-b", Provider: "queue-test", Metadata: map[string]any{"tags": "team-b,eu"}},
+	}
+	for _, auth := range auths {
+		if _, err := m.Register(context.Background(), auth); err != nil {
+			t.Fatalf("register auth: %v", err)
+		}
+		reg.RegisterClient(auth.ID, "queue-test", []*registry.ModelInfo{{ID: "tags-model"}})
+		id := auth.ID
+		t.Cleanup(func() { reg.UnregisterClient(id) })
+	}
+
+	req := cliproxyexecutor.Request{Model: "tags-model"}
+	for range 4 {
+		resp, err := m.Execute(WithRequiredTags(context.Background(), "Team-B"), []string{"queue-test"}, req, cliproxyexecutor.Options{})
+		if err != nil {
+			t.Fatalf("execute with tag team-b: %v", err)
+		}
+		if string(resp.Payload) != "tags-team-b" {
+			t.Fatalf("expected the team-b credential, got %q", resp.Payload)
+		}
+	}
+
+	ctx := WithRequiredTags(WithRequiredTags(context.Background(), "eu"), "team-a")
+	if resp, err := m.Execute(ctx, []string{"queue-test"}, req, cliproxyexecutor.Options{}); err != nil || string(resp.Payload) != "tags-team-a" {
+		t.Fatalf("expected the team-a credential for tags eu and team-a, got %q, %v", resp.Payload, err)
+	}
+
+	_, err := m.Execute(WithRequiredTags(context.Background(), "us"), []string{"queue-test"}, req, cliproxyexecutor.Options{})
+	var authErr *Error
+	if !errors.As(err, &authErr) || authErr.Code != "auth_not_found" {
+		t.Fatalf("expected auth_not_found for an unmatched tag, got %v", err)
+	}
+}
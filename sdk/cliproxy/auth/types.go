@@ -63,6 +63,8 @@ type Auth struct {
 	Runtime any `json:"-"`
 
 	indexAssigned bool `json:"-"`
+	// schedule caches the parsed availability schedule; see cacheSchedule.
+	schedule *cachedAvailability `json:"-"`
 }
 
 // QuotaState contains limiter tracking data for a credential.
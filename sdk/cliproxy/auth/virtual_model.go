@@ -0,0 +1,301 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// VirtualModelProvider is the registry provider key under which virtual models are listed.
+// The conductor resolves virtual models before provider routing, so no executor handles it.
+const VirtualModelProvider = "virtual"
+
+// virtualModelResponsePaths lists the JSON paths where translated responses report the model.
+var virtualModelResponsePaths = []string{"model", "modelVersion", "message.model", "response.model", "response.modelVersion"}
+
+// contextLengthMarkers are lower-cased fragments upstreams use when a prompt exceeds the context window.
+var contextLengthMarkers = []string{
+	"context length",
+	"context_length",
+	"context window",
+	"prompt is too long",
+	"input is too long",
+	"too many tokens",
+	"maximum context",
+	"exceeds the maximum number of tokens",
+	"input token count",
+}
+
+// TargetFilter narrows the providers a virtual model target may be routed to. It returns the
+// providers the caller may use for model; an empty result skips the target.
+type TargetFilter func(model string, providers []string) []string
+
+type targetFilterContextKey struct{}
+
+// WithTargetFilter returns a derived context whose virtual model targets are checked with
+// filter, so fallback chains only reach models and providers the caller may use directly.
+func WithTargetFilter(ctx context.Context, filter TargetFilter) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if filter == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, targetFilterContextKey{}, filter)
+}
+
+func targetFilterFromContext(ctx context.Context) TargetFilter {
+	if ctx == nil {
+		return nil
+	}
+	filter, _ := ctx.Value(targetFilterContextKey{}).(TargetFilter)
+	return filter
+}
+
+type virtualModel struct {
+	name    string
+	targets []string
+	on      map[string]struct{}
+}
+
+// virtualModelTable maps lower-cased virtual model names to their fallback chains.
+type virtualModelTable map[string]*virtualModel
+
+func compileVirtualModelTable(entries []internalconfig.VirtualModel) virtualModelTable {
+	out := make(virtualModelTable, len(entries))
+	for _, entry := range entries {
+		name := strings.TrimSpace(entry.Name)
+		if name == "" || len(entry.Targets) == 0 {
+			continue
+		}
+		key := strings.ToLower(name)
+		if _, exists := out[key]; exists {
+			continue
+		}
+		conditions := entry.FallbackOn
+		if len(conditions) == 0 {
+			conditions = []string{
+				internalconfig.VirtualModelFallbackCooldown,
+				internalconfig.VirtualModelFallbackServerError,
+				internalconfig.VirtualModelFallbackContextLength,
+			}
+		}
+		vm := &virtualModel{name: name, targets: append([]string(nil), entry.Targets...), on: make(map[string]struct{}, len(conditions))}
+		for _, condition := range conditions {
+			vm.on[strings.ToLower(strings.TrimSpace(condition))] = struct{}{}
+		}
+		out[key] = vm
+	}
+	return out
+}
+
+// lookupVirtualModel returns the chain configured for model (ignoring a thinking suffix), or nil.
+func (m *Manager) lookupVirtualModel(model string) *virtualModel {
+	if m == nil {
+		return nil
+	}
+	table, _ := m.virtualModels.Load().(virtualModelTable)
+	if len(table) == 0 {
+		return nil
+	}
+	key := strings.ToLower(strings.TrimSpace(thinking.ParseSuffix(model).ModelName))
+	if key == "" {
+		return nil
+	}
+	return table[key]
+}
+
+// applyRequestedSuffix applies the thinking suffix of the requested model to target unless target has its own.
+func applyRequestedSuffix(requestedModel, target string) string {
+	requested := thinking.ParseSuffix(requestedModel)
+	if !requested.HasSuffix || requested.RawSuffix == "" || thinking.ParseSuffix(target).HasSuffix {
+		return target
+	}
+	return target + "(" + requested.RawSuffix + ")"
+}
+
+// shouldFallback reports whether err matches one of the chain's fallback conditions.
+func (vm *virtualModel) shouldFallback(err error) bool {
+	if err == nil {
+		return false
+	}
+	status := statusCodeFromError(err)
+	if _, ok := vm.on[internalconfig.VirtualModelFallbackCooldown]; ok && isUnavailableError(err, status) {
+		return true
+	}
+	if _, ok := vm.on[internalconfig.VirtualModelFallbackServerError]; ok && status >= http.StatusInternalServerError {
+		return true
+	}
+	if _, ok := vm.on[internalconfig.VirtualModelFallbackContextLength]; ok && isContextLengthError(err, status) {
+		return true
+	}
+	return false
+}
+
+// isUnavailableError reports failures caused by no credential being able to serve the model.
+func isUnavailableError(err error, status int) bool {
+	var cooldownErr *modelCooldownError
+	if errors.As(err, &cooldownErr) {
+		return true
+	}
+	var authErr *Error
+	if errors.As(err, &authErr) && authErr != nil {
+		switch authErr.Code {
+		case "auth_not_found", "auth_unavailable", "auth_limit_reached", "auth_circuit_open", "provider_not_found", "executor_not_found":
+			return true
+		}
+	}
+	return status == http.StatusTooManyRequests
+}
+
+func isContextLengthError(err error, status int) bool {
+	if status != 0 && status != http.StatusBadRequest && status != http.StatusRequestEntityTooLarge {
+		return false
+	}
+	message := strings.ToLower(err.Error())
+	for _, marker := range contextLengthMarkers {
+		if strings.Contains(message, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// executeVirtualModel runs req against each target of vm in order until one succeeds or fails
+// with an error outside the configured fallback conditions. Targets are routed like regular
+// requests, so the original source format is kept and responses are translated back to it.
+// Targets the context's TargetFilter rejects are skipped.
+func executeVirtualModel[T any](ctx context.Context, m *Manager, vm *virtualModel, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, run func(context.Context, []string, cliproxyexecutor.Request, cliproxyexecutor.Options) (T, error)) (T, error) {
+	var zero T
+	var lastErr error
+	filter := targetFilterFromContext(ctx)
+	for i, target := range vm.targets {
+		targetModel := applyRequestedSuffix(req.Model, target)
+		providers := util.GetProviderName(thinking.ParseSuffix(targetModel).ModelName)
+		if filter != nil && len(providers) > 0 {
+			if providers = filter(targetModel, providers); len(providers) == 0 {
+				logEntryWithRequestID(ctx).Debugf("virtual model %s: target %s is not allowed for this client, skipping", vm.name, targetModel)
+				if lastErr == nil {
+					lastErr = &Error{Code: "model_not_allowed", Message: "no target of virtual model " + vm.name + " is allowed for this client", HTTPStatus: http.StatusForbidden}
+				}
+				continue
+			}
+		}
+		var resp T
+		var errRun error
+		if len(providers) == 0 {
+			errRun = &Error{Code: "provider_not_found", Message: "unknown provider for model " + targetModel}
+		} else {
+			targetReq := req
+			targetReq.Model = targetModel
+			runCtx := ctx
+			if i+1 < len(vm.targets) {
+				// Fall through to the next target rather than queueing behind a cooldown.
+				runCtx = withoutCooldownQueue(ctx)
+			}
+			resp, errRun = run(runCtx, providers, targetReq, withRequestedModel(opts, targetModel))
+		}
+		if errRun == nil {
+			return resp, nil
+		}
+		if errCtx := ctx.Err(); errCtx != nil {
+			return zero, errCtx
+		}
+		lastErr = errRun
+		if !vm.shouldFallback(errRun) {
+			break
+		}
+		if i+1 < len(vm.targets) {
+			logEntryWithRequestID(ctx).Debugf("virtual model %s: target %s failed (%v), falling back to %s", vm.name, targetModel, errRun, vm.targets[i+1])
+		}
+	}
+	if lastErr == nil {
+		lastErr = &Error{Code: "auth_not_found", Message: "no target available for virtual model " + vm.name}
+	}
+	return zero, lastErr
+}
+
+// withRequestedModel copies opts with the requested-model metadata replaced by model.
+func withRequestedModel(opts cliproxyexecutor.Options, model string) cliproxyexecutor.Options {
+	meta := make(map[string]any, len(opts.Metadata)+1)
+	for k, v := range opts.Metadata {
+		meta[k] = v
+	}
+	meta[cliproxyexecutor.RequestedModelMetadataKey] = model
+	opts.Metadata = meta
+	return opts
+}
+
+// rewriteVirtualModelResponse reports the virtual model name in a translated response.
+func rewriteVirtualModelResponse(resp cliproxyexecutor.Response, name string) cliproxyexecutor.Response {
+	resp.Payload = rewriteModelInPayload(resp.Payload, name)
+	return resp
+}
+
+// rewriteVirtualModelStream reports the virtual model name in every translated stream chunk.
+func rewriteVirtualModelStream(ctx context.Context, chunks <-chan cliproxyexecutor.StreamChunk, name string) <-chan cliproxyexecutor.StreamChunk {
+	out := make(chan cliproxyexecutor.StreamChunk)
+	go func() {
+		defer close(out)
+		forward := true
+		for chunk := range chunks {
+			if !forward {
+				continue
+			}
+			chunk.Payload = rewriteModelInPayload(chunk.Payload, name)
+			select {
+			case <-ctx.Done():
+				forward = false
+			case out <- chunk:
+			}
+		}
+	}()
+	return out
+}
+
+// rewriteModelInPayload replaces model fields in a JSON document or in the data lines of SSE frames.
+func rewriteModelInPayload(payload []byte, name string) []byte {
+	trimmed := bytes.TrimSpace(payload)
+	if len(trimmed) == 0 {
+		return payload
+	}
+	if trimmed[0] == '{' {
+		return rewriteModelInJSON(payload, name)
+	}
+	if !bytes.Contains(payload, []byte("data:")) {
+		return payload
+	}
+	lines := bytes.Split(payload, []byte("\n"))
+	for i, line := range lines {
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+		data := bytes.TrimLeft(line[len("data:"):], " ")
+		if len(data) == 0 || data[0] != '{' {
+			continue
+		}
+		prefix := line[:len(line)-len(data)]
+		lines[i] = append(append([]byte(nil), prefix...), rewriteModelInJSON(data, name)...)
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
+func rewriteModelInJSON(data []byte, name string) []byte {
+	for _, path := range virtualModelResponsePaths {
+		if value := gjson.GetBytes(data, path); value.Exists() && value.Type == gjson.String {
+			if updated, err := sjson.SetBytes(data, path, name); err == nil {
+				data = updated
+			}
+		}
+	}
+	return data
+}
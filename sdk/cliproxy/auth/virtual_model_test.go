@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+type virtualTestExecutor struct {
+	id     string
+	status int
+	msg    string
+	models []string
+}
+
+func (e *virtualTestExecutor) Identifier() string { return e.id }
+
+func (e *virtualTestExecutor) Execute(_ context.Context, _ *Auth, req cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	e.models = append(e.models, req.Model)
+	if e.status != 0 {
+		return cliproxyexecutor.Response{}, &Error{HTTPStatus: e.status, Message: e.msg}
+	}
+	return cliproxyexecutor.Response{Payload: []byte(`{"model":"` + req.Model + `","ok":true}`)}, nil
+}
+
+func (e *virtualTestExecutor) ExecuteStream(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *virtualTestExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) { return auth, nil }
+
+func (e *virtualTestExecutor) CountTokens(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e *virtualTestExecutor) HttpRequest(context.Context, *Auth, *http.Request) (*http.Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+func newVirtualModelTestManager(t *testing.T, primary, secondary *virtualTestExecutor, fallbackOn []string) *Manager {
+	t.Helper()
+	m := NewManager(nil, nil, nil)
+	m.SetConfig(&internalconfig.Config{VirtualModels: []internalconfig.VirtualModel{{
+		Name:       "smart",
+		Targets:    []string{"vm-primary-model", "vm-secondary-model"},
+		FallbackOn: fallbackOn,
+	}}})
+	reg := registry.GetGlobalRegistry()
+	for _, item := range []struct {
+		exec  *virtualTestExecutor
+		model string
+	}{{primary, "vm-primary-model"}, {secondary, "vm-secondary-model"}} {
+		m.RegisterExecutor(item.exec)
+		authID := item.exec.id + "-auth"
+		if _, err := m.Register(context.Background(), &Auth{ID: authID, Provider: item.exec.id}); err != nil {
+			t.Fatalf("register auth: %v", err)
+		}
+		reg.RegisterClient(authID, item.exec.id, []*registry.ModelInfo{{ID: item.model}})
+		t.Cleanup(func() { reg.UnregisterClient(authID) })
+	}
+	return m
+}
+
+func TestManagerExecute_VirtualModelFallsBackOnServerError(t *testing.T) {
+	primary := &virtualTestExecutor{id: "vm-primary", status: http.StatusBadGateway, msg: "upstream down"}
+	secondary := &virtualTestExecutor{id: "vm-secondary"}
+	m := newVirtualModelTestManager(t, primary, secondary, nil)
+
+	resp, err := m.Execute(context.Background(), []string{VirtualModelProvider}, cliproxyexecutor.Request{Model: "smart"}, cliproxyexecutor.Options{})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if got := string(resp.Payload); got != `{"model":"smart","ok":true}` {
+		t.Fatalf("payload = %s, want virtual model name", got)
+	}
+	if len(primary.models) != 1 || len(secondary.models) != 1 || secondary.models[0] != "vm-secondary-model" {
+		t.Fatalf("unexpected routing: primary=%v secondary=%v", primary.models, secondary.models)
+	}
+}
+
+func TestManagerExecute_VirtualModelHonoursFallbackConditions(t *testing.T) {
+	primary := &virtualTestExecutor{id: "vm-primary", status: http.StatusBadRequest, msg: "prompt is too long: 250000 tokens"}
+	secondary := &virtualTestExecutor{id: "vm-secondary"}
+	m := newVirtualModelTestManager(t, primary, secondary, []string{internalconfig.VirtualModelFallbackServerError})
+
+	_, err := m.Execute(context.Background(), []string{VirtualModelProvider}, cliproxyexecutor.Request{Model: "smart"}, cliproxyexecutor.Options{})
+	if err == nil {
+		t.Fatalf("expected context-length error to be returned when only 5xx falls back")
+	}
+	if len(secondary.models) != 0 {
+		t.Fatalf("secondary target should not be tried, got %v", secondary.models)
+	}
+
+	m.SetConfig(&internalconfig.Config{VirtualModels: []internalconfig.VirtualModel{{
+		Name:       "smart",
+		Targets:    []string{"vm-primary-model", "vm-secondary-model"},
+		FallbackOn: []string{internalconfig.VirtualModelFallbackContextLength},
+	}}})
+	if _, err = m.Execute(context.Background(), []string{VirtualModelProvider}, cliproxyexecutor.Request{Model: "smart(high)"}, cliproxyexecutor.Options{}); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if len(secondary.models) != 1 || secondary.models[0] != "vm-secondary-model(high)" {
+		t.Fatalf("expected thinking suffix to carry over to the fallback target, got %v", secondary.models)
+	}
+}
+
+func TestManagerExecute_VirtualModelSkipsFilteredTargets(t *testing.T) {
+	primary := &virtualTestExecutor{id: "vm-primary"}
+	secondary := &virtualTestExecutor{id: "vm-secondary"}
+	m := newVirtualModelTestManager(t, primary, secondary, nil)
+
+	denyPrimary := WithTargetFilter(context.Background(), func(model string, providers []string) []string {
+		if model == "vm-primary-model" {
+			return nil
+		}
+		return providers
+	})
+	if _, err := m.Execute(denyPrimary, []string{VirtualModelProvider}, cliproxyexecutor.Request{Model: "smart"}, cliproxyexecutor.Options{}); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if len(primary.models) != 0 || len(secondary.models) != 1 {
+		t.Fatalf("expected only the allowed target to run: primary=%v secondary=%v", primary.models, secondary.models)
+	}
+
+	denyAll := WithTargetFilter(context.Background(), func(string, []string) []string { return nil })
+	_, err := m.Execute(denyAll, []string{VirtualModelProvider}, cliproxyexecutor.Request{Model: "smart"}, cliproxyexecutor.Options{})
+	if statusCodeFromError(err) != http.StatusForbidden {
+		t.Fatalf("expected 403 when no target is allowed, got %v", err)
+	}
+	if len(primary.models) != 0 || len(secondary.models) != 1 {
+		t.Fatalf("filtered targets should not run: primary=%v secondary=%v", primary.models, secondary.models)
+	}
+}
+
+func TestRewriteModelInPayload_SSE(t *testing.T) {
+	in := []byte("event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"model\":\"upstream\"}}\n\n")
+	want := "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"model\":\"smart\"}}\n\n"
+	if got := string(rewriteModelInPayload(in, "smart")); got != want {
+		t.Fatalf("rewrite = %q, want %q", got, want)
+	}
+}
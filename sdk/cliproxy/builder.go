@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/api"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/metrics"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
 	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
@@ -207,16 +208,22 @@ func (b *Builder) Build() (*Service, error) {
 		switch strategy {
 		case "fill-first", "fillfirst", "ff":
 			selector = &coreauth.FillFirstSelector{}
+		case "latency", "least-latency", "ll":
+			selector = coreauth.NewLatencySelector()
 		default:
 			selector = &coreauth.RoundRobinSelector{}
 		}
 
 		coreManager = coreauth.NewManager(tokenStore, selector, nil)
 	}
+	// Custom managers report results to the metrics collector as well.
+	coreManager.AddHook(metrics.DefaultHook())
 	// Attach a default RoundTripper provider so providers can opt-in per-auth transports.
 	coreManager.SetRoundTripperProvider(newDefaultRoundTripperProvider())
 	coreManager.SetConfig(b.cfg)
 	coreManager.SetOAuthModelAlias(b.cfg.OAuthModelAlias)
+	// Feed token usage back into the manager so per-credential TPM limits are enforced.
+	attachUsageFeed(coreManager)
 
 	service := &Service{
 		cfg:            b.cfg,
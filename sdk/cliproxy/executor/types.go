@@ -10,6 +10,10 @@ import (
 // RequestedModelMetadataKey stores the client-requested model name in Options.Metadata.
 const RequestedModelMetadataKey = "requested_model"
 
+// SessionIDMetadataKey stores a client-supplied session identifier in Options.Metadata.
+// The conductor uses it as the session affinity key when present.
+const SessionIDMetadataKey = "session_id"
+
 // Request encapsulates the translated payload that will be sent to a provider executor.
 type Request struct {
 	// Model is the upstream model identifier after translation.
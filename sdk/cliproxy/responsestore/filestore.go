@@ -0,0 +1,171 @@
+package responsestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// sweepInterval bounds how often Save triggers a scan for expired records.
+const sweepInterval = 10 * time.Minute
+
+// FileStore keeps one JSON document per response in a directory.
+// Expired records are hidden on Load and removed by a periodic background sweep.
+type FileStore struct {
+	mu        sync.Mutex
+	dir       string
+	ttl       time.Duration
+	lastSweep time.Time
+}
+
+// NewFileStore creates a file-backed store rooted at dir. A ttl <= 0 keeps records forever.
+func NewFileStore(dir string, ttl time.Duration) *FileStore {
+	return &FileStore{dir: dir, ttl: ttl}
+}
+
+// SetTTL updates the retention applied to newly saved records.
+func (s *FileStore) SetTTL(ttl time.Duration) {
+	s.mu.Lock()
+	s.ttl = ttl
+	s.mu.Unlock()
+}
+
+// Save persists the record, stamping CreatedAt and ExpiresAt when they are unset.
+func (s *FileStore) Save(_ context.Context, record *Record) error {
+	if record == nil {
+		return fmt.Errorf("responsestore: record is nil")
+	}
+	path, err := s.pathFor(record.ID)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	now := time.Now()
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = now
+	}
+	if record.ExpiresAt.IsZero() && s.ttl > 0 {
+		record.ExpiresAt = record.CreatedAt.Add(s.ttl)
+	}
+	sweep := now.Sub(s.lastSweep) >= sweepInterval
+	if sweep {
+		s.lastSweep = now
+	}
+	s.mu.Unlock()
+
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("responsestore: marshal record failed: %w", err)
+	}
+	if err = os.MkdirAll(s.dir, 0o700); err != nil {
+		return fmt.Errorf("responsestore: create dir failed: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, raw, 0o600); err != nil {
+		return fmt.Errorf("responsestore: write record failed: %w", err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("responsestore: rename record failed: %w", err)
+	}
+
+	if sweep {
+		go s.sweep(now)
+	}
+	return nil
+}
+
+// Load returns the record for id. Expired records are removed and reported as ErrNotFound.
+func (s *FileStore) Load(_ context.Context, id string) (*Record, error) {
+	path, err := s.pathFor(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("responsestore: read record failed: %w", err)
+	}
+	var record Record
+	if err = json.Unmarshal(raw, &record); err != nil {
+		return nil, fmt.Errorf("responsestore: decode record failed: %w", err)
+	}
+	if record.Expired(time.Now()) {
+		_ = os.Remove(path)
+		return nil, ErrNotFound
+	}
+	return &record, nil
+}
+
+// Delete removes the record for id.
+func (s *FileStore) Delete(_ context.Context, id string) error {
+	path, err := s.pathFor(id)
+	if err != nil {
+		return ErrNotFound
+	}
+	if err = os.Remove(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("responsestore: delete record failed: %w", err)
+	}
+	return nil
+}
+
+// pathFor maps a response ID to a file path, rejecting IDs that could escape the directory.
+func (s *FileStore) pathFor(id string) (string, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return "", fmt.Errorf("responsestore: empty response id")
+	}
+	for _, r := range id {
+		if !(r == '_' || r == '-' || r == '.' || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')) {
+			return "", fmt.Errorf("responsestore: invalid response id %q", id)
+		}
+	}
+	if strings.HasPrefix(id, ".") {
+		return "", fmt.Errorf("responsestore: invalid response id %q", id)
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
+
+func (s *FileStore) sweep(now time.Time) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	removed := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(s.dir, entry.Name())
+		raw, errRead := os.ReadFile(path)
+		if errRead != nil {
+			continue
+		}
+		var record Record
+		if errDecode := json.Unmarshal(raw, &record); errDecode != nil {
+			continue
+		}
+		if record.Expired(now) {
+			if errRemove := os.Remove(path); errRemove == nil {
+				removed++
+			}
+		}
+	}
+	if removed > 0 {
+		log.Debugf("responsestore: removed %d expired responses", removed)
+	}
+}
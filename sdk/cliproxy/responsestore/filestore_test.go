@@ -0,0 +1,71 @@
+package responsestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFileStoreSaveLoadDelete(t *testing.T) {
+	store := NewFileStore(t.TempDir(), time.Hour)
+	ctx := context.Background()
+
+	record := &Record{
+		ID:       "resp_123",
+		Model:    "test-model",
+		Input:    json.RawMessage(`[{"type":"message","role":"user","content":"hi"}]`),
+		Response: json.RawMessage(`{"id":"resp_123","output":[]}`),
+	}
+	if err := store.Save(ctx, record); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if record.ExpiresAt.IsZero() {
+		t.Fatalf("ExpiresAt not stamped")
+	}
+
+	loaded, err := store.Load(ctx, "resp_123")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if loaded.Model != "test-model" || string(loaded.Response) != `{"id":"resp_123","output":[]}` {
+		t.Fatalf("unexpected record: %+v", loaded)
+	}
+
+	if err = store.Delete(ctx, "resp_123"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err = store.Load(ctx, "resp_123"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Load after delete err = %v, want ErrNotFound", err)
+	}
+	if err = store.Delete(ctx, "resp_123"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("second Delete err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFileStoreExpiredAndInvalidIDs(t *testing.T) {
+	store := NewFileStore(t.TempDir(), time.Hour)
+	ctx := context.Background()
+
+	expired := &Record{
+		ID:        "resp_old",
+		Input:     json.RawMessage(`[]`),
+		Response:  json.RawMessage(`{}`),
+		CreatedAt: time.Now().Add(-2 * time.Hour),
+		ExpiresAt: time.Now().Add(-time.Hour),
+	}
+	if err := store.Save(ctx, expired); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := store.Load(ctx, "resp_old"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Load expired err = %v, want ErrNotFound", err)
+	}
+
+	if err := store.Save(ctx, &Record{ID: "../escape"}); err == nil {
+		t.Fatalf("Save with path traversal id succeeded")
+	}
+	if _, err := store.Load(ctx, "../escape"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Load invalid id err = %v, want ErrNotFound", err)
+	}
+}
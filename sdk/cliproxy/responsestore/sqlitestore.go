@@ -0,0 +1,149 @@
+package responsestore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteStore keeps response records in a single SQLite table.
+// Expired records are hidden on Load and removed by a periodic sweep on Save.
+type SQLiteStore struct {
+	mu        sync.Mutex
+	db        *sql.DB
+	path      string
+	ttl       time.Duration
+	lastSweep time.Time
+}
+
+// NewSQLiteStore opens (creating when needed) the SQLite database at path. A ttl <= 0 keeps
+// records forever.
+func NewSQLiteStore(path string, ttl time.Duration) (*SQLiteStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("responsestore: create dir failed: %w", err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("responsestore: open sqlite database failed: %w", err)
+	}
+	// SQLite allows a single writer; serialising connections avoids SQLITE_BUSY errors.
+	db.SetMaxOpenConns(1)
+	const schema = `CREATE TABLE IF NOT EXISTS responses (
+		id TEXT PRIMARY KEY,
+		record BLOB NOT NULL,
+		expires_at INTEGER NOT NULL DEFAULT 0
+	)`
+	if _, err = db.Exec(schema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("responsestore: create table failed: %w", err)
+	}
+	return &SQLiteStore{db: db, path: path, ttl: ttl}, nil
+}
+
+// SetTTL updates the retention applied to newly saved records.
+func (s *SQLiteStore) SetTTL(ttl time.Duration) {
+	s.mu.Lock()
+	s.ttl = ttl
+	s.mu.Unlock()
+}
+
+// Close closes the database.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// Save persists the record, stamping CreatedAt and ExpiresAt when they are unset.
+func (s *SQLiteStore) Save(ctx context.Context, record *Record) error {
+	if record == nil {
+		return fmt.Errorf("responsestore: record is nil")
+	}
+	if strings.TrimSpace(record.ID) == "" {
+		return fmt.Errorf("responsestore: empty response id")
+	}
+
+	s.mu.Lock()
+	now := time.Now()
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = now
+	}
+	if record.ExpiresAt.IsZero() && s.ttl > 0 {
+		record.ExpiresAt = record.CreatedAt.Add(s.ttl)
+	}
+	sweep := now.Sub(s.lastSweep) >= sweepInterval
+	if sweep {
+		s.lastSweep = now
+	}
+	s.mu.Unlock()
+
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("responsestore: marshal record failed: %w", err)
+	}
+	var expiresAt int64
+	if !record.ExpiresAt.IsZero() {
+		expiresAt = record.ExpiresAt.UnixMilli()
+	}
+	const upsert = `INSERT INTO responses (id, record, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET record = excluded.record, expires_at = excluded.expires_at`
+	if _, err = s.db.ExecContext(ctx, upsert, record.ID, raw, expiresAt); err != nil {
+		return fmt.Errorf("responsestore: write record failed: %w", err)
+	}
+
+	if sweep {
+		go s.sweep(now)
+	}
+	return nil
+}
+
+// Load returns the record for id. Expired records are removed and reported as ErrNotFound.
+func (s *SQLiteStore) Load(ctx context.Context, id string) (*Record, error) {
+	var raw []byte
+	err := s.db.QueryRowContext(ctx, `SELECT record FROM responses WHERE id = ?`, id).Scan(&raw)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("responsestore: read record failed: %w", err)
+	}
+	var record Record
+	if err = json.Unmarshal(raw, &record); err != nil {
+		return nil, fmt.Errorf("responsestore: decode record failed: %w", err)
+	}
+	if record.Expired(time.Now()) {
+		_, _ = s.db.ExecContext(ctx, `DELETE FROM responses WHERE id = ?`, id)
+		return nil, ErrNotFound
+	}
+	return &record, nil
+}
+
+// Delete removes the record for id.
+func (s *SQLiteStore) Delete(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM responses WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("responsestore: delete record failed: %w", err)
+	}
+	if n, errRows := result.RowsAffected(); errRows == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) sweep(now time.Time) {
+	result, err := s.db.Exec(`DELETE FROM responses WHERE expires_at > 0 AND expires_at < ?`, now.UnixMilli())
+	if err != nil {
+		return
+	}
+	if removed, errRows := result.RowsAffected(); errRows == nil && removed > 0 {
+		log.Debugf("responsestore: removed %d expired responses", removed)
+	}
+}
@@ -0,0 +1,138 @@
+// Package responsestore persists OpenAI Responses API results so that requests carrying
+// previous_response_id can be expanded into full conversation history before they are
+// translated for a backend that has no server-side conversation state of its own.
+package responsestore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when a response ID is unknown or its record has expired.
+var ErrNotFound = errors.New("responsestore: response not found")
+
+// Record captures one stored response together with the input items it was generated from.
+type Record struct {
+	// ID is the response identifier returned to the client (e.g. "resp_...").
+	ID string `json:"id"`
+	// Owner is the OwnerKey of the client that created the response. Only that client may
+	// read, delete or continue it.
+	Owner string `json:"owner,omitempty"`
+	// Model is the model name the client requested.
+	Model string `json:"model,omitempty"`
+	// Input holds the full input item array sent upstream, including expanded history.
+	Input json.RawMessage `json:"input"`
+	// Response holds the final response object as returned to the client.
+	Response json.RawMessage `json:"response"`
+	// CreatedAt is the time the record was saved.
+	CreatedAt time.Time `json:"created_at"`
+	// ExpiresAt is the time after which the record is no longer served. Zero means never.
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// Expired reports whether the record is past its expiry at the given time.
+func (r *Record) Expired(now time.Time) bool {
+	return r != nil && !r.ExpiresAt.IsZero() && now.After(r.ExpiresAt)
+}
+
+// OwnedBy reports whether the record belongs to the client with the given OwnerKey.
+func (r *Record) OwnedBy(owner string) bool {
+	return r != nil && r.Owner == owner
+}
+
+// OwnerKey derives the Owner stored on records from a client API key or access principal,
+// so records never hold the key itself. An empty principal yields an empty owner.
+func OwnerKey(principal string) string {
+	if principal == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(principal))
+	return hex.EncodeToString(sum[:])
+}
+
+// Store persists response records keyed by response ID.
+type Store interface {
+	// Save creates or replaces the record for record.ID.
+	Save(ctx context.Context, record *Record) error
+	// Load returns the record for id, or ErrNotFound.
+	Load(ctx context.Context, id string) (*Record, error)
+	// Delete removes the record for id, returning ErrNotFound when it does not exist.
+	Delete(ctx context.Context, id string) error
+}
+
+var (
+	storeMu     sync.RWMutex
+	activeStore Store
+	customStore bool
+)
+
+// RegisterStore installs a custom response store, replacing the built-in store.
+// Passing nil removes the custom store; the next Configure call restores the built-in one.
+func RegisterStore(store Store) {
+	storeMu.Lock()
+	activeStore = store
+	customStore = store != nil
+	storeMu.Unlock()
+}
+
+// Configure (re)builds the built-in store from configuration values. driver selects
+// "file" (default, one JSON document per response under dir) or "sqlite" (responses.db
+// under dir). It leaves a store installed through RegisterStore untouched.
+func Configure(enabled bool, driver, dir string, ttl time.Duration) error {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	if customStore {
+		return nil
+	}
+	if !enabled {
+		closeStore(activeStore)
+		activeStore = nil
+		return nil
+	}
+	switch strings.ToLower(strings.TrimSpace(driver)) {
+	case "", "file":
+		if existing, ok := activeStore.(*FileStore); ok && existing.dir == dir {
+			existing.SetTTL(ttl)
+			return nil
+		}
+		closeStore(activeStore)
+		activeStore = NewFileStore(dir, ttl)
+	case "sqlite", "sqlite3":
+		path := filepath.Join(dir, "responses.db")
+		if existing, ok := activeStore.(*SQLiteStore); ok && existing.path == path {
+			existing.SetTTL(ttl)
+			return nil
+		}
+		store, err := NewSQLiteStore(path, ttl)
+		if err != nil {
+			return err
+		}
+		closeStore(activeStore)
+		activeStore = store
+	default:
+		return fmt.Errorf("responsestore: unsupported driver %q", driver)
+	}
+	return nil
+}
+
+func closeStore(store Store) {
+	if closer, ok := store.(io.Closer); ok {
+		_ = closer.Close()
+	}
+}
+
+// GetStore returns the active response store, or nil when persistence is disabled.
+func GetStore() Store {
+	storeMu.RLock()
+	defer storeMu.RUnlock()
+	return activeStore
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/executor"
 	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/watcher"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/wsrelay"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
@@ -336,6 +337,32 @@ func (s *Service) applyRetryConfig(cfg *config.Config) {
 	s.coreManager.SetRetryConfig(cfg.RequestRetry, maxInterval)
 }
 
+// virtualModelsClientID identifies the registry entry listing config-defined virtual models.
+const virtualModelsClientID = "virtual-models"
+
+// registerVirtualModels lists config-defined virtual models in the global registry so they are
+// advertised by the model endpoints and routed to the conductor, which resolves their targets.
+func (s *Service) registerVirtualModels(cfg *config.Config) {
+	if cfg == nil || len(cfg.VirtualModels) == 0 {
+		GlobalModelRegistry().UnregisterClient(virtualModelsClientID)
+		return
+	}
+	now := time.Now().Unix()
+	models := make([]*ModelInfo, 0, len(cfg.VirtualModels))
+	for _, vm := range cfg.VirtualModels {
+		models = append(models, &ModelInfo{
+			ID:          vm.Name,
+			Object:      "model",
+			Created:     now,
+			OwnedBy:     coreauth.VirtualModelProvider,
+			Type:        coreauth.VirtualModelProvider,
+			DisplayName: vm.Name,
+			UserDefined: true,
+		})
+	}
+	GlobalModelRegistry().RegisterClient(virtualModelsClientID, coreauth.VirtualModelProvider, models)
+}
+
 func openAICompatInfoFromAuth(a *coreauth.Auth) (providerKey string, compatName string, ok bool) {
 	if a == nil {
 		return "", "", false
@@ -450,6 +477,7 @@ func (s *Service) Run(ctx context.Context) error {
 	}
 
 	s.applyRetryConfig(s.cfg)
+	s.registerVirtualModels(s.cfg)
 
 	if s.coreManager != nil {
 		if errLoad := s.coreManager.Load(ctx); errLoad != nil {
@@ -548,6 +576,8 @@ func (s *Service) Run(ctx context.Context) error {
 			switch strategy {
 			case "fill-first", "fillfirst", "ff":
 				return "fill-first"
+			case "latency", "least-latency", "ll":
+				return "latency"
 			default:
 				return "round-robin"
 			}
@@ -559,6 +589,8 @@ func (s *Service) Run(ctx context.Context) error {
 			switch nextStrategy {
 			case "fill-first":
 				selector = &coreauth.FillFirstSelector{}
+			case "latency":
+				selector = coreauth.NewLatencySelector()
 			default:
 				selector = &coreauth.RoundRobinSelector{}
 			}
@@ -566,6 +598,7 @@ func (s *Service) Run(ctx context.Context) error {
 		}
 
 		s.applyRetryConfig(newCfg)
+		s.registerVirtualModels(newCfg)
 		s.applyPprofConfig(newCfg)
 		if s.server != nil {
 			s.server.UpdateClients(newCfg)
@@ -640,6 +673,7 @@ func (s *Service) Shutdown(ctx context.Context) error {
 		}
 		if s.coreManager != nil {
 			s.coreManager.StopAutoRefresh()
+			detachUsageFeed(s.coreManager)
 		}
 		if s.watcher != nil {
 			if err := s.watcher.Stop(); err != nil {
@@ -1055,7 +1089,7 @@ func applyExcludedModels(models []*ModelInfo, excluded []string) []*ModelInfo {
 		modelID := strings.ToLower(strings.TrimSpace(model.ID))
 		blocked := false
 		for _, pattern := range patterns {
-			if matchWildcard(pattern, modelID) {
+			if util.MatchWildcard(pattern, modelID) {
 				blocked = true
 				break
 			}
@@ -1109,50 +1143,6 @@ func applyModelPrefixes(models []*ModelInfo, prefix string, forceModelPrefix boo
 	return out
 }
 
-// matchWildcard performs case-insensitive wildcard matching where '*' matches any substring.
-func matchWildcard(pattern, value string) bool {
-	if pattern == "" {
-		return false
-	}
-
-	// Fast path for exact match (no wildcard present).
-	if !strings.Contains(pattern, "*") {
-		return pattern == value
-	}
-
-	parts := strings.Split(pattern, "*")
-	// Handle prefix.
-	if prefix := parts[0]; prefix != "" {
-		if !strings.HasPrefix(value, prefix) {
-			return false
-		}
-		value = value[len(prefix):]
-	}
-
-	// Handle suffix.
-	if suffix := parts[len(parts)-1]; suffix != "" {
-		if !strings.HasSuffix(value, suffix) {
-			return false
-		}
-		value = value[:len(value)-len(suffix)]
-	}
-
-	// Handle middle segments in order.
-	for i := 1; i < len(parts)-1; i++ {
-		segment := parts[i]
-		if segment == "" {
-			continue
-		}
-		idx := strings.Index(value, segment)
-		if idx < 0 {
-			return false
-		}
-		value = value[idx+len(segment):]
-	}
-
-	return true
-}
-
 type modelEntry interface {
 	GetName() string
 	GetAlias() string
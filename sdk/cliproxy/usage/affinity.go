@@ -0,0 +1,30 @@
+package usage
+
+import "context"
+
+// Session affinity outcomes reported in Record.SessionAffinity.
+const (
+	// AffinityHit marks a request served by the credential its session was pinned to.
+	AffinityHit = "hit"
+	// AffinityMiss marks a request whose session had no usable pinned credential.
+	AffinityMiss = "miss"
+)
+
+type affinityContextKey struct{}
+
+// WithSessionAffinity annotates ctx with the session affinity outcome of credential selection.
+func WithSessionAffinity(ctx context.Context, outcome string) context.Context {
+	if ctx == nil || outcome == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, affinityContextKey{}, outcome)
+}
+
+// SessionAffinityFromContext returns the outcome stored by WithSessionAffinity, if any.
+func SessionAffinityFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	outcome, _ := ctx.Value(affinityContextKey{}).(string)
+	return outcome
+}
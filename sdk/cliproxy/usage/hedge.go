@@ -0,0 +1,30 @@
+package usage
+
+import "context"
+
+// Hedged request roles reported in Record.Hedge.
+const (
+	// HedgePrimary marks the first attempt of a hedged request.
+	HedgePrimary = "primary"
+	// HedgeSecondary marks the attempt launched because the primary was slow to respond.
+	HedgeSecondary = "hedge"
+)
+
+type hedgeContextKey struct{}
+
+// WithHedgeRole annotates ctx with the role of an attempt within a hedged request.
+func WithHedgeRole(ctx context.Context, role string) context.Context {
+	if ctx == nil || role == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, hedgeContextKey{}, role)
+}
+
+// HedgeRoleFromContext returns the role stored by WithHedgeRole, if any.
+func HedgeRoleFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	role, _ := ctx.Value(hedgeContextKey{}).(string)
+	return role
+}
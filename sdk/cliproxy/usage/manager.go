@@ -18,16 +18,25 @@ type Record struct {
 	Source      string
 	RequestedAt time.Time
 	Failed      bool
-	Detail      Detail
+	// SessionAffinity is AffinityHit or AffinityMiss when session affinity routed the request.
+	SessionAffinity string
+	// Hedge is HedgePrimary or HedgeSecondary when the request was hedged.
+	Hedge string
+	// Variant names the traffic split variant the request was routed to.
+	Variant string
+	// Shadow is set for requests mirrored by a shadow traffic policy.
+	Shadow bool
+	Detail Detail
 }
 
 // Detail holds the token usage breakdown.
 type Detail struct {
-	InputTokens     int64
-	OutputTokens    int64
-	ReasoningTokens int64
-	CachedTokens    int64
-	TotalTokens     int64
+	InputTokens      int64
+	OutputTokens     int64
+	ReasoningTokens  int64
+	CachedTokens     int64
+	CacheWriteTokens int64
+	TotalTokens      int64
 }
 
 // Plugin consumes usage records emitted by the proxy runtime.
@@ -109,6 +118,10 @@ func (m *Manager) Publish(ctx context.Context, record Record) {
 	if m == nil {
 		return
 	}
+	if record.Shadow {
+		// Recorded synchronously so the mirror sees its usage as soon as the request ends.
+		addShadowUsage(ctx, record.Detail)
+	}
 	// ensure worker is running even if Start was not called explicitly
 	m.Start(context.Background())
 	m.mu.Lock()
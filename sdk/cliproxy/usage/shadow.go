@@ -0,0 +1,64 @@
+package usage
+
+import (
+	"context"
+	"sync"
+)
+
+type shadowContextKey struct{}
+
+// shadowState accumulates the token usage published for a mirrored request.
+type shadowState struct {
+	mu     sync.Mutex
+	detail Detail
+}
+
+// WithShadow marks ctx as belonging to a request mirrored by a shadow traffic policy.
+// Token usage published with the returned context is available through ShadowUsage.
+func WithShadow(ctx context.Context) context.Context {
+	if ctx == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, shadowContextKey{}, &shadowState{})
+}
+
+// IsShadow reports whether ctx was marked by WithShadow.
+func IsShadow(ctx context.Context) bool {
+	_, ok := shadowStateFromContext(ctx)
+	return ok
+}
+
+// ShadowUsage returns the token usage published so far for the mirrored request of ctx.
+func ShadowUsage(ctx context.Context) Detail {
+	state, ok := shadowStateFromContext(ctx)
+	if !ok {
+		return Detail{}
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return state.detail
+}
+
+// addShadowUsage adds detail to the usage of the mirrored request of ctx, if any.
+func addShadowUsage(ctx context.Context, detail Detail) {
+	state, ok := shadowStateFromContext(ctx)
+	if !ok {
+		return
+	}
+	state.mu.Lock()
+	state.detail.InputTokens += detail.InputTokens
+	state.detail.OutputTokens += detail.OutputTokens
+	state.detail.ReasoningTokens += detail.ReasoningTokens
+	state.detail.CachedTokens += detail.CachedTokens
+	state.detail.CacheWriteTokens += detail.CacheWriteTokens
+	state.detail.TotalTokens += detail.TotalTokens
+	state.mu.Unlock()
+}
+
+func shadowStateFromContext(ctx context.Context) (*shadowState, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	state, ok := ctx.Value(shadowContextKey{}).(*shadowState)
+	return state, ok && state != nil
+}
@@ -0,0 +1,22 @@
+package usage
+
+import "context"
+
+type trafficVariantContextKey struct{}
+
+// WithTrafficVariant annotates ctx with the traffic split variant chosen for a request.
+func WithTrafficVariant(ctx context.Context, variant string) context.Context {
+	if ctx == nil || variant == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, trafficVariantContextKey{}, variant)
+}
+
+// TrafficVariantFromContext returns the variant stored by WithTrafficVariant, if any.
+func TrafficVariantFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	variant, _ := ctx.Value(trafficVariantContextKey{}).(string)
+	return variant
+}
@@ -0,0 +1,47 @@
+package cliproxy
+
+import (
+	"context"
+	"sync"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+// managerUsageFeed feeds token usage back into the auth managers of live services so
+// per-credential TPM limits are enforced. It is registered with the usage pipeline once per
+// process; services attach their manager when built and detach it on shutdown.
+type managerUsageFeed struct {
+	mu       sync.RWMutex
+	managers map[*coreauth.Manager]struct{}
+}
+
+var (
+	usageFeed         = &managerUsageFeed{managers: make(map[*coreauth.Manager]struct{})}
+	usageFeedRegister sync.Once
+)
+
+func attachUsageFeed(manager *coreauth.Manager) {
+	if manager == nil {
+		return
+	}
+	usageFeedRegister.Do(func() { usage.RegisterPlugin(usageFeed) })
+	usageFeed.mu.Lock()
+	usageFeed.managers[manager] = struct{}{}
+	usageFeed.mu.Unlock()
+}
+
+func detachUsageFeed(manager *coreauth.Manager) {
+	usageFeed.mu.Lock()
+	delete(usageFeed.managers, manager)
+	usageFeed.mu.Unlock()
+}
+
+// HandleUsage implements usage.Plugin.
+func (f *managerUsageFeed) HandleUsage(ctx context.Context, record usage.Record) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for manager := range f.managers {
+		manager.HandleUsage(ctx, record)
+	}
+}
@@ -9,14 +9,31 @@ import internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 type SDKConfig = internalconfig.SDKConfig
 type AccessConfig = internalconfig.AccessConfig
 type AccessProvider = internalconfig.AccessProvider
+type ClientKey = internalconfig.ClientKey
 
 type Config = internalconfig.Config
 
 type StreamingConfig = internalconfig.StreamingConfig
+type ResponsesStoreConfig = internalconfig.ResponsesStoreConfig
+type SessionAffinityConfig = internalconfig.SessionAffinityConfig
+type TrafficSplit = internalconfig.TrafficSplit
+type TrafficSplitVariant = internalconfig.TrafficSplitVariant
 type TLSConfig = internalconfig.TLSConfig
+type MetricsConfig = internalconfig.MetricsConfig
+type TracingConfig = internalconfig.TracingConfig
+type UsageStoreConfig = internalconfig.UsageStoreConfig
+type ModelPrice = internalconfig.ModelPrice
 type RemoteManagement = internalconfig.RemoteManagement
+type ManagementToken = internalconfig.ManagementToken
+type ConfigVersion = internalconfig.ConfigVersion
 type AmpCode = internalconfig.AmpCode
 type OAuthModelAlias = internalconfig.OAuthModelAlias
+type VirtualModel = internalconfig.VirtualModel
+type HedgePolicy = internalconfig.HedgePolicy
+type ShadowPolicy = internalconfig.ShadowPolicy
+type AvailabilitySchedule = internalconfig.AvailabilitySchedule
+type CircuitBreakerConfig = internalconfig.CircuitBreakerConfig
+type CooldownQueueConfig = internalconfig.CooldownQueueConfig
 type PayloadConfig = internalconfig.PayloadConfig
 type PayloadRule = internalconfig.PayloadRule
 type PayloadFilterRule = internalconfig.PayloadFilterRule
@@ -35,8 +52,11 @@ type TLS = internalconfig.TLSConfig
 
 const (
 	AccessProviderTypeConfigAPIKey = internalconfig.AccessProviderTypeConfigAPIKey
+	AccessProviderTypeJWT          = internalconfig.AccessProviderTypeJWT
 	DefaultAccessProviderName      = internalconfig.DefaultAccessProviderName
 	DefaultPanelGitHubRepository   = internalconfig.DefaultPanelGitHubRepository
+	TrafficSplitByClientKey        = internalconfig.TrafficSplitByClientKey
+	TrafficSplitBySession          = internalconfig.TrafficSplitBySession
 )
 
 func MakeInlineAPIKeyProvider(keys []string) *AccessProvider {